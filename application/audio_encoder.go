@@ -0,0 +1,58 @@
+package application
+
+import (
+	"fmt"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+)
+
+// EncoderChain encodes with the first encoder that supports the format and the effect, so in process encoders are
+// preferred and external programs are only needed for what nothing else can write.
+type EncoderChain struct {
+	encoders []domain.AudioEncoder
+}
+
+func NewEncoderChain(encoders ...domain.AudioEncoder) *EncoderChain {
+	return &EncoderChain{encoders: encoders}
+}
+
+func (c *EncoderChain) Encode(inputs []string, output string, options domain.EncodeOptions) error {
+	if encoder, ok := c.pick(options); ok {
+		return encoder.Encode(inputs, output, options)
+	}
+	return fmt.Errorf("no encoder available for %s with effect %s", options.Format, options.Effect.Name())
+}
+
+func (c *EncoderChain) Stream(output string, options domain.EncodeOptions) (domain.AudioStream, error) {
+	if encoder, ok := c.pick(options); ok {
+		return encoder.Stream(output, options)
+	}
+	return nil, fmt.Errorf("no encoder available for %s with effect %s", options.Format, options.Effect.Name())
+}
+
+func (c *EncoderChain) Supports(format domain.AudioFormat) bool {
+	for _, encoder := range c.encoders {
+		if encoder.Supports(format) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *EncoderChain) SupportsEffect(effect domain.VoiceEffect) bool {
+	for _, encoder := range c.encoders {
+		if encoder.SupportsEffect(effect) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *EncoderChain) pick(options domain.EncodeOptions) (domain.AudioEncoder, bool) {
+	for _, encoder := range c.encoders {
+		if encoder.Supports(options.Format) && encoder.SupportsEffect(options.Effect) {
+			return encoder, true
+		}
+	}
+	return nil, false
+}
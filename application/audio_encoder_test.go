@@ -0,0 +1,58 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEncoderChain_Encode(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        domain.AudioFormat
+		effect        domain.VoiceEffect
+		expectedError bool
+		inProcessUsed bool
+		externalUsed  bool
+	}{
+		{name: "prefers the first encoder supporting the format", format: domain.AudioFormatOpus, inProcessUsed: true},
+		{name: "falls back to the next encoder", format: domain.AudioFormatMP3, externalUsed: true},
+		{name: "when no encoder supports the format, return error", format: domain.AudioFormatM4A, expectedError: true},
+		{name: "skips encoders that cannot render the effect", format: domain.AudioFormatOpus, effect: domain.VoiceEffectEcho, externalUsed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inProcess := &domainmocks.AudioEncoder{}
+			inProcess.On("Supports", domain.AudioFormatOpus).Return(true)
+			inProcess.On("Supports", mock.Anything).Return(false)
+			inProcess.On("SupportsEffect", domain.VoiceEffectNone).Return(true)
+			inProcess.On("SupportsEffect", mock.Anything).Return(false)
+			inProcess.On("Encode", mock.Anything, "out", mock.Anything).Return(nil)
+			external := &domainmocks.AudioEncoder{}
+			external.On("Supports", domain.AudioFormatMP3).Return(true)
+			external.On("Supports", domain.AudioFormatOpus).Return(true)
+			external.On("Supports", mock.Anything).Return(false)
+			external.On("SupportsEffect", mock.Anything).Return(true)
+			external.On("Encode", mock.Anything, "out", mock.Anything).Return(nil)
+
+			chain := NewEncoderChain(inProcess, external)
+			err := chain.Encode([]string{"in"}, "out", domain.EncodeOptions{Format: tt.format, Effect: tt.effect})
+
+			assert.Equal(t, tt.expectedError, err != nil)
+			assert.Equal(t, !tt.expectedError, chain.Supports(tt.format))
+			if tt.inProcessUsed {
+				inProcess.AssertNumberOfCalls(t, "Encode", 1)
+			} else {
+				inProcess.AssertNumberOfCalls(t, "Encode", 0)
+			}
+			if tt.externalUsed {
+				external.AssertNumberOfCalls(t, "Encode", 1)
+			} else {
+				external.AssertNumberOfCalls(t, "Encode", 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+)
+
+// streamBufferPackets bounds the packets waiting to be encoded, 10 seconds of 20ms packets
+const streamBufferPackets = 500
+
+var (
+	errStreamBehind  = errors.New("encoder fell behind the recording")
+	errNoStreamSlots = errors.New("too many recordings encoding at once")
+)
+
+// streamSlots bound the encoding streams running at once. Streams run outside of the job queue for as long as their
+// recording lasts, so they are not queued, recordings without a free slot are encoded once they end instead
+type streamSlots chan struct{}
+
+func newStreamSlots(size int) streamSlots {
+	if size < 1 {
+		size = 1
+	}
+	return make(streamSlots, size)
+}
+
+func (s streamSlots) acquire() bool {
+	select {
+	case s <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s streamSlots) release() {
+	<-s
+}
+
+// encodingStream hands the packets of a recording to an encoder stream in the background, so the audio file is ready
+// as soon as the recording ends. Once the encoder falls behind or fails, the packets are dropped and finish
+// returns the error, the recording can still be encoded from its ogg file.
+type encodingStream struct {
+	packets chan *discord.Packet
+	done    chan error
+	// err is only touched by the goroutine writing the packets
+	err error
+}
+
+// startEncodingStream takes one of the slots until the stream is finished, failing when none is free
+func startEncodingStream(encoder domain.AudioEncoder, output string, options domain.EncodeOptions, slots streamSlots) (*encodingStream, error) {
+	if !slots.acquire() {
+		return nil, errNoStreamSlots
+	}
+	stream, err := encoder.Stream(output, options)
+	if err != nil {
+		slots.release()
+		return nil, fmt.Errorf("err starting encoding stream, %w", err)
+	}
+	s := &encodingStream{
+		packets: make(chan *discord.Packet, streamBufferPackets),
+		done:    make(chan error, 1),
+	}
+	go s.run(stream, slots)
+	return s, nil
+}
+
+func (s *encodingStream) run(stream domain.AudioStream, slots streamSlots) {
+	defer slots.release()
+	var err error
+	for p := range s.packets {
+		if err != nil {
+			continue
+		}
+		if err = stream.Write(p); err != nil {
+			err = fmt.Errorf("err encoding packet, %w", err)
+		}
+	}
+	if closeErr := stream.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("err finishing encoding stream, %w", closeErr)
+	}
+	s.done <- err
+}
+
+// write never blocks the recording, a full buffer abandons the stream
+func (s *encodingStream) write(p *discord.Packet) {
+	if s.err != nil {
+		return
+	}
+	select {
+	case s.packets <- p:
+	default:
+		s.err = errStreamBehind
+		close(s.packets)
+	}
+}
+
+// finish waits for the encoder to write the whole file and returns why it could not, if it did not
+func (s *encodingStream) finish() error {
+	if s.err == nil {
+		close(s.packets)
+	}
+	err := <-s.done
+	if s.err != nil {
+		return s.err
+	}
+	return err
+}
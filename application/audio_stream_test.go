@@ -0,0 +1,134 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEncodingStream_finish(t *testing.T) {
+	tests := []struct {
+		name          string
+		packets       int
+		expectedError error
+		on            func(stream *domainmocks.AudioStream, release chan struct{})
+		assertMocks   func(t *testing.T, stream *domainmocks.AudioStream)
+	}{
+		{
+			name:    "writes every packet and closes the stream",
+			packets: 3,
+			on: func(stream *domainmocks.AudioStream, release chan struct{}) {
+				stream.On("Write", mock.Anything).Return(nil)
+				stream.On("Close").Return(nil)
+			},
+			assertMocks: func(t *testing.T, stream *domainmocks.AudioStream) {
+				stream.AssertNumberOfCalls(t, "Write", 3)
+				stream.AssertNumberOfCalls(t, "Close", 1)
+			},
+		},
+		{
+			name:          "when encoding fails, stop writing and still close the stream",
+			packets:       3,
+			expectedError: errors.New("err encoding packet, err ffmpeg"),
+			on: func(stream *domainmocks.AudioStream, release chan struct{}) {
+				stream.On("Write", mock.Anything).Return(errors.New("err ffmpeg"))
+				stream.On("Close").Return(nil)
+			},
+			assertMocks: func(t *testing.T, stream *domainmocks.AudioStream) {
+				stream.AssertNumberOfCalls(t, "Write", 1)
+				stream.AssertNumberOfCalls(t, "Close", 1)
+			},
+		},
+		{
+			name:          "when finishing the file fails, return error",
+			packets:       1,
+			expectedError: errors.New("err finishing encoding stream, err disk"),
+			on: func(stream *domainmocks.AudioStream, release chan struct{}) {
+				stream.On("Write", mock.Anything).Return(nil)
+				stream.On("Close").Return(errors.New("err disk"))
+			},
+		},
+		{
+			name:          "when encoder falls behind, abandon the stream without blocking the recording",
+			packets:       streamBufferPackets + 2,
+			expectedError: errStreamBehind,
+			on: func(stream *domainmocks.AudioStream, release chan struct{}) {
+				stream.On("Write", mock.Anything).Return(nil).Run(func(mock.Arguments) { <-release })
+				stream.On("Close").Return(nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &domainmocks.AudioStream{}
+			release := make(chan struct{})
+			tt.on(stream, release)
+			encoder := &domainmocks.AudioEncoder{}
+			encoder.On("Stream", "out.mp3", mock.Anything).Return(stream, nil)
+
+			slots := newStreamSlots(1)
+			s, err := startEncodingStream(encoder, "out.mp3", domain.EncodeOptions{Format: domain.AudioFormatMP3}, slots)
+			assert.NoError(t, err)
+			for i := 0; i < tt.packets; i++ {
+				s.write(&discord.Packet{Opus: []byte{0xf8}})
+			}
+			close(release)
+			err = s.finish()
+
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, stream)
+			}
+			assert.Empty(t, slots, "finishing the stream frees its slot")
+		})
+	}
+}
+
+func Test_startEncodingStream(t *testing.T) {
+	tests := []struct {
+		name            string
+		slots           int
+		taken           int
+		streamErr       error
+		expectedError   error
+		expectedStreams int
+		expectedTaken   int
+	}{
+		{name: "when a slot is free, start the stream and take it", slots: 2, taken: 1, expectedStreams: 1, expectedTaken: 2},
+		{name: "when every slot is taken, do not start the stream", slots: 2, taken: 2, expectedError: errNoStreamSlots, expectedTaken: 2},
+		{name: "when starting the stream fails, free the slot", slots: 1, streamErr: errors.New("err ffmpeg"), expectedError: errors.New("err starting encoding stream, err ffmpeg"), expectedStreams: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder := &domainmocks.AudioEncoder{}
+			var stream domain.AudioStream
+			if tt.streamErr == nil {
+				stream = &domainmocks.AudioStream{}
+			}
+			encoder.On("Stream", "out.mp3", mock.Anything).Return(stream, tt.streamErr)
+			slots := newStreamSlots(tt.slots)
+			for i := 0; i < tt.taken; i++ {
+				slots.acquire()
+			}
+
+			_, err := startEncodingStream(encoder, "out.mp3", domain.EncodeOptions{Format: domain.AudioFormatMP3}, slots)
+
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			encoder.AssertNumberOfCalls(t, "Stream", tt.expectedStreams)
+			assert.Len(t, slots, tt.expectedTaken)
+		})
+	}
+}
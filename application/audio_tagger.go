@@ -0,0 +1,35 @@
+package application
+
+import (
+	"fmt"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+)
+
+// TaggerChain tags with the first tagger that supports the format, so in process taggers are preferred
+// and external programs are only needed for what nothing else can write.
+type TaggerChain struct {
+	taggers []domain.AudioTagger
+}
+
+func NewTaggerChain(taggers ...domain.AudioTagger) *TaggerChain {
+	return &TaggerChain{taggers: taggers}
+}
+
+func (c *TaggerChain) Tag(path string, format domain.AudioFormat, metadata domain.AudioMetadata) error {
+	for _, tagger := range c.taggers {
+		if tagger.Supports(format) {
+			return tagger.Tag(path, format, metadata)
+		}
+	}
+	return fmt.Errorf("no tagger available for %s", format)
+}
+
+func (c *TaggerChain) Supports(format domain.AudioFormat) bool {
+	for _, tagger := range c.taggers {
+		if tagger.Supports(format) {
+			return true
+		}
+	}
+	return false
+}
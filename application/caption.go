@@ -0,0 +1,90 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/hectorgabucio/taterubot-dc/kit/command"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+)
+
+const CaptionCommandType command.Type = "command.caption"
+
+type CaptionCommand struct {
+	InteractionToken string
+	GuildID          string
+	UserID           string
+	Caption          string
+}
+
+func NewCaptionCommand(interactionToken string, guildID string, userID string, caption string) CaptionCommand {
+	return CaptionCommand{
+		InteractionToken: interactionToken,
+		GuildID:          guildID,
+		UserID:           userID,
+		Caption:          caption,
+	}
+}
+
+func (c CaptionCommand) Type() command.Type {
+	return CaptionCommandType
+}
+
+type CaptionCommandHandler struct {
+	service *CaptionSetter
+}
+
+// NewCaptionCommandHandler initializes a new CaptionCommandHandler.
+func NewCaptionCommandHandler(service *CaptionSetter) CaptionCommandHandler {
+	return CaptionCommandHandler{
+		service: service,
+	}
+}
+
+// Handle implements the command.Handler interface.
+func (h CaptionCommandHandler) Handle(ctx context.Context, cmd command.Command) error {
+	captionCmd, ok := cmd.(CaptionCommand)
+	if !ok {
+		return errors.New("unexpected command")
+	}
+	return h.service.set(captionCmd)
+}
+
+// CaptionSetter keeps the caption the next recording of a user is titled with
+type CaptionSetter struct {
+	discordClient   discord.Client
+	localization    *localizations.Localizer
+	pendingCaptions domain.PendingCaptionRepository
+}
+
+func NewCaptionSetter(discord discord.Client, localization *localizations.Localizer, pendingCaptions domain.PendingCaptionRepository) *CaptionSetter {
+	return &CaptionSetter{
+		discordClient:   discord,
+		localization:    localization,
+		pendingCaptions: pendingCaptions,
+	}
+}
+
+func (service *CaptionSetter) set(cmd CaptionCommand) error {
+	caption := strings.Join(strings.Fields(cmd.Caption), " ")
+	if caption == "" {
+		return service.reply(cmd.InteractionToken, "texts.caption_empty")
+	}
+	if utf8.RuneCountInString(caption) > domain.MaxCaptionLength {
+		return service.reply(cmd.InteractionToken, "texts.caption_too_long", &localizations.Replacements{"max": domain.MaxCaptionLength})
+	}
+	service.pendingCaptions.SetPending(cmd.GuildID, cmd.UserID, caption)
+	return service.reply(cmd.InteractionToken, "texts.caption_set", &localizations.Replacements{"caption": caption})
+}
+
+func (service *CaptionSetter) reply(interactionToken string, key string, replacements ...*localizations.Replacements) error {
+	if err := service.discordClient.EditInteraction(interactionToken, service.localization.Get(key, replacements...)); err != nil {
+		return fmt.Errorf("err sending interaction response, %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package application
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCaptionSetter_set(t *testing.T) {
+	type fields struct {
+		discordClient   *discordmocks.Client
+		pendingCaptions *domainmocks.PendingCaptionRepository
+	}
+	tests := []struct {
+		name          string
+		cmd           CaptionCommand
+		expectedError bool
+		on            func(*fields)
+		assertMocks   func(t *testing.T, f *fields)
+	}{
+		{
+			name: "when caption is blank, tell it without keeping it",
+			cmd:  NewCaptionCommand("token", "guild", "user", "  \n "),
+			on: func(fields *fields) {
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.pendingCaptions.AssertNumberOfCalls(t, "SetPending", 0)
+			},
+		},
+		{
+			name: "when caption is too long, tell it without keeping it",
+			cmd:  NewCaptionCommand("token", "guild", "user", strings.Repeat("ñ", 101)),
+			on: func(fields *fields) {
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.pendingCaptions.AssertNumberOfCalls(t, "SetPending", 0)
+			},
+		},
+		{
+			name: "keep the caption in a single line and confirm it",
+			cmd:  NewCaptionCommand("token", "guild", "user", " Road\ntrip   notes "),
+			on: func(fields *fields) {
+				fields.pendingCaptions.On("SetPending", "guild", "user", "Road trip notes").Return()
+				fields.discordClient.On("EditInteraction", "token", mock.MatchedBy(func(message string) bool {
+					return strings.Contains(message, "Road trip notes")
+				})).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.pendingCaptions.AssertNumberOfCalls(t, "SetPending", 1)
+			},
+		},
+		{
+			name:          "when reply fails, return error",
+			cmd:           NewCaptionCommand("token", "guild", "user", "notes"),
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.pendingCaptions.On("SetPending", "guild", "user", "notes").Return()
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(errors.New("err discord"))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := fields{discordClient: &discordmocks.Client{}, pendingCaptions: &domainmocks.PendingCaptionRepository{}}
+			service := &CaptionSetter{
+				discordClient:   fields.discordClient,
+				localization:    localizations.New("en", "en"),
+				pendingCaptions: fields.pendingCaptions,
+			}
+			if tt.on != nil {
+				tt.on(&fields)
+			}
+			err := service.set(tt.cmd)
+			assert.Equal(t, tt.expectedError, err != nil)
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/hectorgabucio/taterubot-dc/kit/command"
+	"github.com/hectorgabucio/taterubot-dc/kit/event"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+)
+
+const DeliveriesCommandType command.Type = "command.deliveries"
+
+// actions of the deliveries command
+const (
+	DeliveriesActionList   = "list"
+	DeliveriesActionReplay = "replay"
+)
+
+const (
+	// maxListedDeliveries keeps the list under the message length limit
+	maxListedDeliveries = 10
+	// maxDeliveryErrorLength is how much of the error of every delivery is listed
+	maxDeliveryErrorLength = 100
+)
+
+type DeliveriesCommand struct {
+	InteractionToken string
+	GuildID          string
+	Action           string
+	DeliveryID       string
+}
+
+func NewDeliveriesCommand(interactionToken string, guildID string, action string, deliveryID string) DeliveriesCommand {
+	return DeliveriesCommand{
+		InteractionToken: interactionToken,
+		GuildID:          guildID,
+		Action:           action,
+		DeliveryID:       deliveryID,
+	}
+}
+
+func (c DeliveriesCommand) Type() command.Type {
+	return DeliveriesCommandType
+}
+
+type DeliveriesCommandHandler struct {
+	service *DeliveryReplayer
+}
+
+// NewDeliveriesCommandHandler initializes a new DeliveriesCommandHandler.
+func NewDeliveriesCommandHandler(service *DeliveryReplayer) DeliveriesCommandHandler {
+	return DeliveriesCommandHandler{
+		service: service,
+	}
+}
+
+// Handle implements the command.Handler interface.
+func (h DeliveriesCommandHandler) Handle(ctx context.Context, cmd command.Command) error {
+	deliveriesCmd, ok := cmd.(DeliveriesCommand)
+	if !ok {
+		return errors.New("unexpected command")
+	}
+	switch deliveriesCmd.Action {
+	case DeliveriesActionList:
+		return h.service.list(deliveriesCmd)
+	case DeliveriesActionReplay:
+		return h.service.replay(deliveriesCmd)
+	default:
+		return fmt.Errorf("unknown deliveries action %s", deliveriesCmd.Action)
+	}
+}
+
+// DeliveryReplayer lets admins see what could not be delivered to discord and send it again. Replays of the audio of a
+// recording publish its domain.AudioSentEvent with the message they posted, as the delivery would have done.
+// The client should not keep failed deliveries, the replay would be kept twice.
+type DeliveryReplayer struct {
+	discordClient discord.Client
+	localization  *localizations.Localizer
+	deadLetters   domain.DeadLetterRepository
+	eventBus      event.Bus
+}
+
+func NewDeliveryReplayer(discord discord.Client, localization *localizations.Localizer, deadLetters domain.DeadLetterRepository, eventBus event.Bus) *DeliveryReplayer {
+	return &DeliveryReplayer{
+		discordClient: discord,
+		localization:  localization,
+		deadLetters:   deadLetters,
+		eventBus:      eventBus,
+	}
+}
+
+func (service *DeliveryReplayer) list(cmd DeliveriesCommand) error {
+	deliveries, err := service.deadLetters.ListByGuild(cmd.GuildID)
+	if err != nil {
+		return fmt.Errorf("err listing failed deliveries, %w", err)
+	}
+	if len(deliveries) == 0 {
+		return service.reply(cmd.InteractionToken, "texts.deliveries_empty")
+	}
+	var lines []string
+	for i, delivery := range deliveries {
+		if i == maxListedDeliveries {
+			lines = append(lines, service.localization.Get("texts.deliveries_more", &localizations.Replacements{"count": len(deliveries) - i}))
+			break
+		}
+		reason := delivery.Error
+		if len(reason) > maxDeliveryErrorLength {
+			reason = reason[:maxDeliveryErrorLength] + "…"
+		}
+		lines = append(lines, fmt.Sprintf("`%s` %s <#%s> <t:%d:R>: %s", delivery.ID, delivery.Kind, delivery.ChannelID, delivery.FailedAt.Unix(), reason))
+	}
+	return service.reply(cmd.InteractionToken, "texts.deliveries_list", &localizations.Replacements{"deliveries": strings.Join(lines, "\n")})
+}
+
+func (service *DeliveryReplayer) replay(cmd DeliveriesCommand) error {
+	delivery, found, err := service.deadLetters.Get(strings.TrimSpace(cmd.DeliveryID))
+	if err != nil {
+		return fmt.Errorf("err getting failed delivery, %w", err)
+	}
+	// deliveries of other guilds are not for their admins to see
+	if !found || delivery.GuildID != cmd.GuildID {
+		return service.reply(cmd.InteractionToken, "texts.delivery_not_found")
+	}
+	sent, err := service.deliver(delivery)
+	if err != nil {
+		return service.reply(cmd.InteractionToken, "texts.delivery_replay_failed", &localizations.Replacements{"error": err.Error()})
+	}
+	if delivery.AudioSent != nil {
+		service.publishAudioSent(*delivery.AudioSent, delivery.ChannelID, sent)
+	}
+	if err := service.deadLetters.Delete(delivery.ID); err != nil {
+		return fmt.Errorf("err deleting replayed delivery, %w", err)
+	}
+	return service.reply(cmd.InteractionToken, "texts.delivery_replayed")
+}
+
+// deliver sends the delivery again, returning the message it posted, empty for the deliveries that post none
+func (service *DeliveryReplayer) deliver(delivery domain.FailedDelivery) (discord.Message, error) {
+	switch delivery.Kind {
+	case domain.DeliveryKindText:
+		return discord.Message{}, service.discordClient.SendTextMessage(delivery.ChannelID, delivery.Content)
+	case domain.DeliveryKindFile:
+		return service.discordClient.SendFileReply(delivery.ChannelID, delivery.MessageID, delivery.Content, delivery.FileName, delivery.ContentType, bytes.NewReader(delivery.File))
+	case domain.DeliveryKindVoice:
+		return service.discordClient.SendVoiceMessage(delivery.ChannelID, delivery.FileName, bytes.NewReader(delivery.File), delivery.Voice)
+	case domain.DeliveryKindReplaceFile:
+		return service.discordClient.ReplaceFileMessage(delivery.ChannelID, delivery.MessageID, delivery.FileName, delivery.ContentType, bytes.NewReader(delivery.File))
+	case domain.DeliveryKindEmbed:
+		return discord.Message{}, service.discordClient.SetEmbeds(delivery.ChannelID, delivery.MessageID, delivery.EmbedsWithImages(), delivery.Buttons)
+	default:
+		return discord.Message{}, fmt.Errorf("unknown delivery kind %s", delivery.Kind)
+	}
+}
+
+// publishAudioSent publishes the event of the recording the replay delivered, about the message it posted
+func (service *DeliveryReplayer) publishAudioSent(evt domain.AudioSentEvent, channelID string, sent discord.Message) {
+	evt.MAggregateID = sent.ID
+	evt.ChannelID = channelID
+	evt.AttachmentID = sent.AttachmentID
+	evt.AttachmentURL = sent.AttachmentURL
+	go func() {
+		if err := service.eventBus.Publish(context.Background(), []event.Event{evt}); err != nil {
+			log.Println("err publishing audio sent event of replayed delivery", err)
+		}
+	}()
+}
+
+// keepAudioSent keeps the event of the recording along its delivery when the delivery was dead lettered, so its replay
+// publishes it. Deliveries that were not kept are lost, and so is the event.
+func keepAudioSent(deadLetters domain.DeadLetterRepository, err error, evt domain.AudioSentEvent) {
+	var deadLettered *domain.DeadLetteredError
+	if !errors.As(err, &deadLettered) {
+		return
+	}
+	if err := deadLetters.SetAudioSent(deadLettered.DeliveryID, evt); err != nil {
+		log.Println("err keeping the recording of failed delivery, its replay will not be recorded:", err)
+	}
+}
+
+func (service *DeliveryReplayer) reply(interactionToken string, key string, replacements ...*localizations.Replacements) error {
+	if err := service.discordClient.EditInteraction(interactionToken, service.localization.Get(key, replacements...)); err != nil {
+		return fmt.Errorf("err sending interaction response, %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/hectorgabucio/taterubot-dc/kit/event"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDeliveryReplayer_list(t *testing.T) {
+	type fields struct {
+		discordClient *discordmocks.Client
+		deadLetters   *domainmocks.DeadLetterRepository
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		expectedError bool
+		on            func(*fields)
+		assertMocks   func(t *testing.T, f *fields)
+	}{
+		{
+			name:          "when listing fails, return error",
+			fields:        fields{discordClient: &discordmocks.Client{}, deadLetters: &domainmocks.DeadLetterRepository{}},
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.deadLetters.On("ListByGuild", "guild").Return(nil, errors.New("err db"))
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertNumberOfCalls(t, "EditInteraction", 0)
+			},
+		},
+		{
+			name:   "when there are no failed deliveries, tell it",
+			fields: fields{discordClient: &discordmocks.Client{}, deadLetters: &domainmocks.DeadLetterRepository{}},
+			on: func(fields *fields) {
+				fields.deadLetters.On("ListByGuild", "guild").Return([]domain.FailedDelivery{}, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.MatchedBy(func(message string) bool {
+					return strings.Contains(message, "nothing to replay")
+				})).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertExpectations(t)
+			},
+		},
+		{
+			name:   "when there are failed deliveries, list them with their ids",
+			fields: fields{discordClient: &discordmocks.Client{}, deadLetters: &domainmocks.DeadLetterRepository{}},
+			on: func(fields *fields) {
+				fields.deadLetters.On("ListByGuild", "guild").Return([]domain.FailedDelivery{
+					{ID: "first", Kind: domain.DeliveryKindVoice, ChannelID: "channel", Error: "err 500", FailedAt: time.Now()},
+					{ID: "second", Kind: domain.DeliveryKindEmbed, ChannelID: "channel", Error: strings.Repeat("x", 300), FailedAt: time.Now()},
+				}, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.MatchedBy(func(message string) bool {
+					return strings.Contains(message, "`first` voice <#channel>") && strings.Contains(message, "`second` embed") && len(message) < 400
+				})).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertExpectations(t)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &DeliveryReplayer{
+				discordClient: tt.fields.discordClient,
+				localization:  localizations.New("en", "en"),
+				deadLetters:   tt.fields.deadLetters,
+			}
+			if tt.on != nil {
+				tt.on(&tt.fields)
+			}
+			err := service.list(NewDeliveriesCommand("token", "guild", DeliveriesActionList, ""))
+			assert.Equal(t, tt.expectedError, err != nil)
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &tt.fields)
+			}
+		})
+	}
+}
+
+func TestDeliveryReplayer_replay(t *testing.T) {
+	type fields struct {
+		discordClient *discordmocks.Client
+		deadLetters   *domainmocks.DeadLetterRepository
+	}
+	fileDelivery := domain.FailedDelivery{ID: "id", GuildID: "guild", Kind: domain.DeliveryKindFile, ChannelID: "channel", MessageID: "reply", FileName: "audio.mp3", ContentType: "audio/mpeg", File: []byte("audio")}
+	tests := []struct {
+		name          string
+		fields        fields
+		expectedError bool
+		on            func(*fields)
+		assertMocks   func(t *testing.T, f *fields)
+	}{
+		{
+			name:   "when delivery does not exist, tell it",
+			fields: fields{discordClient: &discordmocks.Client{}, deadLetters: &domainmocks.DeadLetterRepository{}},
+			on: func(fields *fields) {
+				fields.deadLetters.On("Get", "id").Return(domain.FailedDelivery{}, false, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertNumberOfCalls(t, "SendFileReply", 0)
+			},
+		},
+		{
+			name:   "when delivery is of another guild, tell it does not exist",
+			fields: fields{discordClient: &discordmocks.Client{}, deadLetters: &domainmocks.DeadLetterRepository{}},
+			on: func(fields *fields) {
+				other := fileDelivery
+				other.GuildID = "other"
+				fields.deadLetters.On("Get", "id").Return(other, true, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.MatchedBy(func(message string) bool {
+					return strings.Contains(message, "no failed delivery")
+				})).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertNumberOfCalls(t, "SendFileReply", 0)
+				f.deadLetters.AssertNumberOfCalls(t, "Delete", 0)
+			},
+		},
+		{
+			name:   "when replay fails again, keep the delivery",
+			fields: fields{discordClient: &discordmocks.Client{}, deadLetters: &domainmocks.DeadLetterRepository{}},
+			on: func(fields *fields) {
+				fields.deadLetters.On("Get", "id").Return(fileDelivery, true, nil)
+				fields.discordClient.On("SendFileReply", "channel", "reply", "", "audio.mp3", "audio/mpeg", mock.Anything).Return(discord.Message{}, errors.New("err 403"))
+				fields.discordClient.On("EditInteraction", "token", mock.MatchedBy(func(message string) bool {
+					return strings.Contains(message, "err 403")
+				})).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertExpectations(t)
+				f.deadLetters.AssertNumberOfCalls(t, "Delete", 0)
+			},
+		},
+		{
+			name:   "when replay is delivered, delete the delivery",
+			fields: fields{discordClient: &discordmocks.Client{}, deadLetters: &domainmocks.DeadLetterRepository{}},
+			on: func(fields *fields) {
+				fields.deadLetters.On("Get", "id").Return(fileDelivery, true, nil)
+				fields.discordClient.On("SendFileReply", "channel", "reply", "", "audio.mp3", "audio/mpeg", mock.Anything).Return(discord.Message{ID: "msg"}, nil)
+				fields.deadLetters.On("Delete", "id").Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.deadLetters.AssertExpectations(t)
+				f.discordClient.AssertNumberOfCalls(t, "EditInteraction", 1)
+			},
+		},
+		{
+			name:   "when replaying embeds, upload their images again",
+			fields: fields{discordClient: &discordmocks.Client{}, deadLetters: &domainmocks.DeadLetterRepository{}},
+			on: func(fields *fields) {
+				fields.deadLetters.On("Get", "id").Return(domain.FailedDelivery{
+					ID: "id", GuildID: "guild", Kind: domain.DeliveryKindEmbed, ChannelID: "channel", MessageID: "msg",
+					Embeds:      []discord.MessageEmbed{{Title: "first", Image: &discord.File{Name: "waveform.png"}}, {Title: "second"}},
+					EmbedImages: [][]byte{[]byte("png"), nil},
+				}, true, nil)
+				fields.discordClient.On("SetEmbeds", "channel", "msg", mock.MatchedBy(func(embeds []discord.MessageEmbed) bool {
+					if len(embeds) != 2 || embeds[0].Image == nil || embeds[1].Image != nil {
+						return false
+					}
+					image, err := io.ReadAll(embeds[0].Image.Reader)
+					return err == nil && string(image) == "png"
+				}), []discord.Button(nil)).Return(nil)
+				fields.deadLetters.On("Delete", "id").Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertExpectations(t)
+				f.deadLetters.AssertExpectations(t)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &DeliveryReplayer{
+				discordClient: tt.fields.discordClient,
+				localization:  localizations.New("en", "en"),
+				deadLetters:   tt.fields.deadLetters,
+			}
+			if tt.on != nil {
+				tt.on(&tt.fields)
+			}
+			err := service.replay(NewDeliveriesCommand("token", "guild", DeliveriesActionReplay, " id "))
+			assert.Equal(t, tt.expectedError, err != nil)
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &tt.fields)
+			}
+		})
+	}
+}
+
+func TestDeliveryReplayer_replay_audioSent(t *testing.T) {
+	discordClient := &discordmocks.Client{}
+	deadLetters := &domainmocks.DeadLetterRepository{}
+	bus := &publishedEvents{events: make(chan event.Event, 1)}
+	evt := domain.NewVoiceMessageSentEvent("", "user", "guild", "", "username", "avatar", "./tmp/username-1.ogg", "username-1", "", "", 12.7)
+	deadLetters.On("Get", "id").Return(domain.FailedDelivery{
+		ID: "id", GuildID: "guild", Kind: domain.DeliveryKindVoice, ChannelID: "channel", FileName: "voice-message.ogg", File: []byte("ogg"),
+		Voice: discord.VoiceMessage{DurationSecs: 12.7}, AudioSent: &evt,
+	}, true, nil)
+	discordClient.On("SendVoiceMessage", "channel", "voice-message.ogg", mock.Anything, discord.VoiceMessage{DurationSecs: 12.7}).
+		Return(discord.Message{ID: "msg", AttachmentID: "attachment", AttachmentURL: "url"}, nil)
+	deadLetters.On("Delete", "id").Return(nil)
+	discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+	service := &DeliveryReplayer{discordClient: discordClient, localization: localizations.New("en", "en"), deadLetters: deadLetters, eventBus: bus}
+
+	err := service.replay(NewDeliveriesCommand("token", "guild", DeliveriesActionReplay, "id"))
+
+	assert.NoError(t, err)
+	deadLetters.AssertExpectations(t)
+	select {
+	case published := <-bus.events:
+		sent, ok := published.(domain.AudioSentEvent)
+		if assert.True(t, ok) {
+			assert.Equal(t, "msg", sent.AggregateID())
+			assert.Equal(t, "channel", sent.ChannelID)
+			assert.Equal(t, "attachment", sent.AttachmentID)
+			assert.Equal(t, "url", sent.AttachmentURL)
+			assert.Equal(t, evt.ID(), sent.ID(), "the voice data keeps the id of the recording")
+			assert.True(t, sent.VoiceMessage)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("audio sent event was not published")
+	}
+}
+
+func Test_keepAudioSent(t *testing.T) {
+	evt := domain.NewAudioSentEvent("", "user", "guild", "", "username", "avatar", "./tmp/username-1.mp3", domain.AudioFormatMP3, "username-1", "", "", "", domain.ProcessReport{})
+	tests := []struct {
+		name        string
+		err         error
+		on          func(deadLetters *domainmocks.DeadLetterRepository)
+		expectedSet bool
+	}{
+		{name: "when delivery was dead lettered, keep the event along it", err: fmt.Errorf("err sending, %w", &domain.DeadLetteredError{DeliveryID: "id", Err: errors.New("err 500")}), expectedSet: true},
+		{name: "when delivery was not kept, there is nothing to keep the event along", err: errors.New("err opening file")},
+		{
+			name: "when event cannot be kept, go on",
+			err:  &domain.DeadLetteredError{DeliveryID: "id", Err: errors.New("err 500")},
+			on: func(deadLetters *domainmocks.DeadLetterRepository) {
+				deadLetters.On("SetAudioSent", "id", evt).Return(errors.New("err db"))
+			},
+			expectedSet: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deadLetters := &domainmocks.DeadLetterRepository{}
+			if tt.on != nil {
+				tt.on(deadLetters)
+			} else {
+				deadLetters.On("SetAudioSent", "id", evt).Return(nil)
+			}
+
+			keepAudioSent(deadLetters, tt.err, evt)
+
+			if tt.expectedSet {
+				deadLetters.AssertCalled(t, "SetAudioSent", "id", evt)
+			} else {
+				deadLetters.AssertNumberOfCalls(t, "SetAudioSent", 0)
+			}
+		})
+	}
+}
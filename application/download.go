@@ -0,0 +1,45 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+)
+
+func downloadFile(fsRepo domain.FileRepository, url, fileName string) error {
+	response, err := http.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download file, %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Println("error closing http response body", err)
+		}
+	}(response.Body)
+
+	if response.StatusCode != 200 {
+		return errors.New("received non 200 response code")
+	}
+	file, err := fsRepo.CreateEmpty(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to create empty file to write response, %w", err)
+	}
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			log.Println("error closing file", err)
+		}
+	}(file)
+	_, err = io.Copy(file, response.Body)
+	if err != nil {
+		return fmt.Errorf("failed to copy response body to file, %w", err)
+	}
+
+	return nil
+}
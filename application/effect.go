@@ -0,0 +1,293 @@
+package application
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/hectorgabucio/taterubot-dc/kit/command"
+	"github.com/hectorgabucio/taterubot-dc/kit/event"
+	"github.com/hectorgabucio/taterubot-dc/kit/job"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+)
+
+const EffectCommandType command.Type = "command.effect"
+
+const RemixCommandType command.Type = "command.remix"
+
+const EffectActionSet = "set"
+
+const (
+	// RemixComponentID is the custom id of the button that remixes a voice message with an effect
+	RemixComponentID = "remix"
+	// RemixEffectComponentID prefixes the custom id of the menu choosing the effect, followed by the message to remix
+	RemixEffectComponentID = "remix-effect"
+)
+
+type EffectCommand struct {
+	InteractionToken string
+	GuildID          string
+	UserID           string
+	Action           string
+	// Effect is the name of the effect, "none" to record without effects
+	Effect string
+}
+
+func NewEffectCommand(interactionToken string, guildID string, userID string, action string, effect string) EffectCommand {
+	return EffectCommand{
+		InteractionToken: interactionToken,
+		GuildID:          guildID,
+		UserID:           userID,
+		Action:           action,
+		Effect:           effect,
+	}
+}
+
+func (c EffectCommand) Type() command.Type {
+	return EffectCommandType
+}
+
+type RemixCommand struct {
+	InteractionToken string
+	GuildID          string
+	UserID           string
+	Username         string
+	AvatarURL        string
+	// ChannelID and MessageID identify the voice message to remix
+	ChannelID string
+	MessageID string
+	// Effect is the name of the effect to render, when empty the user is asked to choose one
+	Effect string
+}
+
+func NewRemixCommand(interactionToken string, guildID string, userID string, username string, avatarURL string, channelID string, messageID string, effect string) RemixCommand {
+	return RemixCommand{
+		InteractionToken: interactionToken,
+		GuildID:          guildID,
+		UserID:           userID,
+		Username:         username,
+		AvatarURL:        avatarURL,
+		ChannelID:        channelID,
+		MessageID:        messageID,
+		Effect:           effect,
+	}
+}
+
+func (c RemixCommand) Type() command.Type {
+	return RemixCommandType
+}
+
+// ParseRemixEffectComponentID returns the voice message the effect menu was shown for, false if the id is not one of them
+func ParseRemixEffectComponentID(customID string) (string, string, bool) {
+	name, args := discord.ParseCustomID(customID)
+	if name != RemixEffectComponentID || len(args) != 2 || args[0] == "" || args[1] == "" {
+		return "", "", false
+	}
+	return args[0], args[1], true
+}
+
+func remixEffectComponentID(channelID string, messageID string) (string, error) {
+	return discord.CustomID(RemixEffectComponentID, channelID, messageID)
+}
+
+type EffectCommandHandler struct {
+	service *EffectManager
+}
+
+// NewEffectCommandHandler initializes a new EffectCommandHandler.
+func NewEffectCommandHandler(service *EffectManager) EffectCommandHandler {
+	return EffectCommandHandler{
+		service: service,
+	}
+}
+
+// Handle implements the command.Handler interface.
+func (h EffectCommandHandler) Handle(ctx context.Context, cmd command.Command) error {
+	effectCmd, ok := cmd.(EffectCommand)
+	if !ok {
+		return errors.New("unexpected command")
+	}
+	switch effectCmd.Action {
+	case EffectActionSet:
+		return h.service.set(effectCmd)
+	default:
+		return fmt.Errorf("unknown effect action %s", effectCmd.Action)
+	}
+}
+
+type RemixCommandHandler struct {
+	service *EffectManager
+}
+
+// NewRemixCommandHandler initializes a new RemixCommandHandler.
+func NewRemixCommandHandler(service *EffectManager) RemixCommandHandler {
+	return RemixCommandHandler{
+		service: service,
+	}
+}
+
+// Handle implements the command.Handler interface.
+func (h RemixCommandHandler) Handle(ctx context.Context, cmd command.Command) error {
+	remixCmd, ok := cmd.(RemixCommand)
+	if !ok {
+		return errors.New("unexpected command")
+	}
+	if remixCmd.Effect == "" {
+		return h.service.chooseRemixEffect(remixCmd)
+	}
+	return h.service.remix(remixCmd)
+}
+
+type EffectManager struct {
+	discordClient     discord.Client
+	localization      *localizations.Localizer
+	effectRepo        domain.VoiceEffectRepository
+	guildSettingsRepo domain.GuildSettingsRepository
+	encoder           domain.AudioEncoder
+	fsRepo            domain.FileRepository
+	eventBus          event.Bus
+	jobs              job.Queue
+}
+
+func NewEffectManager(discord discord.Client, localization *localizations.Localizer, effectRepo domain.VoiceEffectRepository, guildSettingsRepo domain.GuildSettingsRepository, encoder domain.AudioEncoder, fsRepo domain.FileRepository, eventBus event.Bus, jobs job.Queue) *EffectManager {
+	return &EffectManager{
+		discordClient:     discord,
+		localization:      localization,
+		effectRepo:        effectRepo,
+		guildSettingsRepo: guildSettingsRepo,
+		encoder:           encoder,
+		fsRepo:            fsRepo,
+		eventBus:          eventBus,
+		jobs:              jobs,
+	}
+}
+
+func (service *EffectManager) set(cmd EffectCommand) error {
+	effect, ok := domain.ParseVoiceEffect(cmd.Effect)
+	if !ok {
+		return service.reply(cmd.InteractionToken, "texts.effect_unknown", &localizations.Replacements{"effect": cmd.Effect})
+	}
+	if !service.encoder.SupportsEffect(effect) {
+		return service.reply(cmd.InteractionToken, "texts.effect_unavailable")
+	}
+	if err := service.effectRepo.Save(cmd.GuildID, cmd.UserID, effect); err != nil {
+		return fmt.Errorf("err saving voice effect, %w", err)
+	}
+	if effect == domain.VoiceEffectNone {
+		return service.reply(cmd.InteractionToken, "texts.effect_cleared")
+	}
+	return service.reply(cmd.InteractionToken, "texts.effect_set", &localizations.Replacements{"effect": effect.Label()})
+}
+
+// chooseRemixEffect answers with a menu of the effects available for the voice message
+func (service *EffectManager) chooseRemixEffect(cmd RemixCommand) error {
+	effects := domain.SupportedVoiceEffects(service.encoder)
+	if len(effects) == 0 {
+		return service.reply(cmd.InteractionToken, "texts.effect_unavailable")
+	}
+	options := make([]discord.SelectOption, len(effects))
+	for i, effect := range effects {
+		options[i] = discord.SelectOption{Label: effect.Label(), Value: effect.Name()}
+	}
+	customID, err := remixEffectComponentID(cmd.ChannelID, cmd.MessageID)
+	if err != nil {
+		return fmt.Errorf("err encoding remix effects menu, %w", err)
+	}
+	err = service.discordClient.EditInteractionComplex(cmd.InteractionToken, discord.ComplexInteractionEdit{
+		Content: service.localization.Get("texts.remix_choose"),
+		Select: &discord.SelectMenu{
+			CustomID:    customID,
+			Placeholder: service.localization.Get("texts.remix_placeholder"),
+			Options:     options,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("err sending remix effects, %w", err)
+	}
+	return nil
+}
+
+// remix renders the audio of the voice message with the effect and sends it as a new voice message
+func (service *EffectManager) remix(cmd RemixCommand) error {
+	effect, ok := domain.ParseVoiceEffect(cmd.Effect)
+	if !ok || effect == domain.VoiceEffectNone || !service.encoder.SupportsEffect(effect) {
+		return service.reply(cmd.InteractionToken, "texts.effect_unavailable")
+	}
+	message, err := service.discordClient.GetMessage(cmd.ChannelID, cmd.MessageID)
+	if err != nil || message.AttachmentURL == "" {
+		if err != nil {
+			log.Println("err getting message to remix", err)
+		}
+		return service.reply(cmd.InteractionToken, "texts.remix_message_not_found")
+	}
+
+	settings, err := service.guildSettingsRepo.Get(cmd.GuildID)
+	if err != nil {
+		log.Println("err getting guild settings, using defaults:", err)
+		settings = domain.DefaultGuildSettings(cmd.GuildID)
+	}
+	options := domain.EncodeOptions{Format: settings.AudioFormat, Bitrate: settings.AudioBitrate, Effect: effect}
+	if !service.encoder.Supports(options.Format) {
+		options.Format = domain.AudioFormatOpus
+	}
+
+	fileName := fmt.Sprintf("%s-remix-%s", cmd.MessageID, uuid.New().String())
+	sourceFileName := fmt.Sprintf("%s-source", fileName)
+	defer service.fsRepo.DeleteAll(sourceFileName)
+	if err := downloadFile(service.fsRepo, message.AttachmentURL, sourceFileName); err != nil {
+		return err
+	}
+	audioFullName := service.fsRepo.GetFullPath(fmt.Sprintf("%s.%s", fileName, options.Format.Extension()))
+	runJob(service.jobs, cmd.GuildID, job.PriorityEncode, func() {
+		err = service.encoder.Encode([]string{service.fsRepo.GetFullPath(sourceFileName)}, audioFullName, options)
+	})
+	if err != nil {
+		log.Println("err rendering remix", err)
+		service.fsRepo.DeleteAll(audioFullName)
+		return service.reply(cmd.InteractionToken, "texts.remix_failed")
+	}
+
+	var messageSent discord.Message
+	runJob(service.jobs, cmd.GuildID, job.PriorityUpload, func() {
+		messageSent, err = service.send(cmd.ChannelID, audioFullName, options.Format)
+	})
+	if err != nil {
+		service.fsRepo.DeleteAll(audioFullName)
+		return fmt.Errorf("err sending remix, %w", err)
+	}
+	evt := domain.NewAudioSentEvent(messageSent.ID, cmd.UserID, cmd.GuildID, messageSent.ChannelID, cmd.Username, cmd.AvatarURL, audioFullName, options.Format, fileName, messageSent.AttachmentID, messageSent.AttachmentURL, "", domain.ProcessReport{})
+	evt.Effect = effect
+	go func() {
+		err := service.eventBus.Publish(context.Background(), []event.Event{evt})
+		if err != nil {
+			log.Println("err publishing audio sent event", err)
+		}
+	}()
+	return service.reply(cmd.InteractionToken, "texts.remix_sent", &localizations.Replacements{"effect": effect.Label()})
+}
+
+func (service *EffectManager) send(channelID string, audioFullName string, format domain.AudioFormat) (discord.Message, error) {
+	file, err := service.fsRepo.Open(audioFullName)
+	if err != nil {
+		return discord.Message{}, fmt.Errorf("err opening remix, %w", err)
+	}
+	defer func(file *os.File) {
+		if err := file.Close(); err != nil {
+			log.Println(err)
+		}
+	}(file)
+	return service.discordClient.SendFileMessage(channelID, audioFullName, format.ContentType(), bufio.NewReader(file))
+}
+
+func (service *EffectManager) reply(interactionToken string, key string, replacements ...*localizations.Replacements) error {
+	if err := service.discordClient.EditInteraction(interactionToken, service.localization.Get(key, replacements...)); err != nil {
+		return fmt.Errorf("err sending interaction response, %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEffectManager_set(t *testing.T) {
+	type fields struct {
+		discordClient *discordmocks.Client
+		effectRepo    *domainmocks.VoiceEffectRepository
+		encoder       *domainmocks.AudioEncoder
+	}
+	tests := []struct {
+		name          string
+		cmd           EffectCommand
+		expectedError bool
+		on            func(*fields)
+		assertMocks   func(t *testing.T, f *fields)
+	}{
+		{
+			name: "when effect is unknown, tell it without saving",
+			cmd:  NewEffectCommand("token", "guild", "user", EffectActionSet, "whisper"),
+			on: func(fields *fields) {
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.effectRepo.AssertNumberOfCalls(t, "Save", 0)
+			},
+		},
+		{
+			name: "when effect cannot be rendered in this host, tell it without saving",
+			cmd:  NewEffectCommand("token", "guild", "user", EffectActionSet, "robot"),
+			on: func(fields *fields) {
+				fields.encoder.On("SupportsEffect", domain.VoiceEffectRobot).Return(false)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.effectRepo.AssertNumberOfCalls(t, "Save", 0)
+			},
+		},
+		{
+			name: "save the effect and confirm it",
+			cmd:  NewEffectCommand("token", "guild", "user", EffectActionSet, "Robot"),
+			on: func(fields *fields) {
+				fields.encoder.On("SupportsEffect", domain.VoiceEffectRobot).Return(true)
+				fields.effectRepo.On("Save", "guild", "user", domain.VoiceEffectRobot).Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.effectRepo.AssertNumberOfCalls(t, "Save", 1)
+			},
+		},
+		{
+			name: "none clears the effect",
+			cmd:  NewEffectCommand("token", "guild", "user", EffectActionSet, "none"),
+			on: func(fields *fields) {
+				fields.encoder.On("SupportsEffect", domain.VoiceEffectNone).Return(true)
+				fields.effectRepo.On("Save", "guild", "user", domain.VoiceEffectNone).Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.effectRepo.AssertNumberOfCalls(t, "Save", 1)
+			},
+		},
+		{
+			name:          "when saving fails, return error",
+			cmd:           NewEffectCommand("token", "guild", "user", EffectActionSet, "echo"),
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.encoder.On("SupportsEffect", domain.VoiceEffectEcho).Return(true)
+				fields.effectRepo.On("Save", "guild", "user", domain.VoiceEffectEcho).Return(errors.New("err db"))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fields{discordClient: &discordmocks.Client{}, effectRepo: &domainmocks.VoiceEffectRepository{}, encoder: &domainmocks.AudioEncoder{}}
+			service := &EffectManager{
+				discordClient: f.discordClient,
+				localization:  localizations.New("en", "en"),
+				effectRepo:    f.effectRepo,
+				encoder:       f.encoder,
+			}
+			if tt.on != nil {
+				tt.on(&f)
+			}
+			err := service.set(tt.cmd)
+			assert.Equal(t, tt.expectedError, err != nil)
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &f)
+			}
+		})
+	}
+}
+
+func TestEffectManager_chooseRemixEffect(t *testing.T) {
+	discordClient := &discordmocks.Client{}
+	encoder := &domainmocks.AudioEncoder{}
+	encoder.On("SupportsEffect", domain.VoiceEffectRobot).Return(true)
+	encoder.On("SupportsEffect", mock.Anything).Return(false)
+	discordClient.On("EditInteractionComplex", "token", mock.AnythingOfType("discord.ComplexInteractionEdit")).Return(nil)
+	service := &EffectManager{discordClient: discordClient, localization: localizations.New("en", "en"), encoder: encoder}
+
+	err := service.chooseRemixEffect(NewRemixCommand("token", "guild", "user", "name", "avatar", "channel", "msg", ""))
+
+	assert.NoError(t, err)
+	edit := discordClient.Calls[0].Arguments.Get(1).(discord.ComplexInteractionEdit)
+	assert.Equal(t, []discord.SelectOption{{Label: "Robot", Value: "robot"}}, edit.Select.Options)
+	channelID, messageID, ok := ParseRemixEffectComponentID(edit.Select.CustomID)
+	assert.True(t, ok)
+	assert.Equal(t, "channel", channelID)
+	assert.Equal(t, "msg", messageID)
+}
+
+func TestEffectManager_remix(t *testing.T) {
+	type fields struct {
+		discordClient *discordmocks.Client
+		encoder       *domainmocks.AudioEncoder
+	}
+	tests := []struct {
+		name        string
+		effect      string
+		on          func(*fields)
+		assertMocks func(t *testing.T, f *fields)
+	}{
+		{
+			name:   "when effect is none, tell it without looking for the message",
+			effect: "none",
+			on: func(fields *fields) {
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertNumberOfCalls(t, "GetMessage", 0)
+			},
+		},
+		{
+			name:   "when message has no audio, tell it without encoding",
+			effect: "echo",
+			on: func(fields *fields) {
+				fields.encoder.On("SupportsEffect", domain.VoiceEffectEcho).Return(true)
+				fields.discordClient.On("GetMessage", "channel", "msg").Return(discord.Message{ID: "msg"}, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.encoder.AssertNumberOfCalls(t, "Encode", 0)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fields{discordClient: &discordmocks.Client{}, encoder: &domainmocks.AudioEncoder{}}
+			service := &EffectManager{discordClient: f.discordClient, localization: localizations.New("en", "en"), encoder: f.encoder}
+			if tt.on != nil {
+				tt.on(&f)
+			}
+			err := service.remix(NewRemixCommand("token", "guild", "user", "name", "avatar", "channel", "msg", tt.effect))
+			assert.NoError(t, err)
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &f)
+			}
+		})
+	}
+}
+
+func TestParseRemixEffectComponentID(t *testing.T) {
+	tests := []struct {
+		customID string
+		ok       bool
+	}{
+		{customID: "remix-effect:channel:msg", ok: true},
+		{customID: "remix-effect:channel", ok: false},
+		{customID: "remix-effect::msg", ok: false},
+		{customID: "play:channel:msg", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.customID, func(t *testing.T) {
+			_, _, ok := ParseRemixEffectComponentID(tt.customID)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
@@ -1,14 +1,15 @@
 package application
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
 	"image"
+	"image/png"
 	"io"
 	"log"
 	"math"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -17,21 +18,28 @@ import (
 	"github.com/EdlinOrg/prominentcolor"
 	"github.com/hectorgabucio/taterubot-dc/domain"
 	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/hectorgabucio/taterubot-dc/domain/ogg"
 	"github.com/hectorgabucio/taterubot-dc/kit/event"
+	"github.com/hectorgabucio/taterubot-dc/kit/job"
 	"github.com/hectorgabucio/taterubot-dc/localizations"
 )
 
+// waveformImageName is the name of the waveform attachment the embed shows
+const waveformImageName = "waveform.png"
+
 type AddMetadataOnAudioSent struct {
 	discord       discord.Client
 	localizer     *localizations.Localizer
 	fsRepo        domain.FileRepository
 	voiceDataRepo domain.VoiceDataRepository
-	decoder       domain.MP3Decoder
+	prober        domain.MediaProber
+	oggAnalyzer   ogg.Analyzer
 	bus           event.Bus
+	jobs          job.Queue
 }
 
-func NewAddMetadataOnAudioSent(discord discord.Client, localizer *localizations.Localizer, fsRepo domain.FileRepository, voiceDataRepo domain.VoiceDataRepository, decoder domain.MP3Decoder, bus event.Bus) *AddMetadataOnAudioSent {
-	return &AddMetadataOnAudioSent{discord: discord, localizer: localizer, fsRepo: fsRepo, voiceDataRepo: voiceDataRepo, decoder: decoder, bus: bus}
+func NewAddMetadataOnAudioSent(discord discord.Client, localizer *localizations.Localizer, fsRepo domain.FileRepository, voiceDataRepo domain.VoiceDataRepository, prober domain.MediaProber, analyzer ogg.Analyzer, bus event.Bus, jobs job.Queue) *AddMetadataOnAudioSent {
+	return &AddMetadataOnAudioSent{discord: discord, localizer: localizer, fsRepo: fsRepo, voiceDataRepo: voiceDataRepo, prober: prober, oggAnalyzer: analyzer, bus: bus, jobs: jobs}
 }
 
 func (handler *AddMetadataOnAudioSent) Handle(ctx context.Context, evt event.Event) error {
@@ -40,44 +48,48 @@ func (handler *AddMetadataOnAudioSent) Handle(ctx context.Context, evt event.Eve
 		return errors.New("unexpected event")
 	}
 
-	dominantColor := handler.getDominantAvatarColor(audioSentEvt.UserAvatarURL, audioSentEvt.FileName)
-	t := handler.getDuration(audioSentEvt.Mp3Fullname)
-	seconds := int(t)
-	newEmbed := discord.MessageEmbed{
-		Title:     audioSentEvt.Username,
-		Timestamp: time.Now().Format(time.RFC3339),
-		Color:     dominantColor,
-		Thumbnail: audioSentEvt.UserAvatarURL,
-		Fields: []*discord.MessageEmbedField{
-			{
-				Name:  handler.localizer.Get("texts.duration"),
-				Value: formatSeconds(seconds),
-			},
-			{
-				Name:  handler.localizer.Get("texts.download_link_title"),
-				Value: fmt.Sprintf("[:floppy_disk: MP3](https://cdn.discordapp.com/attachments/%s/%s/tmp_%s.mp3 'MP3')", audioSentEvt.ChannelID, audioSentEvt.AttachmentID, strings.ReplaceAll(audioSentEvt.FileName, " ", "_")),
-			},
-		},
-	}
-
-	err := handler.discord.SetEmbed(audioSentEvt.ChannelID, audioSentEvt.AggregateID(), newEmbed)
-	if err != nil {
-		return fmt.Errorf("err setting embed in message, %w", err)
-	}
+	var properties domain.MediaProperties
+	runJob(handler.jobs, audioSentEvt.GuildID, job.PriorityEmbed, func() {
+		properties = handler.probe(audioSentEvt)
+		// voice messages render their own player and cannot hold an embed
+		if audioSentEvt.VoiceMessage {
+			return
+		}
+		// the audio was delivered anyway, so it is still recorded and its files removed
+		if err := handler.setEmbed(audioSentEvt, int(properties.DurationSecs)); err != nil {
+			log.Println("err setting embed of audio", err)
+		}
+	})
+	seconds := int(properties.DurationSecs)
 
 	voiceData := domain.VoiceData{
-		GuildID:   audioSentEvt.GuildID,
-		ID:        audioSentEvt.ID(),
-		Timestamp: audioSentEvt.MOccurredOn,
-		Name:      audioSentEvt.FileName,
-		UserID:    audioSentEvt.UserID,
-		Duration:  seconds,
-	}
-	if err := handler.voiceDataRepo.Save(voiceData); err != nil {
+		GuildID:        audioSentEvt.GuildID,
+		ID:             audioSentEvt.ID(),
+		Timestamp:      audioSentEvt.MOccurredOn,
+		Name:           audioSentEvt.FileName,
+		UserID:         audioSentEvt.UserID,
+		Duration:       seconds,
+		MessageID:      audioSentEvt.AggregateID(),
+		ChannelID:      audioSentEvt.ChannelID,
+		AttachmentURL:  audioSentEvt.AttachmentURL,
+		Anonymous:      audioSentEvt.Anonymous,
+		SampleRate:     properties.SampleRate,
+		Channels:       properties.Channels,
+		Bitrate:        properties.Bitrate,
+		Size:           properties.Size,
+		DroppedPackets: audioSentEvt.ReceiveStats.Dropped,
+		LatePackets:    audioSentEvt.ReceiveStats.Late,
+	}
+	if audioSentEvt.ContinuedVoiceDataID != "" {
+		voiceData.ID = audioSentEvt.ContinuedVoiceDataID
+		if err := handler.voiceDataRepo.Update(voiceData); err != nil {
+			log.Println("err updating voice data", err)
+		}
+	} else if err := handler.voiceDataRepo.Save(voiceData); err != nil {
 		log.Println("err saving voice data", err)
 	}
 	go func() {
-		err := handler.bus.Publish(ctx, []event.Event{domain.NewDoneProcessingFilesEvent(audioSentEvt.FileName)})
+		err := handler.bus.Publish(ctx, []event.Event{domain.NewDoneProcessingFilesEvent(audioSentEvt.FileName, audioSentEvt.AudioFormat)})
 		if err != nil {
 			log.Println(err)
 		}
@@ -85,13 +97,112 @@ func (handler *AddMetadataOnAudioSent) Handle(ctx context.Context, evt event.Eve
 	return nil
 }
 
-func (handler *AddMetadataOnAudioSent) getDominantAvatarColor(url string, fileName string) int {
-	err := handler.downloadFile(url, fmt.Sprintf("%s.png", fileName))
+func (handler *AddMetadataOnAudioSent) setEmbed(evt domain.AudioSentEvent, seconds int) error {
+	title, dominantColor := evt.Username, 0
+	if evt.Anonymous {
+		title = handler.localizer.Get("texts.anonymous")
+	} else {
+		dominantColor = dominantAvatarColor(handler.fsRepo, evt.UserAvatarURL, evt.FileName)
+	}
+	var fields []*discord.MessageEmbedField
+	if seconds > 0 {
+		fields = append(fields, &discord.MessageEmbedField{
+			Name:  handler.localizer.Get("texts.duration"),
+			Value: formatSeconds(seconds),
+		})
+	}
+	if processing := handler.describeProcessing(evt.Processing); processing != "" {
+		fields = append(fields, &discord.MessageEmbedField{
+			Name:  handler.localizer.Get("texts.processing"),
+			Value: processing,
+		})
+	}
+	if evt.Effect != domain.VoiceEffectNone {
+		fields = append(fields, &discord.MessageEmbedField{
+			Name:  handler.localizer.Get("texts.effect"),
+			Value: evt.Effect.Label(),
+		})
+	}
+	fields = append(fields, &discord.MessageEmbedField{
+		Name:  handler.localizer.Get("texts.download_link_title"),
+		Value: downloadLink(evt),
+	})
+	newEmbed := discord.MessageEmbed{
+		Title:       title,
+		Description: evt.Caption,
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Color:       dominantColor,
+		Thumbnail:   evt.UserAvatarURL,
+		Fields:      fields,
+	}
+	// the recording of continued voice messages only holds the new audio, its waveform would be misleading
+	if evt.ContinuedVoiceDataID == "" {
+		waveform, err := handler.renderWaveformImage(evt.FileName, dominantColor)
+		if err != nil {
+			log.Println("err rendering waveform, sending the embed without it:", err)
+		} else {
+			defer func(file *os.File) {
+				if err := file.Close(); err != nil {
+					log.Println("err closing waveform image", err)
+				}
+			}(waveform)
+			newEmbed.Image = &discord.File{Name: waveformImageName, ContentType: "image/png", Reader: bufio.NewReader(waveform)}
+		}
+	}
+
+	buttons := []discord.Button{
+		{Label: handler.localizer.Get("texts.play_in_voice"), Emoji: "🔊", CustomID: PlayComponentID},
+		{Label: handler.localizer.Get("texts.remix"), Emoji: "🎛️", CustomID: RemixComponentID},
+		{Label: handler.localizer.Get("texts.export_video"), Emoji: "🎞️", CustomID: ExportVideoComponentID},
+	}
+	err := handler.discord.SetEmbeds(evt.ChannelID, evt.AggregateID(), []discord.MessageEmbed{newEmbed}, buttons)
+	if err != nil {
+		return fmt.Errorf("err setting embed in message, %w", err)
+	}
+	return nil
+}
+
+// renderWaveformImage draws the waveform of the recording in a png file, returned ready to be read
+func (handler *AddMetadataOnAudioSent) renderWaveformImage(fileName string, color int) (*os.File, error) {
+	_, amplitudes, err := handler.oggAnalyzer.Analyze(handler.fsRepo.GetFullPath(fmt.Sprintf("%s.ogg", fileName)), waveformBars)
+	if err != nil {
+		return nil, fmt.Errorf("err analyzing recording, %w", err)
+	}
+	file, err := handler.fsRepo.CreateEmpty(fmt.Sprintf("%s-waveform.png", fileName))
+	if err != nil {
+		return nil, fmt.Errorf("err creating waveform image, %w", err)
+	}
+	err = png.Encode(file, renderWaveform(amplitudes, color))
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("err writing waveform image, %w", err)
+	}
+	return file, nil
+}
+
+// describeProcessing lists the post-processing steps that changed the audio, empty if none did
+func (handler *AddMetadataOnAudioSent) describeProcessing(report domain.ProcessReport) string {
+	var steps []string
+	if report.Normalized {
+		steps = append(steps, handler.localizer.Get("texts.processing_normalized", &localizations.Replacements{"gain": fmt.Sprintf("%+.1f", report.GainDB)}))
+	}
+	if report.TrimmedSecs > 0 {
+		steps = append(steps, handler.localizer.Get("texts.processing_trimmed", &localizations.Replacements{"seconds": fmt.Sprintf("%.1f", report.TrimmedSecs)}))
+	}
+	return strings.Join(steps, ", ")
+}
+
+// dominantAvatarColor downloads the avatar to <fileName>.png and returns its prominent color, 0 when it cannot be known
+func dominantAvatarColor(fsRepo domain.FileRepository, url string, fileName string) int {
+	err := downloadFile(fsRepo, url, fmt.Sprintf("%s.png", fileName))
 	if err != nil {
 		log.Println(err)
 		return 0
 	}
-	color, err := handler.prominentColor(fileName)
+	color, err := prominentColor(fsRepo, fileName)
 	if err != nil {
 		log.Println("couldnt get prominent color,", err)
 		return 0
@@ -99,8 +210,8 @@ func (handler *AddMetadataOnAudioSent) getDominantAvatarColor(url string, fileNa
 	return color
 }
 
-func (handler *AddMetadataOnAudioSent) prominentColor(fileName string) (int, error) {
-	img, err := handler.loadImage(fmt.Sprintf("%s.png", fileName))
+func prominentColor(fsRepo domain.FileRepository, fileName string) (int, error) {
+	img, err := loadImage(fsRepo, fmt.Sprintf("%s.png", fileName))
 	if err != nil {
 		return 0, fmt.Errorf("failed to load image: %w", err)
 	}
@@ -122,8 +233,8 @@ func (handler *AddMetadataOnAudioSent) prominentColor(fileName string) (int, err
 	return 0, errors.New("couldnt get any dominant color")
 }
 
-func (handler *AddMetadataOnAudioSent) loadImage(fileInput string) (image.Image, error) {
-	f, err := handler.fsRepo.Open(fileInput)
+func loadImage(fsRepo domain.FileRepository, fileInput string) (image.Image, error) {
+	f, err := fsRepo.Open(fileInput)
 	if err != nil {
 		return nil, fmt.Errorf("err opening image file, %w", err)
 	}
@@ -140,50 +251,31 @@ func (handler *AddMetadataOnAudioSent) loadImage(fileInput string) (image.Image,
 	return img, nil
 }
 
-func (handler *AddMetadataOnAudioSent) downloadFile(url, fileName string) error {
-	response, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to download file, %w", err)
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
+// probe reads the properties of the sent audio, unknown ones are left as 0.
+// The duration known from the recording is kept, probing may need to decode the whole file to find it.
+func (handler *AddMetadataOnAudioSent) probe(evt domain.AudioSentEvent) domain.MediaProperties {
+	var properties domain.MediaProperties
+	if handler.prober.Supports(evt.AudioFormat) {
+		probed, err := handler.prober.Probe(evt.AudioFullname, evt.AudioFormat)
 		if err != nil {
-			log.Println("error closing http response body", err)
+			log.Println("err probing sent audio", err)
+		} else {
+			properties = probed
 		}
-	}(response.Body)
-
-	if response.StatusCode != 200 {
-		return errors.New("received non 200 response code")
-	}
-	file, err := handler.fsRepo.CreateEmpty(fileName)
-	if err != nil {
-		return fmt.Errorf("failed to create empty file to write response, %w", err)
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			log.Println("error closing file", err)
-		}
-	}(file)
-	_, err = io.Copy(file, response.Body)
-	if err != nil {
-		return fmt.Errorf("failed to copy response body to file, %w", err)
+	if evt.DurationSecs > 0 {
+		properties.DurationSecs = evt.DurationSecs
 	}
-
-	return nil
+	return properties
 }
 
-func (handler *AddMetadataOnAudioSent) getDuration(fileName string) float64 {
-	file1, err := handler.fsRepo.Open(fileName)
-	if err != nil {
-		return 0
+func downloadLink(evt domain.AudioSentEvent) string {
+	url := evt.AttachmentURL
+	if url == "" {
+		url = fmt.Sprintf("https://cdn.discordapp.com/attachments/%s/%s/tmp_%s.%s", evt.ChannelID, evt.AttachmentID, strings.ReplaceAll(evt.FileName, " ", "_"), evt.AudioFormat.Extension())
 	}
-	defer func(file *os.File) {
-		_ = file.Close()
-	}(file1)
-
-	duration := handler.decoder.GetDuration(file1)
-	return duration
+	label := evt.AudioFormat.Label()
+	return fmt.Sprintf("[:floppy_disk: %s](%s '%s')", label, url, label)
 }
 
 func formatSeconds(inSeconds int) string {
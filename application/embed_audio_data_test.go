@@ -0,0 +1,158 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/hectorgabucio/taterubot-dc/kit/event"
+	"github.com/hectorgabucio/taterubot-dc/kit/job"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type publishedEvents struct {
+	events chan event.Event
+}
+
+func (b *publishedEvents) Publish(_ context.Context, events []event.Event) error {
+	for _, evt := range events {
+		b.events <- evt
+	}
+	return nil
+}
+
+func (b *publishedEvents) Subscribe(event.Type, event.Handler) {}
+
+func (b *publishedEvents) Close() error {
+	return nil
+}
+
+func TestAddMetadataOnAudioSent_Handle_voiceMessage(t *testing.T) {
+	discordClient := &discordmocks.Client{}
+	voiceDataRepo := &domainmocks.VoiceDataRepository{}
+	bus := &publishedEvents{events: make(chan event.Event, 1)}
+	prober := &domainmocks.MediaProber{}
+	voiceDataRepo.On("Save", mock.Anything).Return(nil)
+	prober.On("Supports", domain.AudioFormatOpus).Return(true)
+	prober.On("Probe", "./tmp/username-1.ogg", domain.AudioFormatOpus).Return(domain.NewMediaProperties(12.6, 48000, 2, 60000), nil)
+
+	jobs := &inlineJobs{}
+	handler := &AddMetadataOnAudioSent{discord: discordClient, voiceDataRepo: voiceDataRepo, prober: prober, bus: bus, jobs: jobs}
+	evt := domain.NewVoiceMessageSentEvent("msg", "user", "guild", "channel", "username", "avatar", "./tmp/username-1.ogg", "username-1", "attachment", "url", 12.7)
+	evt.ReceiveStats = discord.ReceiveStats{Received: 700, Dropped: 12, Late: 3}
+
+	err := handler.Handle(context.Background(), evt)
+
+	assert.NoError(t, err)
+	discordClient.AssertNumberOfCalls(t, "SetEmbeds", 0)
+	if assert.Len(t, jobs.submitted, 1) {
+		assert.Equal(t, job.PriorityEmbed, jobs.submitted[0].Priority)
+	}
+	voiceDataRepo.AssertCalled(t, "Save", mock.MatchedBy(func(data domain.VoiceData) bool {
+		return data.Duration == 12 && data.MessageID == "msg" && data.AttachmentURL == "url" &&
+			data.SampleRate == 48000 && data.Channels == 2 && data.Bitrate == 38 && data.Size == 60000 &&
+			data.DroppedPackets == 12 && data.LatePackets == 3
+	}))
+	done, ok := (<-bus.events).(domain.DoneProcessingFilesEvent)
+	if assert.True(t, ok) {
+		assert.Equal(t, "username-1", done.AggregateID())
+		assert.Equal(t, domain.AudioFormatOpus, done.AudioFormat)
+	}
+}
+
+func TestAddMetadataOnAudioSent_Handle_embedFails(t *testing.T) {
+	discordClient := &discordmocks.Client{}
+	voiceDataRepo := &domainmocks.VoiceDataRepository{}
+	bus := &publishedEvents{events: make(chan event.Event, 1)}
+	prober := &domainmocks.MediaProber{}
+	voiceDataRepo.On("Update", mock.Anything).Return(nil)
+	prober.On("Supports", domain.AudioFormatMP3).Return(true)
+	prober.On("Probe", "./tmp/username-1.mp3", domain.AudioFormatMP3).Return(domain.NewMediaProperties(12.6, 44100, 1, 60000), nil)
+	discordClient.On("SetEmbeds", "channel", "msg", mock.Anything, mock.Anything).Return(errors.New("err 404 unknown message"))
+
+	handler := &AddMetadataOnAudioSent{discord: discordClient, localizer: localizations.New("en", "en"), voiceDataRepo: voiceDataRepo, prober: prober, bus: bus, jobs: &inlineJobs{}}
+	evt := domain.NewAudioSentEvent("msg", "user", "guild", "channel", "", "", "./tmp/username-1.mp3", domain.AudioFormatMP3, "username-1", "attachment", "url", "previous", domain.ProcessReport{})
+	evt.Anonymous = true
+
+	err := handler.Handle(context.Background(), evt)
+
+	assert.NoError(t, err)
+	discordClient.AssertExpectations(t)
+	voiceDataRepo.AssertCalled(t, "Update", mock.MatchedBy(func(data domain.VoiceData) bool {
+		return data.ID == "previous" && data.MessageID == "msg" && data.Duration == 12
+	}))
+	done, ok := (<-bus.events).(domain.DoneProcessingFilesEvent)
+	if assert.True(t, ok) {
+		assert.Equal(t, "username-1", done.AggregateID())
+	}
+}
+
+func TestAddMetadataOnAudioSent_probe(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration float64
+		on       func(prober *domainmocks.MediaProber)
+		expected domain.MediaProperties
+	}{
+		{
+			name:     "when duration is known from the recording, keep it",
+			duration: 3.5,
+			on: func(prober *domainmocks.MediaProber) {
+				prober.On("Supports", domain.AudioFormatMP3).Return(true)
+				prober.On("Probe", "rec.mp3", domain.AudioFormatMP3).Return(domain.MediaProperties{DurationSecs: 3.55, SampleRate: 44100, Channels: 1, Bitrate: 64, Size: 28400}, nil)
+			},
+			expected: domain.MediaProperties{DurationSecs: 3.5, SampleRate: 44100, Channels: 1, Bitrate: 64, Size: 28400},
+		},
+		{
+			name: "when probing fails, properties are unknown",
+			on: func(prober *domainmocks.MediaProber) {
+				prober.On("Supports", domain.AudioFormatMP3).Return(true)
+				prober.On("Probe", "rec.mp3", domain.AudioFormatMP3).Return(domain.MediaProperties{}, errors.New("corrupt file"))
+			},
+			expected: domain.MediaProperties{},
+		},
+		{
+			name:     "when no prober reads the format, only the duration is known",
+			duration: 3.5,
+			on: func(prober *domainmocks.MediaProber) {
+				prober.On("Supports", domain.AudioFormatMP3).Return(false)
+			},
+			expected: domain.MediaProperties{DurationSecs: 3.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prober := &domainmocks.MediaProber{}
+			tt.on(prober)
+			handler := &AddMetadataOnAudioSent{prober: prober}
+			evt := domain.NewAudioSentEvent("msg", "user", "guild", "channel", "username", "avatar", "rec.mp3", domain.AudioFormatMP3, "rec", "attachment", "url", "", domain.ProcessReport{})
+			evt.DurationSecs = tt.duration
+
+			assert.Equal(t, tt.expected, handler.probe(evt))
+		})
+	}
+}
+
+func TestAddMetadataOnAudioSent_describeProcessing(t *testing.T) {
+	tests := []struct {
+		name     string
+		report   domain.ProcessReport
+		expected string
+	}{
+		{name: "nothing changed", report: domain.ProcessReport{}, expected: ""},
+		{name: "normalized", report: domain.ProcessReport{Normalized: true, GainDB: 6.02}, expected: "normalized +6.0 dB"},
+		{name: "normalized and trimmed", report: domain.ProcessReport{Normalized: true, GainDB: -3.5, TrimmedSecs: 1.24}, expected: "normalized -3.5 dB, trimmed 1.2s of silence"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &AddMetadataOnAudioSent{localizer: localizations.New("en", "en")}
+			assert.Equal(t, tt.expected, handler.describeProcessing(tt.report))
+		})
+	}
+}
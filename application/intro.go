@@ -0,0 +1,273 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/hectorgabucio/taterubot-dc/kit/command"
+	"github.com/hectorgabucio/taterubot-dc/kit/event"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+)
+
+const IntroCommandType command.Type = "command.intro"
+
+const PlayIntroCommandType command.Type = "command.intro.play"
+
+const (
+	IntroActionSet   = "set"
+	IntroActionClear = "clear"
+)
+
+const opusFrameDuration = 20 * time.Millisecond
+
+type IntroCommand struct {
+	InteractionToken string
+	GuildID          string
+	UserID           string
+	ChannelID        string
+	Action           string
+	// MessageRef is the link or id of the voice message to use as intro, when empty the next recording is used
+	MessageRef string
+}
+
+func NewIntroCommand(interactionToken string, guildID string, userID string, channelID string, action string, messageRef string) IntroCommand {
+	return IntroCommand{
+		InteractionToken: interactionToken,
+		GuildID:          guildID,
+		UserID:           userID,
+		ChannelID:        channelID,
+		Action:           action,
+		MessageRef:       messageRef,
+	}
+}
+
+func (c IntroCommand) Type() command.Type {
+	return IntroCommandType
+}
+
+type PlayIntroCommand struct {
+	GuildID        string
+	UserID         string
+	VoiceChannelID string
+}
+
+func NewPlayIntroCommand(guildID string, userID string, voiceChannelID string) PlayIntroCommand {
+	return PlayIntroCommand{
+		GuildID:        guildID,
+		UserID:         userID,
+		VoiceChannelID: voiceChannelID,
+	}
+}
+
+func (c PlayIntroCommand) Type() command.Type {
+	return PlayIntroCommandType
+}
+
+type IntroCommandHandler struct {
+	service *IntroManager
+}
+
+// NewIntroCommandHandler initializes a new IntroCommandHandler.
+func NewIntroCommandHandler(service *IntroManager) IntroCommandHandler {
+	return IntroCommandHandler{
+		service: service,
+	}
+}
+
+// Handle implements the command.Handler interface.
+func (h IntroCommandHandler) Handle(ctx context.Context, cmd command.Command) error {
+	introCmd, ok := cmd.(IntroCommand)
+	if !ok {
+		return errors.New("unexpected command")
+	}
+	return h.service.manage(introCmd)
+}
+
+type PlayIntroCommandHandler struct {
+	service *IntroManager
+}
+
+// NewPlayIntroCommandHandler initializes a new PlayIntroCommandHandler.
+func NewPlayIntroCommandHandler(service *IntroManager) PlayIntroCommandHandler {
+	return PlayIntroCommandHandler{
+		service: service,
+	}
+}
+
+// Handle implements the command.Handler interface.
+func (h PlayIntroCommandHandler) Handle(ctx context.Context, cmd command.Command) error {
+	playIntroCmd, ok := cmd.(PlayIntroCommand)
+	if !ok {
+		return errors.New("unexpected command")
+	}
+	return h.service.playIntro(playIntroCmd)
+}
+
+type IntroManager struct {
+	discordClient        discord.Client
+	localization         *localizations.Localizer
+	configChannelName    string
+	introRepo            domain.IntroRepository
+	pendingIntros        domain.PendingIntroRepository
+	lockedUserRepository domain.LockedUserRepository
+	fsRepo               domain.FileRepository
+	transcoder           domain.OpusTranscoder
+	player               *VoicePlayer
+	// maxDuration is the longest clip kept as intro, longer ones are cut
+	maxDuration time.Duration
+	// cooldown is the minimum time between two intros of the same user
+	cooldown time.Duration
+
+	mu         sync.Mutex
+	lastPlayed map[string]time.Time
+}
+
+func NewIntroManager(discord discord.Client, localization *localizations.Localizer, configChannelName string, introRepo domain.IntroRepository, pendingIntros domain.PendingIntroRepository, lockedUserRepository domain.LockedUserRepository, fsRepo domain.FileRepository, transcoder domain.OpusTranscoder, player *VoicePlayer, maxDuration time.Duration, cooldown time.Duration) *IntroManager {
+	return &IntroManager{
+		discordClient:        discord,
+		localization:         localization,
+		configChannelName:    configChannelName,
+		introRepo:            introRepo,
+		pendingIntros:        pendingIntros,
+		lockedUserRepository: lockedUserRepository,
+		fsRepo:               fsRepo,
+		transcoder:           transcoder,
+		player:               player,
+		maxDuration:          maxDuration,
+		cooldown:             cooldown,
+		lastPlayed:           make(map[string]time.Time),
+	}
+}
+
+func (service *IntroManager) manage(cmd IntroCommand) error {
+	switch cmd.Action {
+	case IntroActionClear:
+		if err := service.introRepo.Delete(cmd.GuildID, cmd.UserID); err != nil {
+			return fmt.Errorf("err deleting intro, %w", err)
+		}
+		return service.reply(cmd.InteractionToken, "texts.intro_cleared")
+	case IntroActionSet:
+		if cmd.MessageRef == "" {
+			service.pendingIntros.SetPending(cmd.GuildID, cmd.UserID, cmd.InteractionToken)
+			return service.reply(cmd.InteractionToken, "texts.intro_waiting_recording", &localizations.Replacements{"voiceChannel": service.configChannelName})
+		}
+		return service.setFromMessage(cmd)
+	default:
+		return fmt.Errorf("unknown intro action %s", cmd.Action)
+	}
+}
+
+func (service *IntroManager) setFromMessage(cmd IntroCommand) error {
+	channelID, messageID := parseMessageReference(cmd.MessageRef, cmd.ChannelID)
+	message, err := service.discordClient.GetMessage(channelID, messageID)
+	if err != nil {
+		log.Println("err getting intro message", err)
+		return service.reply(cmd.InteractionToken, "texts.intro_not_found")
+	}
+	if message.AttachmentURL == "" {
+		return service.reply(cmd.InteractionToken, "texts.intro_not_found")
+	}
+	fileName := fmt.Sprintf("%s-%s-intro", cmd.GuildID, cmd.UserID)
+	defer service.fsRepo.DeleteAll(fileName)
+	if err := downloadFile(service.fsRepo, message.AttachmentURL, fileName); err != nil {
+		return err
+	}
+	frames, err := service.transcoder.ToOpusFrames(fileName)
+	if err != nil {
+		return fmt.Errorf("err transcoding intro, %w", err)
+	}
+	return service.save(cmd.InteractionToken, cmd.GuildID, cmd.UserID, frames)
+}
+
+// Handle implements the event.Handler interface, saving the recording requested with /intro set.
+func (service *IntroManager) Handle(ctx context.Context, evt event.Event) error {
+	introEvt, ok := evt.(domain.IntroRecordedEvent)
+	if !ok {
+		return errors.New("unexpected event")
+	}
+	oggFileName := fmt.Sprintf("%s.ogg", introEvt.AggregateID())
+	defer service.fsRepo.DeleteAll(oggFileName)
+	frames, err := service.transcoder.ToOpusFrames(oggFileName)
+	if err != nil {
+		return fmt.Errorf("err transcoding intro, %w", err)
+	}
+	return service.save(introEvt.InteractionToken, introEvt.GuildID, introEvt.UserID, frames)
+}
+
+// save stores the frames as the intro of the user, cutting them to the max duration
+func (service *IntroManager) save(interactionToken string, guildID string, userID string, frames [][]byte) error {
+	if len(frames) == 0 {
+		return service.reply(interactionToken, "texts.intro_empty")
+	}
+	if maxFrames := int(service.maxDuration / opusFrameDuration); len(frames) > maxFrames {
+		frames = frames[:maxFrames]
+	}
+	if err := service.introRepo.Save(domain.Intro{GuildID: guildID, UserID: userID, Frames: frames}); err != nil {
+		return fmt.Errorf("err saving intro, %w", err)
+	}
+	seconds := fmt.Sprintf("%.1f", (time.Duration(len(frames)) * opusFrameDuration).Seconds())
+	return service.reply(interactionToken, "texts.intro_saved", &localizations.Replacements{"seconds": seconds})
+}
+
+func (service *IntroManager) playIntro(cmd PlayIntroCommand) error {
+	if lockedUser, _ := service.lockedUserRepository.GetCurrentLock(cmd.GuildID); lockedUser != "" {
+		return nil
+	}
+	channel, err := service.discordClient.GetChannel(cmd.VoiceChannelID)
+	if err != nil {
+		return fmt.Errorf("err getting channel, %w", err)
+	}
+	// joining the recording channel starts a recording instead
+	if channel.Name == service.configChannelName {
+		return nil
+	}
+	intro, found, err := service.introRepo.Get(cmd.GuildID, cmd.UserID)
+	if err != nil {
+		return fmt.Errorf("err getting intro, %w", err)
+	}
+	if !found || len(intro.Frames) == 0 {
+		return nil
+	}
+	if !service.takeCooldown(cmd.GuildID, cmd.UserID) {
+		return nil
+	}
+	if _, ok := service.player.enqueueFrames(cmd.GuildID, cmd.VoiceChannelID, intro.Frames); !ok {
+		log.Println("playback queue full, skipping intro of", cmd.UserID)
+	}
+	return nil
+}
+
+// takeCooldown returns false if the intro of the user was played recently, otherwise starts its cooldown
+func (service *IntroManager) takeCooldown(guildID string, userID string) bool {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	key := guildID + "/" + userID
+	if last, ok := service.lastPlayed[key]; ok && time.Since(last) < service.cooldown {
+		return false
+	}
+	service.lastPlayed[key] = time.Now()
+	return true
+}
+
+func (service *IntroManager) reply(interactionToken string, key string, replacements ...*localizations.Replacements) error {
+	if err := service.discordClient.EditInteraction(interactionToken, service.localization.Get(key, replacements...)); err != nil {
+		return fmt.Errorf("err sending interaction response, %w", err)
+	}
+	return nil
+}
+
+// parseMessageReference accepts a message link or a bare message id, which is looked up in the fallback channel
+func parseMessageReference(ref string, fallbackChannelID string) (string, string) {
+	parts := strings.Split(strings.Trim(strings.TrimSpace(ref), "/"), "/")
+	if len(parts) >= 2 {
+		return parts[len(parts)-2], parts[len(parts)-1]
+	}
+	return fallbackChannelID, parts[0]
+}
@@ -0,0 +1,219 @@
+package application
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIntroManager_save(t *testing.T) {
+	frames := make([][]byte, 300)
+	type fields struct {
+		discordClient *discordmocks.Client
+		introRepo     *domainmocks.IntroRepository
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		frames         [][]byte
+		expectedError  bool
+		expectedFrames int
+		on             func(*fields)
+		assertMocks    func(t *testing.T, f *fields)
+	}{
+		{
+			name:   "when recording has no audio, dont save it",
+			fields: fields{discordClient: &discordmocks.Client{}, introRepo: &domainmocks.IntroRepository{}},
+			on: func(fields *fields) {
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.introRepo.AssertNumberOfCalls(t, "Save", 0)
+				f.discordClient.AssertNumberOfCalls(t, "EditInteraction", 1)
+			},
+		},
+		{
+			name:          "when saving fails, return error",
+			fields:        fields{discordClient: &discordmocks.Client{}, introRepo: &domainmocks.IntroRepository{}},
+			frames:        frames[:10],
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.introRepo.On("Save", mock.Anything).Return(errors.New("err db"))
+			},
+		},
+		{
+			name:           "when clip is short, save it whole",
+			fields:         fields{discordClient: &discordmocks.Client{}, introRepo: &domainmocks.IntroRepository{}},
+			frames:         frames[:10],
+			expectedFrames: 10,
+			on: func(fields *fields) {
+				fields.introRepo.On("Save", mock.Anything).Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+		},
+		{
+			name:           "when clip is too long, cut it to the max duration",
+			fields:         fields{discordClient: &discordmocks.Client{}, introRepo: &domainmocks.IntroRepository{}},
+			frames:         frames,
+			expectedFrames: 250,
+			on: func(fields *fields) {
+				fields.introRepo.On("Save", mock.Anything).Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &IntroManager{
+				discordClient: tt.fields.discordClient,
+				localization:  localizations.New("en", "en"),
+				introRepo:     tt.fields.introRepo,
+				maxDuration:   5 * time.Second,
+			}
+			if tt.on != nil {
+				tt.on(&tt.fields)
+			}
+			err := service.save("token", "guild", "user", tt.frames)
+			assert.Equal(t, tt.expectedError, err != nil)
+
+			if tt.expectedFrames > 0 {
+				tt.fields.introRepo.AssertCalled(t, "Save", mock.MatchedBy(func(intro domain.Intro) bool {
+					return intro.GuildID == "guild" && intro.UserID == "user" && len(intro.Frames) == tt.expectedFrames
+				}))
+			}
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &tt.fields)
+			}
+		})
+	}
+}
+
+func TestIntroManager_playIntro(t *testing.T) {
+	intro := domain.Intro{GuildID: "guild", UserID: "user", Frames: [][]byte{{0x1}}}
+	type fields struct {
+		discordClient *discordmocks.Client
+		introRepo     *domainmocks.IntroRepository
+		lockedUsers   *domainmocks.LockedUserRepository
+		lastPlayed    map[string]time.Time
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		expectedError bool
+		expectedQueue int
+		on            func(*fields)
+		assertMocks   func(t *testing.T, f *fields)
+	}{
+		{
+			name:   "when someone is being recorded, dont play the intro",
+			fields: fields{discordClient: &discordmocks.Client{}, introRepo: &domainmocks.IntroRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			on: func(fields *fields) {
+				fields.lockedUsers.On("GetCurrentLock", "guild").Return("recorded-user", make(chan bool))
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.introRepo.AssertNumberOfCalls(t, "Get", 0)
+			},
+		},
+		{
+			name:          "when getting channel fails, return error",
+			fields:        fields{discordClient: &discordmocks.Client{}, introRepo: &domainmocks.IntroRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.discordClient.On("GetChannel", "voice").Return(discord.Channel{}, errors.New("err discord"))
+			},
+		},
+		{
+			name:   "when user joins the recording channel, dont play the intro",
+			fields: fields{discordClient: &discordmocks.Client{}, introRepo: &domainmocks.IntroRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			on: func(fields *fields) {
+				fields.discordClient.On("GetChannel", "voice").Return(discord.Channel{Name: "TATERU"}, nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.introRepo.AssertNumberOfCalls(t, "Get", 0)
+			},
+		},
+		{
+			name:   "when user has no intro, dont play anything",
+			fields: fields{discordClient: &discordmocks.Client{}, introRepo: &domainmocks.IntroRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			on: func(fields *fields) {
+				fields.discordClient.On("GetChannel", "voice").Return(discord.Channel{Name: "general"}, nil)
+				fields.introRepo.On("Get", "guild", "user").Return(domain.Intro{}, false, nil)
+			},
+		},
+		{
+			name:   "when intro was played recently, dont play it again",
+			fields: fields{discordClient: &discordmocks.Client{}, introRepo: &domainmocks.IntroRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}, lastPlayed: map[string]time.Time{"guild/user": time.Now().Add(-time.Minute)}},
+			on: func(fields *fields) {
+				fields.discordClient.On("GetChannel", "voice").Return(discord.Channel{Name: "general"}, nil)
+				fields.introRepo.On("Get", "guild", "user").Return(intro, true, nil)
+			},
+		},
+		{
+			name:          "when cooldown is over, queue the intro",
+			fields:        fields{discordClient: &discordmocks.Client{}, introRepo: &domainmocks.IntroRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}, lastPlayed: map[string]time.Time{"guild/user": time.Now().Add(-time.Hour)}},
+			expectedQueue: 1,
+			on: func(fields *fields) {
+				fields.discordClient.On("GetChannel", "voice").Return(discord.Channel{Name: "general"}, nil)
+				fields.introRepo.On("Get", "guild", "user").Return(intro, true, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := make(chan playbackRequest, playbackQueueSize)
+			player := &VoicePlayer{queues: map[string]chan playbackRequest{"guild": queue}}
+			lastPlayed := tt.fields.lastPlayed
+			if lastPlayed == nil {
+				lastPlayed = map[string]time.Time{}
+			}
+			service := &IntroManager{
+				discordClient:        tt.fields.discordClient,
+				configChannelName:    "TATERU",
+				introRepo:            tt.fields.introRepo,
+				lockedUserRepository: tt.fields.lockedUsers,
+				player:               player,
+				cooldown:             10 * time.Minute,
+				lastPlayed:           lastPlayed,
+			}
+			if tt.on != nil {
+				tt.on(&tt.fields)
+			}
+			tt.fields.lockedUsers.On("GetCurrentLock", "guild").Return("", make(chan bool))
+
+			err := service.playIntro(NewPlayIntroCommand("guild", "user", "voice"))
+			assert.Equal(t, tt.expectedError, err != nil)
+			assert.Equal(t, tt.expectedQueue, len(queue))
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &tt.fields)
+			}
+		})
+	}
+}
+
+func Test_parseMessageReference(t *testing.T) {
+	tests := []struct {
+		name              string
+		ref               string
+		expectedChannelID string
+		expectedMessageID string
+	}{
+		{name: "message link", ref: "https://discord.com/channels/1/2/3", expectedChannelID: "2", expectedMessageID: "3"},
+		{name: "message id", ref: " 3 ", expectedChannelID: "fallback", expectedMessageID: "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channelID, messageID := parseMessageReference(tt.ref, "fallback")
+			assert.Equal(t, tt.expectedChannelID, channelID)
+			assert.Equal(t, tt.expectedMessageID, messageID)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package application
+
+import (
+	"context"
+	"log"
+
+	"github.com/hectorgabucio/taterubot-dc/kit/job"
+)
+
+// runJob runs the work in a worker of the queue and waits for it, so heavy work never runs in more goroutines than there are workers.
+// It must not be called from a job, the worker would wait for itself when the queue is busy.
+// The work runs right away when the queue does not take it, as when it is closing.
+func runJob(jobs job.Queue, guildID string, priority job.Priority, work func()) {
+	done := make(chan struct{})
+	err := jobs.Submit(context.Background(), job.Job{GuildID: guildID, Priority: priority, Run: func() {
+		defer close(done)
+		work()
+	}})
+	if err != nil {
+		log.Println("err queueing job, running it right away:", err)
+		work()
+		return
+	}
+	<-done
+}
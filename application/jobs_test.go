@@ -0,0 +1,57 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/kit/job"
+	"github.com/stretchr/testify/assert"
+)
+
+// inlineJobs runs every job as soon as it is submitted, recording them, or fails when err is set
+type inlineJobs struct {
+	submitted []job.Job
+	err       error
+}
+
+func (q *inlineJobs) Submit(_ context.Context, j job.Job) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.submitted = append(q.submitted, j)
+	j.Run()
+	return nil
+}
+
+func (q *inlineJobs) Stats() map[job.Priority]job.Stats {
+	return nil
+}
+
+func (q *inlineJobs) Close() error {
+	return nil
+}
+
+func Test_runJob(t *testing.T) {
+	tests := []struct {
+		name              string
+		jobs              *inlineJobs
+		expectedSubmitted int
+	}{
+		{name: "when queue takes the job, wait for a worker to run it", jobs: &inlineJobs{}, expectedSubmitted: 1},
+		{name: "when queue does not take the job, run it right away", jobs: &inlineJobs{err: errors.New("job queue is closed")}, expectedSubmitted: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := 0
+			runJob(tt.jobs, "guild", job.PriorityEncode, func() { runs++ })
+
+			assert.Equal(t, 1, runs)
+			assert.Len(t, tt.jobs.submitted, tt.expectedSubmitted)
+			for _, j := range tt.jobs.submitted {
+				assert.Equal(t, "guild", j.GuildID)
+				assert.Equal(t, job.PriorityEncode, j.Priority)
+			}
+		})
+	}
+}
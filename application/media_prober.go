@@ -0,0 +1,35 @@
+package application
+
+import (
+	"fmt"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+)
+
+// ProberChain probes with the first prober that supports the format, so in process probers are preferred
+// and ffprobe is only needed for what nothing else can read.
+type ProberChain struct {
+	probers []domain.MediaProber
+}
+
+func NewProberChain(probers ...domain.MediaProber) *ProberChain {
+	return &ProberChain{probers: probers}
+}
+
+func (c *ProberChain) Probe(path string, format domain.AudioFormat) (domain.MediaProperties, error) {
+	for _, prober := range c.probers {
+		if prober.Supports(format) {
+			return prober.Probe(path, format)
+		}
+	}
+	return domain.MediaProperties{}, fmt.Errorf("no prober available for %s", format)
+}
+
+func (c *ProberChain) Supports(format domain.AudioFormat) bool {
+	for _, prober := range c.probers {
+		if prober.Supports(format) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/hectorgabucio/taterubot-dc/kit/command"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+)
+
+const PlayCommandType command.Type = "command.play"
+
+// PlayComponentID is the custom id of the button that plays a voice message
+const PlayComponentID = "play"
+
+const (
+	playbackQueueSize   = 10
+	playbackIdleTimeout = 30 * time.Second
+)
+
+type PlayCommand struct {
+	InteractionToken string
+	GuildID          string
+	UserID           string
+	// ChannelID and MessageID identify the voice message to play, when empty the last one sent by TargetUserID is played
+	ChannelID    string
+	MessageID    string
+	TargetUserID string
+}
+
+func NewPlayCommand(interactionToken string, guildID string, userID string, channelID string, messageID string, targetUserID string) PlayCommand {
+	return PlayCommand{
+		InteractionToken: interactionToken,
+		GuildID:          guildID,
+		UserID:           userID,
+		ChannelID:        channelID,
+		MessageID:        messageID,
+		TargetUserID:     targetUserID,
+	}
+}
+
+func (c PlayCommand) Type() command.Type {
+	return PlayCommandType
+}
+
+type PlayCommandHandler struct {
+	service *VoicePlayer
+}
+
+// NewPlayCommandHandler initializes a new PlayCommandHandler.
+func NewPlayCommandHandler(service *VoicePlayer) PlayCommandHandler {
+	return PlayCommandHandler{
+		service: service,
+	}
+}
+
+// Handle implements the command.Handler interface.
+func (h PlayCommandHandler) Handle(ctx context.Context, cmd command.Command) error {
+	playCmd, ok := cmd.(PlayCommand)
+	if !ok {
+		return errors.New("unexpected command")
+	}
+	return h.service.enqueue(playCmd)
+}
+
+type playbackRequest struct {
+	voiceChannelID string
+	channelID      string
+	messageID      string
+	// frames are played as they are instead of fetching the voice message
+	frames [][]byte
+}
+
+type VoicePlayer struct {
+	discordClient        discord.Client
+	localization         *localizations.Localizer
+	voiceDataRepo        domain.VoiceDataRepository
+	lockedUserRepository domain.LockedUserRepository
+	fsRepo               domain.FileRepository
+	transcoder           domain.OpusTranscoder
+
+	mu     sync.Mutex
+	queues map[string]chan playbackRequest
+}
+
+func NewVoicePlayer(discord discord.Client, localization *localizations.Localizer, voiceDataRepo domain.VoiceDataRepository, lockedUserRepository domain.LockedUserRepository, fsRepo domain.FileRepository, transcoder domain.OpusTranscoder) *VoicePlayer {
+	return &VoicePlayer{
+		discordClient:        discord,
+		localization:         localization,
+		voiceDataRepo:        voiceDataRepo,
+		lockedUserRepository: lockedUserRepository,
+		fsRepo:               fsRepo,
+		transcoder:           transcoder,
+		queues:               make(map[string]chan playbackRequest),
+	}
+}
+
+func (service *VoicePlayer) enqueue(cmd PlayCommand) error {
+	voiceChannelID, err := service.discordClient.GetUserVoiceChannel(cmd.GuildID, cmd.UserID)
+	if err != nil {
+		return fmt.Errorf("err getting user voice channel, %w", err)
+	}
+	if voiceChannelID == "" {
+		return service.reply(cmd.InteractionToken, "texts.playback_not_in_voice")
+	}
+	if lockedUser, _ := service.lockedUserRepository.GetCurrentLock(cmd.GuildID); lockedUser != "" {
+		return service.reply(cmd.InteractionToken, "texts.playback_busy")
+	}
+
+	request := playbackRequest{voiceChannelID: voiceChannelID, channelID: cmd.ChannelID, messageID: cmd.MessageID}
+	if request.messageID == "" {
+		voiceData, found, err := service.getLastVoiceData(cmd.GuildID, cmd.TargetUserID)
+		if err != nil {
+			return err
+		}
+		if !found || voiceData.MessageID == "" {
+			return service.reply(cmd.InteractionToken, "texts.playback_not_found")
+		}
+		request.channelID = voiceData.ChannelID
+		request.messageID = voiceData.MessageID
+	}
+
+	position, ok := service.push(cmd.GuildID, request)
+	if !ok {
+		return service.reply(cmd.InteractionToken, "texts.playback_queue_full")
+	}
+	return service.reply(cmd.InteractionToken, "texts.playback_queued", &localizations.Replacements{"position": position})
+}
+
+// enqueueFrames queues already decoded audio to be played in the voice channel, false if the queue is full
+func (service *VoicePlayer) enqueueFrames(guildID string, voiceChannelID string, frames [][]byte) (int, bool) {
+	return service.push(guildID, playbackRequest{voiceChannelID: voiceChannelID, frames: frames})
+}
+
+// push adds the request to the queue of the guild, starting it if needed, and returns its position
+func (service *VoicePlayer) push(guildID string, request playbackRequest) (int, bool) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	queue, ok := service.queues[guildID]
+	if !ok {
+		queue = make(chan playbackRequest, playbackQueueSize)
+		service.queues[guildID] = queue
+		go service.playQueue(guildID, queue)
+	}
+	select {
+	case queue <- request:
+		return len(queue), true
+	default:
+		return 0, false
+	}
+}
+
+func (service *VoicePlayer) getLastVoiceData(guildID string, userID string) (domain.VoiceData, bool, error) {
+	var voiceData domain.VoiceData
+	var found bool
+	var err error
+	if userID != "" {
+		voiceData, found, err = service.voiceDataRepo.GetLastByUser(guildID, userID)
+	} else {
+		voiceData, found, err = service.voiceDataRepo.GetLastByGuild(guildID)
+	}
+	if err != nil {
+		return domain.VoiceData{}, false, fmt.Errorf("err getting last voice data, %w", err)
+	}
+	return voiceData, found, nil
+}
+
+// playQueue plays the requests of a guild one after the other, leaving the channel once the queue stays empty
+func (service *VoicePlayer) playQueue(guildID string, queue chan playbackRequest) {
+	var voice *discord.VoiceConnection
+	voiceChannelID := ""
+	defer func() {
+		if voice == nil {
+			return
+		}
+		if err := service.discordClient.DisconnectVoice(voice); err != nil {
+			log.Println("err leaving playback voice channel", err)
+		}
+	}()
+	for {
+		select {
+		case request := <-queue:
+			if request.voiceChannelID != voiceChannelID {
+				var err error
+				voice, err = service.discordClient.EstablishPlaybackConnection(guildID, request.voiceChannelID)
+				if err != nil {
+					log.Println("err joining voice channel to play", err)
+					voiceChannelID = ""
+					continue
+				}
+				voiceChannelID = request.voiceChannelID
+			}
+			if err := service.play(voice, request); err != nil {
+				log.Println("err playing voice message", err)
+			}
+		case <-time.After(playbackIdleTimeout):
+			service.mu.Lock()
+			if len(queue) == 0 {
+				delete(service.queues, guildID)
+				service.mu.Unlock()
+				return
+			}
+			service.mu.Unlock()
+		}
+	}
+}
+
+func (service *VoicePlayer) play(voice *discord.VoiceConnection, request playbackRequest) error {
+	if request.frames != nil {
+		if err := service.discordClient.PlayOpus(voice, request.frames); err != nil {
+			return fmt.Errorf("err playing audio, %w", err)
+		}
+		return nil
+	}
+	message, err := service.discordClient.GetMessage(request.channelID, request.messageID)
+	if err != nil {
+		return fmt.Errorf("err getting voice message, %w", err)
+	}
+	if message.AttachmentURL == "" {
+		return errors.New("voice message has no audio attached")
+	}
+	fileName := fmt.Sprintf("%s-playback", request.messageID)
+	defer service.fsRepo.DeleteAll(fileName)
+	if err := downloadFile(service.fsRepo, message.AttachmentURL, fileName); err != nil {
+		return err
+	}
+	frames, err := service.transcoder.ToOpusFrames(fileName)
+	if err != nil {
+		return fmt.Errorf("err transcoding voice message, %w", err)
+	}
+	if err := service.discordClient.PlayOpus(voice, frames); err != nil {
+		return fmt.Errorf("err playing voice message, %w", err)
+	}
+	return nil
+}
+
+func (service *VoicePlayer) reply(interactionToken string, key string, replacements ...*localizations.Replacements) error {
+	if err := service.discordClient.EditInteraction(interactionToken, service.localization.Get(key, replacements...)); err != nil {
+		return fmt.Errorf("err sending interaction response, %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestVoicePlayer_enqueue(t *testing.T) {
+	type fields struct {
+		discordClient *discordmocks.Client
+		voiceDataRepo *domainmocks.VoiceDataRepository
+		lockedUsers   *domainmocks.LockedUserRepository
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		cmd           PlayCommand
+		expectedError bool
+		expectedQueue int
+		on            func(*fields)
+		assertMocks   func(t *testing.T, f *fields)
+	}{
+		{
+			name:          "when getting user voice channel fails, return error",
+			fields:        fields{discordClient: &discordmocks.Client{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			cmd:           NewPlayCommand("token", "1", "user", "", "", ""),
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.discordClient.On("GetUserVoiceChannel", "1", "user").Return("", errors.New("err state"))
+			},
+		},
+		{
+			name:   "when user is not in a voice channel, tell it",
+			fields: fields{discordClient: &discordmocks.Client{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			cmd:    NewPlayCommand("token", "1", "user", "", "", ""),
+			on: func(fields *fields) {
+				fields.discordClient.On("GetUserVoiceChannel", "1", "user").Return("", nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertNumberOfCalls(t, "EditInteraction", 1)
+			},
+		},
+		{
+			name:   "when someone is being recorded, dont queue anything",
+			fields: fields{discordClient: &discordmocks.Client{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			cmd:    NewPlayCommand("token", "1", "user", "", "", ""),
+			on: func(fields *fields) {
+				fields.lockedUsers.On("GetCurrentLock", "1").Return("recorded-user", make(chan bool))
+				fields.discordClient.On("GetUserVoiceChannel", "1", "user").Return("voice", nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.voiceDataRepo.AssertNumberOfCalls(t, "GetLastByGuild", 0)
+			},
+		},
+		{
+			name:   "when there is no voice message to play, tell it",
+			fields: fields{discordClient: &discordmocks.Client{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			cmd:    NewPlayCommand("token", "1", "user", "", "", ""),
+			on: func(fields *fields) {
+				fields.discordClient.On("GetUserVoiceChannel", "1", "user").Return("voice", nil)
+				fields.voiceDataRepo.On("GetLastByGuild", "1").Return(domain.VoiceData{}, false, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertNumberOfCalls(t, "EditInteraction", 1)
+			},
+		},
+		{
+			name:          "queue the last voice message of the target user",
+			fields:        fields{discordClient: &discordmocks.Client{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			cmd:           NewPlayCommand("token", "1", "user", "", "", "target"),
+			expectedQueue: 1,
+			on: func(fields *fields) {
+				fields.discordClient.On("GetUserVoiceChannel", "1", "user").Return("voice", nil)
+				fields.voiceDataRepo.On("GetLastByUser", "1", "target").Return(domain.VoiceData{ChannelID: "text", MessageID: "msg"}, true, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.voiceDataRepo.AssertNumberOfCalls(t, "GetLastByUser", 1)
+			},
+		},
+		{
+			name:          "queue the clicked voice message without looking for the last one",
+			fields:        fields{discordClient: &discordmocks.Client{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			cmd:           NewPlayCommand("token", "1", "user", "text", "msg", ""),
+			expectedQueue: 1,
+			on: func(fields *fields) {
+				fields.discordClient.On("GetUserVoiceChannel", "1", "user").Return("voice", nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.voiceDataRepo.AssertNumberOfCalls(t, "GetLastByGuild", 0)
+				f.voiceDataRepo.AssertNumberOfCalls(t, "GetLastByUser", 0)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := make(chan playbackRequest, playbackQueueSize)
+			service := &VoicePlayer{
+				discordClient:        tt.fields.discordClient,
+				localization:         localizations.New("en", "en"),
+				voiceDataRepo:        tt.fields.voiceDataRepo,
+				lockedUserRepository: tt.fields.lockedUsers,
+				// an already running queue, so nothing gets played during the test
+				queues: map[string]chan playbackRequest{"1": queue},
+			}
+			if tt.on != nil {
+				tt.on(&tt.fields)
+			}
+			tt.fields.lockedUsers.On("GetCurrentLock", "1").Return("", make(chan bool))
+			err := service.enqueue(tt.cmd)
+
+			assert.Equal(t, tt.expectedError, err != nil)
+			assert.Equal(t, tt.expectedQueue, len(queue))
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &tt.fields)
+			}
+		})
+	}
+}
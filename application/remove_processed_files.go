@@ -23,7 +23,8 @@ func (handler *RemoveFilesWhenNotNeeded) Handle(ctx context.Context, evt event.E
 	}
 	handler.fsRepo.DeleteAll(
 		fmt.Sprintf("%s.png", doneProcessingEvt.AggregateID()),
-		fmt.Sprintf("%s.mp3", doneProcessingEvt.AggregateID()),
+		fmt.Sprintf("%s-waveform.png", doneProcessingEvt.AggregateID()),
+		fmt.Sprintf("%s.%s", doneProcessingEvt.AggregateID(), doneProcessingEvt.AudioFormat.Extension()),
 		fmt.Sprintf("%s.ogg", doneProcessingEvt.AggregateID()),
 	)
 
@@ -35,10 +35,10 @@ func TestRemoveFilesWhenNotNeeded_Handle(t *testing.T) {
 		{
 			name:    "correct event should trigger 3 files removal",
 			fields:  fields{&domainmocks.FileRepository{}},
-			args:    args{evt: domain.NewDoneProcessingFilesEvent("1")},
+			args:    args{evt: domain.NewDoneProcessingFilesEvent("1", domain.AudioFormatMP3)},
 			wantErr: false,
 			on: func(fields *fields) {
-				fields.fsRepo.On("DeleteAll", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return()
+				fields.fsRepo.On("DeleteAll", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return()
 			},
 			assertMocks: func(t *testing.T, f *fields) {
 				f.fsRepo.AssertNumberOfCalls(t, "DeleteAll", 1)
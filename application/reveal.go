@@ -0,0 +1,122 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/hectorgabucio/taterubot-dc/kit/command"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+)
+
+const RevealCommandType command.Type = "command.reveal"
+
+type RevealCommand struct {
+	InteractionToken string
+	GuildID          string
+	ModeratorID      string
+	ChannelID        string
+	// MessageRef is the link or id of the anonymous voice message
+	MessageRef string
+	Reason     string
+}
+
+func NewRevealCommand(interactionToken string, guildID string, moderatorID string, channelID string, messageRef string, reason string) RevealCommand {
+	return RevealCommand{
+		InteractionToken: interactionToken,
+		GuildID:          guildID,
+		ModeratorID:      moderatorID,
+		ChannelID:        channelID,
+		MessageRef:       messageRef,
+		Reason:           reason,
+	}
+}
+
+func (c RevealCommand) Type() command.Type {
+	return RevealCommandType
+}
+
+type RevealCommandHandler struct {
+	service *AuthorRevealer
+}
+
+// NewRevealCommandHandler initializes a new RevealCommandHandler.
+func NewRevealCommandHandler(service *AuthorRevealer) RevealCommandHandler {
+	return RevealCommandHandler{
+		service: service,
+	}
+}
+
+// Handle implements the command.Handler interface.
+func (h RevealCommandHandler) Handle(ctx context.Context, cmd command.Command) error {
+	revealCmd, ok := cmd.(RevealCommand)
+	if !ok {
+		return errors.New("unexpected command")
+	}
+	return h.service.reveal(revealCmd)
+}
+
+// AuthorRevealer tells moderators who sent an anonymous voice message, every reveal is audited before answering
+type AuthorRevealer struct {
+	discordClient discord.Client
+	localization  *localizations.Localizer
+	voiceDataRepo domain.VoiceDataRepository
+	auditRepo     domain.RevealAuditRepository
+	hasher        domain.AuthorHasher
+}
+
+func NewAuthorRevealer(discord discord.Client, localization *localizations.Localizer, voiceDataRepo domain.VoiceDataRepository, auditRepo domain.RevealAuditRepository, hasher domain.AuthorHasher) *AuthorRevealer {
+	return &AuthorRevealer{
+		discordClient: discord,
+		localization:  localization,
+		voiceDataRepo: voiceDataRepo,
+		auditRepo:     auditRepo,
+		hasher:        hasher,
+	}
+}
+
+func (service *AuthorRevealer) reveal(cmd RevealCommand) error {
+	reason := strings.TrimSpace(cmd.Reason)
+	if reason == "" {
+		return service.reply(cmd.InteractionToken, "texts.reveal_reason_required")
+	}
+	_, messageID := parseMessageReference(cmd.MessageRef, cmd.ChannelID)
+	voiceData, found, err := service.voiceDataRepo.GetByMessage(cmd.GuildID, messageID)
+	if err != nil {
+		return fmt.Errorf("err getting voice data of message, %w", err)
+	}
+	if !found || !voiceData.Anonymous {
+		return service.reply(cmd.InteractionToken, "texts.reveal_not_anonymous")
+	}
+
+	// nothing is revealed unless the audit is kept
+	audit := domain.RevealAudit{GuildID: cmd.GuildID, MessageID: messageID, ModeratorID: cmd.ModeratorID, Reason: reason, CreatedAt: time.Now()}
+	if err := service.auditRepo.Save(audit); err != nil {
+		return fmt.Errorf("err saving reveal audit, %w", err)
+	}
+	log.Printf("moderator %s revealed the author of anonymous message %s in guild %s: %s\n", cmd.ModeratorID, messageID, cmd.GuildID, reason)
+
+	// the hash cannot be reversed, so it is compared with the hash of every member
+	users, err := service.discordClient.GetGuildUsers(cmd.GuildID)
+	if err != nil {
+		return fmt.Errorf("err getting guild users, %w", err)
+	}
+	for _, user := range users {
+		if service.hasher.Hash(cmd.GuildID, user.ID) == voiceData.UserID {
+			return service.reply(cmd.InteractionToken, "texts.reveal_author", &localizations.Replacements{"user": fmt.Sprintf("<@%s>", user.ID)})
+		}
+	}
+	return service.reply(cmd.InteractionToken, "texts.reveal_author_left")
+}
+
+func (service *AuthorRevealer) reply(interactionToken string, key string, replacements ...*localizations.Replacements) error {
+	if err := service.discordClient.EditInteraction(interactionToken, service.localization.Get(key, replacements...)); err != nil {
+		return fmt.Errorf("err sending interaction response, %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package application
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuthorRevealer_reveal(t *testing.T) {
+	hasher := domain.NewAuthorHasher("secret")
+	type fields struct {
+		discordClient *discordmocks.Client
+		voiceDataRepo *domainmocks.VoiceDataRepository
+		auditRepo     *domainmocks.RevealAuditRepository
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		cmd           RevealCommand
+		expectedError bool
+		on            func(*fields)
+		assertMocks   func(t *testing.T, f *fields)
+	}{
+		{
+			name:   "when there is no reason, dont reveal it",
+			fields: fields{discordClient: &discordmocks.Client{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}, auditRepo: &domainmocks.RevealAuditRepository{}},
+			cmd:    NewRevealCommand("token", "guild", "mod", "channel", "msg", "  "),
+			on: func(fields *fields) {
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.voiceDataRepo.AssertNumberOfCalls(t, "GetByMessage", 0)
+				f.auditRepo.AssertNumberOfCalls(t, "Save", 0)
+			},
+		},
+		{
+			name:   "when message is not anonymous, tell it without auditing",
+			fields: fields{discordClient: &discordmocks.Client{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}, auditRepo: &domainmocks.RevealAuditRepository{}},
+			cmd:    NewRevealCommand("token", "guild", "mod", "channel", "https://discord.com/channels/guild/other/msg", "reported"),
+			on: func(fields *fields) {
+				fields.voiceDataRepo.On("GetByMessage", "guild", "msg").Return(domain.VoiceData{UserID: "user"}, true, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.auditRepo.AssertNumberOfCalls(t, "Save", 0)
+				f.discordClient.AssertNumberOfCalls(t, "GetGuildUsers", 0)
+			},
+		},
+		{
+			name:          "when audit cannot be saved, return error without revealing",
+			fields:        fields{discordClient: &discordmocks.Client{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}, auditRepo: &domainmocks.RevealAuditRepository{}},
+			cmd:           NewRevealCommand("token", "guild", "mod", "channel", "msg", "reported"),
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.voiceDataRepo.On("GetByMessage", "guild", "msg").Return(domain.VoiceData{UserID: hasher.Hash("guild", "user"), Anonymous: true}, true, nil)
+				fields.auditRepo.On("Save", mock.AnythingOfType("domain.RevealAudit")).Return(errors.New("err db"))
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertNumberOfCalls(t, "GetGuildUsers", 0)
+				f.discordClient.AssertNumberOfCalls(t, "EditInteraction", 0)
+			},
+		},
+		{
+			name:   "audit and reveal the member whose hash matches",
+			fields: fields{discordClient: &discordmocks.Client{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}, auditRepo: &domainmocks.RevealAuditRepository{}},
+			cmd:    NewRevealCommand("token", "guild", "mod", "channel", "msg", " reported "),
+			on: func(fields *fields) {
+				fields.voiceDataRepo.On("GetByMessage", "guild", "msg").Return(domain.VoiceData{UserID: hasher.Hash("guild", "user"), Anonymous: true}, true, nil)
+				fields.auditRepo.On("Save", mock.MatchedBy(func(audit domain.RevealAudit) bool {
+					return audit.GuildID == "guild" && audit.MessageID == "msg" && audit.ModeratorID == "mod" && audit.Reason == "reported"
+				})).Return(nil)
+				fields.discordClient.On("GetGuildUsers", "guild").Return([]discord.User{{ID: "other"}, {ID: "user"}}, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.MatchedBy(func(message string) bool {
+					return strings.Contains(message, "<@user>")
+				})).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.auditRepo.AssertNumberOfCalls(t, "Save", 1)
+				f.discordClient.AssertNumberOfCalls(t, "EditInteraction", 1)
+			},
+		},
+		{
+			name:   "when author left the guild, tell it",
+			fields: fields{discordClient: &discordmocks.Client{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}, auditRepo: &domainmocks.RevealAuditRepository{}},
+			cmd:    NewRevealCommand("token", "guild", "mod", "channel", "msg", "reported"),
+			on: func(fields *fields) {
+				fields.voiceDataRepo.On("GetByMessage", "guild", "msg").Return(domain.VoiceData{UserID: hasher.Hash("guild", "user"), Anonymous: true}, true, nil)
+				fields.auditRepo.On("Save", mock.AnythingOfType("domain.RevealAudit")).Return(nil)
+				fields.discordClient.On("GetGuildUsers", "guild").Return([]discord.User{{ID: "other"}}, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertNumberOfCalls(t, "EditInteraction", 1)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &AuthorRevealer{
+				discordClient: tt.fields.discordClient,
+				localization:  localizations.New("en", "en"),
+				voiceDataRepo: tt.fields.voiceDataRepo,
+				auditRepo:     tt.fields.auditRepo,
+				hasher:        hasher,
+			}
+			if tt.on != nil {
+				tt.on(&tt.fields)
+			}
+			err := service.reveal(tt.cmd)
+			assert.Equal(t, tt.expectedError, err != nil)
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &tt.fields)
+			}
+		})
+	}
+}
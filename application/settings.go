@@ -0,0 +1,184 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/hectorgabucio/taterubot-dc/kit/command"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+)
+
+const SettingsCommandType command.Type = "command.settings"
+
+// Settings that can be changed per guild
+const (
+	SettingStartCue         = "start-cue"
+	SettingVoiceMessages    = "voice-messages"
+	SettingFormat           = "format"
+	SettingBitrate          = "bitrate"
+	SettingNormalize        = "normalize"
+	SettingTrimSilence      = "trim-silence"
+	SettingSilenceThreshold = "silence-threshold"
+	// SettingAnonymousChannel takes the id of a voice channel, empty to stop recording anonymously
+	SettingAnonymousChannel = "anonymous-channel"
+)
+
+type SettingsCommand struct {
+	InteractionToken string
+	GuildID          string
+	Setting          string
+	Value            string
+}
+
+func NewSettingsCommand(interactionToken string, guildID string, setting string, value string) SettingsCommand {
+	return SettingsCommand{InteractionToken: interactionToken, GuildID: guildID, Setting: setting, Value: value}
+}
+
+func (c SettingsCommand) Type() command.Type {
+	return SettingsCommandType
+}
+
+type SettingsCommandHandler struct {
+	service *GuildSettingsUpdater
+}
+
+// NewSettingsCommandHandler initializes a new SettingsCommandHandler.
+func NewSettingsCommandHandler(service *GuildSettingsUpdater) SettingsCommandHandler {
+	return SettingsCommandHandler{
+		service: service,
+	}
+}
+
+// Handle implements the command.Handler interface.
+func (h SettingsCommandHandler) Handle(ctx context.Context, cmd command.Command) error {
+	settingsCmd, ok := cmd.(SettingsCommand)
+	if !ok {
+		return errors.New("unexpected command")
+	}
+	return h.service.update(settingsCmd.InteractionToken, settingsCmd.GuildID, settingsCmd.Setting, settingsCmd.Value)
+}
+
+type GuildSettingsUpdater struct {
+	discordClient discord.Client
+	localization  *localizations.Localizer
+	settingsRepo  domain.GuildSettingsRepository
+	encoder       domain.AudioEncoder
+	startCue      domain.StartCue
+}
+
+func NewGuildSettingsUpdater(discord discord.Client, localization *localizations.Localizer, settingsRepo domain.GuildSettingsRepository, encoder domain.AudioEncoder, startCue domain.StartCue) *GuildSettingsUpdater {
+	return &GuildSettingsUpdater{
+		discord,
+		localization,
+		settingsRepo,
+		encoder,
+		startCue,
+	}
+}
+
+func (service *GuildSettingsUpdater) update(interactionToken string, guildID string, setting string, value string) error {
+	settings, err := service.settingsRepo.Get(guildID)
+	if err != nil {
+		return fmt.Errorf("err getting guild settings, %w", err)
+	}
+	if err := applySetting(&settings, setting, value); err != nil {
+		return err
+	}
+	if setting == SettingFormat && !service.encoder.Supports(settings.AudioFormat) {
+		return service.reply(interactionToken, "texts.settings_format_unavailable", &localizations.Replacements{"format": settings.AudioFormat.Label(), "available": service.availableFormats()})
+	}
+	if setting == SettingStartCue && settings.StartCueEnabled && !service.startCue.Available() {
+		return service.reply(interactionToken, "texts.settings_start_cue_unavailable")
+	}
+	if setting == SettingAnonymousChannel && value != "" && !service.encoder.SupportsEffect(domain.VoiceEffectDisguise) {
+		return service.reply(interactionToken, "texts.settings_anonymous_unavailable")
+	}
+	if err := service.settingsRepo.Save(settings); err != nil {
+		return fmt.Errorf("err saving guild settings, %w", err)
+	}
+	if setting == SettingAnonymousChannel {
+		if value == "" {
+			return service.reply(interactionToken, "texts.settings_anonymous_cleared")
+		}
+		return service.reply(interactionToken, "texts.settings_anonymous_set", &localizations.Replacements{"channel": fmt.Sprintf("<#%s>", value)})
+	}
+	return service.reply(interactionToken, "texts.settings_updated", &localizations.Replacements{"setting": setting, "value": value})
+}
+
+func (service *GuildSettingsUpdater) availableFormats() string {
+	var labels []string
+	for _, format := range domain.SupportedAudioFormats(service.encoder) {
+		labels = append(labels, format.Label())
+	}
+	return strings.Join(labels, ", ")
+}
+
+func (service *GuildSettingsUpdater) reply(interactionToken string, key string, replacements ...*localizations.Replacements) error {
+	if err := service.discordClient.EditInteraction(interactionToken, service.localization.Get(key, replacements...)); err != nil {
+		return fmt.Errorf("err sending interaction response, %w", err)
+	}
+	return nil
+}
+
+func applySetting(settings *domain.GuildSettings, setting string, value string) error {
+	switch setting {
+	case SettingStartCue:
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("err parsing %s value, %w", setting, err)
+		}
+		settings.StartCueEnabled = enabled
+	case SettingVoiceMessages:
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("err parsing %s value, %w", setting, err)
+		}
+		settings.VoiceMessagesEnabled = enabled
+	case SettingFormat:
+		format, ok := domain.ParseAudioFormat(value)
+		if !ok {
+			return fmt.Errorf("unsupported audio format %s", value)
+		}
+		settings.AudioFormat = format
+	case SettingBitrate:
+		bitrate, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("err parsing %s value, %w", setting, err)
+		}
+		if bitrate < domain.MinAudioBitrate || bitrate > domain.MaxAudioBitrate {
+			return fmt.Errorf("bitrate %d out of range", bitrate)
+		}
+		settings.AudioBitrate = bitrate
+	case SettingNormalize:
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("err parsing %s value, %w", setting, err)
+		}
+		settings.NormalizeEnabled = enabled
+	case SettingTrimSilence:
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("err parsing %s value, %w", setting, err)
+		}
+		settings.TrimSilenceEnabled = enabled
+	case SettingSilenceThreshold:
+		threshold, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("err parsing %s value, %w", setting, err)
+		}
+		if threshold < domain.MinSilenceThreshold || threshold > domain.MaxSilenceThreshold {
+			return fmt.Errorf("silence threshold %d out of range", threshold)
+		}
+		settings.SilenceThreshold = threshold
+	case SettingAnonymousChannel:
+		settings.AnonymousChannelID = value
+	default:
+		return fmt.Errorf("unknown setting %s", setting)
+	}
+	return nil
+}
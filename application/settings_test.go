@@ -0,0 +1,309 @@
+package application
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGuildSettingsUpdater_update(t *testing.T) {
+	type fields struct {
+		discordClient *discordmocks.Client
+		localization  *localizations.Localizer
+		settingsRepo  *domainmocks.GuildSettingsRepository
+		encoder       *domainmocks.AudioEncoder
+		startCue      *domainmocks.StartCue
+	}
+	type args struct {
+		setting string
+		value   string
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		args          args
+		expectedError bool
+		on            func(*fields)
+		assertMocks   func(t *testing.T, f *fields)
+	}{
+		{
+			name:          "when getting settings fails, return error",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}},
+			args:          args{setting: SettingStartCue, value: "true"},
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.GuildSettings{}, errors.New("err db"))
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 0)
+			},
+		},
+		{
+			name:          "when setting is unknown, return error without saving",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}},
+			args:          args{setting: "unknown", value: "true"},
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 0)
+			},
+		},
+		{
+			name:          "when value is not valid, return error without saving",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}},
+			args:          args{setting: SettingStartCue, value: "maybe"},
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 0)
+			},
+		},
+		{
+			name:          "disable start cue and confirm it",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}, localization: localizations.New("en", "en")},
+			args:          args{setting: SettingStartCue, value: "false"},
+			expectedError: false,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+				fields.settingsRepo.On("Save", domain.GuildSettings{GuildID: "1", StartCueEnabled: false, VoiceMessagesEnabled: true, AudioFormat: domain.AudioFormatMP3, AudioBitrate: 96, SilenceThreshold: -50}).Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 1)
+				f.discordClient.AssertNumberOfCalls(t, "EditInteraction", 1)
+			},
+		},
+		{
+			name:          "enable start cue and confirm it",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}, localization: localizations.New("en", "en")},
+			args:          args{setting: SettingStartCue, value: "true"},
+			expectedError: false,
+			on: func(fields *fields) {
+				settings := domain.DefaultGuildSettings("1")
+				settings.StartCueEnabled = false
+				fields.settingsRepo.On("Get", "1").Return(settings, nil)
+				fields.settingsRepo.On("Save", domain.DefaultGuildSettings("1")).Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 1)
+			},
+		},
+		{
+			name:          "when start cue cannot be played in this host, do not enable it",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}, localization: localizations.New("en", "en"), startCue: &domainmocks.StartCue{}},
+			args:          args{setting: SettingStartCue, value: "true"},
+			expectedError: false,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+				fields.startCue.On("Available").Return(false)
+				fields.discordClient.On("EditInteraction", "token", mock.MatchedBy(func(message string) bool {
+					return strings.Contains(message, "ffmpeg")
+				})).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 0)
+				f.discordClient.AssertExpectations(t)
+			},
+		},
+		{
+			name:          "disable voice messages and confirm it",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}, localization: localizations.New("en", "en")},
+			args:          args{setting: SettingVoiceMessages, value: "false"},
+			expectedError: false,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+				fields.settingsRepo.On("Save", domain.GuildSettings{GuildID: "1", StartCueEnabled: true, VoiceMessagesEnabled: false, AudioFormat: domain.AudioFormatMP3, AudioBitrate: 96, SilenceThreshold: -50}).Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 1)
+			},
+		},
+		{
+			name:          "when format is not supported, return error without saving",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}},
+			args:          args{setting: SettingFormat, value: "flac"},
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 0)
+			},
+		},
+		{
+			name:          "change format and confirm it",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}, localization: localizations.New("en", "en")},
+			args:          args{setting: SettingFormat, value: "m4a"},
+			expectedError: false,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+				fields.settingsRepo.On("Save", domain.GuildSettings{GuildID: "1", StartCueEnabled: true, VoiceMessagesEnabled: true, AudioFormat: domain.AudioFormatM4A, AudioBitrate: 96, SilenceThreshold: -50}).Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 1)
+			},
+		},
+		{
+			name:          "when format is not available in this host, tell it without saving",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}, localization: localizations.New("en", "en"), encoder: &domainmocks.AudioEncoder{}},
+			args:          args{setting: SettingFormat, value: "m4a"},
+			expectedError: false,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+				fields.encoder.On("Supports", domain.AudioFormatM4A).Return(false)
+				fields.discordClient.On("EditInteraction", "token", mock.MatchedBy(func(message string) bool {
+					return strings.Contains(message, "OGG, MP3, WAV")
+				})).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 0)
+				f.discordClient.AssertNumberOfCalls(t, "EditInteraction", 1)
+			},
+		},
+		{
+			name:          "enable normalization and confirm it",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}, localization: localizations.New("en", "en")},
+			args:          args{setting: SettingNormalize, value: "true"},
+			expectedError: false,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+				fields.settingsRepo.On("Save", domain.GuildSettings{GuildID: "1", StartCueEnabled: true, VoiceMessagesEnabled: true, AudioFormat: domain.AudioFormatMP3, AudioBitrate: 96, NormalizeEnabled: true, SilenceThreshold: -50}).Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 1)
+			},
+		},
+		{
+			name:          "when silence threshold is out of range, return error without saving",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}},
+			args:          args{setting: SettingSilenceThreshold, value: "-5"},
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 0)
+			},
+		},
+		{
+			name:          "when bitrate is out of range, return error without saving",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}},
+			args:          args{setting: SettingBitrate, value: "1000"},
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 0)
+			},
+		},
+		{
+			name:          "change bitrate and confirm it",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}, localization: localizations.New("en", "en")},
+			args:          args{setting: SettingBitrate, value: "128"},
+			expectedError: false,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+				fields.settingsRepo.On("Save", domain.GuildSettings{GuildID: "1", StartCueEnabled: true, VoiceMessagesEnabled: true, AudioFormat: domain.AudioFormatMP3, AudioBitrate: 128, SilenceThreshold: -50}).Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 1)
+			},
+		},
+		{
+			name:          "when voice disguise is not available, refuse the anonymous channel without saving",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}, localization: localizations.New("en", "en"), encoder: &domainmocks.AudioEncoder{}},
+			args:          args{setting: SettingAnonymousChannel, value: "voice"},
+			expectedError: false,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+				fields.encoder.On("SupportsEffect", domain.VoiceEffectDisguise).Return(false)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 0)
+				f.discordClient.AssertNumberOfCalls(t, "EditInteraction", 1)
+			},
+		},
+		{
+			name:          "set anonymous channel and confirm it",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}, localization: localizations.New("en", "en"), encoder: &domainmocks.AudioEncoder{}},
+			args:          args{setting: SettingAnonymousChannel, value: "voice"},
+			expectedError: false,
+			on: func(fields *fields) {
+				fields.settingsRepo.On("Get", "1").Return(domain.DefaultGuildSettings("1"), nil)
+				fields.encoder.On("SupportsEffect", domain.VoiceEffectDisguise).Return(true)
+				fields.settingsRepo.On("Save", domain.GuildSettings{GuildID: "1", StartCueEnabled: true, VoiceMessagesEnabled: true, AudioFormat: domain.AudioFormatMP3, AudioBitrate: 96, SilenceThreshold: -50, AnonymousChannelID: "voice"}).Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.MatchedBy(func(message string) bool {
+					return strings.Contains(message, "<#voice>")
+				})).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 1)
+				f.discordClient.AssertNumberOfCalls(t, "EditInteraction", 1)
+			},
+		},
+		{
+			name:          "clear anonymous channel without checking the disguise",
+			fields:        fields{discordClient: &discordmocks.Client{}, settingsRepo: &domainmocks.GuildSettingsRepository{}, localization: localizations.New("en", "en"), encoder: &domainmocks.AudioEncoder{}},
+			args:          args{setting: SettingAnonymousChannel, value: ""},
+			expectedError: false,
+			on: func(fields *fields) {
+				settings := domain.DefaultGuildSettings("1")
+				settings.AnonymousChannelID = "voice"
+				fields.settingsRepo.On("Get", "1").Return(settings, nil)
+				fields.settingsRepo.On("Save", domain.DefaultGuildSettings("1")).Return(nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.settingsRepo.AssertNumberOfCalls(t, "Save", 1)
+				f.encoder.AssertNumberOfCalls(t, "SupportsEffect", 0)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.fields.encoder == nil {
+				tt.fields.encoder = &domainmocks.AudioEncoder{}
+			}
+			if tt.fields.startCue == nil {
+				tt.fields.startCue = &domainmocks.StartCue{}
+			}
+			service := &GuildSettingsUpdater{
+				discordClient: tt.fields.discordClient,
+				localization:  tt.fields.localization,
+				settingsRepo:  tt.fields.settingsRepo,
+				encoder:       tt.fields.encoder,
+				startCue:      tt.fields.startCue,
+			}
+			if tt.on != nil {
+				tt.on(&tt.fields)
+			}
+			tt.fields.encoder.On("Supports", mock.Anything).Return(true)
+			tt.fields.startCue.On("Available").Return(true)
+			err := service.update("token", "1", tt.args.setting, tt.args.value)
+
+			assert.Equal(t, tt.expectedError, err != nil)
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &tt.fields)
+			}
+		})
+	}
+}
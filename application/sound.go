@@ -0,0 +1,343 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/hectorgabucio/taterubot-dc/kit/command"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+)
+
+const SoundCommandType command.Type = "command.sound"
+
+const SoundAutocompleteCommandType command.Type = "command.sound.autocomplete"
+
+const (
+	SoundActionSave = "save"
+	SoundActionPlay = "play"
+)
+
+const (
+	maxSoundNameLength = 32
+	// maxAutocompleteChoices is the most choices discord accepts in an autocomplete response
+	maxAutocompleteChoices = 25
+)
+
+type SoundCommand struct {
+	InteractionToken string
+	GuildID          string
+	UserID           string
+	ChannelID        string
+	Action           string
+	Name             string
+	// MessageRef is the link or id of the voice message to save, when empty the last one of the guild is saved
+	MessageRef string
+}
+
+func NewSoundCommand(interactionToken string, guildID string, userID string, channelID string, action string, name string, messageRef string) SoundCommand {
+	return SoundCommand{
+		InteractionToken: interactionToken,
+		GuildID:          guildID,
+		UserID:           userID,
+		ChannelID:        channelID,
+		Action:           action,
+		Name:             name,
+		MessageRef:       messageRef,
+	}
+}
+
+func (c SoundCommand) Type() command.Type {
+	return SoundCommandType
+}
+
+type SoundAutocompleteCommand struct {
+	InteractionID    string
+	InteractionToken string
+	GuildID          string
+	Prefix           string
+}
+
+func NewSoundAutocompleteCommand(interactionID string, interactionToken string, guildID string, prefix string) SoundAutocompleteCommand {
+	return SoundAutocompleteCommand{
+		InteractionID:    interactionID,
+		InteractionToken: interactionToken,
+		GuildID:          guildID,
+		Prefix:           prefix,
+	}
+}
+
+func (c SoundAutocompleteCommand) Type() command.Type {
+	return SoundAutocompleteCommandType
+}
+
+type SoundCommandHandler struct {
+	service *Soundboard
+}
+
+// NewSoundCommandHandler initializes a new SoundCommandHandler.
+func NewSoundCommandHandler(service *Soundboard) SoundCommandHandler {
+	return SoundCommandHandler{
+		service: service,
+	}
+}
+
+// Handle implements the command.Handler interface.
+func (h SoundCommandHandler) Handle(ctx context.Context, cmd command.Command) error {
+	soundCmd, ok := cmd.(SoundCommand)
+	if !ok {
+		return errors.New("unexpected command")
+	}
+	switch soundCmd.Action {
+	case SoundActionSave:
+		return h.service.save(soundCmd)
+	case SoundActionPlay:
+		return h.service.play(soundCmd)
+	default:
+		return fmt.Errorf("unknown sound action %s", soundCmd.Action)
+	}
+}
+
+type SoundAutocompleteCommandHandler struct {
+	service *Soundboard
+}
+
+// NewSoundAutocompleteCommandHandler initializes a new SoundAutocompleteCommandHandler.
+func NewSoundAutocompleteCommandHandler(service *Soundboard) SoundAutocompleteCommandHandler {
+	return SoundAutocompleteCommandHandler{
+		service: service,
+	}
+}
+
+// Handle implements the command.Handler interface.
+func (h SoundAutocompleteCommandHandler) Handle(ctx context.Context, cmd command.Command) error {
+	autocompleteCmd, ok := cmd.(SoundAutocompleteCommand)
+	if !ok {
+		return errors.New("unexpected command")
+	}
+	return h.service.autocomplete(autocompleteCmd)
+}
+
+type Soundboard struct {
+	discordClient        discord.Client
+	localization         *localizations.Localizer
+	soundRepo            domain.SoundRepository
+	soundStore           domain.SoundStore
+	voiceDataRepo        domain.VoiceDataRepository
+	lockedUserRepository domain.LockedUserRepository
+	fsRepo               domain.FileRepository
+	transcoder           domain.OpusTranscoder
+	player               *VoicePlayer
+}
+
+func NewSoundboard(discord discord.Client, localization *localizations.Localizer, soundRepo domain.SoundRepository, soundStore domain.SoundStore, voiceDataRepo domain.VoiceDataRepository, lockedUserRepository domain.LockedUserRepository, fsRepo domain.FileRepository, transcoder domain.OpusTranscoder, player *VoicePlayer) *Soundboard {
+	return &Soundboard{
+		discordClient:        discord,
+		localization:         localization,
+		soundRepo:            soundRepo,
+		soundStore:           soundStore,
+		voiceDataRepo:        voiceDataRepo,
+		lockedUserRepository: lockedUserRepository,
+		fsRepo:               fsRepo,
+		transcoder:           transcoder,
+		player:               player,
+	}
+}
+
+func (service *Soundboard) save(cmd SoundCommand) error {
+	name := normalizeSoundName(cmd.Name)
+	if name == "" {
+		return service.reply(cmd.InteractionToken, "texts.sound_invalid_name")
+	}
+	_, exists, err := service.soundRepo.Get(cmd.GuildID, name)
+	if err != nil {
+		return fmt.Errorf("err getting sound, %w", err)
+	}
+	if exists {
+		return service.reply(cmd.InteractionToken, "texts.sound_exists", &localizations.Replacements{"name": name})
+	}
+
+	channelID, messageID, err := service.locateVoiceMessage(cmd)
+	if err != nil {
+		return err
+	}
+	if messageID == "" {
+		return service.reply(cmd.InteractionToken, "texts.sound_message_not_found")
+	}
+	message, err := service.discordClient.GetMessage(channelID, messageID)
+	if err != nil {
+		log.Println("err getting sound message", err)
+		return service.reply(cmd.InteractionToken, "texts.sound_message_not_found")
+	}
+	if message.AttachmentURL == "" {
+		return service.reply(cmd.InteractionToken, "texts.sound_message_not_found")
+	}
+
+	fileKey := fmt.Sprintf("%s-%s", cmd.GuildID, uuid.New().String())
+	if err := service.archive(message.AttachmentURL, fileKey); err != nil {
+		return err
+	}
+	sound := domain.Sound{
+		GuildID:   cmd.GuildID,
+		Name:      name,
+		FileKey:   fileKey,
+		SavedBy:   cmd.UserID,
+		CreatedAt: time.Now(),
+	}
+	if err := service.soundRepo.Save(sound); err != nil {
+		if err := service.soundStore.Delete(fileKey); err != nil {
+			log.Println("err deleting orphan sound file", err)
+		}
+		return fmt.Errorf("err saving sound, %w", err)
+	}
+	return service.reply(cmd.InteractionToken, "texts.sound_saved", &localizations.Replacements{"name": name})
+}
+
+// locateVoiceMessage returns the message referenced by the command or the last voice message of the guild
+func (service *Soundboard) locateVoiceMessage(cmd SoundCommand) (string, string, error) {
+	if cmd.MessageRef != "" {
+		channelID, messageID := parseMessageReference(cmd.MessageRef, cmd.ChannelID)
+		return channelID, messageID, nil
+	}
+	voiceData, found, err := service.voiceDataRepo.GetLastByGuild(cmd.GuildID)
+	if err != nil {
+		return "", "", fmt.Errorf("err getting last voice data, %w", err)
+	}
+	if !found {
+		return "", "", nil
+	}
+	return voiceData.ChannelID, voiceData.MessageID, nil
+}
+
+// archive downloads the audio and moves it to the sound store
+func (service *Soundboard) archive(url string, fileKey string) error {
+	fileName := fmt.Sprintf("%s-download", fileKey)
+	defer service.fsRepo.DeleteAll(fileName)
+	if err := downloadFile(service.fsRepo, url, fileName); err != nil {
+		return err
+	}
+	file, err := service.fsRepo.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("err opening downloaded sound, %w", err)
+	}
+	defer func(file *os.File) {
+		if err := file.Close(); err != nil {
+			log.Println(err)
+		}
+	}(file)
+	if err := service.soundStore.Put(fileKey, file); err != nil {
+		return fmt.Errorf("err archiving sound, %w", err)
+	}
+	return nil
+}
+
+func (service *Soundboard) play(cmd SoundCommand) error {
+	voiceChannelID, err := service.discordClient.GetUserVoiceChannel(cmd.GuildID, cmd.UserID)
+	if err != nil {
+		return fmt.Errorf("err getting user voice channel, %w", err)
+	}
+	if voiceChannelID == "" {
+		return service.reply(cmd.InteractionToken, "texts.playback_not_in_voice")
+	}
+	if lockedUser, _ := service.lockedUserRepository.GetCurrentLock(cmd.GuildID); lockedUser != "" {
+		return service.reply(cmd.InteractionToken, "texts.playback_busy")
+	}
+	name := normalizeSoundName(cmd.Name)
+	sound, found, err := service.soundRepo.Get(cmd.GuildID, name)
+	if err != nil {
+		return fmt.Errorf("err getting sound, %w", err)
+	}
+	if !found {
+		return service.reply(cmd.InteractionToken, "texts.sound_unknown", &localizations.Replacements{"name": name})
+	}
+
+	frames, err := service.frames(sound)
+	if err != nil {
+		return err
+	}
+	position, ok := service.player.enqueueFrames(cmd.GuildID, voiceChannelID, frames)
+	if !ok {
+		return service.reply(cmd.InteractionToken, "texts.playback_queue_full")
+	}
+	if err := service.soundRepo.IncrementPlays(cmd.GuildID, name); err != nil {
+		log.Println("err counting sound play", err)
+	}
+	return service.reply(cmd.InteractionToken, "texts.playback_queued", &localizations.Replacements{"position": position})
+}
+
+// frames copies the archived sound to a temporary file and transcodes it
+func (service *Soundboard) frames(sound domain.Sound) ([][]byte, error) {
+	archived, err := service.soundStore.Get(sound.FileKey)
+	if err != nil {
+		return nil, fmt.Errorf("err getting archived sound, %w", err)
+	}
+	defer func(archived io.ReadCloser) {
+		if err := archived.Close(); err != nil {
+			log.Println(err)
+		}
+	}(archived)
+
+	fileName := fmt.Sprintf("%s-playback", sound.FileKey)
+	defer service.fsRepo.DeleteAll(fileName)
+	file, err := service.fsRepo.CreateEmpty(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("err creating sound playback file, %w", err)
+	}
+	_, err = io.Copy(file, archived)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("err copying archived sound, %w", err)
+	}
+	frames, err := service.transcoder.ToOpusFrames(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("err transcoding sound, %w", err)
+	}
+	return frames, nil
+}
+
+func (service *Soundboard) autocomplete(cmd SoundAutocompleteCommand) error {
+	names, err := service.soundRepo.SearchNames(cmd.GuildID, normalizeSoundName(cmd.Prefix), maxAutocompleteChoices)
+	if err != nil {
+		return fmt.Errorf("err searching sounds, %w", err)
+	}
+	if err := service.discordClient.RespondAutocomplete(cmd.InteractionID, cmd.InteractionToken, names); err != nil {
+		return fmt.Errorf("err responding sound autocomplete, %w", err)
+	}
+	return nil
+}
+
+func (service *Soundboard) reply(interactionToken string, key string, replacements ...*localizations.Replacements) error {
+	if err := service.discordClient.EditInteraction(interactionToken, service.localization.Get(key, replacements...)); err != nil {
+		return fmt.Errorf("err sending interaction response, %w", err)
+	}
+	return nil
+}
+
+// normalizeSoundName lowercases the name and keeps only letters, digits and dashes, spaces become dashes
+func normalizeSoundName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune('-')
+		}
+	}
+	normalized := []rune(b.String())
+	if len(normalized) > maxSoundNameLength {
+		normalized = normalized[:maxSoundNameLength]
+	}
+	return string(normalized)
+}
@@ -0,0 +1,209 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSoundboard_save(t *testing.T) {
+	type fields struct {
+		discordClient *discordmocks.Client
+		soundRepo     *domainmocks.SoundRepository
+		voiceDataRepo *domainmocks.VoiceDataRepository
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		cmd           SoundCommand
+		expectedError bool
+		on            func(*fields)
+		assertMocks   func(t *testing.T, f *fields)
+	}{
+		{
+			name:   "when name has no valid characters, tell it",
+			fields: fields{discordClient: &discordmocks.Client{}, soundRepo: &domainmocks.SoundRepository{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}},
+			cmd:    NewSoundCommand("token", "guild", "user", "channel", SoundActionSave, " !? ", ""),
+			on: func(fields *fields) {
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.soundRepo.AssertNumberOfCalls(t, "Get", 0)
+			},
+		},
+		{
+			name:   "when name is taken, dont save it",
+			fields: fields{discordClient: &discordmocks.Client{}, soundRepo: &domainmocks.SoundRepository{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}},
+			cmd:    NewSoundCommand("token", "guild", "user", "channel", SoundActionSave, "Classic", ""),
+			on: func(fields *fields) {
+				fields.soundRepo.On("Get", "guild", "classic").Return(domain.Sound{}, true, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.voiceDataRepo.AssertNumberOfCalls(t, "GetLastByGuild", 0)
+				f.soundRepo.AssertNumberOfCalls(t, "Save", 0)
+			},
+		},
+		{
+			name:          "when getting sound fails, return error",
+			fields:        fields{discordClient: &discordmocks.Client{}, soundRepo: &domainmocks.SoundRepository{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}},
+			cmd:           NewSoundCommand("token", "guild", "user", "channel", SoundActionSave, "classic", ""),
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.soundRepo.On("Get", "guild", "classic").Return(domain.Sound{}, false, errors.New("err db"))
+			},
+		},
+		{
+			name:   "when guild has no voice messages, tell it",
+			fields: fields{discordClient: &discordmocks.Client{}, soundRepo: &domainmocks.SoundRepository{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}},
+			cmd:    NewSoundCommand("token", "guild", "user", "channel", SoundActionSave, "classic", ""),
+			on: func(fields *fields) {
+				fields.soundRepo.On("Get", "guild", "classic").Return(domain.Sound{}, false, nil)
+				fields.voiceDataRepo.On("GetLastByGuild", "guild").Return(domain.VoiceData{}, false, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertNumberOfCalls(t, "GetMessage", 0)
+			},
+		},
+		{
+			name:   "when referenced message has no audio, tell it",
+			fields: fields{discordClient: &discordmocks.Client{}, soundRepo: &domainmocks.SoundRepository{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}},
+			cmd:    NewSoundCommand("token", "guild", "user", "channel", SoundActionSave, "classic", "https://discord.com/channels/guild/other/msg"),
+			on: func(fields *fields) {
+				fields.soundRepo.On("Get", "guild", "classic").Return(domain.Sound{}, false, nil)
+				fields.discordClient.On("GetMessage", "other", "msg").Return(discord.Message{ID: "msg"}, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.voiceDataRepo.AssertNumberOfCalls(t, "GetLastByGuild", 0)
+				f.soundRepo.AssertNumberOfCalls(t, "Save", 0)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &Soundboard{
+				discordClient: tt.fields.discordClient,
+				localization:  localizations.New("en", "en"),
+				soundRepo:     tt.fields.soundRepo,
+				voiceDataRepo: tt.fields.voiceDataRepo,
+			}
+			if tt.on != nil {
+				tt.on(&tt.fields)
+			}
+			err := service.save(tt.cmd)
+			assert.Equal(t, tt.expectedError, err != nil)
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &tt.fields)
+			}
+		})
+	}
+}
+
+func TestSoundboard_play(t *testing.T) {
+	type fields struct {
+		discordClient *discordmocks.Client
+		soundRepo     *domainmocks.SoundRepository
+		lockedUsers   *domainmocks.LockedUserRepository
+	}
+	tests := []struct {
+		name          string
+		fields        fields
+		expectedError bool
+		on            func(*fields)
+		assertMocks   func(t *testing.T, f *fields)
+	}{
+		{
+			name:   "when user is not in a voice channel, tell it",
+			fields: fields{discordClient: &discordmocks.Client{}, soundRepo: &domainmocks.SoundRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			on: func(fields *fields) {
+				fields.discordClient.On("GetUserVoiceChannel", "guild", "user").Return("", nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.soundRepo.AssertNumberOfCalls(t, "Get", 0)
+			},
+		},
+		{
+			name:   "when someone is being recorded, dont play anything",
+			fields: fields{discordClient: &discordmocks.Client{}, soundRepo: &domainmocks.SoundRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			on: func(fields *fields) {
+				fields.discordClient.On("GetUserVoiceChannel", "guild", "user").Return("voice", nil)
+				fields.lockedUsers.On("GetCurrentLock", "guild").Return("recorded-user", make(chan bool))
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.soundRepo.AssertNumberOfCalls(t, "Get", 0)
+			},
+		},
+		{
+			name:   "when sound does not exist, tell it",
+			fields: fields{discordClient: &discordmocks.Client{}, soundRepo: &domainmocks.SoundRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			on: func(fields *fields) {
+				fields.discordClient.On("GetUserVoiceChannel", "guild", "user").Return("voice", nil)
+				fields.soundRepo.On("Get", "guild", "classic").Return(domain.Sound{}, false, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.soundRepo.AssertNumberOfCalls(t, "IncrementPlays", 0)
+			},
+		},
+		{
+			name:          "when getting sound fails, return error",
+			fields:        fields{discordClient: &discordmocks.Client{}, soundRepo: &domainmocks.SoundRepository{}, lockedUsers: &domainmocks.LockedUserRepository{}},
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.discordClient.On("GetUserVoiceChannel", "guild", "user").Return("voice", nil)
+				fields.soundRepo.On("Get", "guild", "classic").Return(domain.Sound{}, false, errors.New("err db"))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &Soundboard{
+				discordClient:        tt.fields.discordClient,
+				localization:         localizations.New("en", "en"),
+				soundRepo:            tt.fields.soundRepo,
+				lockedUserRepository: tt.fields.lockedUsers,
+			}
+			if tt.on != nil {
+				tt.on(&tt.fields)
+			}
+			tt.fields.lockedUsers.On("GetCurrentLock", "guild").Return("", make(chan bool))
+
+			err := service.play(NewSoundCommand("token", "guild", "user", "channel", SoundActionPlay, "classic", ""))
+			assert.Equal(t, tt.expectedError, err != nil)
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &tt.fields)
+			}
+		})
+	}
+}
+
+func Test_normalizeSoundName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "lowercases and replaces spaces", input: " Hola Caracola ", expected: "hola-caracola"},
+		{name: "drops symbols", input: "100%_real!", expected: "100real"},
+		{name: "keeps accented letters", input: "Ñoño", expected: "ñoño"},
+		{name: "cuts long names", input: "abcdefghijklmnopqrstuvwxyz0123456789", expected: "abcdefghijklmnopqrstuvwxyz012345"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, normalizeSoundName(tt.input))
+		})
+	}
+}
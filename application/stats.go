@@ -98,6 +98,10 @@ func (service *StatsMessageCreator) buildStatsMessage(voiceStats []domain.VoiceD
 
 	for _, voiceData := range voiceStats {
 		globalDuration += voiceData.Duration
+		// anonymous voice data only counts in the global stats, achievements would point to its author
+		if voiceData.Anonymous {
+			continue
+		}
 		_, ok := usersData[voiceData.UserID]
 		if !ok {
 			usersData[voiceData.UserID] = &userData{
@@ -134,23 +138,25 @@ func (service *StatsMessageCreator) buildStatsMessage(voiceStats []domain.VoiceD
 
 	embeds := make([]*discord.MessageEmbed, 0)
 
-	user, err := service.discordClient.GetUser(longestAudioUser)
-	if err != nil {
-		return discord.ComplexInteractionEdit{}, fmt.Errorf("err.stats.get.user:%w", err)
-	}
-	embeds = append(embeds, service.buildAchievementEmbed(user,
-		service.localization.Get("texts.achievement_longest_audio_title"),
-		"https://images.emojiterra.com/google/android-11/512px/1fac1.png",
-		service.localization.Get("texts.achievement_longest_audio_description", &localizations.Replacements{"seconds": usersData[longestAudioUser].longestAudioDuration})))
-
-	user, err = service.discordClient.GetUser(mostAudiosSentUser)
-	if err != nil {
-		return discord.ComplexInteractionEdit{}, fmt.Errorf("err.stats.get.user:%w", err)
+	if len(usersData) > 0 {
+		user, err := service.discordClient.GetUser(longestAudioUser)
+		if err != nil {
+			return discord.ComplexInteractionEdit{}, fmt.Errorf("err.stats.get.user:%w", err)
+		}
+		embeds = append(embeds, service.buildAchievementEmbed(user,
+			service.localization.Get("texts.achievement_longest_audio_title"),
+			"https://images.emojiterra.com/google/android-11/512px/1fac1.png",
+			service.localization.Get("texts.achievement_longest_audio_description", &localizations.Replacements{"seconds": usersData[longestAudioUser].longestAudioDuration})))
+
+		user, err = service.discordClient.GetUser(mostAudiosSentUser)
+		if err != nil {
+			return discord.ComplexInteractionEdit{}, fmt.Errorf("err.stats.get.user:%w", err)
+		}
+		embeds = append(embeds, service.buildAchievementEmbed(user,
+			service.localization.Get("texts.achievement_most_audios_sent_title"),
+			"https://www.emojirequest.com/images/TalkingTooMuchEmoji.jpg",
+			service.localization.Get("texts.achievement_most_audios_sent_description", &localizations.Replacements{"audios": usersData[user.ID].audiosSent})))
 	}
-	embeds = append(embeds, service.buildAchievementEmbed(user,
-		service.localization.Get("texts.achievement_most_audios_sent_title"),
-		"https://www.emojirequest.com/images/TalkingTooMuchEmoji.jpg",
-		service.localization.Get("texts.achievement_most_audios_sent_description", &localizations.Replacements{"audios": usersData[user.ID].audiosSent})))
 
 	guildUsers, err := service.discordClient.GetGuildUsers(guildID)
 	if err != nil {
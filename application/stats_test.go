@@ -94,6 +94,21 @@ func TestStatsMessageCreator_send(t *testing.T) {
 
 			},
 		},
+		{
+			name:          "when all voice data is anonymous, it should not give achievements to its authors",
+			expectedError: false,
+			fields:        fields{discordClient: &discordmocks.Client{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}, localization: localizations.New("en", "en")},
+			args:          args{interactionToken: "token", guildID: "1"},
+			on: func(fields *fields) {
+				fields.voiceDataRepo.On("GetOnRange", "1", mock.Anything, mock.Anything).Return([]domain.VoiceData{{UserID: "hash", Duration: 5, Anonymous: true}}, nil)
+				fields.discordClient.On("GetGuildUsers", "1").Return([]discord.User{{ID: "1"}}, nil)
+				fields.discordClient.On("EditInteractionComplex", "token", mock.AnythingOfType("discord.ComplexInteractionEdit")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertNumberOfCalls(t, "GetUser", 0)
+				f.discordClient.AssertNumberOfCalls(t, "EditInteractionComplex", 1)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,155 @@
+package application
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math"
+	"os"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+)
+
+const (
+	// uploadLimitMargin of the limit is used, the rest is left for the encoders missing their bitrate a bit
+	uploadLimitMargin = 0.95
+	// maxUploadParts a recording can be split in, longer recordings are dropped
+	maxUploadParts = 10
+)
+
+// guildUploadLimit returns the biggest file that can be uploaded in the guild, the lowest limit when it cannot be known
+func guildUploadLimit(client discord.Client, guildID string) int64 {
+	guild, err := client.GetGuild(guildID)
+	if err != nil {
+		log.Println("err getting guild, using the default upload limit:", err)
+		return discord.DefaultUploadLimit
+	}
+	return guild.UploadLimit()
+}
+
+// fits tells if the file can be uploaded, files that cannot be measured are tried anyway
+func (usecase *VoiceRecorder) fits(fullName string, limit int64) bool {
+	size, err := fileSize(usecase.fsRepo, fullName)
+	if err != nil {
+		log.Println("err measuring file to upload", err)
+		return true
+	}
+	return size <= limit
+}
+
+// fitUploadLimit makes the audio of the recording fit in the upload limit: it is kept when it fits, re-encoded at a
+// lower bitrate when that is enough and split in parts otherwise. It returns the files to send, in order.
+func (usecase *VoiceRecorder) fitUploadLimit(fileName string, options domain.EncodeOptions, limit int64) ([]string, error) {
+	audioFullName := usecase.audioFullName(fileName, options.Format)
+	size, err := fileSize(usecase.fsRepo, audioFullName)
+	if err != nil {
+		return nil, err
+	}
+	if size <= limit {
+		return []string{audioFullName}, nil
+	}
+
+	oggFullName := usecase.fsRepo.GetFullPath(fmt.Sprintf("%s.ogg", fileName))
+	if bitrate := fittingBitrate(options, size, limit); bitrate >= domain.MinAudioBitrate {
+		log.Printf("recording %s is too large to upload, encoding it at %d kbps\n", fileName, bitrate)
+		lowered := options
+		lowered.Bitrate = bitrate
+		if err := usecase.encoder.Encode([]string{oggFullName}, audioFullName, lowered); err != nil {
+			return nil, fmt.Errorf("err encoding recording at a lower bitrate, %w", err)
+		}
+		if usecase.fits(audioFullName, limit) {
+			return []string{audioFullName}, nil
+		}
+	}
+	log.Printf("recording %s is too large to upload, splitting it\n", fileName)
+	return usecase.splitRecording(fileName, options, size, limit)
+}
+
+// fittingBitrate is the bitrate that brings an audio of that size under the limit, 0 when the format has no bitrate to lower
+func fittingBitrate(options domain.EncodeOptions, size int64, limit int64) int {
+	if options.Format == domain.AudioFormatOpus || options.Format.Lossless() {
+		return 0
+	}
+	return int(float64(options.Bitrate) * float64(limit) / float64(size) * uploadLimitMargin)
+}
+
+// splitRecording splits the recording in as few parts as fit in the limit, starting with the count its size suggests
+func (usecase *VoiceRecorder) splitRecording(fileName string, options domain.EncodeOptions, size int64, limit int64) ([]string, error) {
+	count := int(math.Ceil(float64(size) / (float64(limit) * uploadLimitMargin)))
+	if count < 2 {
+		count = 2
+	}
+	for ; count <= maxUploadParts; count++ {
+		parts, biggest, err := usecase.encodeParts(fileName, options, count)
+		if err != nil {
+			return nil, err
+		}
+		if biggest <= limit {
+			return parts, nil
+		}
+		usecase.fsRepo.DeleteAll(parts...)
+	}
+	return nil, fmt.Errorf("recording does not fit in %d parts", maxUploadParts)
+}
+
+// encodeParts splits the recording in count parts and encodes them, returning their files and the size of the biggest one
+func (usecase *VoiceRecorder) encodeParts(fileName string, options domain.EncodeOptions, count int) ([]string, int64, error) {
+	sources := make([]string, count)
+	parts := make([]string, count)
+	for i := range parts {
+		sources[i] = usecase.fsRepo.GetFullPath(fmt.Sprintf("%s-split%d.ogg", fileName, i+1))
+		parts[i] = usecase.fsRepo.GetFullPath(fmt.Sprintf("%s-part%d.%s", fileName, i+1, options.Format.Extension()))
+	}
+	defer usecase.fsRepo.DeleteAll(sources...)
+	if err := usecase.oggSplitter.Split(usecase.fsRepo.GetFullPath(fmt.Sprintf("%s.ogg", fileName)), sources); err != nil {
+		return nil, 0, fmt.Errorf("err splitting recording, %w", err)
+	}
+	var biggest int64
+	for i, source := range sources {
+		err := usecase.encoder.Encode([]string{source}, parts[i], options)
+		var size int64
+		if err == nil {
+			size, err = fileSize(usecase.fsRepo, parts[i])
+		}
+		if err != nil {
+			usecase.fsRepo.DeleteAll(parts...)
+			return nil, 0, fmt.Errorf("err encoding part %d of the recording, %w", i+1, err)
+		}
+		if size > biggest {
+			biggest = size
+		}
+	}
+	return parts, biggest, nil
+}
+
+func fileSize(fsRepo domain.FileRepository, fullName string) (int64, error) {
+	file, err := fsRepo.Open(fullName)
+	if err != nil {
+		return 0, fmt.Errorf("err opening file to measure, %w", err)
+	}
+	defer func(file *os.File) {
+		if err := file.Close(); err != nil {
+			log.Println(err)
+		}
+	}(file)
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("err measuring file, %w", err)
+	}
+	return info.Size(), nil
+}
+
+// sendPart sends a part of a recording numbered as "2/3", as a reply to the previous part unless it is the first one
+func (usecase *VoiceRecorder) sendPart(chID string, replyToID string, index int, count int, partFullName string, uploadName string, format domain.AudioFormat) (discord.Message, error) {
+	file, err := usecase.fsRepo.Open(partFullName)
+	if err != nil {
+		return discord.Message{}, fmt.Errorf("err opening part, %w", err)
+	}
+	defer func(file *os.File) {
+		if err := file.Close(); err != nil {
+			log.Println(err)
+		}
+	}(file)
+	return usecase.discord.SendFileReply(chID, replyToID, fmt.Sprintf("%d/%d", index+1, count), uploadName, format.ContentType(), bufio.NewReader(file))
+}
@@ -0,0 +1,240 @@
+package application
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeSplitter writes an empty file for every part
+type fakeSplitter struct {
+	calls int
+}
+
+func (s *fakeSplitter) Split(path string, outputs []string) error {
+	s.calls++
+	for _, output := range outputs {
+		if err := os.WriteFile(output, nil, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSized(size int) func(args mock.Arguments) {
+	return func(args mock.Arguments) {
+		_ = os.WriteFile(args.String(1), make([]byte, size), 0600)
+	}
+}
+
+func TestVoiceRecorder_fitUploadLimit(t *testing.T) {
+	const limit = 100
+	type fields struct {
+		encoder  *domainmocks.AudioEncoder
+		splitter *fakeSplitter
+	}
+	tests := []struct {
+		name          string
+		options       domain.EncodeOptions
+		audioSize     int
+		expectedParts []string
+		expectedError bool
+		on            func(dir string, f *fields)
+		assertMocks   func(t *testing.T, f *fields)
+	}{
+		{
+			name:          "when audio fits, send it as it is",
+			options:       domain.EncodeOptions{Format: domain.AudioFormatMP3, Bitrate: 96},
+			audioSize:     limit,
+			expectedParts: []string{"rec.mp3"},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.encoder.AssertNumberOfCalls(t, "Encode", 0)
+				assert.Equal(t, 0, f.splitter.calls)
+			},
+		},
+		{
+			name:          "when a lower bitrate is enough, encode it again with it",
+			options:       domain.EncodeOptions{Format: domain.AudioFormatMP3, Bitrate: 96},
+			audioSize:     2 * limit,
+			expectedParts: []string{"rec.mp3"},
+			on: func(dir string, f *fields) {
+				lowered := domain.EncodeOptions{Format: domain.AudioFormatMP3, Bitrate: 45}
+				f.encoder.On("Encode", []string{filepath.Join(dir, "rec.ogg")}, filepath.Join(dir, "rec.mp3"), lowered).Run(writeSized(limit - 10)).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.encoder.AssertNumberOfCalls(t, "Encode", 1)
+				assert.Equal(t, 0, f.splitter.calls)
+			},
+		},
+		{
+			name:          "when format has no bitrate, split it",
+			options:       domain.EncodeOptions{Format: domain.AudioFormatOpus},
+			audioSize:     limit + 50,
+			expectedParts: []string{"rec-part1.ogg", "rec-part2.ogg"},
+			on: func(dir string, f *fields) {
+				f.encoder.On("Encode", mock.Anything, mock.Anything, domain.EncodeOptions{Format: domain.AudioFormatOpus}).Run(writeSized(limit - 20)).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.encoder.AssertNumberOfCalls(t, "Encode", 2)
+				assert.Equal(t, 1, f.splitter.calls)
+			},
+		},
+		{
+			name:          "when parts are still too large, split it in more parts",
+			options:       domain.EncodeOptions{Format: domain.AudioFormatWAV},
+			audioSize:     limit + 50,
+			expectedParts: []string{"rec-part1.wav", "rec-part2.wav", "rec-part3.wav"},
+			on: func(dir string, f *fields) {
+				options := domain.EncodeOptions{Format: domain.AudioFormatWAV}
+				f.encoder.On("Encode", []string{filepath.Join(dir, "rec-split1.ogg")}, filepath.Join(dir, "rec-part1.wav"), options).Run(writeSized(limit + 1)).Return(nil).Once()
+				f.encoder.On("Encode", mock.Anything, mock.Anything, options).Run(writeSized(limit)).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				assert.Equal(t, 2, f.splitter.calls)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			fields := fields{encoder: &domainmocks.AudioEncoder{}, splitter: &fakeSplitter{}}
+			fsRepo := &domainmocks.FileRepository{}
+			fsRepo.On("GetFullPath", mock.AnythingOfType("string")).Return(func(name string) string {
+				return filepath.Join(dir, name)
+			})
+			fsRepo.On("Open", mock.AnythingOfType("string")).Return(func(name string) *os.File {
+				f, _ := os.Open(name)
+				return f
+			}, func(name string) error {
+				_, err := os.Stat(name)
+				return err
+			})
+			fsRepo.On("DeleteAll", mock.Anything, mock.Anything).Return()
+			fsRepo.On("DeleteAll", mock.Anything, mock.Anything, mock.Anything).Return()
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "rec."+tt.options.Format.Extension()), make([]byte, tt.audioSize), 0600))
+			if tt.on != nil {
+				tt.on(dir, &fields)
+			}
+			usecase := &VoiceRecorder{fsRepo: fsRepo, encoder: fields.encoder, oggSplitter: fields.splitter}
+
+			parts, err := usecase.fitUploadLimit("rec", tt.options, limit)
+
+			assert.Equal(t, tt.expectedError, err != nil)
+			var expected []string
+			for _, part := range tt.expectedParts {
+				expected = append(expected, filepath.Join(dir, part))
+			}
+			assert.Equal(t, expected, parts)
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &fields)
+			}
+		})
+	}
+}
+
+func Test_fittingBitrate(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  domain.EncodeOptions
+		expected int
+	}{
+		{name: "opus has no bitrate to lower", options: domain.EncodeOptions{Format: domain.AudioFormatOpus, Bitrate: 96}, expected: 0},
+		{name: "lossless formats have no bitrate to lower", options: domain.EncodeOptions{Format: domain.AudioFormatWAV, Bitrate: 96}, expected: 0},
+		{name: "lower it as much as the audio is over the limit, with some margin", options: domain.EncodeOptions{Format: domain.AudioFormatMP3, Bitrate: 96}, expected: 45},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, fittingBitrate(tt.options, 200, 100))
+		})
+	}
+}
+
+func TestVoiceRecorder_tag(t *testing.T) {
+	const limit = 100
+	metadata := domain.AudioMetadata{Artist: "user", Album: "guild", CoverPath: "cover.png"}
+	withoutCover := domain.AudioMetadata{Artist: "user", Album: "guild"}
+	tests := []struct {
+		name        string
+		format      domain.AudioFormat
+		on          func(path string, tagger *domainmocks.AudioTagger)
+		assertMocks func(t *testing.T, tagger *domainmocks.AudioTagger)
+	}{
+		{
+			name:   "when format cannot be tagged, leave it as it is",
+			format: domain.AudioFormatWAV,
+			on: func(path string, tagger *domainmocks.AudioTagger) {
+				tagger.On("Supports", domain.AudioFormatWAV).Return(false)
+			},
+			assertMocks: func(t *testing.T, tagger *domainmocks.AudioTagger) {
+				tagger.AssertNumberOfCalls(t, "Tag", 0)
+			},
+		},
+		{
+			name:   "when tagged file fits, keep the cover",
+			format: domain.AudioFormatMP3,
+			on: func(path string, tagger *domainmocks.AudioTagger) {
+				tagger.On("Supports", domain.AudioFormatMP3).Return(true)
+				tagger.On("Tag", path, domain.AudioFormatMP3, metadata).Run(func(args mock.Arguments) {
+					_ = os.WriteFile(path, make([]byte, limit), 0600)
+				}).Return(nil)
+			},
+			assertMocks: func(t *testing.T, tagger *domainmocks.AudioTagger) {
+				tagger.AssertNumberOfCalls(t, "Tag", 1)
+			},
+		},
+		{
+			name:   "when cover makes it too large, tag it again without cover",
+			format: domain.AudioFormatMP3,
+			on: func(path string, tagger *domainmocks.AudioTagger) {
+				tagger.On("Supports", domain.AudioFormatMP3).Return(true)
+				tagger.On("Tag", path, domain.AudioFormatMP3, metadata).Run(func(args mock.Arguments) {
+					_ = os.WriteFile(path, make([]byte, limit+1), 0600)
+				}).Return(nil)
+				tagger.On("Tag", path, domain.AudioFormatMP3, withoutCover).Run(func(args mock.Arguments) {
+					_ = os.WriteFile(path, make([]byte, limit-10), 0600)
+				}).Return(nil)
+			},
+			assertMocks: func(t *testing.T, tagger *domainmocks.AudioTagger) {
+				tagger.AssertCalled(t, "Tag", mock.Anything, domain.AudioFormatMP3, withoutCover)
+			},
+		},
+		{
+			name:   "when tagging fails, do not try again",
+			format: domain.AudioFormatMP3,
+			on: func(path string, tagger *domainmocks.AudioTagger) {
+				tagger.On("Supports", domain.AudioFormatMP3).Return(true)
+				tagger.On("Tag", path, domain.AudioFormatMP3, metadata).Return(errors.New("ffmpeg failed"))
+			},
+			assertMocks: func(t *testing.T, tagger *domainmocks.AudioTagger) {
+				tagger.AssertNumberOfCalls(t, "Tag", 1)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rec."+tt.format.Extension())
+			assert.NoError(t, os.WriteFile(path, make([]byte, limit-20), 0600))
+			fsRepo := &domainmocks.FileRepository{}
+			fsRepo.On("Open", mock.AnythingOfType("string")).Return(func(name string) *os.File {
+				f, _ := os.Open(name)
+				return f
+			}, func(name string) error {
+				_, err := os.Stat(name)
+				return err
+			})
+			tagger := &domainmocks.AudioTagger{}
+			tt.on(path, tagger)
+			usecase := &VoiceRecorder{fsRepo: fsRepo, tagger: tagger}
+
+			usecase.tag(path, tt.format, metadata, limit)
+
+			tt.assertMocks(t, tagger)
+		})
+	}
+}
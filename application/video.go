@@ -0,0 +1,220 @@
+package application
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/hectorgabucio/taterubot-dc/kit/command"
+	"github.com/hectorgabucio/taterubot-dc/kit/job"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+)
+
+const ExportVideoCommandType command.Type = "command.export_video"
+
+// ExportVideoComponentID is the custom id of the button that exports a voice message as a video
+const ExportVideoComponentID = "export-video"
+
+const (
+	// maxVideoSecs bounds the voice messages exported as videos, longer ones take too long to render
+	maxVideoSecs = 600
+	// maxVideoBitrate in kbps is plenty for a still picture with a waveform, lower ones are used to fit the upload limit
+	maxVideoBitrate = 1000
+	// minVideoBitrate in kbps under which the video is not worth watching
+	minVideoBitrate = 150
+	// videoAudioBitrate in kbps of the audio track
+	videoAudioBitrate = 128
+)
+
+type ExportVideoCommand struct {
+	InteractionToken string
+	GuildID          string
+	// ChannelID and MessageID identify the voice message to export
+	ChannelID string
+	MessageID string
+}
+
+func NewExportVideoCommand(interactionToken string, guildID string, channelID string, messageID string) ExportVideoCommand {
+	return ExportVideoCommand{
+		InteractionToken: interactionToken,
+		GuildID:          guildID,
+		ChannelID:        channelID,
+		MessageID:        messageID,
+	}
+}
+
+func (c ExportVideoCommand) Type() command.Type {
+	return ExportVideoCommandType
+}
+
+type ExportVideoCommandHandler struct {
+	service *VideoExporter
+}
+
+// NewExportVideoCommandHandler initializes a new ExportVideoCommandHandler.
+func NewExportVideoCommandHandler(service *VideoExporter) ExportVideoCommandHandler {
+	return ExportVideoCommandHandler{
+		service: service,
+	}
+}
+
+// Handle implements the command.Handler interface.
+func (h ExportVideoCommandHandler) Handle(ctx context.Context, cmd command.Command) error {
+	exportCmd, ok := cmd.(ExportVideoCommand)
+	if !ok {
+		return errors.New("unexpected command")
+	}
+	return h.service.export(exportCmd)
+}
+
+type VideoExporter struct {
+	discordClient discord.Client
+	localization  *localizations.Localizer
+	voiceDataRepo domain.VoiceDataRepository
+	renderer      domain.VideoRenderer
+	fsRepo        domain.FileRepository
+	jobs          job.Queue
+}
+
+func NewVideoExporter(discord discord.Client, localization *localizations.Localizer, voiceDataRepo domain.VoiceDataRepository, renderer domain.VideoRenderer, fsRepo domain.FileRepository, jobs job.Queue) *VideoExporter {
+	return &VideoExporter{
+		discordClient: discord,
+		localization:  localization,
+		voiceDataRepo: voiceDataRepo,
+		renderer:      renderer,
+		fsRepo:        fsRepo,
+		jobs:          jobs,
+	}
+}
+
+// export renders the voice message as an audiogram and posts it as a follow-up of the interaction
+func (service *VideoExporter) export(cmd ExportVideoCommand) error {
+	if !service.renderer.Available() {
+		return service.reply(cmd.InteractionToken, "texts.video_unavailable")
+	}
+	voiceData, ok, err := service.voiceDataRepo.GetByMessage(cmd.GuildID, cmd.MessageID)
+	if err != nil {
+		return fmt.Errorf("err getting voice data of message, %w", err)
+	}
+	if !ok {
+		return service.reply(cmd.InteractionToken, "texts.video_message_not_found")
+	}
+	if voiceData.Duration > maxVideoSecs {
+		return service.reply(cmd.InteractionToken, "texts.video_too_long", &localizations.Replacements{"minutes": maxVideoSecs / 60})
+	}
+	limit := guildUploadLimit(service.discordClient, cmd.GuildID)
+	videoBitrate := fittingVideoBitrate(voiceData.Duration, limit)
+	if videoBitrate < minVideoBitrate {
+		return service.reply(cmd.InteractionToken, "texts.video_too_large")
+	}
+	message, err := service.discordClient.GetMessage(cmd.ChannelID, cmd.MessageID)
+	if err != nil || message.AttachmentURL == "" {
+		if err != nil {
+			log.Println("err getting message to export", err)
+		}
+		return service.reply(cmd.InteractionToken, "texts.video_message_not_found")
+	}
+
+	fileName := fmt.Sprintf("%s-video-%s", cmd.MessageID, uuid.New().String())
+	sourceFileName := fmt.Sprintf("%s-source", fileName)
+	avatarFileName := fmt.Sprintf("%s-avatar", fileName)
+	videoFullName := service.fsRepo.GetFullPath(fmt.Sprintf("%s.mp4", fileName))
+	defer service.fsRepo.DeleteAll(sourceFileName, fmt.Sprintf("%s.png", avatarFileName), videoFullName)
+	if err := downloadFile(service.fsRepo, message.AttachmentURL, sourceFileName); err != nil {
+		return err
+	}
+
+	audiogram := service.audiogram(voiceData, avatarFileName)
+	audiogram.AudioPath = service.fsRepo.GetFullPath(sourceFileName)
+	audiogram.VideoBitrate = videoBitrate
+	audiogram.AudioBitrate = videoAudioBitrate
+	runJob(service.jobs, cmd.GuildID, job.PriorityEncode, func() {
+		err = service.renderer.RenderAudiogram(audiogram, videoFullName)
+	})
+	if err != nil {
+		log.Println("err rendering video", err)
+		return service.reply(cmd.InteractionToken, "texts.video_failed")
+	}
+	// the bitrates are an estimate, the video is checked before trying to upload it
+	size, err := fileSize(service.fsRepo, videoFullName)
+	if err != nil {
+		return err
+	}
+	if size > limit {
+		return service.reply(cmd.InteractionToken, "texts.video_too_large")
+	}
+
+	runJob(service.jobs, cmd.GuildID, job.PriorityUpload, func() {
+		err = service.send(cmd, voiceData.Name, videoFullName)
+	})
+	if err != nil {
+		return err
+	}
+	return service.reply(cmd.InteractionToken, "texts.video_sent")
+}
+
+// audiogram shows the avatar and name of the author, anonymous voice messages show neither
+func (service *VideoExporter) audiogram(voiceData domain.VoiceData, avatarFileName string) domain.Audiogram {
+	if voiceData.Anonymous {
+		return domain.Audiogram{Title: service.localization.Get("texts.video_anonymous")}
+	}
+	user, err := service.discordClient.GetUser(voiceData.UserID)
+	if err != nil {
+		log.Println("err getting author of the video, rendering it without name nor avatar:", err)
+		return domain.Audiogram{}
+	}
+	audiogram := domain.Audiogram{Title: user.Username}
+	if user.AvatarURL == "" {
+		return audiogram
+	}
+	audiogram.Color = dominantAvatarColor(service.fsRepo, user.AvatarURL, avatarFileName)
+	avatarFullName := service.fsRepo.GetFullPath(fmt.Sprintf("%s.png", avatarFileName))
+	if _, err := fileSize(service.fsRepo, avatarFullName); err == nil {
+		audiogram.AvatarPath = avatarFullName
+	}
+	return audiogram
+}
+
+// fittingVideoBitrate is the bitrate in kbps that keeps a video of that duration under the limit
+func fittingVideoBitrate(durationSecs int, limit int64) int {
+	if durationSecs <= 0 {
+		return maxVideoBitrate
+	}
+	bitrate := int(float64(limit)*8/1000*uploadLimitMargin/float64(durationSecs)) - videoAudioBitrate
+	if bitrate > maxVideoBitrate {
+		return maxVideoBitrate
+	}
+	return bitrate
+}
+
+func (service *VideoExporter) send(cmd ExportVideoCommand, name string, videoFullName string) error {
+	file, err := service.fsRepo.Open(videoFullName)
+	if err != nil {
+		return fmt.Errorf("err opening video, %w", err)
+	}
+	defer func(file *os.File) {
+		if err := file.Close(); err != nil {
+			log.Println(err)
+		}
+	}(file)
+	link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", cmd.GuildID, cmd.ChannelID, cmd.MessageID)
+	content := service.localization.Get("texts.video_caption", &localizations.Replacements{"link": link})
+	video := discord.File{Name: fmt.Sprintf("%s.mp4", name), ContentType: "video/mp4", Reader: bufio.NewReader(file)}
+	if _, err := service.discordClient.SendFollowup(cmd.InteractionToken, content, video); err != nil {
+		return fmt.Errorf("err sending video, %w", err)
+	}
+	return nil
+}
+
+func (service *VideoExporter) reply(interactionToken string, key string, replacements ...*localizations.Replacements) error {
+	if err := service.discordClient.EditInteraction(interactionToken, service.localization.Get(key, replacements...)); err != nil {
+		return fmt.Errorf("err sending interaction response, %w", err)
+	}
+	return nil
+}
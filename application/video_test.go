@@ -0,0 +1,137 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestVideoExporter_export(t *testing.T) {
+	type fields struct {
+		discordClient *discordmocks.Client
+		voiceDataRepo *domainmocks.VoiceDataRepository
+		renderer      *domainmocks.VideoRenderer
+	}
+	cmd := NewExportVideoCommand("token", "guild", "channel", "msg")
+	tests := []struct {
+		name          string
+		expectedError bool
+		on            func(*fields)
+		assertMocks   func(t *testing.T, f *fields)
+	}{
+		{
+			name: "when videos cannot be rendered, tell it",
+			on: func(fields *fields) {
+				fields.renderer.On("Available").Return(false)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.voiceDataRepo.AssertNumberOfCalls(t, "GetByMessage", 0)
+			},
+		},
+		{
+			name:          "when voice data cannot be read, return error",
+			expectedError: true,
+			on: func(fields *fields) {
+				fields.renderer.On("Available").Return(true)
+				fields.voiceDataRepo.On("GetByMessage", "guild", "msg").Return(domain.VoiceData{}, false, errors.New("err db"))
+			},
+		},
+		{
+			name: "when message is not a voice message, tell it",
+			on: func(fields *fields) {
+				fields.renderer.On("Available").Return(true)
+				fields.voiceDataRepo.On("GetByMessage", "guild", "msg").Return(domain.VoiceData{}, false, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.renderer.AssertNumberOfCalls(t, "RenderAudiogram", 0)
+			},
+		},
+		{
+			name: "when voice message is too long, dont render it",
+			on: func(fields *fields) {
+				fields.renderer.On("Available").Return(true)
+				fields.voiceDataRepo.On("GetByMessage", "guild", "msg").Return(domain.VoiceData{Duration: maxVideoSecs + 1}, true, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertNumberOfCalls(t, "GetMessage", 0)
+				f.renderer.AssertNumberOfCalls(t, "RenderAudiogram", 0)
+			},
+		},
+		{
+			name: "when video would not fit in the upload limit, dont render it",
+			on: func(fields *fields) {
+				fields.renderer.On("Available").Return(true)
+				fields.voiceDataRepo.On("GetByMessage", "guild", "msg").Return(domain.VoiceData{Duration: maxVideoSecs}, true, nil)
+				fields.discordClient.On("GetGuild", "guild").Return(discord.Guild{ID: "guild"}, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertNumberOfCalls(t, "GetMessage", 0)
+				f.renderer.AssertNumberOfCalls(t, "RenderAudiogram", 0)
+			},
+		},
+		{
+			name: "when message has no audio anymore, tell it",
+			on: func(fields *fields) {
+				fields.renderer.On("Available").Return(true)
+				fields.voiceDataRepo.On("GetByMessage", "guild", "msg").Return(domain.VoiceData{Duration: 10}, true, nil)
+				fields.discordClient.On("GetGuild", "guild").Return(discord.Guild{}, errors.New("err discord"))
+				fields.discordClient.On("GetMessage", "channel", "msg").Return(discord.Message{ID: "msg"}, nil)
+				fields.discordClient.On("EditInteraction", "token", mock.AnythingOfType("string")).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.renderer.AssertNumberOfCalls(t, "RenderAudiogram", 0)
+				f.discordClient.AssertNumberOfCalls(t, "EditInteraction", 1)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := fields{discordClient: &discordmocks.Client{}, voiceDataRepo: &domainmocks.VoiceDataRepository{}, renderer: &domainmocks.VideoRenderer{}}
+			service := &VideoExporter{
+				discordClient: fields.discordClient,
+				localization:  localizations.New("en", "en"),
+				voiceDataRepo: fields.voiceDataRepo,
+				renderer:      fields.renderer,
+				fsRepo:        &domainmocks.FileRepository{},
+			}
+			if tt.on != nil {
+				tt.on(&fields)
+			}
+			err := service.export(cmd)
+			assert.Equal(t, tt.expectedError, err != nil)
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &fields)
+			}
+		})
+	}
+}
+
+func Test_fittingVideoBitrate(t *testing.T) {
+	tests := []struct {
+		name         string
+		durationSecs int
+		limit        int64
+		expected     int
+	}{
+		{name: "when duration is unknown, use the highest bitrate", durationSecs: 0, limit: discord.DefaultUploadLimit, expected: maxVideoBitrate},
+		{name: "short videos use the highest bitrate", durationSecs: 30, limit: discord.DefaultUploadLimit, expected: maxVideoBitrate},
+		{name: "long videos lower it to fit, leaving room for the audio", durationSecs: 300, limit: discord.DefaultUploadLimit, expected: 137},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, fittingVideoBitrate(tt.durationSecs, tt.limit))
+		})
+	}
+}
@@ -8,18 +8,30 @@ import (
 	"io"
 	"log"
 	"os"
+	"time"
 
 	"github.com/hectorgabucio/taterubot-dc/domain"
 	"github.com/hectorgabucio/taterubot-dc/domain/discord"
 	"github.com/hectorgabucio/taterubot-dc/domain/ogg"
 	"github.com/hectorgabucio/taterubot-dc/kit/command"
 	"github.com/hectorgabucio/taterubot-dc/kit/event"
-	ffmpeg "github.com/u2takey/ffmpeg-go"
+	"github.com/hectorgabucio/taterubot-dc/kit/job"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
 )
 
 const RecordingCommandType command.Type = "command.recording"
 
+const (
+	// voiceMessageFileName is the name discord clients give to voice messages
+	voiceMessageFileName     = "voice-message.ogg"
+	voiceMessageWaveformSize = 256
+	// anonymousFileName replaces the username in the files of anonymous recordings, as their names are uploaded too
+	anonymousFileName = "anonymous"
+)
+
 type RecordingCommand struct {
+	// Intent is what the user did, only joining, leaving and moving between channels start or finish recordings
+	Intent           discord.VoiceIntent
 	UserID           string
 	CurrentChannelID string
 	GuildID          string
@@ -27,8 +39,9 @@ type RecordingCommand struct {
 	AvatarURL        string
 }
 
-func NewRecordingCommand(userID string, channelID string, guildID string, username string, avatarURL string) RecordingCommand {
+func NewRecordingCommand(intent discord.VoiceIntent, userID string, channelID string, guildID string, username string, avatarURL string) RecordingCommand {
 	return RecordingCommand{
+		Intent:           intent,
 		UserID:           userID,
 		CurrentChannelID: channelID,
 		GuildID:          guildID,
@@ -58,130 +71,681 @@ func (h RecordingCommandHandler) Handle(ctx context.Context, cmd command.Command
 	if !ok {
 		return errors.New("unexpected command")
 	}
-	return h.service.handleVoiceRecording(recordingCmd.UserID, recordingCmd.CurrentChannelID, recordingCmd.GuildID, recordingCmd.Username, recordingCmd.AvatarURL)
+	return h.service.handleVoiceRecording(recordingCmd.Intent, recordingCmd.UserID, recordingCmd.CurrentChannelID, recordingCmd.GuildID, recordingCmd.Username, recordingCmd.AvatarURL)
+}
+
+// author is who recordings are sent as, anonymous authors are only known by their AuthorHasher hash
+type author struct {
+	userID    string
+	username  string
+	avatarURL string
+	anonymous bool
 }
 
 type VoiceRecorder struct {
 	lockedUserRepository domain.LockedUserRepository
 	eventBus             event.Bus
 	discord              discord.Client
+	localization         *localizations.Localizer
 	configChannelName    string
 	fsRepo               domain.FileRepository
 	oggWriter            ogg.Writer
+	oggAnalyzer          ogg.Analyzer
+	oggSplitter          ogg.Splitter
+	voiceDataRepo        domain.VoiceDataRepository
+	guildSettingsRepo    domain.GuildSettingsRepository
+	startCue             domain.StartCue
+	encoder              domain.AudioEncoder
+	pendingIntros        domain.PendingIntroRepository
+	processor            domain.AudioProcessor
+	effectRepo           domain.VoiceEffectRepository
+	hasher               domain.AuthorHasher
+	tagger               domain.AudioTagger
+	fileNames            *domain.FileNameTemplate
+	pendingCaptions      domain.PendingCaptionRepository
+	deadLetters          domain.DeadLetterRepository
+	jobs                 job.Queue
+	streamSlots          streamSlots
+	// continuationWindow is how long after a voice message a new recording gets appended to it, 0 disables it
+	continuationWindow time.Duration
+}
+
+// VoiceMedia are the collaborators of VoiceRecorder that write, read and transform the recordings
+type VoiceMedia struct {
+	Files     domain.FileRepository
+	Writer    ogg.Writer
+	Analyzer  ogg.Analyzer
+	Splitter  ogg.Splitter
+	StartCue  domain.StartCue
+	Encoder   domain.AudioEncoder
+	Processor domain.AudioProcessor
+	Tagger    domain.AudioTagger
+}
+
+// VoiceRepositories keep the state VoiceRecorder reads and leaves behind, from the locks of the users being recorded
+// to the deliveries that could not be sent
+type VoiceRepositories struct {
+	LockedUsers     domain.LockedUserRepository
+	VoiceData       domain.VoiceDataRepository
+	GuildSettings   domain.GuildSettingsRepository
+	PendingIntros   domain.PendingIntroRepository
+	Effects         domain.VoiceEffectRepository
+	PendingCaptions domain.PendingCaptionRepository
+	DeadLetters     domain.DeadLetterRepository
+}
+
+// VoiceSettings are the configuration of VoiceRecorder
+type VoiceSettings struct {
+	// ChannelName is the text channel the recordings are sent to
+	ChannelName string
+	FileNames   *domain.FileNameTemplate
+	// MaxStreams is how many recordings are encoded while they are recorded at once
+	MaxStreams int
+	// ContinuationWindow is how long after a voice message a new recording gets appended to it, 0 disables it
+	ContinuationWindow time.Duration
 }
 
-func NewVoiceRecorder(discord discord.Client, configChannelName string, lockedUserRepository domain.LockedUserRepository, eventBus event.Bus, fsRepo domain.FileRepository, writer ogg.Writer) *VoiceRecorder {
+func NewVoiceRecorder(discord discord.Client, localization *localizations.Localizer, eventBus event.Bus, hasher domain.AuthorHasher, jobs job.Queue, media VoiceMedia, repositories VoiceRepositories, settings VoiceSettings) *VoiceRecorder {
 	return &VoiceRecorder{
-		lockedUserRepository: lockedUserRepository,
+		localization:         localization,
+		lockedUserRepository: repositories.LockedUsers,
 		eventBus:             eventBus,
 		discord:              discord,
-		configChannelName:    configChannelName,
-		fsRepo:               fsRepo,
-		oggWriter:            writer,
+		configChannelName:    settings.ChannelName,
+		fsRepo:               media.Files,
+		oggWriter:            media.Writer,
+		oggAnalyzer:          media.Analyzer,
+		oggSplitter:          media.Splitter,
+		voiceDataRepo:        repositories.VoiceData,
+		guildSettingsRepo:    repositories.GuildSettings,
+		startCue:             media.StartCue,
+		encoder:              media.Encoder,
+		pendingIntros:        repositories.PendingIntros,
+		processor:            media.Processor,
+		effectRepo:           repositories.Effects,
+		hasher:               hasher,
+		tagger:               media.Tagger,
+		fileNames:            settings.FileNames,
+		pendingCaptions:      repositories.PendingCaptions,
+		deadLetters:          repositories.DeadLetters,
+		jobs:                 jobs,
+		streamSlots:          newStreamSlots(settings.MaxStreams),
+		continuationWindow:   settings.ContinuationWindow,
 	}
 }
-func (usecase *VoiceRecorder) handleVoiceRecording(userID string, nowChannelID string, guildID string, username string, avatarURL string) error {
-	currentLockedUser, done := usecase.lockedUserRepository.GetCurrentLock(guildID)
-
-	if nowChannelID == "" && currentLockedUser != userID {
+func (usecase *VoiceRecorder) handleVoiceRecording(intent discord.VoiceIntent, userID string, nowChannelID string, guildID string, username string, avatarURL string) error {
+	// muting, deafening, streaming and the like keep the user in the channel, so the recording goes on
+	if !intent.ChangesChannel() {
 		return nil
 	}
+	currentLockedUser, done := usecase.lockedUserRepository.GetCurrentLock(guildID)
+
 	if currentLockedUser == userID {
-		done <- true
+		if intent == discord.VoiceIntentJoined {
+			// the user is already being recorded, a join without a known previous state changes nothing
+			return nil
+		}
+		// leaving or moving away from the recorded channel finishes the recording. Nobody reads done once the
+		// connection was given up on, so the signal is not waited for
+		select {
+		case done <- true:
+		default:
+		}
 		usecase.lockedUserRepository.ReleaseUserLock(guildID)
 		return nil
 	}
+	if intent == discord.VoiceIntentLeft {
+		return nil
+	}
 
 	channel, err := usecase.discord.GetChannel(nowChannelID)
 	if err != nil {
 		return fmt.Errorf("err getting channel, %w", err)
 	}
-	if channel.Name != usecase.configChannelName {
+	settings, err := usecase.guildSettingsRepo.Get(guildID)
+	if err != nil {
+		log.Println("err getting guild settings, using defaults:", err)
+		settings = domain.DefaultGuildSettings(guildID)
+	}
+	anonymous := settings.AnonymousChannelID != "" && channel.ID == settings.AnonymousChannelID
+	if channel.Name != usecase.configChannelName && !anonymous {
+		return nil
+	}
+	if anonymous && !usecase.encoder.SupportsEffect(domain.VoiceEffectDisguise) {
+		// recording an undisguised voice would give the author away
+		log.Println("voice disguise is not available, anonymous recordings are disabled")
 		return nil
 	}
 
 	usecase.lockedUserRepository.SetLock(guildID, userID)
-	return usecase.recordAndSend(userID, guildID, nowChannelID, username, avatarURL, done)
+	return usecase.recordAndSend(userID, guildID, nowChannelID, username, avatarURL, done, settings, anonymous)
 }
 
-func (usecase *VoiceRecorder) recordAndSend(userID string, guildID string, channelID string, username string, avatarURL string, done chan bool) error {
-	v, err := usecase.discord.EstablishVoiceConnection(guildID, channelID, true, false, done)
+func (usecase *VoiceRecorder) recordAndSend(userID string, guildID string, channelID string, username string, avatarURL string, done chan bool, settings domain.GuildSettings, anonymous bool) error {
+	startedAt := time.Now()
+	// the cue is enabled by default, hosts that cannot play it just record without it
+	cue := settings.StartCueEnabled && usecase.startCue.Available()
+	// the bot only needs to be unmuted to play the start cue
+	v, err := usecase.discord.EstablishVoiceConnection(guildID, channelID, !cue, false, done)
 	if err != nil {
 		return fmt.Errorf("err joining voice channel, %w", err)
 	}
-	usecase.handleVoice(v.VoiceReceiver, userID, guildID, username, avatarURL)
+	if cue {
+		if err := usecase.playStartCue(v); err != nil {
+			log.Println("err playing start cue", err)
+		}
+	}
+	usecase.handleVoice(v, userID, guildID, username, avatarURL, startedAt, settings, anonymous)
+	if receiveErr(v) != nil {
+		usecase.notifyInterrupted(guildID, userID, anonymous)
+	}
 	return nil
 }
 
-func (usecase *VoiceRecorder) handleVoice(c chan *discord.Packet, userID string, guildID string, username string, avatarURL string) []string {
+// receiveErr tells why the connection stopped receiving before the user left, nil if it did not
+func receiveErr(v *discord.VoiceConnection) error {
+	if v.Err == nil {
+		return nil
+	}
+	return v.Err()
+}
+
+// notifyInterrupted lets the user know the recording was finished because the voice connection was lost,
+// anonymous recordings do not mention them
+func (usecase *VoiceRecorder) notifyInterrupted(guildID string, userID string, anonymous bool) {
+	chID := usecase.textChannelID(guildID)
+	if chID == "" {
+		return
+	}
+	message := usecase.localization.Get("texts.recording_interrupted", &localizations.Replacements{"user": fmt.Sprintf("<@%s>", userID)})
+	if anonymous {
+		message = usecase.localization.Get("texts.recording_interrupted_anonymous")
+	}
+	if err := usecase.discord.SendTextMessage(chID, message); err != nil {
+		log.Println("err notifying interrupted recording", err)
+	}
+}
+
+// playStartCue plays the cue and discards everything received meanwhile, so the recording starts after it
+func (usecase *VoiceRecorder) playStartCue(v *discord.VoiceConnection) error {
+	frames, err := usecase.startCue.Frames()
+	if err != nil {
+		return fmt.Errorf("err getting start cue frames, %w", err)
+	}
+	played := make(chan error, 1)
+	go func() {
+		played <- usecase.discord.PlayOpus(v, frames)
+	}()
+	for {
+		select {
+		case err := <-played:
+			return err
+		case _, ok := <-v.VoiceReceiver:
+			if !ok {
+				return <-played
+			}
+		}
+	}
+}
+
+func (usecase *VoiceRecorder) handleVoice(v *discord.VoiceConnection, userID string, guildID string, username string, avatarURL string, startedAt time.Time, settings domain.GuildSettings, anonymous bool) []string {
+	options := domain.EncodeOptions{Format: settings.AudioFormat, Bitrate: settings.AudioBitrate}
+	if !usecase.encoder.Supports(options.Format) {
+		// opus is only remuxed, so every host can write it
+		log.Printf("audio format %s is not available, sending opus instead\n", options.Format)
+		options.Format = domain.AudioFormatOpus
+	}
+	sender := author{userID: userID, username: username, avatarURL: avatarURL}
+	fileNamePrefix := username
+	var continued *domain.VoiceData
+	var effect domain.VoiceEffect
+	if anonymous {
+		// anonymous voice messages are never continued, that would tie them to the previous message of the author
+		sender = author{userID: usecase.hasher.Hash(guildID, userID), anonymous: true}
+		fileNamePrefix = anonymousFileName
+		effect = domain.VoiceEffectDisguise
+	} else {
+		continued = usecase.getContinuableVoiceData(guildID, userID, startedAt)
+		effect = usecase.userEffect(guildID, userID)
+	}
+	processing := settings.ProcessOptions()
+	// voice messages and opus audio are the recorded ogg itself, continuations need the previous audio first
+	// and effects and processing need the whole recording
+	streaming := !settings.VoiceMessagesEnabled && continued == nil && options.Format != domain.AudioFormatOpus && !processing.Enabled() && effect == domain.VoiceEffectNone
+
 	files := make(map[string]io.Closer)
-	for p := range c {
-		name := username + "-" + fmt.Sprintf("%d", p.SSRC)
+	streams := make(map[string]*encodingStream)
+	failed := make(map[string]bool)
+	for p := range v.VoiceReceiver {
+		name := fileNamePrefix + "-" + fmt.Sprintf("%d", p.SSRC)
+		if failed[name] {
+			continue
+		}
 		file, ok := files[name]
 		if !ok {
 			var err error
 			file, err = usecase.oggWriter.NewWriter(usecase.fsRepo.GetFullPath(fmt.Sprintf("%s.ogg", name)))
 			if err != nil {
-				log.Printf("failed to create file %d.ogg, giving up on recording: %v\n", p.SSRC, err)
-				return nil
+				// keep draining the channel, the voice connection blocks otherwise
+				log.Printf("failed to create file %s.ogg, dropping its audio: %v\n", name, err)
+				failed[name] = true
+				continue
 			}
 			files[name] = file
+			if streaming {
+				stream, err := startEncodingStream(usecase.encoder, usecase.audioFullName(name, options.Format), options, usecase.streamSlots)
+				if err != nil {
+					log.Println("err starting encoding stream, it will be encoded after the recording:", err)
+				} else {
+					streams[name] = stream
+				}
+			}
 		}
 		err := usecase.oggWriter.WriteVoice(file, p)
 		if err != nil {
-			log.Printf("failed to write to file %d.ogg, giving up on recording: %v\n", p.SSRC, err)
+			log.Printf("failed to write to file %s.ogg: %v\n", name, err)
+		}
+		if stream, ok := streams[name]; ok {
+			stream.write(p)
 		}
 	}
 
-	mp3Names := make([]string, len(files))
-	i := 0
+	if err := receiveErr(v); err != nil {
+		log.Println("voice connection lost, finishing the recording early:", err)
+		// the user is still in the channel, so leaving would not release the lock
+		usecase.lockedUserRepository.ReleaseUserLock(guildID)
+	}
+
+	recordings := usecase.closeRecordings(files, streams, options.Format)
+	var receive discord.ReceiveStats
+	if v.ReceiveStats != nil {
+		receive = v.ReceiveStats()
+	}
+	if len(recordings) > 0 {
+		if interactionToken, ok := usecase.pendingIntros.PopPending(guildID, userID); ok {
+			usecase.discardStreams(streams, options.Format)
+			usecase.sendAsIntro(recordings, guildID, userID, interactionToken)
+			return nil
+		}
+	}
+	// voice messages cannot show a caption, it is only used when there are recordings left to send as audio files
+	caption, _ := usecase.pendingCaptions.PopPending(guildID, userID)
+	var effects map[string]domain.VoiceEffect
+	var reports map[string]domain.ProcessReport
+	var durations map[string]float64
+	runJob(usecase.jobs, guildID, job.PriorityEncode, func() {
+		effects = usecase.applyEffect(recordings, effect, settings.AudioBitrate)
+		if anonymous {
+			recordings = usecase.dropUndisguised(recordings, effects)
+		}
+		reports = usecase.process(recordings, processing)
+		durations = usecase.recordedDurations(files, recordings, effects, reports)
+	})
+
+	var sent []string
+	if settings.VoiceMessagesEnabled {
+		// voice messages cannot be edited, so they are never continued
+		runJob(usecase.jobs, guildID, job.PriorityUpload, func() {
+			sent = usecase.sendVoiceMessages(recordings, guildID, sender, receive)
+		})
+		recordings = without(recordings, sent)
+		if len(recordings) == 0 {
+			return sent
+		}
+		log.Println("some recordings could not be sent as voice messages, sending them as audio files")
+	}
+
+	var audioNames []string
+	runJob(usecase.jobs, guildID, job.PriorityEncode, func() {
+		audioNames, continued = usecase.encodeRecordings(recordings, streams, continued, options)
+	})
+
+	if len(audioNames) > 0 {
+		coverFileName := fmt.Sprintf("%s-cover.png", audioNames[0])
+		defer usecase.fsRepo.DeleteAll(coverFileName)
+		metadata := usecase.audioMetadata(guildID, sender, startedAt, caption, coverFileName)
+		runJob(usecase.jobs, guildID, job.PriorityUpload, func() {
+			usecase.sendAudioFiles(guildID, sender, audioNames, continued, options, reports, effects, durations, receive, metadata)
+		})
+	}
+
+	return append(sent, audioNames...)
+}
+
+// encodeRecordings finishes the encoding of the recordings in the format, the first one is appended to the continued voice message.
+// It returns the recordings encoded and the continued voice message, nil when appending failed.
+func (usecase *VoiceRecorder) encodeRecordings(recordings []string, streams map[string]*encodingStream, continued *domain.VoiceData, options domain.EncodeOptions) ([]string, *domain.VoiceData) {
+	var audioNames []string
+	for _, fileName := range recordings {
+		if stream, ok := streams[fileName]; ok {
+			err := stream.finish()
+			if err == nil {
+				audioNames = append(audioNames, fileName)
+				continue
+			}
+			log.Printf("err encoding recording %s while recording, encoding it again: %v\n", fileName, err)
+		}
+
+		appended := false
+		if continued != nil && len(audioNames) == 0 {
+			if err := usecase.appendToPrevious(*continued, fileName, options); err != nil {
+				log.Println("err appending to previous voice message, sending a new one:", err)
+				continued = nil
+			} else {
+				appended = true
+			}
+		}
+		if !appended {
+			err := usecase.encoder.Encode([]string{usecase.fsRepo.GetFullPath(fmt.Sprintf("%s.ogg", fileName))}, usecase.audioFullName(fileName, options.Format), options)
+			if err != nil {
+				log.Printf("err encoding recording %s, dropping it: %v\n", fileName, err)
+				usecase.fsRepo.DeleteAll(fmt.Sprintf("%s.ogg", fileName), usecase.audioFullName(fileName, options.Format))
+				continue
+			}
+		}
+		audioNames = append(audioNames, fileName)
+	}
+	return audioNames, continued
+}
+
+// closeRecordings finishes the ogg files and returns the names of the ones that can be sent, the others are dropped
+func (usecase *VoiceRecorder) closeRecordings(files map[string]io.Closer, streams map[string]*encodingStream, format domain.AudioFormat) []string {
+	var recordings []string
 	for fileName, f := range files {
-		err := f.Close()
+		if err := f.Close(); err != nil {
+			log.Printf("err closing recording %s, dropping it: %v\n", fileName, err)
+			usecase.discardStreams(map[string]*encodingStream{fileName: streams[fileName]}, format)
+			delete(streams, fileName)
+			usecase.fsRepo.DeleteAll(fmt.Sprintf("%s.ogg", fileName))
+			continue
+		}
+		recordings = append(recordings, fileName)
+	}
+	return recordings
+}
+
+// userEffect returns the default effect of the user, none when it cannot be rendered in this host
+func (usecase *VoiceRecorder) userEffect(guildID string, userID string) domain.VoiceEffect {
+	effect, err := usecase.effectRepo.Get(guildID, userID)
+	if err != nil {
+		log.Println("err getting voice effect of user, recording without it:", err)
+		return domain.VoiceEffectNone
+	}
+	if effect != domain.VoiceEffectNone && !usecase.encoder.SupportsEffect(effect) {
+		log.Printf("voice effect %s is not available, recording without it\n", effect.Name())
+		return domain.VoiceEffectNone
+	}
+	return effect
+}
+
+// recordedDurations takes the durations from the granule positions the ogg writer followed while recording,
+// leaving out the recordings whose tempo an effect may have changed, as their sent file is probed instead
+func (usecase *VoiceRecorder) recordedDurations(files map[string]io.Closer, recordings []string, effects map[string]domain.VoiceEffect, reports map[string]domain.ProcessReport) map[string]float64 {
+	durations := make(map[string]float64)
+	for _, fileName := range recordings {
+		if _, ok := effects[fileName]; ok {
+			continue
+		}
+		duration := usecase.oggWriter.Duration(files[fileName]) - reports[fileName].TrimmedSecs
+		if duration > 0 {
+			durations[fileName] = duration
+		}
+	}
+	return durations
+}
+
+// applyEffect renders the effect in the recordings themselves, so every later step sees it, and returns the effect of each
+// one, recordings it fails for are sent without it
+func (usecase *VoiceRecorder) applyEffect(recordings []string, effect domain.VoiceEffect, bitrate int) map[string]domain.VoiceEffect {
+	effects := make(map[string]domain.VoiceEffect)
+	if effect == domain.VoiceEffectNone {
+		return effects
+	}
+	options := domain.EncodeOptions{Format: domain.AudioFormatOpus, Bitrate: bitrate, Effect: effect}
+	for _, fileName := range recordings {
+		oggFullName := usecase.fsRepo.GetFullPath(fmt.Sprintf("%s.ogg", fileName))
+		if err := usecase.encoder.Encode([]string{oggFullName}, oggFullName, options); err != nil {
+			log.Printf("err applying voice effect to recording %s, sending it without it: %v\n", fileName, err)
+			continue
+		}
+		effects[fileName] = effect
+	}
+	return effects
+}
+
+// dropUndisguised deletes the recordings the disguise could not be rendered in, they would give the author away
+func (usecase *VoiceRecorder) dropUndisguised(recordings []string, effects map[string]domain.VoiceEffect) []string {
+	var disguised []string
+	for _, fileName := range recordings {
+		if effects[fileName] != domain.VoiceEffectDisguise {
+			log.Printf("dropping anonymous recording %s, its voice could not be disguised\n", fileName)
+			usecase.fsRepo.DeleteAll(fmt.Sprintf("%s.ogg", fileName))
+			continue
+		}
+		disguised = append(disguised, fileName)
+	}
+	return disguised
+}
+
+// process applies the post-processing of the guild to the recordings, they are sent unprocessed if it fails
+func (usecase *VoiceRecorder) process(recordings []string, options domain.ProcessOptions) map[string]domain.ProcessReport {
+	reports := make(map[string]domain.ProcessReport)
+	if !options.Enabled() {
+		return reports
+	}
+	for _, fileName := range recordings {
+		report, err := usecase.processor.Process(usecase.fsRepo.GetFullPath(fmt.Sprintf("%s.ogg", fileName)), options)
 		if err != nil {
-			return nil
+			log.Printf("err processing recording %s, sending it as it is: %v\n", fileName, err)
+			continue
 		}
+		reports[fileName] = report
+	}
+	return reports
+}
 
-		err = convertToMp3(usecase.fsRepo.GetFullPath(fmt.Sprintf("%s.ogg", fileName)), usecase.fsRepo.GetFullPath(fmt.Sprintf("%s.mp3", fileName)))
+func without(names []string, removed []string) []string {
+	var kept []string
+	for _, name := range names {
+		found := false
+		for _, r := range removed {
+			found = found || r == name
+		}
+		if !found {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+// discardStreams stops the streams and removes what they had encoded
+func (usecase *VoiceRecorder) discardStreams(streams map[string]*encodingStream, format domain.AudioFormat) {
+	for fileName, stream := range streams {
+		if stream == nil {
+			continue
+		}
+		if err := stream.finish(); err != nil {
+			log.Println("err finishing discarded encoding stream", err)
+		}
+		usecase.fsRepo.DeleteAll(usecase.audioFullName(fileName, format))
+	}
+}
+
+// sendVoiceMessages sends the recordings as native voice messages and returns the ones that were sent
+func (usecase *VoiceRecorder) sendVoiceMessages(recordings []string, guildID string, sender author, receive discord.ReceiveStats) []string {
+	chID := usecase.textChannelID(guildID)
+	if chID == "" {
+		return nil
+	}
+	limit := guildUploadLimit(usecase.discord, guildID)
+	var sent []string
+	for _, fileName := range recordings {
+		// voice messages are a single attachment, the audio files of recordings too large for it can be split
+		if !usecase.fits(usecase.fsRepo.GetFullPath(fmt.Sprintf("%s.ogg", fileName)), limit) {
+			log.Printf("recording %s is too large for a voice message\n", fileName)
+			continue
+		}
+		if err := usecase.sendVoiceMessage(guildID, sender, chID, fileName, receive); err != nil {
+			log.Println("err sending voice message", err)
+			continue
+		}
+		sent = append(sent, fileName)
+	}
+	return sent
+}
+
+func (usecase *VoiceRecorder) sendVoiceMessage(guildID string, sender author, chID string, fileName string, receive discord.ReceiveStats) error {
+	oggFullName := usecase.fsRepo.GetFullPath(fmt.Sprintf("%s.ogg", fileName))
+	duration, waveform, err := usecase.oggAnalyzer.Analyze(oggFullName, voiceMessageWaveformSize)
+	if err != nil {
+		return fmt.Errorf("err analyzing recording, %w", err)
+	}
+	file, err := usecase.fsRepo.Open(oggFullName)
+	if err != nil {
+		return fmt.Errorf("err opening recording, %w", err)
+	}
+	defer func(file *os.File) {
+		err := file.Close()
 		if err != nil {
 			log.Println(err)
-			return nil
 		}
-		mp3Names[i] = fileName
-		i++
-	}
+	}(file)
 
-	usecase.sendAudioFiles(guildID, userID, mp3Names, username, avatarURL)
+	voice := discord.VoiceMessage{DurationSecs: duration, Waveform: waveform}
+	messageSent, err := usecase.discord.SendVoiceMessage(chID, voiceMessageFileName, bufio.NewReader(file), voice)
 
-	return mp3Names
+	evt := domain.NewVoiceMessageSentEvent(messageSent.ID, sender.userID, guildID, messageSent.ChannelID, sender.username, sender.avatarURL, oggFullName, fileName, messageSent.AttachmentID, messageSent.AttachmentURL, duration)
+	evt.Anonymous = sender.anonymous
+	evt.ReceiveStats = receive
+	if err != nil {
+		keepAudioSent(usecase.deadLetters, err, evt)
+		return err
+	}
+	events := []event.Event{evt}
+	go func() {
+		err := usecase.eventBus.Publish(context.Background(), events)
+		if err != nil {
+			log.Println("err publishing audio sent event", err)
+		}
+	}()
+	return nil
 }
 
-func (usecase *VoiceRecorder) sendAudioFiles(guildID string, userID string, fileNames []string, username string, avatarURL string) {
+// textChannelID returns the first text channel of the guild, where recordings are sent, empty if there is none
+func (usecase *VoiceRecorder) textChannelID(guildID string) string {
 	channels, err := usecase.discord.GetGuildChannels(guildID)
 	if err != nil {
-		return
+		return ""
 	}
-
-	var chID string
 	for _, ch := range channels {
 		if ch.Type == discord.ChannelTypeGuildText {
-			chID = ch.ID
+			return ch.ID
+		}
+	}
+	return ""
+}
 
-			break
+// sendAsIntro hands the recording over to be saved as the user intro instead of sending it
+func (usecase *VoiceRecorder) sendAsIntro(recordings []string, guildID string, userID string, interactionToken string) {
+	introFileName := recordings[0]
+	for _, fileName := range recordings[1:] {
+		usecase.fsRepo.DeleteAll(fmt.Sprintf("%s.ogg", fileName))
+	}
+	events := []event.Event{
+		domain.NewIntroRecordedEvent(introFileName, guildID, userID, interactionToken),
+	}
+	go func() {
+		err := usecase.eventBus.Publish(context.Background(), events)
+		if err != nil {
+			log.Println("err publishing intro recorded event", err)
 		}
+	}()
+}
+
+// getContinuableVoiceData returns the last voice message of the user if the recording started inside the continuation window
+func (usecase *VoiceRecorder) getContinuableVoiceData(guildID string, userID string, startedAt time.Time) *domain.VoiceData {
+	if usecase.continuationWindow <= 0 {
+		return nil
+	}
+	last, found, err := usecase.voiceDataRepo.GetLastByUser(guildID, userID)
+	if err != nil {
+		log.Println("err getting last voice data of user", err)
+		return nil
+	}
+	if !found || last.MessageID == "" || last.AttachmentURL == "" {
+		return nil
+	}
+	if startedAt.Sub(last.Timestamp) > usecase.continuationWindow {
+		return nil
 	}
+	return &last
+}
+
+// appendToPrevious downloads the previous voice message and encodes it followed by the new recording as the audio of fileName
+func (usecase *VoiceRecorder) appendToPrevious(previous domain.VoiceData, fileName string, options domain.EncodeOptions) error {
+	previousFileName := fmt.Sprintf("%s-previous", fileName)
+	defer usecase.fsRepo.DeleteAll(previousFileName)
+	if err := downloadFile(usecase.fsRepo, previous.AttachmentURL, previousFileName); err != nil {
+		return err
+	}
+	inputs := []string{usecase.fsRepo.GetFullPath(previousFileName), usecase.fsRepo.GetFullPath(fmt.Sprintf("%s.ogg", fileName))}
+	return usecase.encoder.Encode(inputs, usecase.audioFullName(fileName, options.Format), options)
+}
 
+func (usecase *VoiceRecorder) audioFullName(fileName string, format domain.AudioFormat) string {
+	return usecase.fsRepo.GetFullPath(fmt.Sprintf("%s.%s", fileName, format.Extension()))
+}
+
+func (usecase *VoiceRecorder) sendAudioFiles(guildID string, sender author, fileNames []string, continued *domain.VoiceData, options domain.EncodeOptions, reports map[string]domain.ProcessReport, effects map[string]domain.VoiceEffect, durations map[string]float64, receive discord.ReceiveStats, metadata domain.AudioMetadata) {
+	chID := usecase.textChannelID(guildID)
 	if chID == "" {
 		return
 	}
 
-	for _, fileName := range fileNames {
-		usecase.sendAudioFile(guildID, userID, chID, fileName, username, avatarURL)
+	limit := guildUploadLimit(usecase.discord, guildID)
+	for i, fileName := range fileNames {
+		if continued != nil && i == 0 {
+			// the previous audio was appended, so the duration of the recording is not the one of the file
+			usecase.sendAudioFile(guildID, sender, continued.ChannelID, fileName, continued, options, limit, reports[fileName], effects[fileName], 0, receive, metadata)
+			continue
+		}
+		usecase.sendAudioFile(guildID, sender, chID, fileName, nil, options, limit, reports[fileName], effects[fileName], durations[fileName], receive, metadata)
 	}
 }
 
-func (usecase *VoiceRecorder) sendAudioFile(guildID string, userID string, chID string, fileName string, username string, avatarURL string) {
-	mp3FullName := usecase.fsRepo.GetFullPath(fileName + ".mp3")
-	file, err := usecase.fsRepo.Open(mp3FullName)
+func (usecase *VoiceRecorder) sendAudioFile(guildID string, sender author, chID string, fileName string, continued *domain.VoiceData, options domain.EncodeOptions, limit int64, processing domain.ProcessReport, effect domain.VoiceEffect, duration float64, receive discord.ReceiveStats, metadata domain.AudioMetadata) {
+	format := options.Format
+	audioFullName := usecase.audioFullName(fileName, format)
+	if continued != nil && !usecase.fits(audioFullName, limit) {
+		// the previous audio stays in its message, only the new recording is sent
+		log.Println("continued voice message is too large to upload, sending the new recording as a new one")
+		oggFullName := usecase.fsRepo.GetFullPath(fmt.Sprintf("%s.ogg", fileName))
+		if err := usecase.encoder.Encode([]string{oggFullName}, audioFullName, options); err != nil {
+			log.Printf("err encoding recording %s, dropping it: %v\n", fileName, err)
+			return
+		}
+		continued = nil
+	}
+	if continued != nil {
+		// the previous audio is sent again along the new one, so are the packets it lacks
+		receive.Dropped += continued.DroppedPackets
+		receive.Late += continued.LatePackets
+	}
+	if continued == nil {
+		parts, err := usecase.fitUploadLimit(fileName, options, limit)
+		if err != nil {
+			log.Printf("err fitting recording %s in the upload limit, dropping it: %v\n", fileName, err)
+			return
+		}
+		if len(parts) > 1 {
+			usecase.sendAudioParts(guildID, sender, chID, fileName, parts, format, processing, effect, duration, receive, metadata, limit)
+			return
+		}
+	}
+	usecase.tag(audioFullName, format, metadata, limit)
+	uploadName := fmt.Sprintf("%s.%s", usecase.fileNames.Render(metadata), format.Extension())
+
+	file, err := usecase.fsRepo.Open(audioFullName)
 	if err != nil {
 		log.Println(err)
 		return
@@ -196,15 +760,122 @@ func (usecase *VoiceRecorder) sendAudioFile(guildID string, userID string, chID
 		}
 	}(file)
 
-	messageSent, err := usecase.discord.SendFileMessage(chID, mp3FullName, "audio/mpeg", reader)
+	var messageSent discord.Message
+	continuedVoiceDataID := ""
+	if continued != nil {
+		messageSent, err = usecase.discord.ReplaceFileMessage(chID, continued.MessageID, uploadName, format.ContentType(), reader)
+		continuedVoiceDataID = continued.ID
+		if err != nil {
+			// voice messages and deleted messages cannot be edited, send the whole audio as a new one
+			log.Println("err replacing previous voice message, sending a new one:", err)
+			if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+				log.Println(seekErr)
+				return
+			}
+			reader.Reset(file)
+			continued, continuedVoiceDataID = nil, ""
+		}
+	}
+	if continued == nil {
+		messageSent, err = usecase.discord.SendFileMessage(chID, uploadName, format.ContentType(), reader)
+	}
+
+	evt := domain.NewAudioSentEvent(messageSent.ID, sender.userID, guildID, messageSent.ChannelID, sender.username, sender.avatarURL, audioFullName, format, fileName, messageSent.AttachmentID, messageSent.AttachmentURL, continuedVoiceDataID, processing)
+	evt.Effect = effect
+	evt.Anonymous = sender.anonymous
+	evt.ReceiveStats = receive
+	evt.Caption = metadata.Title
+	evt.DurationSecs = duration
 	if err != nil {
 		log.Println(err)
+		keepAudioSent(usecase.deadLetters, err, evt)
 		return
 	}
+	usecase.publishAudioSent(evt)
+}
 
-	events := []event.Event{
-		domain.NewAudioSentEvent(messageSent.ID, userID, guildID, messageSent.ChannelID, username, avatarURL, mp3FullName, fileName, messageSent.AttachmentID),
+// sendAudioParts posts the parts as a reply chain, the first part stands for the whole recording so it is recorded once
+func (usecase *VoiceRecorder) sendAudioParts(guildID string, sender author, chID string, fileName string, parts []string, format domain.AudioFormat, processing domain.ProcessReport, effect domain.VoiceEffect, duration float64, receive discord.ReceiveStats, metadata domain.AudioMetadata, limit int64) {
+	defer usecase.fsRepo.DeleteAll(parts...)
+	baseName := usecase.fileNames.Render(metadata)
+	var first, previous discord.Message
+	var firstErr error
+	for i, part := range parts {
+		usecase.tag(part, format, metadata, limit)
+		uploadName := fmt.Sprintf("%s part %d.%s", baseName, i+1, format.Extension())
+		messageSent, err := usecase.sendPart(chID, previous.ID, i, len(parts), part, uploadName, format)
+		if err != nil {
+			log.Printf("err sending part %d of recording %s: %v\n", i+1, fileName, err)
+			if i == 0 {
+				firstErr = err
+			}
+			break
+		}
+		if i == 0 {
+			first = messageSent
+		}
+		previous = messageSent
+	}
+
+	evt := domain.NewAudioSentEvent(first.ID, sender.userID, guildID, first.ChannelID, sender.username, sender.avatarURL, usecase.audioFullName(fileName, format), format, fileName, first.AttachmentID, first.AttachmentURL, "", processing)
+	evt.Effect = effect
+	evt.Anonymous = sender.anonymous
+	evt.ReceiveStats = receive
+	evt.Caption = metadata.Title
+	evt.DurationSecs = duration
+	if first.ID == "" {
+		// the replay of the first part stands for the whole recording as well
+		keepAudioSent(usecase.deadLetters, firstErr, evt)
+		return
 	}
+	usecase.publishAudioSent(evt)
+}
+
+// audioMetadata returns the tags of the recordings of the sender, downloading its avatar to coverFileName as their cover.
+// Anonymous recordings have neither artist nor cover.
+func (usecase *VoiceRecorder) audioMetadata(guildID string, sender author, startedAt time.Time, caption string, coverFileName string) domain.AudioMetadata {
+	metadata := domain.AudioMetadata{Title: caption, Date: startedAt}
+	if guild, err := usecase.discord.GetGuild(guildID); err != nil {
+		log.Println("err getting guild, tagging recordings without album:", err)
+	} else {
+		metadata.Album = guild.Name
+	}
+	if sender.anonymous {
+		return metadata
+	}
+	metadata.Artist = sender.username
+	if sender.avatarURL == "" {
+		return metadata
+	}
+	if err := downloadFile(usecase.fsRepo, sender.avatarURL, coverFileName); err != nil {
+		log.Println("err downloading avatar, tagging recordings without cover:", err)
+		return metadata
+	}
+	metadata.CoverPath = usecase.fsRepo.GetFullPath(coverFileName)
+	return metadata
+}
+
+// tag writes the metadata in the audio file, leaving the cover out when it would not fit in the upload limit anymore.
+// Files that cannot be tagged are sent without tags.
+func (usecase *VoiceRecorder) tag(audioFullName string, format domain.AudioFormat, metadata domain.AudioMetadata, limit int64) {
+	if !usecase.tagger.Supports(format) {
+		return
+	}
+	if err := usecase.tagger.Tag(audioFullName, format, metadata); err != nil {
+		log.Println("err tagging recording, sending it without tags:", err)
+		return
+	}
+	if metadata.CoverPath == "" || usecase.fits(audioFullName, limit) {
+		return
+	}
+	metadata.CoverPath = ""
+	if err := usecase.tagger.Tag(audioFullName, format, metadata); err != nil {
+		log.Println("err tagging recording without cover", err)
+	}
+}
+
+func (usecase *VoiceRecorder) publishAudioSent(evt domain.AudioSentEvent) {
+	events := []event.Event{evt}
 	go func() {
 		err := usecase.eventBus.Publish(context.Background(), events)
 		if err != nil {
@@ -212,11 +883,3 @@ func (usecase *VoiceRecorder) sendAudioFile(guildID string, userID string, chID
 		}
 	}()
 }
-func convertToMp3(input string, output string) error {
-	if err := ffmpeg.Input(input).
-		Output(output, ffmpeg.KwArgs{"acodec": "libmp3lame", "b:a": "96k", "map": "a"}).
-		OverWriteOutput().Run(); err != nil {
-		return fmt.Errorf("failed to convert to mp3, %w", err)
-	}
-	return nil
-}
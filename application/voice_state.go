@@ -0,0 +1,54 @@
+package application
+
+import "github.com/hectorgabucio/taterubot-dc/domain/discord"
+
+// voiceToggles pairs every flag of a voice state with the intents of turning it on and off
+var voiceToggles = []struct {
+	flag func(discord.VoiceState) bool
+	on   discord.VoiceIntent
+	off  discord.VoiceIntent
+}{
+	{func(s discord.VoiceState) bool { return s.SelfMute }, discord.VoiceIntentMuted, discord.VoiceIntentUnmuted},
+	{func(s discord.VoiceState) bool { return s.SelfDeaf }, discord.VoiceIntentDeafened, discord.VoiceIntentUndeafened},
+	{func(s discord.VoiceState) bool { return s.Mute }, discord.VoiceIntentServerMuted, discord.VoiceIntentServerUnmuted},
+	{func(s discord.VoiceState) bool { return s.Deaf }, discord.VoiceIntentServerDeafened, discord.VoiceIntentServerUndeafened},
+	{func(s discord.VoiceState) bool { return s.SelfStream }, discord.VoiceIntentStreamStarted, discord.VoiceIntentStreamStopped},
+	{func(s discord.VoiceState) bool { return s.SelfVideo }, discord.VoiceIntentVideoStarted, discord.VoiceIntentVideoStopped},
+	{func(s discord.VoiceState) bool { return s.Suppress }, discord.VoiceIntentSuppressed, discord.VoiceIntentUnsuppressed},
+}
+
+// ClassifyVoiceStateUpdate tells what the member did, before is nil when the previous state is unknown.
+// Changing channel is a single intent, as the rest of the state is reset with it. Otherwise there is an intent
+// for every flag that changed, several at once when moderators set them together, none when nothing did.
+func ClassifyVoiceStateUpdate(before *discord.VoiceState, after discord.VoiceState) []discord.VoiceIntent {
+	previousChannelID := ""
+	if before != nil {
+		previousChannelID = before.ChannelID
+	}
+	switch {
+	case after.ChannelID == "":
+		// without the previous state a leave is still a leave, the member may be the one being recorded
+		if before == nil || previousChannelID != "" {
+			return []discord.VoiceIntent{discord.VoiceIntentLeft}
+		}
+		return nil
+	case previousChannelID == "":
+		return []discord.VoiceIntent{discord.VoiceIntentJoined}
+	case previousChannelID != after.ChannelID:
+		return []discord.VoiceIntent{discord.VoiceIntentMoved}
+	}
+
+	var intents []discord.VoiceIntent
+	for _, toggle := range voiceToggles {
+		was, is := toggle.flag(*before), toggle.flag(after)
+		if was == is {
+			continue
+		}
+		if is {
+			intents = append(intents, toggle.on)
+		} else {
+			intents = append(intents, toggle.off)
+		}
+	}
+	return intents
+}
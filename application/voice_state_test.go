@@ -0,0 +1,134 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyVoiceStateUpdate(t *testing.T) {
+	inChannel := discord.VoiceState{ChannelID: "voice"}
+	tests := []struct {
+		name     string
+		before   *discord.VoiceState
+		after    discord.VoiceState
+		expected []discord.VoiceIntent
+	}{
+		{
+			name:     "when previous state is unknown and user is in a channel, joined",
+			after:    inChannel,
+			expected: []discord.VoiceIntent{discord.VoiceIntentJoined},
+		},
+		{
+			name:     "when previous state is unknown and user is in no channel, left",
+			after:    discord.VoiceState{},
+			expected: []discord.VoiceIntent{discord.VoiceIntentLeft},
+		},
+		{
+			name:     "when user was in no channel and is in one, joined",
+			before:   &discord.VoiceState{},
+			after:    discord.VoiceState{ChannelID: "voice", SelfMute: true},
+			expected: []discord.VoiceIntent{discord.VoiceIntentJoined},
+		},
+		{
+			name:     "when user was in a channel and is in none, left",
+			before:   &discord.VoiceState{ChannelID: "voice", SelfStream: true},
+			after:    discord.VoiceState{},
+			expected: []discord.VoiceIntent{discord.VoiceIntentLeft},
+		},
+		{
+			name:   "when user was in no channel and is still in none, nothing",
+			before: &discord.VoiceState{},
+			after:  discord.VoiceState{},
+		},
+		{
+			name:     "when user switches channel, moved even if the stream stopped with it",
+			before:   &discord.VoiceState{ChannelID: "voice", SelfStream: true},
+			after:    discord.VoiceState{ChannelID: "other"},
+			expected: []discord.VoiceIntent{discord.VoiceIntentMoved},
+		},
+		{
+			name:   "when nothing changed, nothing",
+			before: &inChannel,
+			after:  inChannel,
+		},
+		{
+			name:     "when user mutes, muted",
+			before:   &inChannel,
+			after:    discord.VoiceState{ChannelID: "voice", SelfMute: true},
+			expected: []discord.VoiceIntent{discord.VoiceIntentMuted},
+		},
+		{
+			name:     "when user unmutes, unmuted",
+			before:   &discord.VoiceState{ChannelID: "voice", SelfMute: true},
+			after:    inChannel,
+			expected: []discord.VoiceIntent{discord.VoiceIntentUnmuted},
+		},
+		{
+			name:     "when user deafens, discord mutes them too",
+			before:   &inChannel,
+			after:    discord.VoiceState{ChannelID: "voice", SelfMute: true, SelfDeaf: true},
+			expected: []discord.VoiceIntent{discord.VoiceIntentMuted, discord.VoiceIntentDeafened},
+		},
+		{
+			name:     "when user undeafens, undeafened and unmuted",
+			before:   &discord.VoiceState{ChannelID: "voice", SelfMute: true, SelfDeaf: true},
+			after:    inChannel,
+			expected: []discord.VoiceIntent{discord.VoiceIntentUnmuted, discord.VoiceIntentUndeafened},
+		},
+		{
+			name:     "when a moderator mutes and deafens, server muted and deafened",
+			before:   &inChannel,
+			after:    discord.VoiceState{ChannelID: "voice", Mute: true, Deaf: true},
+			expected: []discord.VoiceIntent{discord.VoiceIntentServerMuted, discord.VoiceIntentServerDeafened},
+		},
+		{
+			name:     "when a moderator lifts the mute and deafen, server unmuted and undeafened",
+			before:   &discord.VoiceState{ChannelID: "voice", Mute: true, Deaf: true},
+			after:    inChannel,
+			expected: []discord.VoiceIntent{discord.VoiceIntentServerUnmuted, discord.VoiceIntentServerUndeafened},
+		},
+		{
+			name:     "when user starts streaming, stream started",
+			before:   &inChannel,
+			after:    discord.VoiceState{ChannelID: "voice", SelfStream: true},
+			expected: []discord.VoiceIntent{discord.VoiceIntentStreamStarted},
+		},
+		{
+			name:     "when user stops streaming, stream stopped",
+			before:   &discord.VoiceState{ChannelID: "voice", SelfStream: true},
+			after:    inChannel,
+			expected: []discord.VoiceIntent{discord.VoiceIntentStreamStopped},
+		},
+		{
+			name:     "when user turns the camera on, video started",
+			before:   &inChannel,
+			after:    discord.VoiceState{ChannelID: "voice", SelfVideo: true},
+			expected: []discord.VoiceIntent{discord.VoiceIntentVideoStarted},
+		},
+		{
+			name:     "when user turns the camera off, video stopped",
+			before:   &discord.VoiceState{ChannelID: "voice", SelfVideo: true},
+			after:    inChannel,
+			expected: []discord.VoiceIntent{discord.VoiceIntentVideoStopped},
+		},
+		{
+			name:     "when user is moved to the audience of a stage, suppressed",
+			before:   &inChannel,
+			after:    discord.VoiceState{ChannelID: "voice", Suppress: true},
+			expected: []discord.VoiceIntent{discord.VoiceIntentSuppressed},
+		},
+		{
+			name:     "when user becomes a speaker of a stage, unsuppressed",
+			before:   &discord.VoiceState{ChannelID: "voice", Suppress: true},
+			after:    inChannel,
+			expected: []discord.VoiceIntent{discord.VoiceIntentUnsuppressed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ClassifyVoiceStateUpdate(tt.before, tt.after))
+		})
+	}
+}
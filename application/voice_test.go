@@ -0,0 +1,363 @@
+package application
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestVoiceRecorder_getContinuableVoiceData(t *testing.T) {
+	startedAt := time.Now()
+	type fields struct {
+		voiceDataRepo      *domainmocks.VoiceDataRepository
+		continuationWindow time.Duration
+	}
+	tests := []struct {
+		name        string
+		fields      fields
+		expectedID  string
+		on          func(*fields)
+		assertMocks func(t *testing.T, f *fields)
+	}{
+		{
+			name:   "when continuation window is disabled, dont look for previous voice data",
+			fields: fields{voiceDataRepo: &domainmocks.VoiceDataRepository{}},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.voiceDataRepo.AssertNumberOfCalls(t, "GetLastByUser", 0)
+			},
+		},
+		{
+			name:   "when getting last voice data fails, start a new voice message",
+			fields: fields{voiceDataRepo: &domainmocks.VoiceDataRepository{}, continuationWindow: time.Minute},
+			on: func(fields *fields) {
+				fields.voiceDataRepo.On("GetLastByUser", "guild", "user").Return(domain.VoiceData{}, false, errors.New("err db"))
+			},
+		},
+		{
+			name:   "when user has no previous voice data, start a new voice message",
+			fields: fields{voiceDataRepo: &domainmocks.VoiceDataRepository{}, continuationWindow: time.Minute},
+			on: func(fields *fields) {
+				fields.voiceDataRepo.On("GetLastByUser", "guild", "user").Return(domain.VoiceData{}, false, nil)
+			},
+		},
+		{
+			name:   "when previous voice data is outside the window, start a new voice message",
+			fields: fields{voiceDataRepo: &domainmocks.VoiceDataRepository{}, continuationWindow: time.Minute},
+			on: func(fields *fields) {
+				fields.voiceDataRepo.On("GetLastByUser", "guild", "user").Return(domain.VoiceData{
+					ID: "1", MessageID: "msg", AttachmentURL: "url", Timestamp: startedAt.Add(-2 * time.Minute),
+				}, true, nil)
+			},
+		},
+		{
+			name:   "when previous voice data has no message, start a new voice message",
+			fields: fields{voiceDataRepo: &domainmocks.VoiceDataRepository{}, continuationWindow: time.Minute},
+			on: func(fields *fields) {
+				fields.voiceDataRepo.On("GetLastByUser", "guild", "user").Return(domain.VoiceData{
+					ID: "1", Timestamp: startedAt.Add(-10 * time.Second),
+				}, true, nil)
+			},
+		},
+		{
+			name:       "when previous voice data is inside the window, continue it",
+			fields:     fields{voiceDataRepo: &domainmocks.VoiceDataRepository{}, continuationWindow: time.Minute},
+			expectedID: "1",
+			on: func(fields *fields) {
+				fields.voiceDataRepo.On("GetLastByUser", "guild", "user").Return(domain.VoiceData{
+					ID: "1", MessageID: "msg", AttachmentURL: "url", Timestamp: startedAt.Add(-10 * time.Second),
+				}, true, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := &VoiceRecorder{
+				voiceDataRepo:      tt.fields.voiceDataRepo,
+				continuationWindow: tt.fields.continuationWindow,
+			}
+			if tt.on != nil {
+				tt.on(&tt.fields)
+			}
+			continued := usecase.getContinuableVoiceData("guild", "user", startedAt)
+
+			if tt.expectedID == "" {
+				assert.Nil(t, continued)
+			} else if assert.NotNil(t, continued) {
+				assert.Equal(t, tt.expectedID, continued.ID)
+			}
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &tt.fields)
+			}
+		})
+	}
+}
+
+func TestVoiceRecorder_notifyInterrupted(t *testing.T) {
+	type fields struct {
+		discordClient *discordmocks.Client
+	}
+	tests := []struct {
+		name        string
+		fields      fields
+		anonymous   bool
+		on          func(*fields)
+		assertMocks func(t *testing.T, f *fields)
+	}{
+		{
+			name:   "when guild has no text channel, dont notify",
+			fields: fields{discordClient: &discordmocks.Client{}},
+			on: func(fields *fields) {
+				fields.discordClient.On("GetGuildChannels", "guild").Return([]discord.Channel{{ID: "voice", Type: discord.ChannelTypeGuildVoice}}, nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertNumberOfCalls(t, "SendTextMessage", 0)
+			},
+		},
+		{
+			name:   "when recording was not anonymous, mention the user",
+			fields: fields{discordClient: &discordmocks.Client{}},
+			on: func(fields *fields) {
+				fields.discordClient.On("GetGuildChannels", "guild").Return([]discord.Channel{{ID: "text", Type: discord.ChannelTypeGuildText}}, nil)
+				fields.discordClient.On("SendTextMessage", "text", mock.MatchedBy(func(message string) bool {
+					return strings.Contains(message, "<@user>")
+				})).Return(nil)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertExpectations(t)
+			},
+		},
+		{
+			name:      "when recording was anonymous, dont mention the user",
+			fields:    fields{discordClient: &discordmocks.Client{}},
+			anonymous: true,
+			on: func(fields *fields) {
+				fields.discordClient.On("GetGuildChannels", "guild").Return([]discord.Channel{{ID: "text", Type: discord.ChannelTypeGuildText}}, nil)
+				fields.discordClient.On("SendTextMessage", "text", mock.MatchedBy(func(message string) bool {
+					return !strings.Contains(message, "user")
+				})).Return(errors.New("err sending"))
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.discordClient.AssertExpectations(t)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := &VoiceRecorder{
+				discord:      tt.fields.discordClient,
+				localization: localizations.New("en", "en"),
+			}
+			if tt.on != nil {
+				tt.on(&tt.fields)
+			}
+			usecase.notifyInterrupted("guild", "user", tt.anonymous)
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &tt.fields)
+			}
+		})
+	}
+}
+
+func TestVoiceRecorder_recordAndSend_startCue(t *testing.T) {
+	tests := []struct {
+		name         string
+		enabled      bool
+		available    bool
+		expectedMute bool
+	}{
+		{name: "when start cue is enabled, join unmuted to play it", enabled: true, available: true, expectedMute: false},
+		{name: "when start cue cannot be played in this host, join muted", enabled: true, available: false, expectedMute: true},
+		{name: "when start cue is disabled, join muted", enabled: false, available: true, expectedMute: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discordClient := &discordmocks.Client{}
+			startCue := &domainmocks.StartCue{}
+			startCue.On("Available").Return(tt.available)
+			discordClient.On("EstablishVoiceConnection", "guild", "channel", tt.expectedMute, false, mock.Anything).Return(nil, errors.New("err joining"))
+			usecase := &VoiceRecorder{discord: discordClient, startCue: startCue}
+			settings := domain.DefaultGuildSettings("guild")
+			settings.StartCueEnabled = tt.enabled
+
+			err := usecase.recordAndSend("user", "guild", "channel", "username", "avatar", make(chan bool, 1), settings, false)
+
+			assert.Error(t, err)
+			discordClient.AssertExpectations(t)
+			startCue.AssertNumberOfCalls(t, "Frames", 0)
+		})
+	}
+}
+
+func TestVoiceRecorder_handleVoiceRecording(t *testing.T) {
+	type fields struct {
+		lockedUsers *domainmocks.LockedUserRepository
+		done        chan bool
+	}
+	tests := []struct {
+		name        string
+		fields      fields
+		intent      discord.VoiceIntent
+		channelID   string
+		on          func(*fields)
+		assertMocks func(t *testing.T, f *fields)
+	}{
+		{
+			name:      "when recorded user mutes, keep recording",
+			fields:    fields{lockedUsers: &domainmocks.LockedUserRepository{}},
+			intent:    discord.VoiceIntentMuted,
+			channelID: "voice",
+			assertMocks: func(t *testing.T, f *fields) {
+				f.lockedUsers.AssertNumberOfCalls(t, "GetCurrentLock", 0)
+			},
+		},
+		{
+			name:      "when recorded user starts streaming, keep recording",
+			fields:    fields{lockedUsers: &domainmocks.LockedUserRepository{}},
+			intent:    discord.VoiceIntentStreamStarted,
+			channelID: "voice",
+			assertMocks: func(t *testing.T, f *fields) {
+				f.lockedUsers.AssertNumberOfCalls(t, "GetCurrentLock", 0)
+			},
+		},
+		{
+			name:   "when another user leaves, keep recording",
+			fields: fields{lockedUsers: &domainmocks.LockedUserRepository{}},
+			intent: discord.VoiceIntentLeft,
+			on: func(fields *fields) {
+				fields.lockedUsers.On("GetCurrentLock", "guild").Return("other", fields.done)
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.lockedUsers.AssertNumberOfCalls(t, "ReleaseUserLock", 0)
+			},
+		},
+		{
+			name:   "when recorded user leaves, finish the recording",
+			fields: fields{lockedUsers: &domainmocks.LockedUserRepository{}, done: make(chan bool, 1)},
+			intent: discord.VoiceIntentLeft,
+			on: func(fields *fields) {
+				fields.lockedUsers.On("GetCurrentLock", "guild").Return("user", fields.done)
+				fields.lockedUsers.On("ReleaseUserLock", "guild").Return()
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.lockedUsers.AssertExpectations(t)
+				assert.Len(t, f.done, 1)
+			},
+		},
+		{
+			name:   "when recording was given up on, finish it without waiting for the signal to be read",
+			fields: fields{lockedUsers: &domainmocks.LockedUserRepository{}, done: make(chan bool)},
+			intent: discord.VoiceIntentLeft,
+			on: func(fields *fields) {
+				fields.lockedUsers.On("GetCurrentLock", "guild").Return("user", fields.done)
+				fields.lockedUsers.On("ReleaseUserLock", "guild").Return()
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.lockedUsers.AssertExpectations(t)
+			},
+		},
+		{
+			name:      "when recorded user moves to another channel, finish the recording",
+			fields:    fields{lockedUsers: &domainmocks.LockedUserRepository{}, done: make(chan bool, 1)},
+			intent:    discord.VoiceIntentMoved,
+			channelID: "other",
+			on: func(fields *fields) {
+				fields.lockedUsers.On("GetCurrentLock", "guild").Return("user", fields.done)
+				fields.lockedUsers.On("ReleaseUserLock", "guild").Return()
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.lockedUsers.AssertExpectations(t)
+				assert.Len(t, f.done, 1)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := &VoiceRecorder{
+				lockedUserRepository: tt.fields.lockedUsers,
+			}
+			if tt.on != nil {
+				tt.on(&tt.fields)
+			}
+			err := usecase.handleVoiceRecording(tt.intent, "user", tt.channelID, "guild", "username", "")
+			assert.NoError(t, err)
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &tt.fields)
+			}
+		})
+	}
+}
+
+func TestVoiceRecorder_handleVoice(t *testing.T) {
+	type fields struct {
+		lockedUsers     *domainmocks.LockedUserRepository
+		encoder         *domainmocks.AudioEncoder
+		effectRepo      *domainmocks.VoiceEffectRepository
+		pendingCaptions *domainmocks.PendingCaptionRepository
+	}
+	tests := []struct {
+		name        string
+		fields      fields
+		receiveErr  error
+		on          func(*fields)
+		assertMocks func(t *testing.T, f *fields)
+	}{
+		{
+			name:   "when user leaves, the leave releases the lock",
+			fields: fields{lockedUsers: &domainmocks.LockedUserRepository{}, encoder: &domainmocks.AudioEncoder{}, effectRepo: &domainmocks.VoiceEffectRepository{}, pendingCaptions: &domainmocks.PendingCaptionRepository{}},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.lockedUsers.AssertNumberOfCalls(t, "ReleaseUserLock", 0)
+			},
+		},
+		{
+			name:       "when voice connection is given up on, release the lock as the user did not leave",
+			fields:     fields{lockedUsers: &domainmocks.LockedUserRepository{}, encoder: &domainmocks.AudioEncoder{}, effectRepo: &domainmocks.VoiceEffectRepository{}, pendingCaptions: &domainmocks.PendingCaptionRepository{}},
+			receiveErr: errors.New("voice connection not ready for 16s, err reconnecting to voice channel after 3 attempts"),
+			on: func(fields *fields) {
+				fields.lockedUsers.On("ReleaseUserLock", "guild").Return()
+			},
+			assertMocks: func(t *testing.T, f *fields) {
+				f.lockedUsers.AssertExpectations(t)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := &VoiceRecorder{
+				lockedUserRepository: tt.fields.lockedUsers,
+				encoder:              tt.fields.encoder,
+				effectRepo:           tt.fields.effectRepo,
+				pendingCaptions:      tt.fields.pendingCaptions,
+				jobs:                 &inlineJobs{},
+			}
+			tt.fields.encoder.On("Supports", domain.AudioFormatOpus).Return(true)
+			tt.fields.effectRepo.On("Get", "guild", "user").Return(domain.VoiceEffectNone, nil)
+			tt.fields.pendingCaptions.On("PopPending", "guild", "user").Return("", false)
+			if tt.on != nil {
+				tt.on(&tt.fields)
+			}
+			receiver := make(chan *discord.Packet)
+			close(receiver)
+			v := discord.NewVoiceConnection(nil, receiver)
+			v.Err = func() error { return tt.receiveErr }
+
+			sent := usecase.handleVoice(v, "user", "guild", "username", "", time.Now(), domain.GuildSettings{AudioFormat: domain.AudioFormatOpus}, false)
+			assert.Empty(t, sent)
+
+			if tt.assertMocks != nil {
+				tt.assertMocks(t, &tt.fields)
+			}
+		})
+	}
+}
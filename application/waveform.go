@@ -0,0 +1,48 @@
+package application
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	waveformImageWidth  = 600
+	waveformImageHeight = 120
+	// waveformBars drawn in the image, every bar is followed by a gap as wide as itself
+	waveformBars = 100
+	// defaultWaveformColor is used when there is no avatar to take the color from
+	defaultWaveformColor = 0x5865F2
+	// minBarHeight keeps silent bars visible, so the pauses are seen as pauses and not as missing audio
+	minBarHeight = 2
+)
+
+// renderWaveform draws the amplitudes as vertical bars centered on the middle line, over a transparent background
+func renderWaveform(amplitudes []byte, rgb int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, waveformImageWidth, waveformImageHeight))
+	if len(amplitudes) == 0 {
+		return img
+	}
+	if rgb == 0 {
+		rgb = defaultWaveformColor
+	}
+	barColor := color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}
+	slot := float64(waveformImageWidth) / float64(len(amplitudes))
+	barWidth := int(slot / 2)
+	if barWidth < 1 {
+		barWidth = 1
+	}
+	for i, amplitude := range amplitudes {
+		height := int(amplitude) * waveformImageHeight / 255
+		if height < minBarHeight {
+			height = minBarHeight
+		}
+		left := int(float64(i) * slot)
+		top := (waveformImageHeight - height) / 2
+		for x := left; x < left+barWidth && x < waveformImageWidth; x++ {
+			for y := top; y < top+height; y++ {
+				img.SetRGBA(x, y, barColor)
+			}
+		}
+	}
+	return img
+}
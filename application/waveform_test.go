@@ -0,0 +1,41 @@
+package application
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_renderWaveform(t *testing.T) {
+	middle := waveformImageHeight / 2
+	tests := []struct {
+		name       string
+		amplitudes []byte
+		rgb        int
+		expected   color.RGBA
+		// top of the first bar, the middle when there are no bars
+		expectedTop int
+	}{
+		{name: "without amplitudes, image is empty", amplitudes: nil, rgb: 0xff0000, expected: color.RGBA{}, expectedTop: middle},
+		{name: "loudest bars take the whole height", amplitudes: []byte{255, 0}, rgb: 0xff0000, expected: color.RGBA{R: 0xff, A: 0xff}, expectedTop: 0},
+		{name: "silent bars are still drawn", amplitudes: []byte{0, 255}, rgb: 0x00ff00, expected: color.RGBA{G: 0xff, A: 0xff}, expectedTop: middle - minBarHeight/2},
+		{name: "without color, default one is used", amplitudes: []byte{128}, rgb: 0, expected: color.RGBA{R: 0x58, G: 0x65, B: 0xF2, A: 0xff}, expectedTop: (waveformImageHeight - 128*waveformImageHeight/255) / 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := renderWaveform(tt.amplitudes, tt.rgb)
+			assert.Equal(t, waveformImageWidth, img.Bounds().Dx())
+			assert.Equal(t, waveformImageHeight, img.Bounds().Dy())
+			assert.Equal(t, tt.expected, img.RGBAAt(0, middle))
+			assert.Equal(t, tt.expected, img.RGBAAt(0, tt.expectedTop))
+			if tt.expectedTop > 0 {
+				assert.Equal(t, color.RGBA{}, img.RGBAAt(0, tt.expectedTop-1))
+			}
+			if len(tt.amplitudes) > 1 {
+				// gap between the first two bars
+				assert.Equal(t, color.RGBA{}, img.RGBAAt(waveformImageWidth/len(tt.amplitudes)-1, middle))
+			}
+		})
+	}
+}
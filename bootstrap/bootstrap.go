@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"runtime"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/golang-migrate/migrate/v4"
@@ -13,8 +15,10 @@ import (
 	"github.com/hectorgabucio/taterubot-dc/application"
 	"github.com/hectorgabucio/taterubot-dc/config"
 	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
 	mp3decoder "github.com/hectorgabucio/taterubot-dc/infrastructure/decoder"
 	discordwrapper "github.com/hectorgabucio/taterubot-dc/infrastructure/discordgo"
+	"github.com/hectorgabucio/taterubot-dc/infrastructure/ffmpeg"
 	"github.com/hectorgabucio/taterubot-dc/infrastructure/inmemory"
 	"github.com/hectorgabucio/taterubot-dc/infrastructure/localfs"
 	"github.com/hectorgabucio/taterubot-dc/infrastructure/pion"
@@ -69,6 +73,14 @@ func createServerAndDependencies() (context.Context, *server.Server, []Closer, e
 
 	viper.SetDefault("LANGUAGE", "en")
 	viper.SetDefault("CHANNEL_NAME", "TATERU")
+	viper.SetDefault("CONTINUATION_WINDOW_SECONDS", 30)
+	viper.SetDefault("INTRO_MAX_SECONDS", 5)
+	viper.SetDefault("INTRO_COOLDOWN_SECONDS", 600)
+	viper.SetDefault("SOUND_ARCHIVE_PATH", "./sounds")
+	viper.SetDefault("FILE_NAME_TEMPLATE", domain.DefaultFileNameTemplate)
+	viper.SetDefault("JOB_WORKERS", runtime.NumCPU())
+	viper.SetDefault("JOB_QUEUE_SIZE", 100)
+	viper.SetDefault("DISCORD_CACHE_TTL_SECONDS", 300)
 
 	viper.SetConfigFile(`config.json`)
 	viper.SetConfigType("json")
@@ -84,6 +96,23 @@ func createServerAndDependencies() (context.Context, *server.Server, []Closer, e
 	cfg.ChannelName = viper.GetString("CHANNEL_NAME")
 	cfg.CloudAMQPUrl = viper.GetString("CLOUDAMQP_URL")
 	cfg.DatabaseURL = viper.GetString("DATABASE_URL")
+	cfg.ContinuationWindow = time.Duration(viper.GetInt("CONTINUATION_WINDOW_SECONDS")) * time.Second
+	cfg.IntroMaxDuration = time.Duration(viper.GetInt("INTRO_MAX_SECONDS")) * time.Second
+	cfg.IntroCooldown = time.Duration(viper.GetInt("INTRO_COOLDOWN_SECONDS")) * time.Second
+	cfg.SoundArchivePath = viper.GetString("SOUND_ARCHIVE_PATH")
+	cfg.AnonymitySecret = viper.GetString("ANONYMITY_SECRET")
+	if cfg.AnonymitySecret == "" {
+		// falling back to the bot token would lose the authors whenever it is rotated
+		return nil, nil, nil, errors.New("ANONYMITY_SECRET is required, it keys the hashes of the authors of anonymous voice messages")
+	}
+	cfg.FileNameTemplate = viper.GetString("FILE_NAME_TEMPLATE")
+	cfg.JobWorkers = viper.GetInt("JOB_WORKERS")
+	cfg.JobQueueSize = viper.GetInt("JOB_QUEUE_SIZE")
+	cfg.DiscordCacheTTL = time.Duration(viper.GetInt("DISCORD_CACHE_TTL_SECONDS")) * time.Second
+	fileNames, err := domain.NewFileNameTemplate(cfg.FileNameTemplate)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid FILE_NAME_TEMPLATE, %w", err)
+	}
 
 	// LOCALIZATION
 	l := localizations.New(cfg.Language, "en")
@@ -93,31 +122,95 @@ func createServerAndDependencies() (context.Context, *server.Server, []Closer, e
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("error getting new bot client, %w", err)
 	}
-	s.Identify.Intents = discordgo.MakeIntent(discordgo.IntentsGuildVoiceStates)
+	s.Identify.Intents = discordgo.MakeIntent(discordgo.IntentsGuilds | discordgo.IntentsGuildVoiceStates)
 
 	eventBus := inmemory.NewEventBus()
 	commandBus := inmemory.NewCommandBus()
+	jobQueue := inmemory.NewJobQueue(cfg.JobWorkers, cfg.JobQueueSize)
 
 	lockedUserRepo := inmemory.NewLockedUserRepository()
+	pendingIntroRepo := inmemory.NewPendingIntroRepository()
+	pendingCaptionRepo := inmemory.NewPendingCaptionRepository()
 	fsRepo := localfs.NewRepository(cfg.BasePath)
-	decoder := mp3decoder.NewMP3Decoder()
+	soundStore := localfs.NewSoundStore(cfg.SoundArchivePath)
 
-	discordClient := discordwrapper.NewClient(s)
+	closers := []Closer{commandBus, eventBus}
+	var discordClient discord.Client = discordwrapper.NewClient(s)
+	if cfg.DiscordCacheTTL > 0 {
+		cachedClient := discordwrapper.NewCachedClient(discordClient, s, cfg.DiscordCacheTTL)
+		closers = append(closers, cachedClient)
+		discordClient = cachedClient
+	}
 	oggWriter := &pion.Writer{}
+	oggAnalyzer := pion.NewAnalyzer()
+	oggSplitter := pion.NewSplitter()
+	processor := pion.NewProcessor()
 
 	db := setupSQLConnection(cfg.DatabaseURL)
 	voiceDataRepo := sqlrepo.NewVoiceDataRepository(db)
+	guildSettingsRepo := sqlrepo.NewGuildSettingsRepository(db)
+	introRepo := sqlrepo.NewIntroRepository(db)
+	soundRepo := sqlrepo.NewSoundRepository(db)
+	effectRepo := sqlrepo.NewVoiceEffectRepository(db)
+	revealAuditRepo := sqlrepo.NewRevealAuditRepository(db)
+	deadLetterRepo := sqlrepo.NewDeadLetterRepository(db)
+	// replays go straight to discord, their dead letter is only deleted once they are delivered
+	replayClient := discordClient
+	discordClient = discordwrapper.NewRetryingClient(discordClient, s, deadLetterRepo)
+	authorHasher := domain.NewAuthorHasher(cfg.AnonymitySecret)
+	var startCue domain.StartCue = domain.NoStartCue{}
+	if ffmpeg.Available() {
+		startCue = ffmpeg.NewStartCue(fsRepo)
+	}
+	encoder, opusTranscoder, tagger := setupAudioPipeline(fsRepo)
+	prober := setupMediaProber()
 
 	// APPLICATION LAYER
 	greeting := application.NewGreetingMessageCreator(discordClient, l, cfg.ChannelName)
 	stats := application.NewStatsMessageCreator(discordClient, l, voiceDataRepo)
-	voice := application.NewVoiceRecorder(discordClient, cfg.ChannelName, lockedUserRepo, eventBus, fsRepo, oggWriter)
-	embedAudioData := application.NewAddMetadataOnAudioSent(discordClient, l, fsRepo, voiceDataRepo, decoder, eventBus)
+	voice := application.NewVoiceRecorder(discordClient, l, eventBus, authorHasher, jobQueue,
+		application.VoiceMedia{
+			Files:     fsRepo,
+			Writer:    oggWriter,
+			Analyzer:  oggAnalyzer,
+			Splitter:  oggSplitter,
+			StartCue:  startCue,
+			Encoder:   encoder,
+			Processor: processor,
+			Tagger:    tagger,
+		},
+		application.VoiceRepositories{
+			LockedUsers:     lockedUserRepo,
+			VoiceData:       voiceDataRepo,
+			GuildSettings:   guildSettingsRepo,
+			PendingIntros:   pendingIntroRepo,
+			Effects:         effectRepo,
+			PendingCaptions: pendingCaptionRepo,
+			DeadLetters:     deadLetterRepo,
+		},
+		application.VoiceSettings{
+			ChannelName:        cfg.ChannelName,
+			FileNames:          fileNames,
+			MaxStreams:         cfg.JobWorkers,
+			ContinuationWindow: cfg.ContinuationWindow,
+		},
+	)
+	embedAudioData := application.NewAddMetadataOnAudioSent(discordClient, l, fsRepo, voiceDataRepo, prober, oggAnalyzer, eventBus, jobQueue)
 	removeFiles := application.NewRemoveFilesWhenNotNeeded(fsRepo)
+	settings := application.NewGuildSettingsUpdater(discordClient, l, guildSettingsRepo, encoder, startCue)
+	player := application.NewVoicePlayer(discordClient, l, voiceDataRepo, lockedUserRepo, fsRepo, opusTranscoder)
+	intros := application.NewIntroManager(discordClient, l, cfg.ChannelName, introRepo, pendingIntroRepo, lockedUserRepo, fsRepo, opusTranscoder, player, cfg.IntroMaxDuration, cfg.IntroCooldown)
+	soundboard := application.NewSoundboard(discordClient, l, soundRepo, soundStore, voiceDataRepo, lockedUserRepo, fsRepo, opusTranscoder, player)
+	effects := application.NewEffectManager(discordClient, l, effectRepo, guildSettingsRepo, encoder, fsRepo, eventBus, jobQueue)
+	revealer := application.NewAuthorRevealer(discordClient, l, voiceDataRepo, revealAuditRepo, authorHasher)
+	videos := application.NewVideoExporter(discordClient, l, voiceDataRepo, ffmpeg.NewVideoRenderer(), fsRepo, jobQueue)
+	captions := application.NewCaptionSetter(discordClient, l, pendingCaptionRepo)
+	deliveries := application.NewDeliveryReplayer(replayClient, l, deadLetterRepo, eventBus)
 
 	// EVENT SUBSCRIPTIONS
 	eventBus.Subscribe(domain.AudioSentEventType, embedAudioData)
 	eventBus.Subscribe(domain.DoneProcessingFilesEventType, removeFiles)
+	eventBus.Subscribe(domain.IntroRecordedEventType, intros)
 
 	// COMMAND HANDLING
 	greetingCommandHandler := application.NewGreetingCommandHandler(greeting)
@@ -129,10 +222,71 @@ func createServerAndDependencies() (context.Context, *server.Server, []Closer, e
 	statsCommandHandler := application.NewStatsCommandHandler(stats)
 	commandBus.Register(application.StatsCommandType, statsCommandHandler)
 
-	ctx, srv := server.NewServer(context.Background(), s, commandBus)
-	return ctx, srv, []Closer{
-		commandBus, eventBus, srv, db,
-	}, nil
+	settingsCommandHandler := application.NewSettingsCommandHandler(settings)
+	commandBus.Register(application.SettingsCommandType, settingsCommandHandler)
+
+	playCommandHandler := application.NewPlayCommandHandler(player)
+	commandBus.Register(application.PlayCommandType, playCommandHandler)
+
+	introCommandHandler := application.NewIntroCommandHandler(intros)
+	commandBus.Register(application.IntroCommandType, introCommandHandler)
+
+	playIntroCommandHandler := application.NewPlayIntroCommandHandler(intros)
+	commandBus.Register(application.PlayIntroCommandType, playIntroCommandHandler)
+
+	soundCommandHandler := application.NewSoundCommandHandler(soundboard)
+	commandBus.Register(application.SoundCommandType, soundCommandHandler)
+
+	soundAutocompleteCommandHandler := application.NewSoundAutocompleteCommandHandler(soundboard)
+	commandBus.Register(application.SoundAutocompleteCommandType, soundAutocompleteCommandHandler)
+
+	effectCommandHandler := application.NewEffectCommandHandler(effects)
+	commandBus.Register(application.EffectCommandType, effectCommandHandler)
+
+	remixCommandHandler := application.NewRemixCommandHandler(effects)
+	commandBus.Register(application.RemixCommandType, remixCommandHandler)
+
+	revealCommandHandler := application.NewRevealCommandHandler(revealer)
+	commandBus.Register(application.RevealCommandType, revealCommandHandler)
+
+	exportVideoCommandHandler := application.NewExportVideoCommandHandler(videos)
+	commandBus.Register(application.ExportVideoCommandType, exportVideoCommandHandler)
+
+	captionCommandHandler := application.NewCaptionCommandHandler(captions)
+	commandBus.Register(application.CaptionCommandType, captionCommandHandler)
+
+	deliveriesCommandHandler := application.NewDeliveriesCommandHandler(deliveries)
+	commandBus.Register(application.DeliveriesCommandType, deliveriesCommandHandler)
+
+	ctx, srv := server.NewServer(context.Background(), s, discordClient, commandBus, l)
+	return ctx, srv, append(closers, srv, jobQueue, db), nil
+}
+
+// setupAudioPipeline prefers the in process encoders and taggers and only adds ffmpeg when it is installed, reporting the formats available
+func setupAudioPipeline(fsRepo domain.FileRepository) (domain.AudioEncoder, domain.OpusTranscoder, domain.AudioTagger) {
+	encoders := []domain.AudioEncoder{pion.NewEncoder()}
+	taggers := []domain.AudioTagger{pion.NewTagger()}
+	var fallback domain.OpusTranscoder
+	if ffmpeg.Available() {
+		encoders = append(encoders, ffmpeg.NewEncoder())
+		taggers = append(taggers, ffmpeg.NewTagger())
+		fallback = ffmpeg.NewOpusTranscoder(fsRepo)
+	} else {
+		log.Println("ffmpeg not found, start cues, voice effects, videos, tags and playback of files that are not opus are disabled")
+	}
+	encoder := application.NewEncoderChain(encoders...)
+	log.Println("audio formats available:", domain.SupportedAudioFormats(encoder))
+	log.Println("voice effects available:", domain.SupportedVoiceEffects(encoder))
+	return encoder, pion.NewOpusTranscoder(fsRepo, fallback), application.NewTaggerChain(taggers...)
+}
+
+// setupMediaProber reads opus, wav and mp3 in process, and anything else with ffprobe when it is installed
+func setupMediaProber() domain.MediaProber {
+	probers := []domain.MediaProber{pion.NewProber(), mp3decoder.NewProber()}
+	if ffmpeg.Available() {
+		probers = append(probers, ffmpeg.NewProber())
+	}
+	return application.NewProberChain(probers...)
 }
 
 func Run() error {
@@ -1,10 +1,26 @@
 package config
 
+import "time"
+
 type Config struct {
-	BotToken     string
-	ChannelName  string
-	Language     string
-	BasePath     string
-	CloudAMQPUrl string
-	DatabaseURL  string
+	BotToken           string
+	ChannelName        string
+	Language           string
+	BasePath           string
+	CloudAMQPUrl       string
+	DatabaseURL        string
+	ContinuationWindow time.Duration
+	IntroMaxDuration   time.Duration
+	IntroCooldown      time.Duration
+	SoundArchivePath   string
+	// AnonymitySecret keys the hashes that replace the author of anonymous voice messages
+	AnonymitySecret string
+	// FileNameTemplate is the text/template the names of the uploaded recordings are rendered with
+	FileNameTemplate string
+	// JobWorkers is how many encoding, upload and embedding jobs run at once, and how many recordings are encoded while
+	// recorded. JobQueueSize is how many jobs can wait for them
+	JobWorkers   int
+	JobQueueSize int
+	// DiscordCacheTTL is how long users, channels and guilds are kept before asking discord again, 0 disables the cache
+	DiscordCacheTTL time.Duration
 }
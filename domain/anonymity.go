@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// AuthorHasher hides the author of anonymous voice messages behind a keyed hash, so the same member always gets
+// the same hash in a guild but nobody without the key can tell who it is, nor link it across guilds
+type AuthorHasher struct {
+	key []byte
+}
+
+func NewAuthorHasher(secret string) AuthorHasher {
+	return AuthorHasher{key: []byte(secret)}
+}
+
+func (h AuthorHasher) Hash(guildID string, userID string) string {
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(guildID + ":" + userID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RevealAudit records that a moderator revealed the author of an anonymous voice message
+type RevealAudit struct {
+	GuildID     string
+	MessageID   string
+	ModeratorID string
+	Reason      string
+	CreatedAt   time.Time
+}
+
+//go:generate mockery --name=RevealAuditRepository --case=snake --outpkg=domainmocks
+type RevealAuditRepository interface {
+	Save(audit RevealAudit) error
+}
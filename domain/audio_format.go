@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"strings"
+
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+)
+
+type AudioFormat string
+
+const (
+	// AudioFormatOpus keeps the recorded ogg/opus audio without transcoding it
+	AudioFormatOpus AudioFormat = "opus"
+	AudioFormatMP3  AudioFormat = "mp3"
+	AudioFormatM4A  AudioFormat = "m4a"
+	AudioFormatWAV  AudioFormat = "wav"
+)
+
+const (
+	DefaultAudioFormat  = AudioFormatMP3
+	DefaultAudioBitrate = 96
+	MinAudioBitrate     = 32
+	MaxAudioBitrate     = 320
+)
+
+type audioFormatInfo struct {
+	extension   string
+	contentType string
+	label       string
+	lossless    bool
+}
+
+var audioFormats = map[AudioFormat]audioFormatInfo{
+	AudioFormatOpus: {extension: "ogg", contentType: "audio/ogg", label: "OGG"},
+	AudioFormatMP3:  {extension: "mp3", contentType: "audio/mpeg", label: "MP3"},
+	AudioFormatM4A:  {extension: "m4a", contentType: "audio/mp4", label: "M4A"},
+	AudioFormatWAV:  {extension: "wav", contentType: "audio/wav", label: "WAV", lossless: true},
+}
+
+// AudioFormats returns every supported format, in the order they are offered to users
+func AudioFormats() []AudioFormat {
+	return []AudioFormat{AudioFormatOpus, AudioFormatMP3, AudioFormatM4A, AudioFormatWAV}
+}
+
+// ParseAudioFormat returns the format with that name, false if it is not supported
+func ParseAudioFormat(name string) (AudioFormat, bool) {
+	format := AudioFormat(strings.ToLower(strings.TrimSpace(name)))
+	_, ok := audioFormats[format]
+	return format, ok
+}
+
+// Extension is the file extension of the format, without the dot
+func (f AudioFormat) Extension() string {
+	return audioFormats[f].extension
+}
+
+func (f AudioFormat) ContentType() string {
+	return audioFormats[f].contentType
+}
+
+// Label is the name of the format shown to users
+func (f AudioFormat) Label() string {
+	return audioFormats[f].label
+}
+
+// Lossless formats ignore the bitrate
+func (f AudioFormat) Lossless() bool {
+	return audioFormats[f].lossless
+}
+
+type EncodeOptions struct {
+	Format AudioFormat
+	// Bitrate in kbps
+	Bitrate int
+	// Effect is rendered before encoding, VoiceEffectNone keeps the audio as it is
+	Effect VoiceEffect
+}
+
+//go:generate mockery --name=AudioEncoder --case=snake --outpkg=domainmocks
+type AudioEncoder interface {
+	// Encode writes the inputs one after the other as a single audio file in the given format
+	Encode(inputs []string, output string, options EncodeOptions) error
+	// Stream starts encoding the packets of a recording to the output while they are received
+	Stream(output string, options EncodeOptions) (AudioStream, error)
+	// Supports tells if the encoder can write the format in this host
+	Supports(format AudioFormat) bool
+	// SupportsEffect tells if the encoder can render the effect in this host, every encoder supports VoiceEffectNone
+	SupportsEffect(effect VoiceEffect) bool
+}
+
+//go:generate mockery --name=AudioStream --case=snake --outpkg=domainmocks
+type AudioStream interface {
+	Write(packet *discord.Packet) error
+	// Close finishes the output file, it returns the error that stopped the encoding if there was one
+	Close() error
+}
+
+// SupportedAudioFormats returns the formats the encoder can write, in the order they are offered to users
+func SupportedAudioFormats(encoder AudioEncoder) []AudioFormat {
+	var formats []AudioFormat
+	for _, format := range AudioFormats() {
+		if encoder.Supports(format) {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
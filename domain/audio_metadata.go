@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// DefaultFileNameTemplate names the audio files after their author and when they were recorded
+	DefaultFileNameTemplate = `{{.Artist}} {{.Date.Format "2006-01-02 15.04.05"}}{{with .Title}} - {{.}}{{end}}`
+	// MaxCaptionLength keeps captions short enough for titles and file names
+	MaxCaptionLength = 100
+	// maxFileNameLength leaves room for the extension and part numbers under the limit of most file systems
+	maxFileNameLength = 200
+	fallbackFileName  = "voice-message"
+)
+
+// AudioMetadata is written in the tags of the audio files, so music players can tell who recorded them and when
+type AudioMetadata struct {
+	Artist string
+	Album  string
+	// Title is the caption of the recording, there is no title when empty
+	Title string
+	Date  time.Time
+	// CoverPath is an image embedded as cover art, there is no cover when empty
+	CoverPath string
+}
+
+//go:generate mockery --name=AudioTagger --case=snake --outpkg=domainmocks
+type AudioTagger interface {
+	// Tag writes the metadata in the audio file, replacing the tags it had
+	Tag(path string, format AudioFormat, metadata AudioMetadata) error
+	// Supports tells if the tagger can write the tags of the format in this host
+	Supports(format AudioFormat) bool
+}
+
+// FileNameTemplate renders the names the audio files are uploaded with from their metadata
+type FileNameTemplate struct {
+	tmpl *template.Template
+}
+
+func NewFileNameTemplate(text string) (*FileNameTemplate, error) {
+	tmpl, err := template.New("file-name").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("err parsing file name template, %w", err)
+	}
+	// the template is checked now, so a wrong one is found when the bot starts and not when a recording is sent
+	if err := tmpl.Execute(&bytes.Buffer{}, AudioMetadata{}); err != nil {
+		return nil, fmt.Errorf("err rendering file name template, %w", err)
+	}
+	return &FileNameTemplate{tmpl: tmpl}, nil
+}
+
+// Render returns the file name without extension, with the characters file systems reject replaced
+func (t *FileNameTemplate) Render(metadata AudioMetadata) string {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, metadata); err != nil {
+		return fallbackFileName
+	}
+	name := strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, buf.String())
+	if runes := []rune(name); len(runes) > maxFileNameLength {
+		name = string(runes[:maxFileNameLength])
+	}
+	// a leading dot would hide the file
+	name = strings.Trim(name, " .")
+	if name == "" {
+		return fallbackFileName
+	}
+	return name
+}
@@ -0,0 +1,37 @@
+package domain
+
+const (
+	// DefaultSilenceThreshold in dBFS, quieter audio at the start and end of a recording is trimmed
+	DefaultSilenceThreshold = -50
+	MinSilenceThreshold     = -80
+	MaxSilenceThreshold     = -20
+)
+
+type ProcessOptions struct {
+	// Normalize measures the loudness as EBU R128 does and brings it to a common level
+	Normalize bool
+	// TrimSilence removes the audio under the threshold at the start and end of the recording
+	TrimSilence bool
+	// SilenceThreshold in dBFS
+	SilenceThreshold int
+}
+
+// Enabled tells if there is any step to apply
+func (o ProcessOptions) Enabled() bool {
+	return o.Normalize || o.TrimSilence
+}
+
+// ProcessReport tells what the processing did to a recording
+type ProcessReport struct {
+	Normalized bool
+	// GainDB applied to reach the target loudness
+	GainDB float64
+	// TrimmedSecs of silence removed from the start and end
+	TrimmedSecs float64
+}
+
+//go:generate mockery --name=AudioProcessor --case=snake --outpkg=domainmocks
+type AudioProcessor interface {
+	// Process rewrites the recorded ogg/opus file in place with the enabled steps
+	Process(path string, options ProcessOptions) (ProcessReport, error)
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "errors"
+
+//go:generate mockery --name=StartCue --case=snake --outpkg=domainmocks
+type StartCue interface {
+	// Available tells whether the cue can be played in this host
+	Available() bool
+	// Frames returns the 20ms opus frames of the cue played before recording
+	Frames() ([][]byte, error)
+}
+
+// NoStartCue is the cue of hosts that cannot render one, recordings start without it
+type NoStartCue struct{}
+
+func (NoStartCue) Available() bool {
+	return false
+}
+
+func (NoStartCue) Frames() ([][]byte, error) {
+	return nil, errors.New("start cue is not available")
+}
+
+type OpusTranscoder interface {
+	// ToOpusFrames decodes any audio file and returns it as 20ms opus frames ready to be played in a voice channel
+	ToOpusFrames(fileName string) ([][]byte, error)
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+)
+
+// DeliveryKind is what a failed delivery was doing, it tells how to replay it
+type DeliveryKind string
+
+const (
+	DeliveryKindText DeliveryKind = "text"
+	// DeliveryKindFile is a file message, replying to MessageID when it is set
+	DeliveryKindFile  DeliveryKind = "file"
+	DeliveryKindVoice DeliveryKind = "voice"
+	// DeliveryKindReplaceFile swaps the file of MessageID
+	DeliveryKindReplaceFile DeliveryKind = "replace_file"
+	// DeliveryKindEmbed sets the embeds and buttons of MessageID
+	DeliveryKindEmbed DeliveryKind = "embed"
+)
+
+// FailedDelivery is something that could not be sent to discord after retrying it, kept so an admin can replay it
+type FailedDelivery struct {
+	ID        string
+	GuildID   string
+	ChannelID string
+	// MessageID is the message edited, or the one replied to
+	MessageID   string
+	Kind        DeliveryKind
+	Content     string
+	FileName    string
+	ContentType string
+	File        []byte
+	Voice       discord.VoiceMessage
+	Embeds      []discord.MessageEmbed
+	// EmbedImages are the images the embeds upload, by the index of their embed, nil for the embeds without one
+	EmbedImages [][]byte
+	Buttons     []discord.Button
+	// AudioSent is the event of the recording the delivery was sending, nil for the other deliveries. The replay publishes
+	// it with the message it posted, so the audio still gets its embed and voice data and its files are removed
+	AudioSent *AudioSentEvent
+	// Error is why the last attempt failed
+	Error    string
+	FailedAt time.Time
+}
+
+// EmbedsWithImages returns the embeds ready to be sent, every image with a reader of its own
+func (d FailedDelivery) EmbedsWithImages() []discord.MessageEmbed {
+	embeds := make([]discord.MessageEmbed, len(d.Embeds))
+	for i, embed := range d.Embeds {
+		if embed.Image != nil && i < len(d.EmbedImages) {
+			image := *embed.Image
+			image.Reader = bytes.NewReader(d.EmbedImages[i])
+			embed.Image = &image
+		}
+		embeds[i] = embed
+	}
+	return embeds
+}
+
+// DeadLetteredError is returned for a delivery that failed and was kept to be replayed
+type DeadLetteredError struct {
+	DeliveryID string
+	Err        error
+}
+
+func (e *DeadLetteredError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DeadLetteredError) Unwrap() error {
+	return e.Err
+}
+
+//go:generate mockery --name=DeadLetterRepository --case=snake --outpkg=domainmocks
+type DeadLetterRepository interface {
+	Save(delivery FailedDelivery) error
+	// ListByGuild returns the failed deliveries of the guild, oldest first and without their files
+	ListByGuild(guildID string) ([]FailedDelivery, error)
+	Get(id string) (FailedDelivery, bool, error)
+	// SetAudioSent keeps the event of the recording the delivery was sending
+	SetAudioSent(id string, evt AudioSentEvent) error
+	Delete(id string) error
+}
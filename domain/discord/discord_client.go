@@ -12,6 +12,7 @@ const (
 //go:generate mockery --name=Client --case=snake --outpkg=discordmocks
 type Client interface {
 	GetGuilds() ([]Guild, error)
+	GetGuild(guildID string) (Guild, error)
 	GetGuildUsers(guildID string) ([]User, error)
 	GetUser(userID string) (User, error)
 	GetBotUsername() string
@@ -20,15 +21,56 @@ type Client interface {
 	CreateChannel(guildID string, name string, channelType ChannelType, maxUsers int) (Channel, error)
 	SendTextMessage(channelID string, message string) error
 	SendFileMessage(channelID string, name, contentType string, readable io.Reader) (Message, error)
-	SetEmbed(channelID string, messageID string, embed MessageEmbed) error
+	// SendFileReply sends the file with a text, as a reply to replyToID unless it is empty
+	SendFileReply(channelID string, replyToID string, content string, name, contentType string, readable io.Reader) (Message, error)
+	// SendVoiceMessage uploads an ogg/opus file as a native voice message, which cannot be edited afterwards
+	SendVoiceMessage(channelID string, name string, readable io.Reader, voice VoiceMessage) (Message, error)
+	// ReplaceFileMessage swaps the attachments of an already sent message with the given file
+	ReplaceFileMessage(channelID string, messageID string, name, contentType string, readable io.Reader) (Message, error)
+	// SetEmbeds replaces the embeds and buttons of the message, uploading the Image of the embeds that have one.
+	// Embeds over the limits of discord are truncated
+	SetEmbeds(channelID string, messageID string, embeds []MessageEmbed, buttons []Button) error
+	GetMessage(channelID string, messageID string) (Message, error)
 	EstablishVoiceConnection(guildID, channelID string, mute, deaf bool, done chan bool) (voice *VoiceConnection, err error)
+	// EstablishPlaybackConnection joins the voice channel only to send audio, nothing is received
+	EstablishPlaybackConnection(guildID, channelID string) (voice *VoiceConnection, err error)
+	// PlayOpus sends the 20ms opus frames through the voice connection, blocking until all of them are queued, it fails
+	// when the connection stops taking them
+	PlayOpus(voice *VoiceConnection, frames [][]byte) error
+	DisconnectVoice(voice *VoiceConnection) error
+	// GetUserVoiceChannel returns the voice channel the user is connected to, empty if none
+	GetUserVoiceChannel(guildID string, userID string) (string, error)
 	EditInteraction(token string, message string) error
 	EditInteractionComplex(token string, edit ComplexInteractionEdit) error
+	// SendFollowup posts a message with the file after the answer of the interaction, visible to everyone in the channel
+	SendFollowup(token string, content string, file File) (Message, error)
+	// RespondAutocomplete answers an autocomplete interaction with the given choices
+	RespondAutocomplete(interactionID string, token string, choices []string) error
+}
+
+// VoiceMessage is what discord needs to render an ogg/opus attachment with its inline player
+type VoiceMessage struct {
+	DurationSecs float64
+	// Waveform holds up to 256 amplitudes from 0 to 255
+	Waveform []byte
 }
 
 type ComplexInteractionEdit struct {
 	Content string
 	Embeds  []*MessageEmbed
+	// Select is shown under the content when set
+	Select *SelectMenu
+}
+
+type SelectMenu struct {
+	CustomID    string
+	Placeholder string
+	Options     []SelectOption
+}
+
+type SelectOption struct {
+	Label string
+	Value string
 }
 
 type User struct {
@@ -36,11 +78,33 @@ type User struct {
 	Username    string
 	AvatarURL   string
 	AccentColor int
+	Bot         bool
 }
 
 type Guild struct {
 	ID   string
 	Name string
+	// PremiumTier is the boost level of the guild, from 0 to 3
+	PremiumTier int
+}
+
+// Upload limits in bytes, boosting a guild to level 2 or 3 raises the limit of every member
+const (
+	DefaultUploadLimit int64 = 10 << 20
+	Tier2UploadLimit   int64 = 50 << 20
+	Tier3UploadLimit   int64 = 100 << 20
+)
+
+// UploadLimit is the biggest file the bot can upload in the guild
+func (g Guild) UploadLimit() int64 {
+	switch {
+	case g.PremiumTier >= 3:
+		return Tier3UploadLimit
+	case g.PremiumTier == 2:
+		return Tier2UploadLimit
+	default:
+		return DefaultUploadLimit
+	}
 }
 
 type Channel struct {
@@ -50,30 +114,24 @@ type Channel struct {
 }
 
 type Message struct {
-	ID           string
-	ChannelID    string
-	AttachmentID string
+	ID            string
+	ChannelID     string
+	AttachmentID  string
+	AttachmentURL string
+	Embeds        []MessageEmbed
 }
 
-type MessageEmbed struct {
-	URL         string
-	Title       string
-	Description string
-	Timestamp   string
-	Color       int
-	Thumbnail   string
-	Fields      []*MessageEmbedField
-	Author      *MessageEmbedAuthor
+// File is uploaded as an attachment of a message
+type File struct {
+	Name        string
+	ContentType string
+	Reader      io.Reader
 }
 
-type MessageEmbedAuthor struct {
-	Name    string
-	IconURL string
-}
-
-type MessageEmbedField struct {
-	Name  string `json:"name,omitempty"`
-	Value string `json:"value,omitempty"`
+type Button struct {
+	Label    string
+	Emoji    string
+	CustomID string
 }
 
 type Packet struct {
@@ -85,9 +143,24 @@ type Packet struct {
 	PCM       []int16
 }
 
+// ReceiveStats count the packets received in a voice connection, including the ones left out of the recording
+type ReceiveStats struct {
+	Received int64
+	// Dropped packets did not fit in the receive buffer because the recording fell behind
+	Dropped int64
+	// Late packets arrived after a newer packet of the same speaker, so they could not be recorded in order
+	Late int64
+}
+
 type VoiceConnection struct {
+	// Internals is the connection of the client, which keeps it current when it reconnects
 	Internals     any
 	VoiceReceiver chan *Packet
+	// ReceiveStats returns the packets counted so far, it is nil for connections that do not receive
+	ReceiveStats func() ReceiveStats
+	// Err returns why the connection stopped receiving before the recording was done, nil when it ended normally.
+	// It is nil for connections that do not receive
+	Err func() error
 }
 
 func NewVoiceConnection(internals any, voice chan *Packet) *VoiceConnection {
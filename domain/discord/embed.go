@@ -0,0 +1,235 @@
+package discord
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Limits of discord for the embeds of a message, lengths are in characters
+const (
+	MaxEmbeds                 = 10
+	MaxEmbedsLength           = 6000
+	MaxEmbedTitleLength       = 256
+	MaxEmbedDescriptionLength = 4096
+	MaxEmbedFields            = 25
+	MaxEmbedFieldNameLength   = 256
+	MaxEmbedFieldValueLength  = 1024
+	MaxEmbedFooterLength      = 2048
+	MaxEmbedAuthorNameLength  = 256
+)
+
+// blankText stands in for the empty names and values of fields, which discord rejects
+const blankText = "\u200b"
+
+type MessageEmbed struct {
+	URL         string
+	Title       string
+	Description string
+	// Timestamp is shown in the footer, in RFC3339
+	Timestamp string
+	Color     int
+	// Thumbnail is the URL of the image shown at the top right
+	Thumbnail string
+	Fields    []*MessageEmbedField
+	Author    *MessageEmbedAuthor
+	Footer    *MessageEmbedFooter
+	// Image is uploaded along the embed and shown under its fields, the attachments of the message are kept
+	Image *File
+	// ImageURL is shown under the fields when there is no Image to upload. Embeds read from discord have the URL of
+	// their uploaded image here
+	ImageURL string
+}
+
+type MessageEmbedAuthor struct {
+	Name    string
+	URL     string
+	IconURL string
+}
+
+type MessageEmbedField struct {
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+	// Inline fields are shown side by side, up to three in a row
+	Inline bool `json:"inline,omitempty"`
+}
+
+type MessageEmbedFooter struct {
+	Text    string
+	IconURL string
+}
+
+// Length is how many characters of the embed count against the limit of the message
+func (e MessageEmbed) Length() int {
+	length := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	for _, field := range e.Fields {
+		if field != nil {
+			length += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+		}
+	}
+	if e.Author != nil {
+		length += utf8.RuneCountInString(e.Author.Name)
+	}
+	if e.Footer != nil {
+		length += utf8.RuneCountInString(e.Footer.Text)
+	}
+	return length
+}
+
+// Validate returns the first limit of discord the embed goes over, nil when it fits
+func (e MessageEmbed) Validate() error {
+	if err := checkLength("title", e.Title, MaxEmbedTitleLength); err != nil {
+		return err
+	}
+	if err := checkLength("description", e.Description, MaxEmbedDescriptionLength); err != nil {
+		return err
+	}
+	if len(e.Fields) > MaxEmbedFields {
+		return fmt.Errorf("embed has %d fields, the limit is %d", len(e.Fields), MaxEmbedFields)
+	}
+	for i, field := range e.Fields {
+		if field == nil {
+			continue
+		}
+		if field.Name == "" || field.Value == "" {
+			return fmt.Errorf("field %d of the embed has no name or value", i)
+		}
+		if err := checkLength(fmt.Sprintf("field %d name", i), field.Name, MaxEmbedFieldNameLength); err != nil {
+			return err
+		}
+		if err := checkLength(fmt.Sprintf("field %d value", i), field.Value, MaxEmbedFieldValueLength); err != nil {
+			return err
+		}
+	}
+	if e.Author != nil {
+		if err := checkLength("author name", e.Author.Name, MaxEmbedAuthorNameLength); err != nil {
+			return err
+		}
+	}
+	if e.Footer != nil {
+		if err := checkLength("footer", e.Footer.Text, MaxEmbedFooterLength); err != nil {
+			return err
+		}
+	}
+	if length := e.Length(); length > MaxEmbedsLength {
+		return fmt.Errorf("embed has %d characters, the limit is %d", length, MaxEmbedsLength)
+	}
+	return nil
+}
+
+// ValidateEmbeds checks every embed and the limits of the message as a whole
+func ValidateEmbeds(embeds []MessageEmbed) error {
+	if len(embeds) > MaxEmbeds {
+		return fmt.Errorf("message has %d embeds, the limit is %d", len(embeds), MaxEmbeds)
+	}
+	total := 0
+	for i, embed := range embeds {
+		if err := embed.Validate(); err != nil {
+			return fmt.Errorf("embed %d: %w", i, err)
+		}
+		total += embed.Length()
+	}
+	if total > MaxEmbedsLength {
+		return fmt.Errorf("embeds have %d characters, the limit is %d", total, MaxEmbedsLength)
+	}
+	return nil
+}
+
+func checkLength(name string, text string, limit int) error {
+	if length := utf8.RuneCountInString(text); length > limit {
+		return fmt.Errorf("embed %s has %d characters, the limit is %d", name, length, limit)
+	}
+	return nil
+}
+
+// Truncate returns the embed cut to the limits of discord. Cut texts end with an ellipsis, the fields over the limit
+// are dropped and empty names and values of fields are filled in
+func (e MessageEmbed) Truncate() MessageEmbed {
+	truncated, _ := e.fit(MaxEmbedsLength)
+	return truncated
+}
+
+// TruncateEmbeds returns the embeds cut so the message fits the limits of discord. The embeds after the limit are
+// dropped, and once the characters of the message run out the texts of the last embeds are cut, in the order they
+// are shown, or dropped when nothing of them is left
+func TruncateEmbeds(embeds []MessageEmbed) []MessageEmbed {
+	if len(embeds) > MaxEmbeds {
+		embeds = embeds[:MaxEmbeds]
+	}
+	truncated := make([]MessageEmbed, 0, len(embeds))
+	left := MaxEmbedsLength
+	for _, embed := range embeds {
+		var fitted MessageEmbed
+		fitted, left = embed.fit(left)
+		if embed.Length() > 0 && fitted.Length() == 0 {
+			continue
+		}
+		truncated = append(truncated, fitted)
+	}
+	return truncated
+}
+
+// fit cuts the texts of the embed to their limits and to the characters left in the message, returning what is left after it
+func (e MessageEmbed) fit(left int) (MessageEmbed, int) {
+	take := func(text string, limit int) string {
+		text = truncate(text, min(limit, left))
+		left -= utf8.RuneCountInString(text)
+		return text
+	}
+	fitted := e
+	fitted.Title = take(e.Title, MaxEmbedTitleLength)
+	if e.Author != nil {
+		author := *e.Author
+		author.Name = take(author.Name, MaxEmbedAuthorNameLength)
+		fitted.Author = nil
+		// discord rejects authors without a name
+		if author.Name != "" {
+			fitted.Author = &author
+		}
+	}
+	fitted.Description = take(e.Description, MaxEmbedDescriptionLength)
+	fitted.Fields = nil
+	for _, field := range e.Fields {
+		if field == nil {
+			continue
+		}
+		// a field needs at least a character for its name and another for its value
+		if len(fitted.Fields) == MaxEmbedFields || left < 2 {
+			break
+		}
+		// keeping a character for the value
+		left--
+		name := take(orBlank(field.Name), MaxEmbedFieldNameLength)
+		left++
+		value := take(orBlank(field.Value), MaxEmbedFieldValueLength)
+		fitted.Fields = append(fitted.Fields, &MessageEmbedField{Name: name, Value: value, Inline: field.Inline})
+	}
+	if e.Footer != nil {
+		footer := *e.Footer
+		footer.Text = take(footer.Text, MaxEmbedFooterLength)
+		fitted.Footer = nil
+		// and footers without a text
+		if footer.Text != "" {
+			fitted.Footer = &footer
+		}
+	}
+	return fitted, left
+}
+
+// truncate cuts the text to limit characters, ending it with an ellipsis. It never splits a character
+func truncate(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	if limit <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	return string(runes[:limit-1]) + "…"
+}
+
+func orBlank(text string) string {
+	if text == "" {
+		return blankText
+	}
+	return text
+}
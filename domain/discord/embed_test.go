@@ -0,0 +1,218 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fields(n int, name string, value string) []*MessageEmbedField {
+	f := make([]*MessageEmbedField, n)
+	for i := range f {
+		f[i] = &MessageEmbedField{Name: name, Value: value}
+	}
+	return f
+}
+
+func TestMessageEmbed_Validate(t *testing.T) {
+	tests := []struct {
+		name          string
+		embed         MessageEmbed
+		expectedError string
+	}{
+		{name: "when embed is within the limits, it is valid", embed: MessageEmbed{Title: "title", Description: "description", Fields: fields(MaxEmbedFields, "name", "value"), Author: &MessageEmbedAuthor{Name: "author"}, Footer: &MessageEmbedFooter{Text: "footer"}}},
+		{name: "when lengths are counted, characters count instead of bytes", embed: MessageEmbed{Title: strings.Repeat("ñ", MaxEmbedTitleLength)}},
+		{name: "when title is too long, it is not valid", embed: MessageEmbed{Title: strings.Repeat("a", MaxEmbedTitleLength+1)}, expectedError: "embed title has 257 characters, the limit is 256"},
+		{name: "when description is too long, it is not valid", embed: MessageEmbed{Description: strings.Repeat("a", MaxEmbedDescriptionLength+1)}, expectedError: "embed description has 4097 characters, the limit is 4096"},
+		{name: "when there are too many fields, it is not valid", embed: MessageEmbed{Fields: fields(MaxEmbedFields+1, "name", "value")}, expectedError: "embed has 26 fields, the limit is 25"},
+		{name: "when field has no name, it is not valid", embed: MessageEmbed{Fields: fields(1, "", "value")}, expectedError: "field 0 of the embed has no name or value"},
+		{name: "when field has no value, it is not valid", embed: MessageEmbed{Fields: fields(1, "name", "")}, expectedError: "field 0 of the embed has no name or value"},
+		{name: "when field value is too long, it is not valid", embed: MessageEmbed{Fields: fields(1, "name", strings.Repeat("a", MaxEmbedFieldValueLength+1))}, expectedError: "embed field 0 value has 1025 characters, the limit is 1024"},
+		{name: "when author name is too long, it is not valid", embed: MessageEmbed{Author: &MessageEmbedAuthor{Name: strings.Repeat("a", MaxEmbedAuthorNameLength+1)}}, expectedError: "embed author name has 257 characters, the limit is 256"},
+		{name: "when footer is too long, it is not valid", embed: MessageEmbed{Footer: &MessageEmbedFooter{Text: strings.Repeat("a", MaxEmbedFooterLength+1)}}, expectedError: "embed footer has 2049 characters, the limit is 2048"},
+		{
+			name:          "when every text fits but all of them are too long together, it is not valid",
+			embed:         MessageEmbed{Description: strings.Repeat("a", MaxEmbedDescriptionLength), Fields: fields(2, "name", strings.Repeat("a", MaxEmbedFieldValueLength))},
+			expectedError: "embed has 6152 characters, the limit is 6000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.embed.Validate()
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateEmbeds(t *testing.T) {
+	tests := []struct {
+		name          string
+		embeds        []MessageEmbed
+		expectedError string
+	}{
+		{name: "when embeds fit, they are valid", embeds: []MessageEmbed{{Title: "first"}, {Title: "second"}}},
+		{name: "when there are too many embeds, they are not valid", embeds: make([]MessageEmbed, MaxEmbeds+1), expectedError: "message has 11 embeds, the limit is 10"},
+		{name: "when an embed is not valid, tell which", embeds: []MessageEmbed{{Title: "first"}, {Fields: fields(1, "", "")}}, expectedError: "embed 1: field 0 of the embed has no name or value"},
+		{
+			name:          "when every embed fits but they are too long together, they are not valid",
+			embeds:        []MessageEmbed{{Description: strings.Repeat("a", 3000)}, {Description: strings.Repeat("a", 3000)}, {Title: "third"}},
+			expectedError: "embeds have 6005 characters, the limit is 6000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEmbeds(tt.embeds)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMessageEmbed_Truncate(t *testing.T) {
+	tests := []struct {
+		name   string
+		embed  MessageEmbed
+		assert func(t *testing.T, truncated MessageEmbed)
+	}{
+		{
+			name:  "when embed fits, keep it as it is",
+			embed: MessageEmbed{Title: "title", Description: "description", Fields: fields(2, "name", "value"), Author: &MessageEmbedAuthor{Name: "author"}, Footer: &MessageEmbedFooter{Text: "footer"}},
+			assert: func(t *testing.T, truncated MessageEmbed) {
+				assert.Equal(t, MessageEmbed{Title: "title", Description: "description", Fields: fields(2, "name", "value"), Author: &MessageEmbedAuthor{Name: "author"}, Footer: &MessageEmbedFooter{Text: "footer"}}, truncated)
+			},
+		},
+		{
+			name:  "when texts are too long, cut them ending with an ellipsis",
+			embed: MessageEmbed{Title: strings.Repeat("ñ", MaxEmbedTitleLength+10), Fields: fields(1, "name", strings.Repeat("a", MaxEmbedFieldValueLength+10))},
+			assert: func(t *testing.T, truncated MessageEmbed) {
+				assert.Equal(t, strings.Repeat("ñ", MaxEmbedTitleLength-1)+"…", truncated.Title)
+				assert.Equal(t, strings.Repeat("a", MaxEmbedFieldValueLength-1)+"…", truncated.Fields[0].Value)
+				assert.NoError(t, truncated.Validate())
+			},
+		},
+		{
+			name:  "when there are too many fields, drop the ones after the limit",
+			embed: MessageEmbed{Fields: append(fields(MaxEmbedFields, "name", "value"), &MessageEmbedField{Name: "dropped", Value: "value"})},
+			assert: func(t *testing.T, truncated MessageEmbed) {
+				assert.Len(t, truncated.Fields, MaxEmbedFields)
+				assert.Equal(t, "name", truncated.Fields[MaxEmbedFields-1].Name)
+			},
+		},
+		{
+			name:  "when fields have empty names or values, fill them in",
+			embed: MessageEmbed{Fields: []*MessageEmbedField{{Name: "", Value: "value", Inline: true}, {Name: "name", Value: ""}, nil}},
+			assert: func(t *testing.T, truncated MessageEmbed) {
+				assert.Equal(t, []*MessageEmbedField{{Name: blankText, Value: "value", Inline: true}, {Name: "name", Value: blankText}}, truncated.Fields)
+				assert.NoError(t, truncated.Validate())
+			},
+		},
+		{
+			name:  "when author and footer have no text, drop them",
+			embed: MessageEmbed{Title: "title", Author: &MessageEmbedAuthor{IconURL: "icon"}, Footer: &MessageEmbedFooter{IconURL: "icon"}},
+			assert: func(t *testing.T, truncated MessageEmbed) {
+				assert.Nil(t, truncated.Author)
+				assert.Nil(t, truncated.Footer)
+			},
+		},
+		{
+			name: "when texts are too long together, cut the last ones",
+			embed: MessageEmbed{
+				Title:       "title",
+				Description: strings.Repeat("a", MaxEmbedDescriptionLength),
+				Fields:      fields(3, "name", strings.Repeat("a", MaxEmbedFieldValueLength)),
+				Footer:      &MessageEmbedFooter{Text: "footer"},
+			},
+			assert: func(t *testing.T, truncated MessageEmbed) {
+				assert.Equal(t, strings.Repeat("a", MaxEmbedDescriptionLength), truncated.Description)
+				assert.Len(t, truncated.Fields, 2)
+				assert.Equal(t, strings.Repeat("a", MaxEmbedFieldValueLength), truncated.Fields[0].Value)
+				assert.Nil(t, truncated.Footer, "no character is left for the footer")
+				assert.Equal(t, MaxEmbedsLength, truncated.Length())
+				assert.NoError(t, truncated.Validate())
+			},
+		},
+		{
+			name: "when no character is left for the value of a field, drop the field",
+			// 4096 + 4 + 1024 + 2 + 873 leaves a single character
+			embed: MessageEmbed{Description: strings.Repeat("a", MaxEmbedDescriptionLength), Fields: []*MessageEmbedField{
+				{Name: "name", Value: strings.Repeat("a", MaxEmbedFieldValueLength)},
+				{Name: "nm", Value: strings.Repeat("a", 873)},
+				{Name: "n", Value: "v"},
+			}},
+			assert: func(t *testing.T, truncated MessageEmbed) {
+				assert.Len(t, truncated.Fields, 2)
+				assert.Equal(t, MaxEmbedsLength-1, truncated.Length())
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.assert(t, tt.embed.Truncate())
+		})
+	}
+}
+
+func TestTruncateEmbeds(t *testing.T) {
+	tests := []struct {
+		name   string
+		embeds []MessageEmbed
+		assert func(t *testing.T, truncated []MessageEmbed)
+	}{
+		{
+			name:   "when there are too many embeds, drop the ones after the limit",
+			embeds: make([]MessageEmbed, MaxEmbeds+2),
+			assert: func(t *testing.T, truncated []MessageEmbed) {
+				assert.Len(t, truncated, MaxEmbeds)
+			},
+		},
+		{
+			name:   "when embeds are too long together, cut the texts of the last ones",
+			embeds: []MessageEmbed{{Description: strings.Repeat("a", 4000)}, {Description: strings.Repeat("b", 4000)}},
+			assert: func(t *testing.T, truncated []MessageEmbed) {
+				assert.Len(t, truncated, 2)
+				assert.Equal(t, strings.Repeat("a", 4000), truncated[0].Description)
+				assert.Equal(t, strings.Repeat("b", 1999)+"…", truncated[1].Description)
+				assert.NoError(t, ValidateEmbeds(truncated))
+			},
+		},
+		{
+			name:   "when nothing of an embed is left, drop it",
+			embeds: []MessageEmbed{{Description: strings.Repeat("a", 4000)}, {Description: strings.Repeat("b", 2000)}, {Title: "dropped"}, {ImageURL: "image"}},
+			assert: func(t *testing.T, truncated []MessageEmbed) {
+				assert.Len(t, truncated, 3)
+				assert.Equal(t, "image", truncated[2].ImageURL, "embeds without texts take no characters")
+			},
+		},
+		{
+			name: "when author name is cut to nothing, drop the author",
+			// 4096 + 4 + 1024 + 2 + 871 leaves three characters, all taken by the title shown before the author
+			embeds: []MessageEmbed{
+				{Description: strings.Repeat("a", MaxEmbedDescriptionLength), Fields: []*MessageEmbedField{
+					{Name: "name", Value: strings.Repeat("a", MaxEmbedFieldValueLength)},
+					{Name: "nm", Value: strings.Repeat("a", 871)},
+				}},
+				{Title: "title", Thumbnail: "thumbnail", Author: &MessageEmbedAuthor{Name: "author", IconURL: "icon"}},
+			},
+			assert: func(t *testing.T, truncated []MessageEmbed) {
+				assert.Len(t, truncated, 2)
+				assert.Equal(t, "ti…", truncated[1].Title)
+				assert.Nil(t, truncated[1].Author)
+				assert.Equal(t, "thumbnail", truncated[1].Thumbnail)
+				assert.NoError(t, ValidateEmbeds(truncated))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.assert(t, TruncateEmbeds(tt.embeds))
+		})
+	}
+}
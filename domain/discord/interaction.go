@@ -0,0 +1,122 @@
+package discord
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// MaxCustomIDLength is the longest custom id discord accepts for components and modals
+const MaxCustomIDLength = 100
+
+// customIDSeparator splits the name of a custom id from its arguments, and the arguments between them
+const customIDSeparator = ":"
+
+type InteractionKind int
+
+const (
+	InteractionKindCommand InteractionKind = iota
+	InteractionKindComponent
+	InteractionKindModal
+	InteractionKindAutocomplete
+)
+
+func (k InteractionKind) String() string {
+	switch k {
+	case InteractionKindCommand:
+		return "command"
+	case InteractionKindComponent:
+		return "component"
+	case InteractionKindModal:
+		return "modal"
+	case InteractionKindAutocomplete:
+		return "autocomplete"
+	default:
+		return fmt.Sprintf("interaction kind %d", int(k))
+	}
+}
+
+// Interaction is a slash command, a click on a component, a submitted modal or an autocomplete of a member
+type Interaction struct {
+	Kind  InteractionKind
+	ID    string
+	Token string
+	// GuildID is empty for the interactions in direct messages
+	GuildID   string
+	ChannelID string
+	// MessageID is the message holding the component, empty for the other kinds
+	MessageID string
+	User      User
+	// Name is the name of the command, or the name in the custom id of the component or modal
+	Name string
+	// Args are the arguments encoded in the custom id of the component or modal
+	Args []string
+	// Subcommand is the subcommand used, empty for commands without them
+	Subcommand string
+	// Options are the options of the command or subcommand, or the fields of the modal, as they were sent
+	Options []InteractionOption
+	// Focused is the option being autocompleted
+	Focused string
+	// Values are the options chosen in a select menu
+	Values []string
+}
+
+type InteractionOption struct {
+	Name  string
+	Value string
+}
+
+// Option returns the value of the option, empty when it was not sent
+func (i Interaction) Option(name string) string {
+	for _, option := range i.Options {
+		if option.Name == name {
+			return option.Value
+		}
+	}
+	return ""
+}
+
+// Modal is a form shown to the member, submitted as an interaction of kind modal with its fields as options
+type Modal struct {
+	CustomID string
+	Title    string
+	Fields   []ModalField
+}
+
+type ModalField struct {
+	CustomID    string
+	Label       string
+	Placeholder string
+	// Value fills in the field
+	Value     string
+	Required  bool
+	MinLength int
+	MaxLength int
+	// Paragraph fields take several lines
+	Paragraph bool
+}
+
+// CustomID encodes the name of a component or modal along its arguments, which come back in the interactions with it.
+// The arguments are escaped, so they can hold anything
+func CustomID(name string, args ...string) (string, error) {
+	parts := []string{url.QueryEscape(name)}
+	for _, arg := range args {
+		parts = append(parts, url.QueryEscape(arg))
+	}
+	customID := strings.Join(parts, customIDSeparator)
+	if len(customID) > MaxCustomIDLength {
+		return "", fmt.Errorf("custom id %s is %d characters long, the limit is %d", customID, len(customID), MaxCustomIDLength)
+	}
+	return customID, nil
+}
+
+// ParseCustomID returns the name and arguments encoded in the custom id, parts that were not escaped are kept as they are
+func ParseCustomID(customID string) (string, []string) {
+	parts := strings.Split(customID, customIDSeparator)
+	for i, part := range parts {
+		if unescaped, err := url.QueryUnescape(part); err == nil {
+			parts[i] = unescaped
+		}
+	}
+	return parts[0], parts[1:]
+}
@@ -0,0 +1,63 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomID(t *testing.T) {
+	tests := []struct {
+		name             string
+		customName       string
+		args             []string
+		expectedCustomID string
+		expectedArgs     []string
+		expectedError    bool
+	}{
+		{name: "when there are no args, only the name is encoded", customName: "play", expectedCustomID: "play", expectedArgs: []string{}},
+		{name: "when there are args, they follow the name", customName: "remix", args: []string{"message", "robot"}, expectedCustomID: "remix:message:robot", expectedArgs: []string{"message", "robot"}},
+		{name: "when args hold the separator, they are escaped", customName: "sound", args: []string{"a:b", "c"}, expectedCustomID: "sound:a%3Ab:c", expectedArgs: []string{"a:b", "c"}},
+		{name: "when args hold escapes, they are kept as they are", customName: "sound", args: []string{"100%", "%3A"}, expectedCustomID: "sound:100%25:%253A", expectedArgs: []string{"100%", "%3A"}},
+		{name: "when args are empty, they are kept", customName: "sound", args: []string{"", "b"}, expectedCustomID: "sound::b", expectedArgs: []string{"", "b"}},
+		{name: "when custom id is as long as the limit, encode it", customName: "a", args: []string{strings.Repeat("b", MaxCustomIDLength-2)}, expectedCustomID: "a:" + strings.Repeat("b", MaxCustomIDLength-2), expectedArgs: []string{strings.Repeat("b", MaxCustomIDLength-2)}},
+		{name: "when custom id is over the limit, return error", customName: "a", args: []string{strings.Repeat("b", MaxCustomIDLength-1)}, expectedError: true},
+		{name: "when escaping takes it over the limit, return error", customName: "a", args: []string{strings.Repeat(":", MaxCustomIDLength/3)}, expectedError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customID, err := CustomID(tt.customName, tt.args...)
+			assert.Equal(t, tt.expectedError, err != nil)
+			if tt.expectedError {
+				return
+			}
+			assert.Equal(t, tt.expectedCustomID, customID)
+
+			name, args := ParseCustomID(customID)
+			assert.Equal(t, tt.customName, name)
+			assert.Equal(t, tt.expectedArgs, args)
+		})
+	}
+}
+
+func TestParseCustomID(t *testing.T) {
+	tests := []struct {
+		name         string
+		customID     string
+		expectedName string
+		expectedArgs []string
+	}{
+		{name: "when custom id has no separator, it is the name", customID: "play", expectedName: "play", expectedArgs: []string{}},
+		{name: "when custom id is empty, the name is empty", customID: "", expectedName: "", expectedArgs: []string{}},
+		{name: "when parts are escaped, unescape them", customID: "sound:a%3Ab", expectedName: "sound", expectedArgs: []string{"a:b"}},
+		{name: "when parts were not escaped, keep them as they are", customID: "sound:100%", expectedName: "sound", expectedArgs: []string{"100%"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, args := ParseCustomID(tt.customID)
+			assert.Equal(t, tt.expectedName, name)
+			assert.Equal(t, tt.expectedArgs, args)
+		})
+	}
+}
@@ -36,6 +36,20 @@ func (_m *Client) CreateChannel(guildID string, name string, channelType discord
 	return r0, r1
 }
 
+// DisconnectVoice provides a mock function with given fields: voice
+func (_m *Client) DisconnectVoice(voice *discord.VoiceConnection) error {
+	ret := _m.Called(voice)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*discord.VoiceConnection) error); ok {
+		r0 = rf(voice)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // EditInteraction provides a mock function with given fields: token, message
 func (_m *Client) EditInteraction(token string, message string) error {
 	ret := _m.Called(token, message)
@@ -64,6 +78,29 @@ func (_m *Client) EditInteractionComplex(token string, edit discord.ComplexInter
 	return r0
 }
 
+// EstablishPlaybackConnection provides a mock function with given fields: guildID, channelID
+func (_m *Client) EstablishPlaybackConnection(guildID string, channelID string) (*discord.VoiceConnection, error) {
+	ret := _m.Called(guildID, channelID)
+
+	var r0 *discord.VoiceConnection
+	if rf, ok := ret.Get(0).(func(string, string) *discord.VoiceConnection); ok {
+		r0 = rf(guildID, channelID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*discord.VoiceConnection)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(guildID, channelID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // EstablishVoiceConnection provides a mock function with given fields: guildID, channelID, mute, deaf, done
 func (_m *Client) EstablishVoiceConnection(guildID string, channelID string, mute bool, deaf bool, done chan bool) (*discord.VoiceConnection, error) {
 	ret := _m.Called(guildID, channelID, mute, deaf, done)
@@ -145,6 +182,27 @@ func (_m *Client) GetGuildChannels(guildID string) ([]discord.Channel, error) {
 	return r0, r1
 }
 
+// GetGuild provides a mock function with given fields: guildID
+func (_m *Client) GetGuild(guildID string) (discord.Guild, error) {
+	ret := _m.Called(guildID)
+
+	var r0 discord.Guild
+	if rf, ok := ret.Get(0).(func(string) discord.Guild); ok {
+		r0 = rf(guildID)
+	} else {
+		r0 = ret.Get(0).(discord.Guild)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(guildID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetGuildUsers provides a mock function with given fields: guildID
 func (_m *Client) GetGuildUsers(guildID string) ([]discord.User, error) {
 	ret := _m.Called(guildID)
@@ -191,6 +249,27 @@ func (_m *Client) GetGuilds() ([]discord.Guild, error) {
 	return r0, r1
 }
 
+// GetMessage provides a mock function with given fields: channelID, messageID
+func (_m *Client) GetMessage(channelID string, messageID string) (discord.Message, error) {
+	ret := _m.Called(channelID, messageID)
+
+	var r0 discord.Message
+	if rf, ok := ret.Get(0).(func(string, string) discord.Message); ok {
+		r0 = rf(channelID, messageID)
+	} else {
+		r0 = ret.Get(0).(discord.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(channelID, messageID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUser provides a mock function with given fields: userID
 func (_m *Client) GetUser(userID string) (discord.User, error) {
 	ret := _m.Called(userID)
@@ -212,6 +291,76 @@ func (_m *Client) GetUser(userID string) (discord.User, error) {
 	return r0, r1
 }
 
+// GetUserVoiceChannel provides a mock function with given fields: guildID, userID
+func (_m *Client) GetUserVoiceChannel(guildID string, userID string) (string, error) {
+	ret := _m.Called(guildID, userID)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, string) string); ok {
+		r0 = rf(guildID, userID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(guildID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PlayOpus provides a mock function with given fields: voice, frames
+func (_m *Client) PlayOpus(voice *discord.VoiceConnection, frames [][]byte) error {
+	ret := _m.Called(voice, frames)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*discord.VoiceConnection, [][]byte) error); ok {
+		r0 = rf(voice, frames)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReplaceFileMessage provides a mock function with given fields: channelID, messageID, name, contentType, readable
+func (_m *Client) ReplaceFileMessage(channelID string, messageID string, name string, contentType string, readable io.Reader) (discord.Message, error) {
+	ret := _m.Called(channelID, messageID, name, contentType, readable)
+
+	var r0 discord.Message
+	if rf, ok := ret.Get(0).(func(string, string, string, string, io.Reader) discord.Message); ok {
+		r0 = rf(channelID, messageID, name, contentType, readable)
+	} else {
+		r0 = ret.Get(0).(discord.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string, string, io.Reader) error); ok {
+		r1 = rf(channelID, messageID, name, contentType, readable)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RespondAutocomplete provides a mock function with given fields: interactionID, token, choices
+func (_m *Client) RespondAutocomplete(interactionID string, token string, choices []string) error {
+	ret := _m.Called(interactionID, token, choices)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, []string) error); ok {
+		r0 = rf(interactionID, token, choices)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SendFileMessage provides a mock function with given fields: channelID, name, contentType, readable
 func (_m *Client) SendFileMessage(channelID string, name string, contentType string, readable io.Reader) (discord.Message, error) {
 	ret := _m.Called(channelID, name, contentType, readable)
@@ -247,13 +396,76 @@ func (_m *Client) SendTextMessage(channelID string, message string) error {
 	return r0
 }
 
-// SetEmbed provides a mock function with given fields: channelID, messageID, embed
-func (_m *Client) SetEmbed(channelID string, messageID string, embed discord.MessageEmbed) error {
-	ret := _m.Called(channelID, messageID, embed)
+// SendFileReply provides a mock function with given fields: channelID, replyToID, content, name, contentType, readable
+func (_m *Client) SendFileReply(channelID string, replyToID string, content string, name string, contentType string, readable io.Reader) (discord.Message, error) {
+	ret := _m.Called(channelID, replyToID, content, name, contentType, readable)
+
+	var r0 discord.Message
+	if rf, ok := ret.Get(0).(func(string, string, string, string, string, io.Reader) discord.Message); ok {
+		r0 = rf(channelID, replyToID, content, name, contentType, readable)
+	} else {
+		r0 = ret.Get(0).(discord.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string, string, string, io.Reader) error); ok {
+		r1 = rf(channelID, replyToID, content, name, contentType, readable)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SendFollowup provides a mock function with given fields: token, content, file
+func (_m *Client) SendFollowup(token string, content string, file discord.File) (discord.Message, error) {
+	ret := _m.Called(token, content, file)
+
+	var r0 discord.Message
+	if rf, ok := ret.Get(0).(func(string, string, discord.File) discord.Message); ok {
+		r0 = rf(token, content, file)
+	} else {
+		r0 = ret.Get(0).(discord.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, discord.File) error); ok {
+		r1 = rf(token, content, file)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SendVoiceMessage provides a mock function with given fields: channelID, name, readable, voice
+func (_m *Client) SendVoiceMessage(channelID string, name string, readable io.Reader, voice discord.VoiceMessage) (discord.Message, error) {
+	ret := _m.Called(channelID, name, readable, voice)
+
+	var r0 discord.Message
+	if rf, ok := ret.Get(0).(func(string, string, io.Reader, discord.VoiceMessage) discord.Message); ok {
+		r0 = rf(channelID, name, readable, voice)
+	} else {
+		r0 = ret.Get(0).(discord.Message)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, io.Reader, discord.VoiceMessage) error); ok {
+		r1 = rf(channelID, name, readable, voice)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetEmbeds provides a mock function with given fields: channelID, messageID, embeds, buttons
+func (_m *Client) SetEmbeds(channelID string, messageID string, embeds []discord.MessageEmbed, buttons []discord.Button) error {
+	ret := _m.Called(channelID, messageID, embeds, buttons)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string, discord.MessageEmbed) error); ok {
-		r0 = rf(channelID, messageID, embed)
+	if rf, ok := ret.Get(0).(func(string, string, []discord.MessageEmbed, []discord.Button) error); ok {
+		r0 = rf(channelID, messageID, embeds, buttons)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -0,0 +1,44 @@
+package discord
+
+// VoiceState is how a member is connected to voice, ChannelID is empty when they are not in a voice channel
+type VoiceState struct {
+	ChannelID string
+	SelfMute  bool
+	SelfDeaf  bool
+	// Mute and Deaf are set by the server moderators
+	Mute       bool
+	Deaf       bool
+	SelfStream bool
+	SelfVideo  bool
+	// Suppress is set for members that are not allowed to speak in a stage channel
+	Suppress bool
+}
+
+// VoiceIntent is what a member did with a voice state update
+type VoiceIntent string
+
+const (
+	VoiceIntentJoined VoiceIntent = "joined"
+	VoiceIntentLeft   VoiceIntent = "left"
+	// VoiceIntentMoved is switching from one voice channel to another, without leaving voice
+	VoiceIntentMoved            VoiceIntent = "moved"
+	VoiceIntentMuted            VoiceIntent = "muted"
+	VoiceIntentUnmuted          VoiceIntent = "unmuted"
+	VoiceIntentDeafened         VoiceIntent = "deafened"
+	VoiceIntentUndeafened       VoiceIntent = "undeafened"
+	VoiceIntentServerMuted      VoiceIntent = "server_muted"
+	VoiceIntentServerUnmuted    VoiceIntent = "server_unmuted"
+	VoiceIntentServerDeafened   VoiceIntent = "server_deafened"
+	VoiceIntentServerUndeafened VoiceIntent = "server_undeafened"
+	VoiceIntentStreamStarted    VoiceIntent = "stream_started"
+	VoiceIntentStreamStopped    VoiceIntent = "stream_stopped"
+	VoiceIntentVideoStarted     VoiceIntent = "video_started"
+	VoiceIntentVideoStopped     VoiceIntent = "video_stopped"
+	VoiceIntentSuppressed       VoiceIntent = "suppressed"
+	VoiceIntentUnsuppressed     VoiceIntent = "unsuppressed"
+)
+
+// ChangesChannel tells if the intent is about the channel the member is in, the rest only change how they are in it
+func (i VoiceIntent) ChangesChannel() bool {
+	return i == VoiceIntentJoined || i == VoiceIntentLeft || i == VoiceIntentMoved
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "github.com/hectorgabucio/taterubot-dc/kit/event"
+import (
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/hectorgabucio/taterubot-dc/kit/event"
+)
 
 const AudioSentEventType event.Type = "events.audio.sent"
 
@@ -11,25 +14,54 @@ type AudioSentEvent struct {
 	ChannelID     string
 	Username      string
 	UserAvatarURL string
-	Mp3Fullname   string
+	AudioFullname string
+	AudioFormat   AudioFormat
 	FileName      string
 	AttachmentID  string
+	AttachmentURL string
+	// ContinuedVoiceDataID is the voice data this audio was appended to, empty when it is a new voice message
+	ContinuedVoiceDataID string
+	// VoiceMessage is set when the audio was sent as a native discord voice message, which cannot hold an embed
+	VoiceMessage bool
+	// DurationSecs is known from the recording unless an effect or a continuation changed it, otherwise the audio file is probed
+	DurationSecs float64
+	// Processing is what the post-processing did to the audio
+	Processing ProcessReport
+	// Effect is the voice effect rendered in the audio
+	Effect VoiceEffect
+	// Anonymous audio has the AuthorHasher hash as UserID and neither username nor avatar
+	Anonymous bool
+	// Caption is the title the author gave to the audio with /caption, if any
+	Caption string
+	// ReceiveStats count the packets of the recording session, the dropped and late ones are missing from the audio
+	ReceiveStats discord.ReceiveStats
 }
 
-func NewAudioSentEvent(id string, userID string, guildID string, channelID string, username string, userAvatarURL string, mp3Fullname string, fileName string, attachmentID string) AudioSentEvent {
+func NewAudioSentEvent(id string, userID string, guildID string, channelID string, username string, userAvatarURL string, audioFullname string, audioFormat AudioFormat, fileName string, attachmentID string, attachmentURL string, continuedVoiceDataID string, processing ProcessReport) AudioSentEvent {
 	return AudioSentEvent{
-		BaseEvent:     event.NewBaseEvent(id),
-		UserID:        userID,
-		GuildID:       guildID,
-		ChannelID:     channelID,
-		Username:      username,
-		UserAvatarURL: userAvatarURL,
-		Mp3Fullname:   mp3Fullname,
-		FileName:      fileName,
-		AttachmentID:  attachmentID,
+		BaseEvent:            event.NewBaseEvent(id),
+		UserID:               userID,
+		GuildID:              guildID,
+		ChannelID:            channelID,
+		Username:             username,
+		UserAvatarURL:        userAvatarURL,
+		AudioFullname:        audioFullname,
+		AudioFormat:          audioFormat,
+		FileName:             fileName,
+		AttachmentID:         attachmentID,
+		AttachmentURL:        attachmentURL,
+		ContinuedVoiceDataID: continuedVoiceDataID,
+		Processing:           processing,
 	}
 }
 
+func NewVoiceMessageSentEvent(id string, userID string, guildID string, channelID string, username string, userAvatarURL string, oggFullname string, fileName string, attachmentID string, attachmentURL string, durationSecs float64) AudioSentEvent {
+	evt := NewAudioSentEvent(id, userID, guildID, channelID, username, userAvatarURL, oggFullname, AudioFormatOpus, fileName, attachmentID, attachmentURL, "", ProcessReport{})
+	evt.VoiceMessage = true
+	evt.DurationSecs = durationSecs
+	return evt
+}
+
 func (e AudioSentEvent) Type() event.Type {
 	return AudioSentEventType
 }
@@ -38,14 +70,39 @@ const DoneProcessingFilesEventType event.Type = "events.files.processed"
 
 type DoneProcessingFilesEvent struct {
 	event.BaseEvent
+	AudioFormat AudioFormat
 }
 
-func NewDoneProcessingFilesEvent(id string) DoneProcessingFilesEvent {
+func NewDoneProcessingFilesEvent(id string, audioFormat AudioFormat) DoneProcessingFilesEvent {
 	return DoneProcessingFilesEvent{
-		BaseEvent: event.NewBaseEvent(id),
+		BaseEvent:   event.NewBaseEvent(id),
+		AudioFormat: audioFormat,
 	}
 }
 
 func (e DoneProcessingFilesEvent) Type() event.Type {
 	return DoneProcessingFilesEventType
 }
+
+const IntroRecordedEventType event.Type = "events.intro.recorded"
+
+// IntroRecordedEvent is published instead of sending the audio when the recording was requested as the user intro
+type IntroRecordedEvent struct {
+	event.BaseEvent
+	GuildID          string
+	UserID           string
+	InteractionToken string
+}
+
+func NewIntroRecordedEvent(fileName string, guildID string, userID string, interactionToken string) IntroRecordedEvent {
+	return IntroRecordedEvent{
+		BaseEvent:        event.NewBaseEvent(fileName),
+		GuildID:          guildID,
+		UserID:           userID,
+		InteractionToken: interactionToken,
+	}
+}
+
+func (e IntroRecordedEvent) Type() event.Type {
+	return IntroRecordedEventType
+}
@@ -0,0 +1,29 @@
+package domain
+
+// MediaProperties describe an audio file as it is uploaded
+type MediaProperties struct {
+	DurationSecs float64
+	// SampleRate in Hz, opus is always decoded at 48000
+	SampleRate int
+	Channels   int
+	// Bitrate in kbps, averaged over the whole file
+	Bitrate int
+	// Size in bytes
+	Size int64
+}
+
+// NewMediaProperties averages the bitrate from the size and duration
+func NewMediaProperties(durationSecs float64, sampleRate int, channels int, size int64) MediaProperties {
+	properties := MediaProperties{DurationSecs: durationSecs, SampleRate: sampleRate, Channels: channels, Size: size}
+	if durationSecs > 0 {
+		properties.Bitrate = int(float64(size) * 8 / 1000 / durationSecs)
+	}
+	return properties
+}
+
+//go:generate mockery --name=MediaProber --case=snake --outpkg=domainmocks
+type MediaProber interface {
+	// Probe reads the properties of the file, failing when it is not a valid file of the format
+	Probe(path string, format AudioFormat) (MediaProperties, error)
+	Supports(format AudioFormat) bool
+}
@@ -4,6 +4,7 @@ package domainmocks
 
 import (
 	domain "github.com/hectorgabucio/taterubot-dc/domain"
+
 	mock "github.com/stretchr/testify/mock"
 
 	time "time"
@@ -14,6 +15,90 @@ type VoiceDataRepository struct {
 	mock.Mock
 }
 
+// GetByMessage provides a mock function with given fields: guildID, messageID
+func (_m *VoiceDataRepository) GetByMessage(guildID string, messageID string) (domain.VoiceData, bool, error) {
+	ret := _m.Called(guildID, messageID)
+
+	var r0 domain.VoiceData
+	if rf, ok := ret.Get(0).(func(string, string) domain.VoiceData); ok {
+		r0 = rf(guildID, messageID)
+	} else {
+		r0 = ret.Get(0).(domain.VoiceData)
+	}
+
+	var r1 bool
+	if rf, ok := ret.Get(1).(func(string, string) bool); ok {
+		r1 = rf(guildID, messageID)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string) error); ok {
+		r2 = rf(guildID, messageID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetLastByGuild provides a mock function with given fields: guildID
+func (_m *VoiceDataRepository) GetLastByGuild(guildID string) (domain.VoiceData, bool, error) {
+	ret := _m.Called(guildID)
+
+	var r0 domain.VoiceData
+	if rf, ok := ret.Get(0).(func(string) domain.VoiceData); ok {
+		r0 = rf(guildID)
+	} else {
+		r0 = ret.Get(0).(domain.VoiceData)
+	}
+
+	var r1 bool
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(guildID)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(guildID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetLastByUser provides a mock function with given fields: guildID, userID
+func (_m *VoiceDataRepository) GetLastByUser(guildID string, userID string) (domain.VoiceData, bool, error) {
+	ret := _m.Called(guildID, userID)
+
+	var r0 domain.VoiceData
+	if rf, ok := ret.Get(0).(func(string, string) domain.VoiceData); ok {
+		r0 = rf(guildID, userID)
+	} else {
+		r0 = ret.Get(0).(domain.VoiceData)
+	}
+
+	var r1 bool
+	if rf, ok := ret.Get(1).(func(string, string) bool); ok {
+		r1 = rf(guildID, userID)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string) error); ok {
+		r2 = rf(guildID, userID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // GetOnRange provides a mock function with given fields: guildID, from, to
 func (_m *VoiceDataRepository) GetOnRange(guildID string, from time.Time, to time.Time) ([]domain.VoiceData, error) {
 	ret := _m.Called(guildID, from, to)
@@ -50,3 +135,17 @@ func (_m *VoiceDataRepository) Save(data domain.VoiceData) error {
 
 	return r0
 }
+
+// Update provides a mock function with given fields: data
+func (_m *VoiceDataRepository) Update(data domain.VoiceData) error {
+	ret := _m.Called(data)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(domain.VoiceData) error); ok {
+		r0 = rf(data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -8,4 +8,18 @@ import (
 type Writer interface {
 	NewWriter(path string) (io.Closer, error)
 	WriteVoice(writer io.Closer, packet *discord.Packet) error
+	// Duration returns the seconds of audio written so far, as the granule position of the file tells
+	Duration(writer io.Closer) float64
+}
+
+// Analyzer describes recorded ogg/opus files without decoding their audio
+type Analyzer interface {
+	// Analyze returns the duration in seconds of the file and a waveform of at most waveformSize amplitudes from 0 to 255
+	Analyze(path string, waveformSize int) (float64, []byte, error)
+}
+
+// Splitter cuts recorded ogg/opus files without re-encoding them
+type Splitter interface {
+	// Split writes the file as len(outputs) consecutive parts of about the same duration
+	Split(path string, outputs []string) error
 }
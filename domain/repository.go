@@ -1,10 +1,12 @@
 package domain
 
 import (
+	"io"
 	"os"
 	"time"
 )
 
+//go:generate mockery --name=LockedUserRepository --case=snake --outpkg=domainmocks
 type LockedUserRepository interface {
 	GetCurrentLock(guildID string) (string, chan bool)
 	ReleaseUserLock(guildID string)
@@ -26,10 +28,141 @@ type VoiceData struct {
 	Name      string
 	UserID    string
 	Duration  int
+	// MessageID, ChannelID and AttachmentURL point to the discord message holding the audio file
+	MessageID     string
+	ChannelID     string
+	AttachmentURL string
+	// Anonymous voice data holds the AuthorHasher hash of the author instead of its UserID
+	Anonymous bool
+	// SampleRate, Channels, Bitrate and Size are the MediaProperties of the sent file, 0 when unknown
+	SampleRate int
+	Channels   int
+	Bitrate    int
+	Size       int64
+	// DroppedPackets and LatePackets are the voice packets missing from the audio, as the recording fell behind or they came out of order
+	DroppedPackets int64
+	LatePackets    int64
 }
 
 //go:generate mockery --name=VoiceDataRepository --case=snake --outpkg=domainmocks
 type VoiceDataRepository interface {
 	Save(data VoiceData) error
+	Update(data VoiceData) error
 	GetOnRange(guildID string, from time.Time, to time.Time) ([]VoiceData, error)
+	// GetLastByUser returns the most recent voice data sent by the user in the guild, false if there is none
+	GetLastByUser(guildID string, userID string) (VoiceData, bool, error)
+	// GetLastByGuild returns the most recent voice data sent in the guild, false if there is none
+	GetLastByGuild(guildID string) (VoiceData, bool, error)
+	// GetByMessage returns the voice data of the discord message, false if there is none
+	GetByMessage(guildID string, messageID string) (VoiceData, bool, error)
+}
+
+type GuildSettings struct {
+	GuildID string
+	// StartCueEnabled makes the bot join unmuted and play a beep before it starts recording
+	StartCueEnabled bool
+	// VoiceMessagesEnabled sends recordings as native discord voice messages, otherwise as audio files with an embed
+	VoiceMessagesEnabled bool
+	AudioFormat          AudioFormat
+	// AudioBitrate in kbps, ignored by lossless formats
+	AudioBitrate int
+	// NormalizeEnabled brings every recording to the same loudness
+	NormalizeEnabled bool
+	// TrimSilenceEnabled removes the silence at the start and end of the recordings
+	TrimSilenceEnabled bool
+	// SilenceThreshold in dBFS under which audio is considered silence
+	SilenceThreshold int
+	// AnonymousChannelID is the voice channel whose recordings are sent anonymously, empty if there is none
+	AnonymousChannelID string
+}
+
+// ProcessOptions returns the post-processing steps enabled in the guild
+func (s GuildSettings) ProcessOptions() ProcessOptions {
+	return ProcessOptions{Normalize: s.NormalizeEnabled, TrimSilence: s.TrimSilenceEnabled, SilenceThreshold: s.SilenceThreshold}
+}
+
+func DefaultGuildSettings(guildID string) GuildSettings {
+	return GuildSettings{
+		GuildID:              guildID,
+		StartCueEnabled:      true,
+		VoiceMessagesEnabled: true,
+		AudioFormat:          DefaultAudioFormat,
+		AudioBitrate:         DefaultAudioBitrate,
+		SilenceThreshold:     DefaultSilenceThreshold,
+	}
+}
+
+//go:generate mockery --name=GuildSettingsRepository --case=snake --outpkg=domainmocks
+type GuildSettingsRepository interface {
+	// Get returns the settings of the guild, or the default ones if it never changed them
+	Get(guildID string) (GuildSettings, error)
+	Save(settings GuildSettings) error
+}
+
+type Intro struct {
+	GuildID string
+	UserID  string
+	// Frames are the 20ms opus frames of the clip played when the user joins a voice channel
+	Frames [][]byte
+}
+
+//go:generate mockery --name=IntroRepository --case=snake --outpkg=domainmocks
+type IntroRepository interface {
+	// Get returns the intro of the user in the guild, false if there is none
+	Get(guildID string, userID string) (Intro, bool, error)
+	Save(intro Intro) error
+	Delete(guildID string, userID string) error
+}
+
+//go:generate mockery --name=PendingIntroRepository --case=snake --outpkg=domainmocks
+type PendingIntroRepository interface {
+	// SetPending marks the next recording of the user as its intro, answering to the given interaction
+	SetPending(guildID string, userID string, interactionToken string)
+	// PopPending returns and forgets the interaction waiting for the next recording of the user, false if there is none
+	PopPending(guildID string, userID string) (string, bool)
+}
+
+//go:generate mockery --name=PendingCaptionRepository --case=snake --outpkg=domainmocks
+type PendingCaptionRepository interface {
+	// SetPending keeps the caption for the next recording of the user, replacing the one it had
+	SetPending(guildID string, userID string, caption string)
+	// PopPending returns and forgets the caption of the next recording of the user, false if there is none
+	PopPending(guildID string, userID string) (string, bool)
+}
+
+//go:generate mockery --name=VoiceEffectRepository --case=snake --outpkg=domainmocks
+type VoiceEffectRepository interface {
+	// Get returns the default effect of the user in the guild, VoiceEffectNone if it never chose one
+	Get(guildID string, userID string) (VoiceEffect, error)
+	// Save stores the default effect of the user in the guild, VoiceEffectNone removes it
+	Save(guildID string, userID string, effect VoiceEffect) error
+}
+
+type Sound struct {
+	GuildID string
+	Name    string
+	// FileKey locates the audio of the sound in the SoundStore
+	FileKey   string
+	SavedBy   string
+	Plays     int
+	CreatedAt time.Time
+}
+
+//go:generate mockery --name=SoundRepository --case=snake --outpkg=domainmocks
+type SoundRepository interface {
+	Save(sound Sound) error
+	// Get returns the sound of the guild with that name, false if there is none
+	Get(guildID string, name string) (Sound, bool, error)
+	// SearchNames returns up to limit sound names of the guild starting with prefix, most played first
+	SearchNames(guildID string, prefix string, limit int) ([]string, error)
+	IncrementPlays(guildID string, name string) error
+}
+
+// SoundStore keeps the audio files of the soundboard, which unlike FileRepository files are never cleaned up
+//
+//go:generate mockery --name=SoundStore --case=snake --outpkg=domainmocks
+type SoundStore interface {
+	Put(key string, r io.Reader) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
 }
@@ -0,0 +1,22 @@
+package domain
+
+// Audiogram is a video of a voice message, showing the avatar and name of its author over an animated waveform
+type Audiogram struct {
+	AudioPath string
+	// AvatarPath is an image shown above the name, the video has no avatar when empty
+	AvatarPath string
+	Title      string
+	// Color of the waveform as 0xRRGGBB
+	Color int
+	// VideoBitrate and AudioBitrate in kbps, they bound the size of the video
+	VideoBitrate int
+	AudioBitrate int
+}
+
+//go:generate mockery --name=VideoRenderer --case=snake --outpkg=domainmocks
+type VideoRenderer interface {
+	// Available tells if videos can be rendered in this host
+	Available() bool
+	// RenderAudiogram writes the audiogram as an mp4 video
+	RenderAudiogram(audiogram Audiogram, output string) error
+}
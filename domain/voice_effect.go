@@ -0,0 +1,72 @@
+package domain
+
+import "strings"
+
+type VoiceEffect string
+
+const (
+	// VoiceEffectNone keeps the voice as it was recorded
+	VoiceEffectNone     VoiceEffect = ""
+	VoiceEffectChipmunk VoiceEffect = "chipmunk"
+	VoiceEffectDeep     VoiceEffect = "deep"
+	VoiceEffectRobot    VoiceEffect = "robot"
+	VoiceEffectEcho     VoiceEffect = "echo"
+	VoiceEffectFast     VoiceEffect = "fast"
+	// VoiceEffectDisguise hides who is speaking in anonymous voice messages, it is not offered to users
+	VoiceEffectDisguise VoiceEffect = "disguise"
+)
+
+// voiceEffectNoneName is how users ask for no effect
+const voiceEffectNoneName = "none"
+
+var voiceEffectLabels = map[VoiceEffect]string{
+	VoiceEffectChipmunk: "Chipmunk",
+	VoiceEffectDeep:     "Deep voice",
+	VoiceEffectRobot:    "Robot",
+	VoiceEffectEcho:     "Echo",
+	VoiceEffectFast:     "1.5x speed",
+	VoiceEffectDisguise: "Disguised",
+}
+
+// VoiceEffects returns every effect, in the order they are offered to users
+func VoiceEffects() []VoiceEffect {
+	return []VoiceEffect{VoiceEffectChipmunk, VoiceEffectDeep, VoiceEffectRobot, VoiceEffectEcho, VoiceEffectFast}
+}
+
+// ParseVoiceEffect returns the effect with that name, "none" being VoiceEffectNone, false if there is no such effect
+func ParseVoiceEffect(name string) (VoiceEffect, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == voiceEffectNoneName {
+		return VoiceEffectNone, true
+	}
+	effect := VoiceEffect(name)
+	_, ok := voiceEffectLabels[effect]
+	return effect, ok
+}
+
+// Name is how the effect is stored and chosen, the opposite of ParseVoiceEffect
+func (e VoiceEffect) Name() string {
+	if e == VoiceEffectNone {
+		return voiceEffectNoneName
+	}
+	return string(e)
+}
+
+// Label is the name of the effect shown to users
+func (e VoiceEffect) Label() string {
+	if e == VoiceEffectNone {
+		return "None"
+	}
+	return voiceEffectLabels[e]
+}
+
+// SupportedVoiceEffects returns the effects the encoder can render in this host, in the order they are offered to users
+func SupportedVoiceEffects(encoder AudioEncoder) []VoiceEffect {
+	var effects []VoiceEffect
+	for _, effect := range VoiceEffects() {
+		if encoder.SupportsEffect(effect) {
+			effects = append(effects, effect)
+		}
+	}
+	return effects
+}
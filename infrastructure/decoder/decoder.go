@@ -1,32 +1,111 @@
 package mp3decoder
 
 import (
-	"github.com/faiface/beep"
-	"github.com/faiface/beep/mp3"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
-	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/mp3"
+	"github.com/hectorgabucio/taterubot-dc/domain"
+)
+
+const (
+	id3HeaderSize = 10
+	// monoChannelMode is the channel mode of single channel mp3 frames
+	monoChannelMode = 3
 )
 
-type MP3Decoder struct {
+// Prober reads mp3 files with a pure go decoder, which always outputs stereo,
+// so the channels are read from the header of the first frame
+type Prober struct {
+}
+
+func NewProber() *Prober {
+	return &Prober{}
+}
+
+func (p *Prober) Supports(format domain.AudioFormat) bool {
+	return format == domain.AudioFormatMP3
 }
 
-func (m MP3Decoder) GetDuration(file *os.File) float64 {
-	streamer, format, err := mp3.Decode(file)
+func (p *Prober) Probe(path string, format domain.AudioFormat) (domain.MediaProperties, error) {
+	if !p.Supports(format) {
+		return domain.MediaProperties{}, fmt.Errorf("unsupported audio format %s", format)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return domain.MediaProperties{}, fmt.Errorf("err reading mp3 file, %w", err)
+	}
+	channels, err := channelCount(path)
+	if err != nil {
+		return domain.MediaProperties{}, err
+	}
+	file, err := os.Open(path)
 	if err != nil {
-		log.Fatal(err)
+		return domain.MediaProperties{}, fmt.Errorf("err opening mp3 file, %w", err)
+	}
+	// the streamer closes the file
+	streamer, mp3Format, err := mp3.Decode(file)
+	if err != nil {
+		_ = file.Close()
+		return domain.MediaProperties{}, fmt.Errorf("err decoding mp3 file, %w", err)
 	}
 	defer func(streamer beep.StreamSeekCloser) {
-		err := streamer.Close()
-		if err != nil {
+		if err := streamer.Close(); err != nil {
 			log.Println("err closing audio file", err)
 		}
 	}(streamer)
-	length := format.SampleRate.D(streamer.Len())
-	nTime := length.Round(time.Millisecond).Seconds()
-	return nTime
+	duration := mp3Format.SampleRate.D(streamer.Len()).Seconds()
+	return domain.NewMediaProperties(duration, int(mp3Format.SampleRate), channels, info.Size()), nil
 }
 
-func NewMP3Decoder() *MP3Decoder {
-	return &MP3Decoder{}
+// channelCount skips the id3v2 tag, if any, and reads the channel mode of the first frame
+func channelCount(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("err opening mp3 file, %w", err)
+	}
+	defer func(file *os.File) {
+		if err := file.Close(); err != nil {
+			log.Println("err closing audio file", err)
+		}
+	}(file)
+	reader := bufio.NewReader(file)
+	header, err := reader.Peek(id3HeaderSize)
+	if err != nil {
+		return 0, fmt.Errorf("err reading mp3 header, %w", err)
+	}
+	if string(header[:3]) == "ID3" {
+		// the size of the tag is a syncsafe integer, 7 bits per byte
+		size := int(header[6])<<21 | int(header[7])<<14 | int(header[8])<<7 | int(header[9])
+		if _, err := reader.Discard(id3HeaderSize + size); err != nil {
+			return 0, fmt.Errorf("err skipping id3 tag, %w", err)
+		}
+	}
+	var previous byte
+	for {
+		b, err := reader.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return 0, errors.New("no mp3 frame found")
+		}
+		if err != nil {
+			return 0, fmt.Errorf("err reading mp3 frame, %w", err)
+		}
+		// frames start with 11 set bits
+		if previous == 0xFF && b&0xE0 == 0xE0 {
+			rest, err := reader.Peek(2)
+			if err != nil {
+				return 0, fmt.Errorf("err reading mp3 frame header, %w", err)
+			}
+			if rest[1]>>6 == monoChannelMode {
+				return 1, nil
+			}
+			return 2, nil
+		}
+		previous = b
+	}
 }
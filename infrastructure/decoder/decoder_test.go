@@ -0,0 +1,116 @@
+package mp3decoder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	// frameSize is the size of a 128kbps 44.1khz mpeg 1 layer III frame without padding, 144 * 128000 / 44100
+	frameSize = 417
+	// frameSamples are the samples of every mpeg 1 layer III frame
+	frameSamples = 1152
+)
+
+// writeTestMP3 writes frames of silence, their side information and audio are all zeros. id3Size prepends an id3v2 tag
+// of that many bytes after its header
+func writeTestMP3(t *testing.T, frames int, mono bool, id3Size int) string {
+	t.Helper()
+	var data []byte
+	if id3Size > 0 {
+		data = append(data, 'I', 'D', '3', 4, 0, 0, byte(id3Size>>21&0x7F), byte(id3Size>>14&0x7F), byte(id3Size>>7&0x7F), byte(id3Size&0x7F))
+		data = append(data, make([]byte, id3Size)...)
+	}
+	// sync, mpeg 1, layer III, no crc, 128kbps, 44.1khz, joint stereo or mono
+	header := []byte{0xFF, 0xFB, 0x90, 0x44}
+	if mono {
+		header[3] = 0xC4
+	}
+	for i := 0; i < frames; i++ {
+		data = append(data, header...)
+		data = append(data, make([]byte, frameSize-len(header))...)
+	}
+	path := filepath.Join(t.TempDir(), "sound.mp3")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProber_Probe(t *testing.T) {
+	tests := []struct {
+		name               string
+		file               func(t *testing.T) string
+		format             domain.AudioFormat
+		expectedDuration   float64
+		expectedChannels   int
+		expectedSampleRate int
+		expectedError      bool
+	}{
+		{
+			name:               "when file is stereo mp3, read its properties",
+			file:               func(t *testing.T) string { return writeTestMP3(t, 40, false, 0) },
+			format:             domain.AudioFormatMP3,
+			expectedDuration:   40 * frameSamples / 44100.0,
+			expectedChannels:   2,
+			expectedSampleRate: 44100,
+		},
+		{
+			name:               "when file is mono mp3, read the channels from its first frame",
+			file:               func(t *testing.T) string { return writeTestMP3(t, 40, true, 0) },
+			format:             domain.AudioFormatMP3,
+			expectedDuration:   40 * frameSamples / 44100.0,
+			expectedChannels:   1,
+			expectedSampleRate: 44100,
+		},
+		{
+			name:               "when file starts with an id3 tag, skip it",
+			file:               func(t *testing.T) string { return writeTestMP3(t, 40, true, 300) },
+			format:             domain.AudioFormatMP3,
+			expectedDuration:   40 * frameSamples / 44100.0,
+			expectedChannels:   1,
+			expectedSampleRate: 44100,
+		},
+		{
+			name: "when file has no mp3 frame, return error",
+			file: func(t *testing.T) string {
+				path := filepath.Join(t.TempDir(), "sound.mp3")
+				if err := os.WriteFile(path, make([]byte, 1000), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				return path
+			},
+			format:        domain.AudioFormatMP3,
+			expectedError: true,
+		},
+		{
+			name:          "when file does not exist, return error",
+			file:          func(t *testing.T) string { return filepath.Join(t.TempDir(), "missing.mp3") },
+			format:        domain.AudioFormatMP3,
+			expectedError: true,
+		},
+		{name: "when format is not mp3, return error", file: func(t *testing.T) string { return writeTestMP3(t, 40, false, 0) }, format: domain.AudioFormatOpus, expectedError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.file(t)
+
+			properties, err := NewProber().Probe(path, tt.format)
+
+			assert.Equal(t, tt.expectedError, err != nil, "err %v", err)
+			if tt.expectedError {
+				return
+			}
+			// the decoder counts time in nanoseconds
+			assert.InDelta(t, tt.expectedDuration, properties.DurationSecs, 1e-6)
+			assert.Equal(t, tt.expectedSampleRate, properties.SampleRate)
+			assert.Equal(t, tt.expectedChannels, properties.Channels)
+			info, _ := os.Stat(path)
+			assert.Equal(t, info.Size(), properties.Size)
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package discordgo
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+)
+
+// cacheStatsInterval is how often the expired entries are swept and the hit rates are logged, as long as the caches
+// were used meanwhile
+const cacheStatsInterval = time.Minute
+
+type cacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// cache holds values until they expire or are invalidated, counting the lookups that found them. Expired entries
+// are dropped when they are looked up or swept
+type cache[V any] struct {
+	name    string
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]cacheEntry[V]
+	hits    int64
+	misses  int64
+}
+
+func newCache[V any](name string, ttl time.Duration) *cache[V] {
+	return &cache[V]{name: name, ttl: ttl, now: time.Now, entries: make(map[string]cacheEntry[V])}
+}
+
+func (c *cache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if ok && c.now().Before(entry.expiresAt) {
+		c.hits++
+		return entry.value, true
+	}
+	if ok {
+		delete(c.entries, key)
+	}
+	c.misses++
+	var zero V
+	return zero, false
+}
+
+func (c *cache[V]) set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry[V]{value: value, expiresAt: c.now().Add(c.ttl)}
+}
+
+func (c *cache[V]) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// sweep drops the expired entries, so the ones never looked up again do not pile up, returning how many were dropped
+func (c *cache[V]) sweep() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	swept := 0
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+			swept++
+		}
+	}
+	return swept
+}
+
+// report describes the hit rate since the last report, false when the cache was not used meanwhile
+func (c *cache[V]) report() (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hits, misses := c.hits, c.misses
+	c.hits, c.misses = 0, 0
+	if hits+misses == 0 {
+		return fmt.Sprintf("%s unused", c.name), false
+	}
+	return fmt.Sprintf("%s hits=%d misses=%d hit rate=%.0f%%", c.name, hits, misses, 100*float64(hits)/float64(hits+misses)), true
+}
+
+// CachedClient is a discord.Client that keeps users, channels and guilds for a while, so the REST rate limit
+// is not spent on lookups that rarely change. The gateway events of the session drop the channels and guilds that
+// changed before they expire. Users are only refreshed by the voice state updates of the members in voice, following
+// every member needs the privileged guild members intent, so the ttl is what drops the others.
+type CachedClient struct {
+	discord.Client
+	users          *cache[discord.User]
+	channels       *cache[discord.Channel]
+	guildChannels  *cache[[]discord.Channel]
+	guilds         *cache[discord.Guild]
+	removeHandlers []func()
+	closing        chan struct{}
+}
+
+// NewCachedClient wraps the client, keeping what it returns for ttl
+func NewCachedClient(client discord.Client, session *discordgo.Session, ttl time.Duration) *CachedClient {
+	c := &CachedClient{
+		Client:        client,
+		users:         newCache[discord.User]("users", ttl),
+		channels:      newCache[discord.Channel]("channels", ttl),
+		guildChannels: newCache[[]discord.Channel]("guild channels", ttl),
+		guilds:        newCache[discord.Guild]("guilds", ttl),
+		closing:       make(chan struct{}),
+	}
+	c.removeHandlers = []func(){
+		session.AddHandler(func(s *discordgo.Session, e *discordgo.ChannelCreate) {
+			c.guildChannels.invalidate(e.GuildID)
+		}),
+		session.AddHandler(func(s *discordgo.Session, e *discordgo.ChannelUpdate) {
+			c.channels.invalidate(e.ID)
+			c.guildChannels.invalidate(e.GuildID)
+		}),
+		session.AddHandler(func(s *discordgo.Session, e *discordgo.ChannelDelete) {
+			c.channels.invalidate(e.ID)
+			c.guildChannels.invalidate(e.GuildID)
+		}),
+		// guilds are sent again when the session reconnects, anything may have changed while it was away
+		session.AddHandler(func(s *discordgo.Session, e *discordgo.GuildCreate) {
+			c.guilds.invalidate(e.ID)
+			c.guildChannels.invalidate(e.ID)
+		}),
+		session.AddHandler(func(s *discordgo.Session, e *discordgo.GuildUpdate) {
+			c.guilds.invalidate(e.ID)
+		}),
+		session.AddHandler(func(s *discordgo.Session, e *discordgo.GuildDelete) {
+			c.guilds.invalidate(e.ID)
+			c.guildChannels.invalidate(e.ID)
+		}),
+		// voice state updates carry the member, they keep the users in voice fresh without the members intent
+		session.AddHandler(func(s *discordgo.Session, e *discordgo.VoiceStateUpdate) {
+			if e.Member != nil && e.Member.User != nil {
+				c.users.set(e.Member.User.ID, toUser(e.Member.User))
+			}
+		}),
+	}
+	go c.maintain()
+	return c
+}
+
+func (c *CachedClient) GetUser(userID string) (discord.User, error) {
+	if user, ok := c.users.get(userID); ok {
+		return user, nil
+	}
+	user, err := c.Client.GetUser(userID)
+	if err != nil {
+		return discord.User{}, err
+	}
+	c.users.set(userID, user)
+	return user, nil
+}
+
+// GetGuildUsers is always requested, as members come and go, but the users it returns are cached
+func (c *CachedClient) GetGuildUsers(guildID string) ([]discord.User, error) {
+	users, err := c.Client.GetGuildUsers(guildID)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		c.users.set(user.ID, user)
+	}
+	return users, nil
+}
+
+func (c *CachedClient) GetChannel(channelID string) (discord.Channel, error) {
+	if channel, ok := c.channels.get(channelID); ok {
+		return channel, nil
+	}
+	channel, err := c.Client.GetChannel(channelID)
+	if err != nil {
+		return discord.Channel{}, err
+	}
+	c.channels.set(channelID, channel)
+	return channel, nil
+}
+
+func (c *CachedClient) GetGuildChannels(guildID string) ([]discord.Channel, error) {
+	if channels, ok := c.guildChannels.get(guildID); ok {
+		return append([]discord.Channel(nil), channels...), nil
+	}
+	channels, err := c.Client.GetGuildChannels(guildID)
+	if err != nil {
+		return nil, err
+	}
+	c.guildChannels.set(guildID, append([]discord.Channel(nil), channels...))
+	return channels, nil
+}
+
+func (c *CachedClient) GetGuild(guildID string) (discord.Guild, error) {
+	if guild, ok := c.guilds.get(guildID); ok {
+		return guild, nil
+	}
+	guild, err := c.Client.GetGuild(guildID)
+	if err != nil {
+		return discord.Guild{}, err
+	}
+	c.guilds.set(guildID, guild)
+	return guild, nil
+}
+
+func (c *CachedClient) CreateChannel(guildID string, name string, channelType discord.ChannelType, maxUsers int) (discord.Channel, error) {
+	channel, err := c.Client.CreateChannel(guildID, name, channelType, maxUsers)
+	if err == nil {
+		c.guildChannels.invalidate(guildID)
+	}
+	return channel, err
+}
+
+// Close stops following the gateway events, sweeping and logging the hit rates
+func (c *CachedClient) Close() error {
+	for _, remove := range c.removeHandlers {
+		remove()
+	}
+	close(c.closing)
+	return nil
+}
+
+// maintain sweeps the caches and logs their hit rates until the client is closed
+func (c *CachedClient) maintain() {
+	ticker := time.NewTicker(cacheStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closing:
+			return
+		case <-ticker.C:
+			c.users.sweep()
+			c.channels.sweep()
+			c.guildChannels.sweep()
+			c.guilds.sweep()
+			if stats, active := c.formatStats(); active {
+				log.Println("discord cache:", stats)
+			}
+		}
+	}
+}
+
+// formatStats describes the hit rate of every cache, false when none of them was used
+func (c *CachedClient) formatStats() (string, bool) {
+	var parts []string
+	active := false
+	for _, report := range []func() (string, bool){c.users.report, c.channels.report, c.guildChannels.report, c.guilds.report} {
+		part, used := report()
+		parts = append(parts, part)
+		active = active || used
+	}
+	return strings.Join(parts, ", "), active
+}
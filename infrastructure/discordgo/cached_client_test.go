@@ -0,0 +1,172 @@
+package discordgo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is the time of a cache, moved by hand
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func newTestCache(clock *fakeClock) *cache[string] {
+	c := newCache[string]("test", time.Minute)
+	c.now = clock.Now
+	return c
+}
+
+func Test_cache_get(t *testing.T) {
+	tests := []struct {
+		name          string
+		elapsed       time.Duration
+		invalidate    bool
+		expectedFound bool
+	}{
+		{name: "when entry has not expired, return it", elapsed: 30 * time.Second, expectedFound: true},
+		{name: "when entry has expired, miss it", elapsed: time.Minute},
+		{name: "when entry was invalidated, miss it", elapsed: time.Second, invalidate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Now()}
+			c := newTestCache(clock)
+			c.set("key", "value")
+			if tt.invalidate {
+				c.invalidate("key")
+			}
+			clock.now = clock.now.Add(tt.elapsed)
+
+			value, found := c.get("key")
+			assert.Equal(t, tt.expectedFound, found)
+			if tt.expectedFound {
+				assert.Equal(t, "value", value)
+			} else {
+				assert.Empty(t, c.entries)
+			}
+		})
+	}
+}
+
+func Test_cache_sweep(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	c := newTestCache(clock)
+	c.set("old", "value")
+	c.set("older", "value")
+	clock.now = clock.now.Add(30 * time.Second)
+	c.set("new", "value")
+	clock.now = clock.now.Add(45 * time.Second)
+
+	assert.Equal(t, 2, c.sweep())
+	assert.Len(t, c.entries, 1)
+	assert.Contains(t, c.entries, "new")
+	assert.Equal(t, 0, c.sweep())
+}
+
+func Test_cache_report(t *testing.T) {
+	tests := []struct {
+		name           string
+		hits           int
+		misses         int
+		expectedReport string
+		expectedUsed   bool
+	}{
+		{name: "when cache was not used, tell it", expectedReport: "test unused"},
+		{name: "when cache was only missed, report no hits", misses: 2, expectedReport: "test hits=0 misses=2 hit rate=0%", expectedUsed: true},
+		{name: "when cache was hit and missed, report the hit rate", hits: 3, misses: 1, expectedReport: "test hits=3 misses=1 hit rate=75%", expectedUsed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(&fakeClock{now: time.Now()})
+			c.set("key", "value")
+			for i := 0; i < tt.hits; i++ {
+				c.get("key")
+			}
+			for i := 0; i < tt.misses; i++ {
+				c.get("missing")
+			}
+
+			report, used := c.report()
+			assert.Equal(t, tt.expectedReport, report)
+			assert.Equal(t, tt.expectedUsed, used)
+
+			report, used = c.report()
+			assert.Equal(t, "test unused", report, "counters restart after a report")
+			assert.False(t, used)
+		})
+	}
+}
+
+func newTestCachedClient(client discord.Client) *CachedClient {
+	return &CachedClient{
+		Client:        client,
+		users:         newCache[discord.User]("users", time.Minute),
+		channels:      newCache[discord.Channel]("channels", time.Minute),
+		guildChannels: newCache[[]discord.Channel]("guild channels", time.Minute),
+		guilds:        newCache[discord.Guild]("guilds", time.Minute),
+	}
+}
+
+func TestCachedClient_GetUser(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		expectedCalls int
+	}{
+		{name: "when user is found, ask discord once", expectedCalls: 1},
+		{name: "when user is not found, do not cache the error", err: errors.New("err 404"), expectedCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &discordmocks.Client{}
+			client.On("GetUser", "user").Return(discord.User{ID: "user", Username: "username"}, tt.err)
+			c := newTestCachedClient(client)
+
+			for i := 0; i < 2; i++ {
+				user, err := c.GetUser("user")
+				assert.Equal(t, tt.err, err)
+				if tt.err == nil {
+					assert.Equal(t, "username", user.Username)
+				}
+			}
+			client.AssertNumberOfCalls(t, "GetUser", tt.expectedCalls)
+		})
+	}
+}
+
+func TestCachedClient_CreateChannel(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		expectedCalls int
+	}{
+		{name: "when channel is created, ask discord for the channels again", expectedCalls: 2},
+		{name: "when channel is not created, keep the channels", err: errors.New("err 403"), expectedCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &discordmocks.Client{}
+			client.On("GetGuildChannels", "guild").Return([]discord.Channel{{ID: "text"}}, nil)
+			client.On("CreateChannel", "guild", "voice", discord.ChannelTypeGuildVoice, 1).Return(discord.Channel{ID: "voice"}, tt.err)
+			c := newTestCachedClient(client)
+
+			_, err := c.GetGuildChannels("guild")
+			assert.NoError(t, err)
+			_, err = c.CreateChannel("guild", "voice", discord.ChannelTypeGuildVoice, 1)
+			assert.Equal(t, tt.err, err)
+			_, err = c.GetGuildChannels("guild")
+			assert.NoError(t, err)
+
+			client.AssertNumberOfCalls(t, "GetGuildChannels", tt.expectedCalls)
+		})
+	}
+}
@@ -1,33 +1,43 @@
 package discordgo
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/hectorgabucio/taterubot-dc/domain/discord"
 )
 
 type Client struct {
-	session *discordgo.Session
+	session  *discordgo.Session
+	watchdog *voiceWatchdog
 }
 
+// guildMembersPage is the most members discord returns at once
+const guildMembersPage = 1000
+
+// GetGuildUsers pages through the members of the guild, after the last one of each page, until a page is not full
 func (c *Client) GetGuildUsers(guildID string) ([]discord.User, error) {
-	members, err := c.session.GuildMembers(guildID, "", 1000)
-	if err != nil {
-		return nil, fmt.Errorf("err.discordgo.listusers:%w", err)
-	}
-	users := make([]discord.User, len(members))
-	for i, member := range members {
-		users[i] = discord.User{
-			ID:          member.User.ID,
-			Username:    member.User.Username,
-			AvatarURL:   member.User.AvatarURL(""),
-			AccentColor: member.User.AccentColor,
+	var users []discord.User
+	after := ""
+	for {
+		members, err := c.session.GuildMembers(guildID, after, guildMembersPage)
+		if err != nil {
+			return nil, fmt.Errorf("err.discordgo.listusers:%w", err)
+		}
+		for _, member := range members {
+			users = append(users, toUser(member.User))
 		}
+		if len(members) < guildMembersPage {
+			return users, nil
+		}
+		after = members[len(members)-1].User.ID
 	}
-	return users, nil
 }
 
 func (c *Client) GetUser(userID string) (discord.User, error) {
@@ -35,43 +45,45 @@ func (c *Client) GetUser(userID string) (discord.User, error) {
 	if err != nil {
 		return discord.User{}, fmt.Errorf("err.discordgo.user:%w", err)
 	}
+	return toUser(user), nil
+}
+
+func toUser(user *discordgo.User) discord.User {
 	return discord.User{
 		ID:          user.ID,
 		Username:    user.Username,
 		AvatarURL:   user.AvatarURL(""),
 		AccentColor: user.AccentColor,
-	}, nil
+		Bot:         user.Bot,
+	}
 }
 
 func (c *Client) EditInteractionComplex(token string, edit discord.ComplexInteractionEdit) error {
-	embeds := make([]*discordgo.MessageEmbed, len(edit.Embeds))
-	for i, embed := range edit.Embeds {
-		fields := make([]*discordgo.MessageEmbedField, len(embed.Fields))
-		for j, field := range embed.Fields {
-			fields[j] = &discordgo.MessageEmbedField{
-				Name:   field.Name,
-				Value:  field.Value,
-				Inline: false,
-			}
-		}
-		embeds[i] = &discordgo.MessageEmbed{
-			Title:       embed.Title,
-			Description: embed.Description,
-			Color:       embed.Color,
-			Thumbnail: &discordgo.MessageEmbedThumbnail{
-				URL: embed.Thumbnail,
-			},
-			Author: &discordgo.MessageEmbedAuthor{
-				Name:    embed.Author.Name,
-				IconURL: embed.Author.IconURL,
-			},
-			Fields: fields,
+	var domainEmbeds []discord.MessageEmbed
+	for _, embed := range edit.Embeds {
+		if embed != nil {
+			domainEmbeds = append(domainEmbeds, *embed)
 		}
 	}
-	_, err := c.session.InteractionResponseEdit(&discordgo.Interaction{Token: token, AppID: c.session.State.User.ID}, &discordgo.WebhookEdit{
+	embeds, images := toDiscordgoEmbeds(domainEmbeds)
+	webhookEdit := &discordgo.WebhookEdit{
 		Content: &edit.Content,
 		Embeds:  &embeds,
-	})
+	}
+	for _, image := range images {
+		webhookEdit.Files = append(webhookEdit.Files, &discordgo.File{Name: image.Name, ContentType: image.ContentType, Reader: image.Reader})
+	}
+	if edit.Select != nil {
+		options := make([]discordgo.SelectMenuOption, len(edit.Select.Options))
+		for i, option := range edit.Select.Options {
+			options[i] = discordgo.SelectMenuOption{Label: option.Label, Value: option.Value}
+		}
+		components := []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{CustomID: edit.Select.CustomID, Placeholder: edit.Select.Placeholder, Options: options},
+		}}}
+		webhookEdit.Components = &components
+	}
+	_, err := c.session.InteractionResponseEdit(&discordgo.Interaction{Token: token, AppID: c.session.State.User.ID}, webhookEdit)
 	if err != nil {
 		return fmt.Errorf("discordgo.interaction.edit.complex: %w", err)
 	}
@@ -80,7 +92,7 @@ func (c *Client) EditInteractionComplex(token string, edit discord.ComplexIntera
 }
 
 func NewClient(session *discordgo.Session) *Client {
-	return &Client{session: session}
+	return &Client{session: session, watchdog: newVoiceWatchdog(session)}
 }
 
 func (c *Client) EditInteraction(token string, message string) error {
@@ -93,6 +105,39 @@ func (c *Client) EditInteraction(token string, message string) error {
 	return nil
 }
 
+func (c *Client) SendFollowup(token string, content string, file discord.File) (discord.Message, error) {
+	message, err := c.session.FollowupMessageCreate(&discordgo.Interaction{Token: token, AppID: c.session.State.User.ID}, true, &discordgo.WebhookParams{
+		Content: content,
+		Files:   []*discordgo.File{{Name: file.Name, ContentType: file.ContentType, Reader: file.Reader}},
+	})
+	if err != nil {
+		return discord.Message{}, fmt.Errorf("discordgo.interaction.followup: %w", err)
+	}
+	sent := discord.Message{ID: message.ID, ChannelID: message.ChannelID}
+	if len(message.Attachments) > 0 {
+		sent.AttachmentID = message.Attachments[0].ID
+		sent.AttachmentURL = message.Attachments[0].URL
+	}
+	return sent, nil
+}
+
+func (c *Client) RespondAutocomplete(interactionID string, token string, choices []string) error {
+	dgChoices := make([]*discordgo.ApplicationCommandOptionChoice, len(choices))
+	for i, choice := range choices {
+		dgChoices[i] = &discordgo.ApplicationCommandOptionChoice{Name: choice, Value: choice}
+	}
+	err := c.session.InteractionRespond(&discordgo.Interaction{ID: interactionID, Token: token}, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: dgChoices,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("discordgo.interaction.autocomplete: %w", err)
+	}
+	return nil
+}
+
 func (c *Client) GetGuilds() ([]discord.Guild, error) {
 	infraGuilds, err := c.session.UserGuilds(100, "", "")
 	if err != nil {
@@ -111,6 +156,21 @@ func (c *Client) GetGuilds() ([]discord.Guild, error) {
 	return guilds, nil
 }
 
+func (c *Client) GetGuild(guildID string) (discord.Guild, error) {
+	guild, err := c.session.State.Guild(guildID)
+	if err != nil {
+		guild, err = c.session.Guild(guildID)
+		if err != nil {
+			return discord.Guild{}, fmt.Errorf("err getting guild, %w", err)
+		}
+	}
+	return discord.Guild{
+		ID:          guild.ID,
+		Name:        guild.Name,
+		PremiumTier: int(guild.PremiumTier),
+	}, nil
+}
+
 func (c *Client) GetBotUsername() string {
 	return c.session.State.User.Username
 }
@@ -166,78 +226,189 @@ func (c *Client) SendTextMessage(channelID string, message string) error {
 	return nil
 }
 
-func (c *Client) SetEmbed(channelID string, messageID string, embed discord.MessageEmbed) error {
-	dgEmbed := &discordgo.MessageEmbed{
-
-		Title:     embed.Title,
-		Timestamp: embed.Timestamp,
-		Color:     embed.Color,
-		Thumbnail: &discordgo.MessageEmbedThumbnail{
-			URL: embed.Thumbnail,
-		},
-		Fields: []*discordgo.MessageEmbedField{},
+func (c *Client) SetEmbeds(channelID string, messageID string, embeds []discord.MessageEmbed, buttons []discord.Button) error {
+	edit := discordgo.NewMessageEdit(channelID, messageID)
+	var images []discord.File
+	edit.Embeds, images = toDiscordgoEmbeds(embeds)
+	if len(buttons) > 0 {
+		row := discordgo.ActionsRow{}
+		for _, button := range buttons {
+			row.Components = append(row.Components, discordgo.Button{
+				Label:    button.Label,
+				Style:    discordgo.SecondaryButton,
+				Emoji:    discordgo.ComponentEmoji{Name: button.Emoji},
+				CustomID: button.CustomID,
+			})
+		}
+		edit.Components = []discordgo.MessageComponent{row}
 	}
-
-	for _, field := range embed.Fields {
-		dgEmbed.Fields = append(dgEmbed.Fields, &discordgo.MessageEmbedField{
-			Name:   field.Name,
-			Value:  field.Value,
-			Inline: false,
-		})
+	if len(images) > 0 {
+		return c.editWithImages(edit, images)
 	}
+	if _, err := c.session.ChannelMessageEditComplex(edit); err != nil {
+		return fmt.Errorf("err editing embed, %w", err)
+	}
+	return nil
+}
+
+// embedEditWithFiles is the payload to edit the embed of a message uploading a file, not supported by discordgo.MessageEdit
+type embedEditWithFiles struct {
+	Embeds      []*discordgo.MessageEmbed    `json:"embeds"`
+	Components  []discordgo.MessageComponent `json:"components"`
+	Attachments []any                        `json:"attachments"`
+}
+
+// keptAttachment is an attachment the message already had, attachments left out of an edit are deleted
+type keptAttachment struct {
+	ID string `json:"id"`
+}
 
-	if _, err := c.session.ChannelMessageEditEmbed(channelID, messageID, dgEmbed); err != nil {
+func (c *Client) editWithImages(edit *discordgo.MessageEdit, images []discord.File) error {
+	message, err := c.session.ChannelMessage(edit.Channel, edit.ID)
+	if err != nil {
+		return fmt.Errorf("err getting message to edit, %w", err)
+	}
+	payload := embedEditWithFiles{Embeds: edit.Embeds, Components: edit.Components}
+	for _, attachment := range message.Attachments {
+		payload.Attachments = append(payload.Attachments, keptAttachment{ID: attachment.ID})
+	}
+	files := make([]*discordgo.File, len(images))
+	for i, image := range images {
+		payload.Attachments = append(payload.Attachments, messageEditAttachment{ID: i, Filename: image.Name})
+		files[i] = &discordgo.File{Name: image.Name, ContentType: image.ContentType, Reader: image.Reader}
+	}
+	requestContentType, body, err := discordgo.MultipartBodyWithJSON(payload, files)
+	if err != nil {
+		return fmt.Errorf("err encoding embed edit, %w", err)
+	}
+	endpoint := discordgo.EndpointChannelMessage(edit.Channel, edit.ID)
+	bucket := c.session.Ratelimiter.LockBucket(discordgo.EndpointChannelMessage(edit.Channel, ""))
+	if _, err := c.session.RequestWithLockedBucket("PATCH", endpoint, requestContentType, body, bucket, 0); err != nil {
 		return fmt.Errorf("err editing embed, %w", err)
 	}
 	return nil
 }
 
+func (c *Client) GetMessage(channelID string, messageID string) (discord.Message, error) {
+	message, err := c.session.ChannelMessage(channelID, messageID)
+	if err != nil {
+		return discord.Message{}, fmt.Errorf("err getting message, %w", err)
+	}
+	domainMessage := discord.Message{
+		ID:        message.ID,
+		ChannelID: message.ChannelID,
+		Embeds:    toEmbeds(message.Embeds),
+	}
+	if len(message.Attachments) > 0 {
+		domainMessage.AttachmentID = message.Attachments[0].ID
+		domainMessage.AttachmentURL = message.Attachments[0].URL
+	}
+	return domainMessage, nil
+}
+
+func (c *Client) GetUserVoiceChannel(guildID string, userID string) (string, error) {
+	state, err := c.session.State.VoiceState(guildID, userID)
+	if errors.Is(err, discordgo.ErrStateNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("err getting user voice state, %w", err)
+	}
+	return state.ChannelID, nil
+}
+
 func (c *Client) EstablishVoiceConnection(guildID, channelID string, mute, deaf bool, done chan bool) (voice *discord.VoiceConnection, err error) {
 	conn, err := c.session.ChannelVoiceJoin(guildID, channelID, mute, deaf)
 	if err != nil {
 		return nil, fmt.Errorf("err joining voice channel, %w", err)
 	}
 
-	voiceRecv := make(chan *discord.Packet)
-	go func(voice chan *discordgo.Packet) {
-		for {
-			if !conn.Ready || conn.OpusRecv == nil {
-				log.Printf("Discordgo not to receive opus packets. %+v : %+v", conn.Ready, conn.OpusSend)
-				return
-			}
-
-			select {
-			case packet, ok := <-voice:
-				if !ok {
-					return
-				}
-				voiceRecv <- &discord.Packet{
-					SSRC:      packet.SSRC,
-					Sequence:  packet.Sequence,
-					Timestamp: packet.Timestamp,
-					Type:      packet.Type,
-					Opus:      packet.Opus,
-					PCM:       packet.PCM,
-				}
-			case <-done: // done reading
-				close(voiceRecv)
-				//close(voice)
-
-				err := conn.Disconnect()
-				if err != nil {
-					log.Printf("err disconnecting discord voice conn, %v", err)
-				}
-				return
-			}
-		}
-	}(conn.OpusRecv)
-	domainConn := discord.NewVoiceConnection(conn, voiceRecv)
+	receiver := newVoiceReceiver()
+	handle := newVoiceHandle(conn)
+	go c.watchdog.watchVoice(handle, receiver, guildID, channelID, mute, deaf, done)
+	domainConn := discord.NewVoiceConnection(handle, receiver.packets)
+	domainConn.ReceiveStats = receiver.stats
+	domainConn.Err = receiver.failure
 
 	return domainConn, nil
 }
 
+func (c *Client) EstablishPlaybackConnection(guildID, channelID string) (voice *discord.VoiceConnection, err error) {
+	conn, err := c.session.ChannelVoiceJoin(guildID, channelID, false, true)
+	if err != nil {
+		return nil, fmt.Errorf("err joining voice channel, %w", err)
+	}
+	return discord.NewVoiceConnection(newVoiceHandle(conn), nil), nil
+}
+
+func (c *Client) DisconnectVoice(voice *discord.VoiceConnection) error {
+	handle, ok := voice.Internals.(*voiceHandle)
+	if !ok {
+		return errors.New("voice connection is not a discordgo one")
+	}
+	conn := handle.get()
+	if err := conn.Disconnect(); err != nil {
+		return fmt.Errorf("err disconnecting voice connection, %w", err)
+	}
+	return nil
+}
+
+// silenceFrame is sent a few times after playing audio to avoid opus interpolation artifacts
+var silenceFrame = []byte{0xF8, 0xFF, 0xFE}
+
+func (c *Client) PlayOpus(voice *discord.VoiceConnection, frames [][]byte) error {
+	handle, ok := voice.Internals.(*voiceHandle)
+	if !ok {
+		return errors.New("voice connection is not a discordgo one")
+	}
+	conn := handle.get()
+	if err := conn.Speaking(true); err != nil {
+		return fmt.Errorf("err start speaking, %w", err)
+	}
+	defer func() {
+		if err := conn.Speaking(false); err != nil {
+			log.Println("err stop speaking", err)
+		}
+	}()
+	for i, frame := range frames {
+		if err := sendOpus(conn, frame, opusSendTimeout); err != nil {
+			return fmt.Errorf("err playing frame %d of %d, %w", i+1, len(frames), err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if err := sendOpus(conn, silenceFrame, opusSendTimeout); err != nil {
+			log.Println("err sending silence after playing", err)
+			break
+		}
+	}
+	return nil
+}
+
+// opusSendTimeout is how long a frame waits to be sent, discordgo takes one every 20ms while the connection is alive
+const opusSendTimeout = time.Second
+
+// sendOpus queues the frame in the connection, failing when the connection stops taking frames instead of blocking forever
+func sendOpus(conn *discordgo.VoiceConnection, frame []byte, timeout time.Duration) error {
+	if !conn.Ready || conn.OpusSend == nil {
+		return errors.New("voice connection not ready to send opus packets")
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case conn.OpusSend <- frame:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("voice connection took more than %s to send an opus packet", timeout)
+	}
+}
+
 func (c *Client) SendFileMessage(channelID string, name, contentType string, readable io.Reader) (discord.Message, error) {
-	sendComplex, err := c.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+	return c.SendFileReply(channelID, "", "", name, contentType, readable)
+}
+
+func (c *Client) SendFileReply(channelID string, replyToID string, content string, name, contentType string, readable io.Reader) (discord.Message, error) {
+	send := &discordgo.MessageSend{
+		Content: content,
 		Files: []*discordgo.File{
 			{
 				Name:        name,
@@ -245,13 +416,120 @@ func (c *Client) SendFileMessage(channelID string, name, contentType string, rea
 				Reader:      readable,
 			},
 		},
-	})
+	}
+	if replyToID != "" {
+		send.Reference = &discordgo.MessageReference{MessageID: replyToID, ChannelID: channelID}
+	}
+	sendComplex, err := c.session.ChannelMessageSendComplex(channelID, send)
 	if err != nil {
 		return discord.Message{}, fmt.Errorf("err sending complex message, %w", err)
 	}
 	return discord.Message{
-		ID:           sendComplex.ID,
-		ChannelID:    sendComplex.ChannelID,
-		AttachmentID: sendComplex.Attachments[0].ID,
+		ID:            sendComplex.ID,
+		ChannelID:     sendComplex.ChannelID,
+		AttachmentID:  sendComplex.Attachments[0].ID,
+		AttachmentURL: sendComplex.Attachments[0].URL,
+	}, nil
+}
+
+// voiceMessageFlag marks a message as a voice message, not supported by discordgo.MessageFlags yet
+const voiceMessageFlag = 1 << 13
+
+type voiceMessageSend struct {
+	Flags       int                      `json:"flags"`
+	Attachments []voiceMessageAttachment `json:"attachments"`
+}
+
+type voiceMessageAttachment struct {
+	ID           int     `json:"id"`
+	Filename     string  `json:"filename"`
+	DurationSecs float64 `json:"duration_secs"`
+	Waveform     string  `json:"waveform"`
+}
+
+func (c *Client) SendVoiceMessage(channelID string, name string, readable io.Reader, voice discord.VoiceMessage) (discord.Message, error) {
+	files := []*discordgo.File{
+		{
+			Name:        name,
+			ContentType: "audio/ogg",
+			Reader:      readable,
+		},
+	}
+	payload := voiceMessageSend{
+		Flags: voiceMessageFlag,
+		Attachments: []voiceMessageAttachment{{
+			ID:           0,
+			Filename:     name,
+			DurationSecs: voice.DurationSecs,
+			Waveform:     base64.StdEncoding.EncodeToString(voice.Waveform),
+		}},
+	}
+	requestContentType, body, err := discordgo.MultipartBodyWithJSON(payload, files)
+	if err != nil {
+		return discord.Message{}, fmt.Errorf("err encoding voice message, %w", err)
+	}
+	endpoint := discordgo.EndpointChannelMessages(channelID)
+	bucket := c.session.Ratelimiter.LockBucket(endpoint)
+	response, err := c.session.RequestWithLockedBucket("POST", endpoint, requestContentType, body, bucket, 0)
+	if err != nil {
+		return discord.Message{}, fmt.Errorf("err sending voice message, %w", err)
+	}
+	var sent discordgo.Message
+	if err := json.Unmarshal(response, &sent); err != nil {
+		return discord.Message{}, fmt.Errorf("err decoding voice message, %w", err)
+	}
+	if len(sent.Attachments) == 0 {
+		return discord.Message{}, errors.New("voice message has no attachments")
+	}
+	return discord.Message{
+		ID:            sent.ID,
+		ChannelID:     sent.ChannelID,
+		AttachmentID:  sent.Attachments[0].ID,
+		AttachmentURL: sent.Attachments[0].URL,
+	}, nil
+}
+
+// messageEditWithFiles is the payload to edit a message replacing its attachments, not supported by discordgo.MessageEdit
+type messageEditWithFiles struct {
+	Attachments []messageEditAttachment `json:"attachments"`
+}
+
+type messageEditAttachment struct {
+	ID       int    `json:"id"`
+	Filename string `json:"filename"`
+}
+
+func (c *Client) ReplaceFileMessage(channelID string, messageID string, name, contentType string, readable io.Reader) (discord.Message, error) {
+	files := []*discordgo.File{
+		{
+			Name:        name,
+			ContentType: contentType,
+			Reader:      readable,
+		},
+	}
+	// Only listing the new file in attachments makes discord drop the previous ones
+	payload := messageEditWithFiles{Attachments: []messageEditAttachment{{ID: 0, Filename: name}}}
+	requestContentType, body, err := discordgo.MultipartBodyWithJSON(payload, files)
+	if err != nil {
+		return discord.Message{}, fmt.Errorf("err encoding message edit, %w", err)
+	}
+	endpoint := discordgo.EndpointChannelMessage(channelID, messageID)
+	bucket := c.session.Ratelimiter.LockBucket(discordgo.EndpointChannelMessage(channelID, ""))
+	response, err := c.session.RequestWithLockedBucket("PATCH", endpoint, requestContentType, body, bucket, 0)
+	if err != nil {
+		return discord.Message{}, fmt.Errorf("err replacing message file, %w", err)
+	}
+	var edited discordgo.Message
+	if err := json.Unmarshal(response, &edited); err != nil {
+		return discord.Message{}, fmt.Errorf("err decoding edited message, %w", err)
+	}
+	if len(edited.Attachments) == 0 {
+		return discord.Message{}, errors.New("edited message has no attachments")
+	}
+	return discord.Message{
+		ID:            edited.ID,
+		ChannelID:     edited.ChannelID,
+		AttachmentID:  edited.Attachments[0].ID,
+		AttachmentURL: edited.Attachments[0].URL,
 	}, nil
 }
@@ -0,0 +1,42 @@
+package discordgo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sendOpus(t *testing.T) {
+	tests := []struct {
+		name          string
+		conn          *discordgo.VoiceConnection
+		expectedError string
+	}{
+		{name: "when connection takes the frame, send it", conn: &discordgo.VoiceConnection{Ready: true, OpusSend: make(chan []byte, 1)}},
+		{
+			name:          "when connection stops taking frames, give up after the timeout",
+			conn:          &discordgo.VoiceConnection{Ready: true, OpusSend: make(chan []byte)},
+			expectedError: "voice connection took more than 10ms to send an opus packet",
+		},
+		{
+			name:          "when connection is not ready, return error",
+			conn:          &discordgo.VoiceConnection{OpusSend: make(chan []byte, 1)},
+			expectedError: "voice connection not ready to send opus packets",
+		},
+		{name: "when connection has no sender, return error", conn: &discordgo.VoiceConnection{Ready: true}, expectedError: "voice connection not ready to send opus packets"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sendOpus(tt.conn, silenceFrame, 10*time.Millisecond)
+
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, silenceFrame, <-tt.conn.OpusSend)
+		})
+	}
+}
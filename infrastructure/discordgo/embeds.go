@@ -0,0 +1,93 @@
+package discordgo
+
+import (
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+)
+
+// toDiscordgoEmbeds truncates the embeds to the limits of discord, returning them along the images they upload,
+// which the embeds refer to by their name
+func toDiscordgoEmbeds(embeds []discord.MessageEmbed) ([]*discordgo.MessageEmbed, []discord.File) {
+	if err := discord.ValidateEmbeds(embeds); err != nil {
+		log.Println("embeds over the limits of discord, truncating them:", err)
+		embeds = discord.TruncateEmbeds(embeds)
+	}
+	dgEmbeds := make([]*discordgo.MessageEmbed, len(embeds))
+	var images []discord.File
+	for i, embed := range embeds {
+		dgEmbeds[i] = toDiscordgoEmbed(embed)
+		if embed.Image != nil {
+			dgEmbeds[i].Image = &discordgo.MessageEmbedImage{URL: "attachment://" + embed.Image.Name}
+			images = append(images, *embed.Image)
+		}
+	}
+	return dgEmbeds, images
+}
+
+func toDiscordgoEmbed(embed discord.MessageEmbed) *discordgo.MessageEmbed {
+	dgEmbed := &discordgo.MessageEmbed{
+		URL:         embed.URL,
+		Title:       embed.Title,
+		Description: embed.Description,
+		Timestamp:   embed.Timestamp,
+		Color:       embed.Color,
+	}
+	for _, field := range embed.Fields {
+		if field != nil {
+			dgEmbed.Fields = append(dgEmbed.Fields, &discordgo.MessageEmbedField{Name: field.Name, Value: field.Value, Inline: field.Inline})
+		}
+	}
+	if embed.Thumbnail != "" {
+		dgEmbed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: embed.Thumbnail}
+	}
+	if embed.ImageURL != "" {
+		dgEmbed.Image = &discordgo.MessageEmbedImage{URL: embed.ImageURL}
+	}
+	if embed.Author != nil {
+		dgEmbed.Author = &discordgo.MessageEmbedAuthor{Name: embed.Author.Name, URL: embed.Author.URL, IconURL: embed.Author.IconURL}
+	}
+	if embed.Footer != nil {
+		dgEmbed.Footer = &discordgo.MessageEmbedFooter{Text: embed.Footer.Text, IconURL: embed.Footer.IconURL}
+	}
+	return dgEmbed
+}
+
+func toEmbeds(dgEmbeds []*discordgo.MessageEmbed) []discord.MessageEmbed {
+	var embeds []discord.MessageEmbed
+	for _, dgEmbed := range dgEmbeds {
+		if dgEmbed != nil {
+			embeds = append(embeds, toEmbed(dgEmbed))
+		}
+	}
+	return embeds
+}
+
+func toEmbed(dgEmbed *discordgo.MessageEmbed) discord.MessageEmbed {
+	embed := discord.MessageEmbed{
+		URL:         dgEmbed.URL,
+		Title:       dgEmbed.Title,
+		Description: dgEmbed.Description,
+		Timestamp:   dgEmbed.Timestamp,
+		Color:       dgEmbed.Color,
+	}
+	for _, field := range dgEmbed.Fields {
+		if field != nil {
+			embed.Fields = append(embed.Fields, &discord.MessageEmbedField{Name: field.Name, Value: field.Value, Inline: field.Inline})
+		}
+	}
+	if dgEmbed.Thumbnail != nil {
+		embed.Thumbnail = dgEmbed.Thumbnail.URL
+	}
+	if dgEmbed.Image != nil {
+		embed.ImageURL = dgEmbed.Image.URL
+	}
+	if dgEmbed.Author != nil {
+		embed.Author = &discord.MessageEmbedAuthor{Name: dgEmbed.Author.Name, URL: dgEmbed.Author.URL, IconURL: dgEmbed.Author.IconURL}
+	}
+	if dgEmbed.Footer != nil {
+		embed.Footer = &discord.MessageEmbedFooter{Text: dgEmbed.Footer.Text, IconURL: dgEmbed.Footer.IconURL}
+	}
+	return embed
+}
@@ -0,0 +1,332 @@
+package discordgo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+)
+
+const (
+	// deliveryAttempts is how many times a delivery is tried before giving up on it
+	deliveryAttempts = 4
+	// retryBackoff is the wait before the first retry of a transient failure, doubled for every retry after it
+	retryBackoff = time.Second
+	// maxRetryWait bounds the waits between attempts. Deliveries run in the workers of the job queue, which wait along
+	// with them, so when discord asks to wait longer the delivery is left to the dead letters instead of holding the worker
+	maxRetryWait = 10 * time.Second
+	// postedLookup is how many of the last messages of a channel are checked for a post that failed but went through
+	postedLookup = 10
+	// postedClockSkew is allowed between the clocks of the bot and discord when checking a post went through
+	postedClockSkew = 5 * time.Second
+)
+
+// failure is how a request to discord failed, it tells if and how it can be retried
+type failure int
+
+const (
+	failurePermanent failure = iota
+	// failureRateLimited requests were refused before discord did anything, retrying them is always safe
+	failureRateLimited
+	// failureTransient requests may have been done before failing, messages are looked up before posting them again
+	failureTransient
+)
+
+// classify tells how the request failed and how long discord asked to wait before retrying it, 0 when it did not
+func classify(err error) (failure, time.Duration) {
+	var rateLimit *discordgo.RateLimitError
+	if errors.As(err, &rateLimit) {
+		return failureRateLimited, rateLimit.RetryAfter
+	}
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil {
+		wait := retryAfterHeader(restErr.Response.Header)
+		switch status := restErr.Response.StatusCode; {
+		case status == http.StatusTooManyRequests:
+			return failureRateLimited, wait
+		case status >= http.StatusInternalServerError:
+			return failureTransient, wait
+		default:
+			return failurePermanent, 0
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return failureTransient, 0
+	}
+	return failurePermanent, 0
+}
+
+// retryAfterHeader is the wait in the Retry-After header, in seconds as discord sends it
+func retryAfterHeader(header http.Header) time.Duration {
+	seconds, err := strconv.ParseFloat(header.Get("Retry-After"), 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// RetryingClient is a discord.Client that retries the deliveries that fail for a while, waiting what discord asks for.
+// Messages are only posted again once it is checked the failed attempt did not post them. Deliveries that still fail
+// are kept in the dead letters, so an admin can replay them, except interaction answers as their tokens expire and
+// edits discord refused, like the ones of deleted messages.
+type RetryingClient struct {
+	discord.Client
+	session     *discordgo.Session
+	deadLetters domain.DeadLetterRepository
+	sleep       func(time.Duration)
+}
+
+func NewRetryingClient(client discord.Client, session *discordgo.Session, deadLetters domain.DeadLetterRepository) *RetryingClient {
+	return &RetryingClient{Client: client, session: session, deadLetters: deadLetters, sleep: time.Sleep}
+}
+
+// retry runs the attempt until it succeeds, fails permanently or runs out of attempts. A transient failure is only retried
+// when posted tells the attempt did not go through, posted is nil for requests that can be repeated without harm.
+// The waits between attempts hold the job queue worker running the delivery, at most maxRetryWait each.
+func (c *RetryingClient) retry(operation string, attempt func() error, posted func() (bool, error)) error {
+	backoff := retryBackoff
+	var err error
+	for i := 1; i <= deliveryAttempts; i++ {
+		if err = attempt(); err == nil {
+			return nil
+		}
+		kind, wait := classify(err)
+		if kind == failurePermanent || i == deliveryAttempts {
+			break
+		}
+		if wait == 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		if wait > maxRetryWait {
+			log.Printf("err %s, attempt %d of %d, discord asked to wait %s, giving up: %v\n", operation, i, deliveryAttempts, wait, err)
+			break
+		}
+		log.Printf("err %s, attempt %d of %d, retrying in %s: %v\n", operation, i, deliveryAttempts, wait, err)
+		c.sleep(wait)
+		if kind == failureTransient && posted != nil {
+			done, lookupErr := posted()
+			if lookupErr != nil {
+				// posting again could send it twice, it is left to the dead letters instead
+				return fmt.Errorf("%w, and it could not be checked whether it was posted, %v", err, lookupErr)
+			}
+			if done {
+				log.Printf("%s failed but went through, not posting it again\n", operation)
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+// findPosted looks for a message the bot posted in the channel since the first attempt, with the content and an attachment
+// of that size, or no attachment when size is negative
+func (c *RetryingClient) findPosted(channelID string, since time.Time, content string, size int) (*discordgo.Message, error) {
+	messages, err := c.session.ChannelMessages(channelID, postedLookup, "", "", "")
+	if err != nil {
+		return nil, fmt.Errorf("err getting last messages, %w", err)
+	}
+	for _, message := range messages {
+		if message.Author == nil || message.Author.ID != c.session.State.User.ID || message.Timestamp.Before(since.Add(-postedClockSkew)) {
+			continue
+		}
+		if message.Content != content {
+			continue
+		}
+		if (size < 0 && len(message.Attachments) == 0) || (size >= 0 && len(message.Attachments) == 1 && message.Attachments[0].Size == size) {
+			return message, nil
+		}
+	}
+	return nil, nil
+}
+
+// postedChecker checks for the message of the delivery, keeping what it finds to return it as the sent message
+func (c *RetryingClient) postedChecker(channelID string, content string, size int, found **discordgo.Message) func() (bool, error) {
+	since := time.Now()
+	return func() (bool, error) {
+		message, err := c.findPosted(channelID, since, content, size)
+		if err != nil {
+			return false, err
+		}
+		*found = message
+		return message != nil, nil
+	}
+}
+
+// deadLetter keeps the delivery to be replayed, filling in the guild of the channel and why it failed. It returns the
+// error of the delivery, as a domain.DeadLetteredError once it is kept
+func (c *RetryingClient) deadLetter(delivery domain.FailedDelivery, err error) error {
+	delivery.ID = uuid.New().String()
+	delivery.Error = err.Error()
+	delivery.FailedAt = time.Now()
+	if channel, stateErr := c.session.State.Channel(delivery.ChannelID); stateErr == nil {
+		delivery.GuildID = channel.GuildID
+	} else if channel, restErr := c.session.Channel(delivery.ChannelID); restErr == nil {
+		delivery.GuildID = channel.GuildID
+	}
+	if saveErr := c.deadLetters.Save(delivery); saveErr != nil {
+		log.Printf("err keeping failed %s delivery, it is lost: %v\n", delivery.Kind, saveErr)
+		return err
+	}
+	log.Printf("%s delivery to channel %s failed, kept as %s to be replayed: %v\n", delivery.Kind, delivery.ChannelID, delivery.ID, err)
+	return &domain.DeadLetteredError{DeliveryID: delivery.ID, Err: err}
+}
+
+// editRefused tells the edit of a message failed permanently, like editing a deleted message. Replaying it would fail
+// the same way, so it is not kept in the dead letters
+func editRefused(err error) bool {
+	kind, _ := classify(err)
+	return kind == failurePermanent
+}
+
+// buffer reads the whole file so every attempt can send it, discordgo builds the whole request body in memory anyway
+func buffer(readable io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(readable)
+	if err != nil {
+		return nil, fmt.Errorf("err reading file to send, %w", err)
+	}
+	return data, nil
+}
+
+func toMessage(message *discordgo.Message) discord.Message {
+	sent := discord.Message{ID: message.ID, ChannelID: message.ChannelID, Embeds: toEmbeds(message.Embeds)}
+	if len(message.Attachments) > 0 {
+		sent.AttachmentID = message.Attachments[0].ID
+		sent.AttachmentURL = message.Attachments[0].URL
+	}
+	return sent
+}
+
+func (c *RetryingClient) SendTextMessage(channelID string, message string) error {
+	var found *discordgo.Message
+	err := c.retry("sending text message", func() error {
+		return c.Client.SendTextMessage(channelID, message)
+	}, c.postedChecker(channelID, message, -1, &found))
+	if err != nil {
+		return c.deadLetter(domain.FailedDelivery{Kind: domain.DeliveryKindText, ChannelID: channelID, Content: message}, err)
+	}
+	return nil
+}
+
+func (c *RetryingClient) SendFileMessage(channelID string, name, contentType string, readable io.Reader) (discord.Message, error) {
+	return c.SendFileReply(channelID, "", "", name, contentType, readable)
+}
+
+func (c *RetryingClient) SendFileReply(channelID string, replyToID string, content string, name, contentType string, readable io.Reader) (discord.Message, error) {
+	data, err := buffer(readable)
+	if err != nil {
+		return discord.Message{}, err
+	}
+	var sent discord.Message
+	var found *discordgo.Message
+	err = c.retry("sending file message", func() (err error) {
+		sent, err = c.Client.SendFileReply(channelID, replyToID, content, name, contentType, bytes.NewReader(data))
+		return err
+	}, c.postedChecker(channelID, content, len(data), &found))
+	if err != nil {
+		return discord.Message{}, c.deadLetter(domain.FailedDelivery{Kind: domain.DeliveryKindFile, ChannelID: channelID, MessageID: replyToID, Content: content, FileName: name, ContentType: contentType, File: data}, err)
+	}
+	if found != nil {
+		return toMessage(found), nil
+	}
+	return sent, nil
+}
+
+func (c *RetryingClient) SendVoiceMessage(channelID string, name string, readable io.Reader, voice discord.VoiceMessage) (discord.Message, error) {
+	data, err := buffer(readable)
+	if err != nil {
+		return discord.Message{}, err
+	}
+	var sent discord.Message
+	var found *discordgo.Message
+	err = c.retry("sending voice message", func() (err error) {
+		sent, err = c.Client.SendVoiceMessage(channelID, name, bytes.NewReader(data), voice)
+		return err
+	}, c.postedChecker(channelID, "", len(data), &found))
+	if err != nil {
+		return discord.Message{}, c.deadLetter(domain.FailedDelivery{Kind: domain.DeliveryKindVoice, ChannelID: channelID, FileName: name, ContentType: "audio/ogg", File: data, Voice: voice}, err)
+	}
+	if found != nil {
+		return toMessage(found), nil
+	}
+	return sent, nil
+}
+
+func (c *RetryingClient) ReplaceFileMessage(channelID string, messageID string, name, contentType string, readable io.Reader) (discord.Message, error) {
+	data, err := buffer(readable)
+	if err != nil {
+		return discord.Message{}, err
+	}
+	var sent discord.Message
+	err = c.retry("replacing message file", func() (err error) {
+		sent, err = c.Client.ReplaceFileMessage(channelID, messageID, name, contentType, bytes.NewReader(data))
+		return err
+	}, nil)
+	if err != nil && !editRefused(err) {
+		return sent, c.deadLetter(domain.FailedDelivery{Kind: domain.DeliveryKindReplaceFile, ChannelID: channelID, MessageID: messageID, FileName: name, ContentType: contentType, File: data}, err)
+	}
+	return sent, err
+}
+
+func (c *RetryingClient) SetEmbeds(channelID string, messageID string, embeds []discord.MessageEmbed, buttons []discord.Button) error {
+	delivery := domain.FailedDelivery{Kind: domain.DeliveryKindEmbed, ChannelID: channelID, MessageID: messageID, Embeds: embeds, Buttons: buttons}
+	delivery.EmbedImages = make([][]byte, len(embeds))
+	for i, embed := range embeds {
+		if embed.Image == nil {
+			continue
+		}
+		data, err := buffer(embed.Image.Reader)
+		if err != nil {
+			return err
+		}
+		delivery.EmbedImages[i] = data
+	}
+	err := c.retry("setting embeds", func() error {
+		return c.Client.SetEmbeds(channelID, messageID, delivery.EmbedsWithImages(), buttons)
+	}, nil)
+	if err != nil && !editRefused(err) {
+		return c.deadLetter(delivery, err)
+	}
+	return err
+}
+
+func (c *RetryingClient) EditInteraction(token string, message string) error {
+	return c.retry("editing interaction", func() error {
+		return c.Client.EditInteraction(token, message)
+	}, nil)
+}
+
+func (c *RetryingClient) EditInteractionComplex(token string, edit discord.ComplexInteractionEdit) error {
+	return c.retry("editing interaction", func() error {
+		return c.Client.EditInteractionComplex(token, edit)
+	}, nil)
+}
+
+// SendFollowup is only retried when it was rate limited, as there is no channel to check whether a failed attempt was posted
+func (c *RetryingClient) SendFollowup(token string, content string, file discord.File) (discord.Message, error) {
+	data, err := buffer(file.Reader)
+	if err != nil {
+		return discord.Message{}, err
+	}
+	var sent discord.Message
+	err = c.retry("sending followup", func() (err error) {
+		attempt := file
+		attempt.Reader = bytes.NewReader(data)
+		sent, err = c.Client.SendFollowup(token, content, attempt)
+		return err
+	}, func() (bool, error) {
+		return false, errors.New("followups cannot be looked up")
+	})
+	return sent, err
+}
@@ -0,0 +1,311 @@
+package discordgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	domainmocks "github.com/hectorgabucio/taterubot-dc/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func restError(status int, retryAfter string) error {
+	header := http.Header{}
+	if retryAfter != "" {
+		header.Set("Retry-After", retryAfter)
+	}
+	return &discordgo.RESTError{Response: &http.Response{StatusCode: status, Header: header}}
+}
+
+// timeoutError is a network error, like the ones of a connection that timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func Test_classify(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             error
+		expectedFailure failure
+		expectedWait    time.Duration
+	}{
+		{name: "when rate limited by discordgo, wait what it tells", err: &discordgo.RateLimitError{RateLimit: &discordgo.RateLimit{TooManyRequests: &discordgo.TooManyRequests{RetryAfter: 3 * time.Second}}}, expectedFailure: failureRateLimited, expectedWait: 3 * time.Second},
+		{name: "when rate limit error is wrapped, still find it", err: fmt.Errorf("err sending message, %w", &discordgo.RateLimitError{RateLimit: &discordgo.RateLimit{TooManyRequests: &discordgo.TooManyRequests{RetryAfter: time.Second}}}), expectedFailure: failureRateLimited, expectedWait: time.Second},
+		{name: "when 429 has Retry-After, wait its seconds", err: restError(http.StatusTooManyRequests, "1.5"), expectedFailure: failureRateLimited, expectedWait: 1500 * time.Millisecond},
+		{name: "when 429 has no Retry-After, do not tell a wait", err: restError(http.StatusTooManyRequests, ""), expectedFailure: failureRateLimited},
+		{name: "when 429 has an invalid Retry-After, do not tell a wait", err: restError(http.StatusTooManyRequests, "soon"), expectedFailure: failureRateLimited},
+		{name: "when discord fails with 500, it is transient", err: restError(http.StatusInternalServerError, ""), expectedFailure: failureTransient},
+		{name: "when discord is unavailable, wait its Retry-After", err: restError(http.StatusServiceUnavailable, "2"), expectedFailure: failureTransient, expectedWait: 2 * time.Second},
+		{name: "when discord refuses the request, it is permanent", err: restError(http.StatusForbidden, "2"), expectedFailure: failurePermanent},
+		{name: "when request is invalid, it is permanent", err: restError(http.StatusBadRequest, ""), expectedFailure: failurePermanent},
+		{name: "when REST error has no response, it is permanent", err: &discordgo.RESTError{}, expectedFailure: failurePermanent},
+		{name: "when network fails, it is transient", err: fmt.Errorf("err sending message, %w", timeoutError{}), expectedFailure: failureTransient},
+		{name: "when response is cut, it is transient", err: io.ErrUnexpectedEOF, expectedFailure: failureTransient},
+		{name: "when error is unknown, it is permanent", err: errors.New("err reading file"), expectedFailure: failurePermanent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, wait := classify(tt.err)
+			assert.Equal(t, tt.expectedFailure, kind)
+			assert.Equal(t, tt.expectedWait, wait)
+		})
+	}
+}
+
+func TestRetryingClient_retry(t *testing.T) {
+	tests := []struct {
+		name             string
+		errs             []error
+		posted           func() (bool, error)
+		expectedError    bool
+		expectedAttempts int
+		expectedWaits    []time.Duration
+	}{
+		{name: "when attempt succeeds, do not retry", errs: []error{nil}, expectedAttempts: 1},
+		{name: "when attempt fails permanently, do not retry", errs: []error{restError(http.StatusForbidden, "")}, expectedError: true, expectedAttempts: 1},
+		{
+			name:             "when attempt is rate limited, wait what discord asks",
+			errs:             []error{restError(http.StatusTooManyRequests, "3"), nil},
+			expectedAttempts: 2,
+			expectedWaits:    []time.Duration{3 * time.Second},
+		},
+		{
+			name:             "when discord asks to wait longer than the limit, give up",
+			errs:             []error{restError(http.StatusTooManyRequests, "60")},
+			expectedError:    true,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "when attempt keeps failing, back off until running out of attempts",
+			errs:             []error{restError(http.StatusBadGateway, ""), restError(http.StatusBadGateway, ""), restError(http.StatusBadGateway, ""), restError(http.StatusBadGateway, "")},
+			expectedError:    true,
+			expectedAttempts: deliveryAttempts,
+			expectedWaits:    []time.Duration{retryBackoff, 2 * retryBackoff, 4 * retryBackoff},
+		},
+		{
+			name:             "when transient failure did not post, post again",
+			errs:             []error{restError(http.StatusBadGateway, ""), nil},
+			posted:           func() (bool, error) { return false, nil },
+			expectedAttempts: 2,
+			expectedWaits:    []time.Duration{retryBackoff},
+		},
+		{
+			name:             "when transient failure posted anyway, do not post again",
+			errs:             []error{restError(http.StatusBadGateway, "")},
+			posted:           func() (bool, error) { return true, nil },
+			expectedAttempts: 1,
+			expectedWaits:    []time.Duration{retryBackoff},
+		},
+		{
+			name:             "when it cannot be checked if transient failure posted, give up",
+			errs:             []error{restError(http.StatusBadGateway, "")},
+			posted:           func() (bool, error) { return false, errors.New("err 500") },
+			expectedError:    true,
+			expectedAttempts: 1,
+			expectedWaits:    []time.Duration{retryBackoff},
+		},
+		{
+			name:             "when rate limited, post again without checking, as discord did nothing",
+			errs:             []error{restError(http.StatusTooManyRequests, "1"), nil},
+			posted:           func() (bool, error) { return true, nil },
+			expectedAttempts: 2,
+			expectedWaits:    []time.Duration{time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var waits []time.Duration
+			c := &RetryingClient{sleep: func(wait time.Duration) { waits = append(waits, wait) }}
+			attempts := 0
+
+			err := c.retry("sending", func() error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			}, tt.posted)
+
+			assert.Equal(t, tt.expectedError, err != nil)
+			assert.Equal(t, tt.expectedAttempts, attempts)
+			assert.Equal(t, tt.expectedWaits, waits)
+		})
+	}
+}
+
+// roundTripFunc answers the requests of a session without reaching discord
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newTestSession(t *testing.T, messages []*discordgo.Message, status int) *discordgo.Session {
+	session, err := discordgo.New("Bot token")
+	assert.NoError(t, err)
+	session.State.User = &discordgo.User{ID: "bot"}
+	session.Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, err := json.Marshal(messages)
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(body)), Request: req}, nil
+	})}
+	return session
+}
+
+func TestRetryingClient_findPosted(t *testing.T) {
+	since := time.Now()
+	bot := &discordgo.User{ID: "bot"}
+	tests := []struct {
+		name          string
+		messages      []*discordgo.Message
+		status        int
+		content       string
+		size          int
+		expectedID    string
+		expectedError bool
+	}{
+		{
+			name:       "when bot posted the text, find it",
+			messages:   []*discordgo.Message{{ID: "posted", Author: bot, Content: "hi", Timestamp: since}},
+			content:    "hi",
+			size:       -1,
+			expectedID: "posted",
+		},
+		{
+			name:       "when bot posted the file, find it by its size",
+			messages:   []*discordgo.Message{{ID: "other", Author: bot, Timestamp: since, Attachments: []*discordgo.MessageAttachment{{Size: 10}}}, {ID: "posted", Author: bot, Timestamp: since, Attachments: []*discordgo.MessageAttachment{{Size: 42}}}},
+			size:       42,
+			expectedID: "posted",
+		},
+		{
+			name:     "when text was posted with a file, it is not the text",
+			messages: []*discordgo.Message{{ID: "posted", Author: bot, Content: "hi", Timestamp: since, Attachments: []*discordgo.MessageAttachment{{Size: 42}}}},
+			content:  "hi",
+			size:     -1,
+		},
+		{
+			name:     "when someone else posted it, it was not posted",
+			messages: []*discordgo.Message{{ID: "posted", Author: &discordgo.User{ID: "user"}, Content: "hi", Timestamp: since}},
+			content:  "hi",
+			size:     -1,
+		},
+		{
+			name:     "when bot posted it before the first attempt, it was not posted",
+			messages: []*discordgo.Message{{ID: "posted", Author: bot, Content: "hi", Timestamp: since.Add(-time.Minute)}},
+			content:  "hi",
+			size:     -1,
+		},
+		{
+			name:       "when clock of discord is a bit behind, still find it",
+			messages:   []*discordgo.Message{{ID: "posted", Author: bot, Content: "hi", Timestamp: since.Add(-postedClockSkew / 2)}},
+			content:    "hi",
+			size:       -1,
+			expectedID: "posted",
+		},
+		{
+			name:     "when content differs, it was not posted",
+			messages: []*discordgo.Message{{ID: "posted", Author: bot, Content: "hello", Timestamp: since}},
+			content:  "hi",
+			size:     -1,
+		},
+		{
+			name:          "when messages cannot be read, return error",
+			status:        http.StatusForbidden,
+			size:          -1,
+			expectedError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := tt.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			c := &RetryingClient{session: newTestSession(t, tt.messages, status)}
+
+			message, err := c.findPosted("channel", since, tt.content, tt.size)
+
+			assert.Equal(t, tt.expectedError, err != nil)
+			if tt.expectedID == "" {
+				assert.Nil(t, message)
+				return
+			}
+			if assert.NotNil(t, message) {
+				assert.Equal(t, tt.expectedID, message.ID)
+			}
+		})
+	}
+}
+
+func TestRetryingClient_deadLetter(t *testing.T) {
+	tests := []struct {
+		name         string
+		saveErr      error
+		expectedKept bool
+	}{
+		{name: "when delivery is kept, tell its id along the error", expectedKept: true},
+		{name: "when delivery cannot be kept, return the error as it is", saveErr: errors.New("err db")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deadLetters := &domainmocks.DeadLetterRepository{}
+			deadLetters.On("Save", mock.MatchedBy(func(delivery domain.FailedDelivery) bool {
+				return delivery.ID != "" && delivery.Error == "err 500" && delivery.Kind == domain.DeliveryKindText
+			})).Return(tt.saveErr)
+			session := newTestSession(t, nil, http.StatusNotFound)
+			c := &RetryingClient{session: session, deadLetters: deadLetters}
+			failed := errors.New("err 500")
+
+			err := c.deadLetter(domain.FailedDelivery{Kind: domain.DeliveryKindText, ChannelID: "channel", Content: "hi"}, failed)
+
+			assert.ErrorIs(t, err, failed)
+			var deadLettered *domain.DeadLetteredError
+			assert.Equal(t, tt.expectedKept, errors.As(err, &deadLettered))
+			deadLetters.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRetryingClient_SetEmbeds(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedKept bool
+	}{
+		{name: "when embeds are set, keep nothing"},
+		{name: "when message cannot be edited anymore, do not keep it, replaying it would fail the same way", err: restError(http.StatusNotFound, "")},
+		{name: "when discord keeps failing, keep it to be replayed", err: restError(http.StatusBadGateway, ""), expectedKept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &discordmocks.Client{}
+			client.On("SetEmbeds", "channel", "msg", mock.Anything, []discord.Button(nil)).Return(tt.err)
+			deadLetters := &domainmocks.DeadLetterRepository{}
+			deadLetters.On("Save", mock.Anything).Return(nil)
+			c := &RetryingClient{Client: client, session: newTestSession(t, nil, http.StatusNotFound), deadLetters: deadLetters, sleep: func(time.Duration) {}}
+
+			err := c.SetEmbeds("channel", "msg", []discord.MessageEmbed{{Title: "title"}}, nil)
+
+			assert.Equal(t, tt.err != nil, err != nil)
+			var deadLettered *domain.DeadLetteredError
+			assert.Equal(t, tt.expectedKept, errors.As(err, &deadLettered))
+			if tt.expectedKept {
+				deadLetters.AssertNumberOfCalls(t, "Save", 1)
+			} else {
+				deadLetters.AssertNumberOfCalls(t, "Save", 0)
+			}
+		})
+	}
+}
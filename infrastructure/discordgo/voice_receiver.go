@@ -0,0 +1,82 @@
+package discordgo
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+)
+
+const (
+	// voiceReceiveBuffer holds about 20 seconds of a single speaker, 50 packets a second, while the recording catches up
+	voiceReceiveBuffer = 1024
+	// dropLogInterval logs every this many dropped packets, not to flood the logs while the recording is behind
+	dropLogInterval = 500
+)
+
+// voiceReceiver forwards the packets of a voice connection to a buffered channel without ever blocking,
+// so a slow recording never stalls the receive loop of discordgo. Packets that do not fit in the buffer are dropped
+// and packets older than the last one of their speaker are left out, as the ogg writer needs them in order.
+type voiceReceiver struct {
+	packets  chan *discord.Packet
+	received atomic.Int64
+	dropped  atomic.Int64
+	late     atomic.Int64
+	// err is set before closing the packets, so it is visible once they are drained
+	err error
+	// lastSequence of every speaker, only used by the forwarding goroutine
+	lastSequence map[uint32]uint16
+}
+
+func newVoiceReceiver() *voiceReceiver {
+	return &voiceReceiver{
+		packets:      make(chan *discord.Packet, voiceReceiveBuffer),
+		lastSequence: make(map[uint32]uint16),
+	}
+}
+
+func (r *voiceReceiver) forward(packet *discordgo.Packet) {
+	r.received.Add(1)
+	if last, ok := r.lastSequence[packet.SSRC]; ok && int16(packet.Sequence-last) <= 0 {
+		r.late.Add(1)
+		return
+	}
+	r.lastSequence[packet.SSRC] = packet.Sequence
+	select {
+	case r.packets <- &discord.Packet{
+		SSRC:      packet.SSRC,
+		Sequence:  packet.Sequence,
+		Timestamp: packet.Timestamp,
+		Type:      packet.Type,
+		Opus:      packet.Opus,
+		PCM:       packet.PCM,
+	}:
+	default:
+		if dropped := r.dropped.Add(1); dropped%dropLogInterval == 1 {
+			log.Printf("voice receive buffer is full, the recording is falling behind, %d packets dropped so far\n", dropped)
+		}
+	}
+}
+
+func (r *voiceReceiver) stats() discord.ReceiveStats {
+	return discord.ReceiveStats{Received: r.received.Load(), Dropped: r.dropped.Load(), Late: r.late.Load()}
+}
+
+// resetSequences forgets the last packet of every speaker, sequences start over when the connection is established again
+func (r *voiceReceiver) resetSequences() {
+	r.lastSequence = make(map[uint32]uint16)
+}
+
+func (r *voiceReceiver) failure() error {
+	return r.err
+}
+
+// close ends the recording, logging the packets left out of it. err tells why it ended early, nil if it did not
+func (r *voiceReceiver) close(err error) {
+	r.err = err
+	close(r.packets)
+	if stats := r.stats(); stats.Dropped > 0 || stats.Late > 0 {
+		log.Printf("voice receive finished with %d packets received, %d dropped and %d late\n", stats.Received, stats.Dropped, stats.Late)
+	}
+}
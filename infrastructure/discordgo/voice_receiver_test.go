@@ -0,0 +1,141 @@
+package discordgo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/stretchr/testify/assert"
+)
+
+// speaker is an ssrc with the sequences of the packets it sends, in the order they arrive
+type speaker struct {
+	ssrc      uint32
+	sequences []uint16
+}
+
+// consecutive returns count sequences following start
+func consecutive(start uint16, count int) []uint16 {
+	sequences := make([]uint16, count)
+	for i := range sequences {
+		sequences[i] = start + uint16(i)
+	}
+	return sequences
+}
+
+// drain returns the sequences of the packets forwarded so far
+func drain(receiver *voiceReceiver) []uint16 {
+	var sequences []uint16
+	for {
+		select {
+		case packet := <-receiver.packets:
+			sequences = append(sequences, packet.Sequence)
+		default:
+			return sequences
+		}
+	}
+}
+
+func Test_voiceReceiver_forward(t *testing.T) {
+	tests := []struct {
+		name              string
+		speakers          []speaker
+		expectedForwarded int
+		expectedStats     discord.ReceiveStats
+	}{
+		{
+			name:              "when packets arrive in order, forward all of them",
+			speakers:          []speaker{{ssrc: 1, sequences: consecutive(10, 50)}},
+			expectedForwarded: 50,
+			expectedStats:     discord.ReceiveStats{Received: 50},
+		},
+		{
+			name:              "when the buffer is full, drop the packets that do not fit instead of waiting",
+			speakers:          []speaker{{ssrc: 1, sequences: consecutive(0, voiceReceiveBuffer+dropLogInterval+2)}},
+			expectedForwarded: voiceReceiveBuffer,
+			expectedStats:     discord.ReceiveStats{Received: voiceReceiveBuffer + dropLogInterval + 2, Dropped: dropLogInterval + 2},
+		},
+		{
+			name:              "when packets are older than the last one of their speaker, leave them out as late",
+			speakers:          []speaker{{ssrc: 1, sequences: []uint16{10, 12, 11, 12, 13}}},
+			expectedForwarded: 3,
+			expectedStats:     discord.ReceiveStats{Received: 5, Late: 2},
+		},
+		{
+			name:              "when sequences wrap around, packets after the wrap are newer",
+			speakers:          []speaker{{ssrc: 1, sequences: []uint16{65534, 65535, 0, 1, 65535}}},
+			expectedForwarded: 4,
+			expectedStats:     discord.ReceiveStats{Received: 5, Late: 1},
+		},
+		{
+			name:              "when there are several speakers, every one keeps its own order",
+			speakers:          []speaker{{ssrc: 1, sequences: []uint16{100, 101}}, {ssrc: 2, sequences: []uint16{5, 6, 5}}},
+			expectedForwarded: 4,
+			expectedStats:     discord.ReceiveStats{Received: 5, Late: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := newVoiceReceiver()
+
+			for _, s := range tt.speakers {
+				for _, sequence := range s.sequences {
+					receiver.forward(&discordgo.Packet{SSRC: s.ssrc, Sequence: sequence, Opus: []byte{0xF8, 0xFF, 0xFE}})
+				}
+			}
+
+			assert.Equal(t, tt.expectedStats, receiver.stats())
+			assert.Len(t, drain(receiver), tt.expectedForwarded)
+		})
+	}
+}
+
+func Test_voiceReceiver_forward_fullBuffer(t *testing.T) {
+	receiver := newVoiceReceiver()
+	for _, sequence := range consecutive(0, voiceReceiveBuffer+10) {
+		receiver.forward(&discordgo.Packet{SSRC: 1, Sequence: sequence})
+	}
+
+	assert.Equal(t, consecutive(0, voiceReceiveBuffer), drain(receiver), "the first packets are kept, the last ones dropped")
+
+	receiver.forward(&discordgo.Packet{SSRC: 1, Sequence: voiceReceiveBuffer + 10})
+	assert.Equal(t, []uint16{voiceReceiveBuffer + 10}, drain(receiver), "once drained, packets are forwarded again")
+	assert.Equal(t, discord.ReceiveStats{Received: voiceReceiveBuffer + 11, Dropped: 10}, receiver.stats())
+}
+
+func Test_voiceReceiver_resetSequences(t *testing.T) {
+	receiver := newVoiceReceiver()
+	receiver.forward(&discordgo.Packet{SSRC: 1, Sequence: 500})
+
+	receiver.resetSequences()
+	receiver.forward(&discordgo.Packet{SSRC: 1, Sequence: 3})
+
+	assert.Equal(t, []uint16{500, 3}, drain(receiver), "the new connection starts its sequences over")
+	assert.Zero(t, receiver.stats().Late)
+}
+
+func Test_voiceReceiver_close(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "when recording is done, close without failure"},
+		{name: "when connection was lost, keep why", err: errors.New("voice connection stopped receiving")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := newVoiceReceiver()
+			receiver.forward(&discordgo.Packet{SSRC: 1, Sequence: 1})
+
+			receiver.close(tt.err)
+
+			packet, ok := <-receiver.packets
+			assert.True(t, ok, "packets forwarded before closing are still there")
+			assert.Equal(t, uint16(1), packet.Sequence)
+			_, ok = <-receiver.packets
+			assert.False(t, ok)
+			assert.Equal(t, tt.err, receiver.failure())
+		})
+	}
+}
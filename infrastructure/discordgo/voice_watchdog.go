@@ -0,0 +1,167 @@
+package discordgo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// watchdogInterval is how often the voice connection is checked while recording
+	watchdogInterval = 2 * time.Second
+	// readyGrace lets discordgo reconnect on its own first, its first attempt takes up to 11 seconds
+	readyGrace        = 15 * time.Second
+	reconnectAttempts = 3
+)
+
+// errRecordingDone stops reconnecting when the recording ends meanwhile
+var errRecordingDone = errors.New("recording is done")
+
+// voiceHandle is the Internals of the voice connections, it points to the discordgo connection in use, which changes
+// when the watchdog reconnects
+type voiceHandle struct {
+	mu   sync.RWMutex
+	conn *discordgo.VoiceConnection
+}
+
+func newVoiceHandle(conn *discordgo.VoiceConnection) *voiceHandle {
+	return &voiceHandle{conn: conn}
+}
+
+func (h *voiceHandle) get() *discordgo.VoiceConnection {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.conn
+}
+
+func (h *voiceHandle) set(conn *discordgo.VoiceConnection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conn = conn
+}
+
+// voiceWatchdog keeps the voice connections of the recordings alive. It joins, disconnects and waits through its
+// functions, so tests replace them instead of reaching discord and waiting for real.
+type voiceWatchdog struct {
+	join       func(guildID, channelID string, mute, deaf bool) (*discordgo.VoiceConnection, error)
+	disconnect func(conn *discordgo.VoiceConnection)
+	// newTicker returns the ticks of the checks and the function that stops them
+	newTicker func(d time.Duration) (<-chan time.Time, func())
+	after     func(d time.Duration) <-chan time.Time
+}
+
+func newVoiceWatchdog(session *discordgo.Session) *voiceWatchdog {
+	return &voiceWatchdog{
+		join:       session.ChannelVoiceJoin,
+		disconnect: disconnectVoice,
+		newTicker: func(d time.Duration) (<-chan time.Time, func()) {
+			ticker := time.NewTicker(d)
+			return ticker.C, ticker.Stop
+		},
+		after: time.After,
+	}
+}
+
+// watchVoice forwards the packets of the connection until done, joining the channel again when the connection stops
+// receiving or is not ready for a while, which is how discordgo reports its heartbeat and udp failures. Silence is not
+// taken into account, discord sends nothing while nobody speaks. When reconnecting fails the receiver is closed with
+// the error, so the recording is finished with what was captured.
+func (w *voiceWatchdog) watchVoice(handle *voiceHandle, receiver *voiceReceiver, guildID, channelID string, mute, deaf bool, done chan bool) {
+	ticks, stop := w.newTicker(watchdogInterval)
+	defer stop()
+	conn := handle.get()
+	var unreadySince time.Time
+	for {
+		var stale error
+		select {
+		case packet, ok := <-opusRecv(conn):
+			if !ok {
+				stale = errors.New("voice connection stopped receiving")
+				break
+			}
+			receiver.forward(packet)
+			continue
+		case <-done:
+			receiver.close(nil)
+			w.disconnect(conn)
+			return
+		case now := <-ticks:
+			if isReady(conn) {
+				unreadySince = time.Time{}
+			} else if unreadySince.IsZero() {
+				unreadySince = now
+			}
+			if unreadySince.IsZero() || now.Sub(unreadySince) <= readyGrace {
+				continue
+			}
+			stale = fmt.Errorf("voice connection not ready for %s", now.Sub(unreadySince).Round(time.Second))
+		}
+
+		log.Printf("voice connection of guild %s is stale, reconnecting: %v\n", guildID, stale)
+		reconnected, err := w.reconnectVoice(conn, guildID, channelID, mute, deaf, done)
+		if errors.Is(err, errRecordingDone) {
+			receiver.close(nil)
+			w.disconnect(conn)
+			return
+		}
+		if err != nil {
+			log.Printf("giving up on the voice connection of guild %s: %v\n", guildID, err)
+			receiver.close(fmt.Errorf("%v, %w", stale, err))
+			w.disconnect(conn)
+			return
+		}
+		log.Printf("voice connection of guild %s reconnected\n", guildID)
+		conn = reconnected
+		handle.set(reconnected)
+		receiver.resetSequences()
+		unreadySince = time.Time{}
+	}
+}
+
+// reconnectVoice closes the connection and joins the channel again, as discordgo does on its own
+func (w *voiceWatchdog) reconnectVoice(conn *discordgo.VoiceConnection, guildID, channelID string, mute, deaf bool, done chan bool) (*discordgo.VoiceConnection, error) {
+	wait := time.Second
+	var err error
+	for attempt := 1; attempt <= reconnectAttempts; attempt++ {
+		conn.Close()
+		var reconnected *discordgo.VoiceConnection
+		reconnected, err = w.join(guildID, channelID, mute, deaf)
+		if err == nil {
+			return reconnected, nil
+		}
+		log.Printf("err reconnecting to voice channel, attempt %d of %d: %v\n", attempt, reconnectAttempts, err)
+		if attempt == reconnectAttempts {
+			break
+		}
+		select {
+		case <-done:
+			return nil, errRecordingDone
+		case <-w.after(wait):
+		}
+		wait *= 2
+	}
+	return nil, fmt.Errorf("err reconnecting to voice channel after %d attempts, %w", reconnectAttempts, err)
+}
+
+// opusRecv is nil until the connection is established, receiving from it blocks until then
+func opusRecv(conn *discordgo.VoiceConnection) chan *discordgo.Packet {
+	conn.RLock()
+	defer conn.RUnlock()
+	return conn.OpusRecv
+}
+
+func isReady(conn *discordgo.VoiceConnection) bool {
+	conn.RLock()
+	defer conn.RUnlock()
+	return conn.Ready
+}
+
+func disconnectVoice(conn *discordgo.VoiceConnection) {
+	if err := conn.Disconnect(); err != nil {
+		log.Printf("err disconnecting discord voice conn, %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package discordgo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+// testWatchdog is a watchdog whose ticks are sent by hand, whose waits are over at once unless they wait forever, and whose joins fail with
+// joinErrs, one per attempt, before succeeding with reconnected
+type testWatchdog struct {
+	*voiceWatchdog
+	ticks        chan time.Time
+	joins        chan struct{}
+	disconnected chan *discordgo.VoiceConnection
+	waits        []time.Duration
+}
+
+func newTestWatchdog(joinErrs []error, reconnected *discordgo.VoiceConnection, waitForever bool) *testWatchdog {
+	w := &testWatchdog{
+		ticks:        make(chan time.Time),
+		joins:        make(chan struct{}, 10),
+		disconnected: make(chan *discordgo.VoiceConnection, 1),
+	}
+	attempt := 0
+	w.voiceWatchdog = &voiceWatchdog{
+		join: func(guildID, channelID string, mute, deaf bool) (*discordgo.VoiceConnection, error) {
+			w.joins <- struct{}{}
+			attempt++
+			if attempt <= len(joinErrs) {
+				return nil, joinErrs[attempt-1]
+			}
+			return reconnected, nil
+		},
+		disconnect: func(conn *discordgo.VoiceConnection) {
+			w.disconnected <- conn
+		},
+		newTicker: func(time.Duration) (<-chan time.Time, func()) {
+			return w.ticks, func() {}
+		},
+		after: func(d time.Duration) <-chan time.Time {
+			w.waits = append(w.waits, d)
+			over := make(chan time.Time, 1)
+			if !waitForever {
+				over <- time.Time{}
+			}
+			return over
+		},
+	}
+	return w
+}
+
+// waitClosed drains the receiver until the watchdog closes it
+func waitClosed(t *testing.T, receiver *voiceReceiver) {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-receiver.packets:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("the watchdog did not close the receiver")
+		}
+	}
+}
+
+func TestVoiceWatchdog_watchVoice(t *testing.T) {
+	errJoin := errors.New("err timeout waiting for voice")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		// conn is the connection watched at first
+		conn *discordgo.VoiceConnection
+		// ticks are sent after start, the ones that reconnect go last
+		ticks []time.Duration
+		// packetBefore is received in the first connection before the ticks
+		packetBefore      bool
+		joinErrs          []error
+		waitForever       bool
+		expectedJoins     int
+		expectedWaits     []time.Duration
+		expectedReconnect bool
+		expectedError     string
+	}{
+		{
+			name:  "when connection stays ready, never reconnect",
+			conn:  &discordgo.VoiceConnection{Ready: true},
+			ticks: []time.Duration{0, watchdogInterval, 30 * time.Second},
+		},
+		{
+			name:  "when connection is not ready for the grace period, let discordgo reconnect on its own",
+			conn:  &discordgo.VoiceConnection{},
+			ticks: []time.Duration{0, 10 * time.Second, readyGrace},
+		},
+		{
+			name:              "when connection is not ready for longer than the grace period, reconnect",
+			conn:              &discordgo.VoiceConnection{OpusRecv: make(chan *discordgo.Packet)},
+			packetBefore:      true,
+			ticks:             []time.Duration{0, 10 * time.Second, readyGrace + watchdogInterval},
+			expectedJoins:     1,
+			expectedReconnect: true,
+		},
+		{
+			name:              "when connection stops receiving, reconnect at once",
+			conn:              &discordgo.VoiceConnection{Ready: true, OpusRecv: closedRecv()},
+			expectedJoins:     1,
+			expectedReconnect: true,
+		},
+		{
+			name:              "when joining fails, try again backing off until it succeeds",
+			conn:              &discordgo.VoiceConnection{Ready: true, OpusRecv: closedRecv()},
+			joinErrs:          []error{errJoin, errJoin},
+			expectedJoins:     3,
+			expectedWaits:     []time.Duration{time.Second, 2 * time.Second},
+			expectedReconnect: true,
+		},
+		{
+			name:          "when every attempt fails, give up closing the receiver with why",
+			conn:          &discordgo.VoiceConnection{},
+			ticks:         []time.Duration{0, readyGrace + watchdogInterval},
+			joinErrs:      []error{errJoin, errJoin, errJoin},
+			expectedJoins: 3,
+			expectedWaits: []time.Duration{time.Second, 2 * time.Second},
+			expectedError: "voice connection not ready for 17s, err reconnecting to voice channel after 3 attempts, err timeout waiting for voice",
+		},
+		{
+			name:          "when recording is done while waiting to try again, stop without failure",
+			conn:          &discordgo.VoiceConnection{Ready: true, OpusRecv: closedRecv()},
+			joinErrs:      []error{errJoin},
+			waitForever:   true,
+			expectedJoins: 1,
+			expectedWaits: []time.Duration{time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reconnected := &discordgo.VoiceConnection{Ready: true, OpusRecv: make(chan *discordgo.Packet)}
+			w := newTestWatchdog(tt.joinErrs, reconnected, tt.waitForever)
+			handle := newVoiceHandle(tt.conn)
+			receiver := newVoiceReceiver()
+			done := make(chan bool)
+
+			go w.watchVoice(handle, receiver, "guild", "channel", true, false, done)
+
+			if tt.packetBefore {
+				tt.conn.OpusRecv <- &discordgo.Packet{SSRC: 1, Sequence: 500}
+			}
+			for _, tick := range tt.ticks {
+				w.ticks <- start.Add(tick)
+			}
+			for i := 0; i < tt.expectedJoins; i++ {
+				<-w.joins
+			}
+			if tt.expectedReconnect {
+				// sequences start over in the new connection
+				reconnected.OpusRecv <- &discordgo.Packet{SSRC: 1, Sequence: 3}
+			}
+			close(done)
+			waitClosed(t, receiver)
+
+			assert.Len(t, w.joins, 0, "no more joins than expected")
+			assert.Equal(t, tt.expectedWaits, w.waits)
+			if tt.expectedError != "" {
+				assert.EqualError(t, receiver.failure(), tt.expectedError)
+			} else {
+				assert.NoError(t, receiver.failure())
+			}
+			// the connection in use when the recording ends is the one left
+			expectedConn := tt.conn
+			if tt.expectedReconnect {
+				expectedConn = reconnected
+			}
+			assert.Same(t, expectedConn, <-w.disconnected)
+			if tt.expectedReconnect {
+				assert.Same(t, reconnected, handle.get(), "the handle points to the new connection")
+				assert.Zero(t, receiver.stats().Late, "the packets of the new connection are not late")
+			}
+		})
+	}
+}
+
+func closedRecv() chan *discordgo.Packet {
+	recv := make(chan *discordgo.Packet)
+	close(recv)
+	return recv
+}
@@ -0,0 +1,35 @@
+package ffmpeg
+
+import (
+	"sync"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+const cueFileName = "start-cue.ogg"
+
+// StartCue renders a short beep with ffmpeg the first time it is needed and keeps its opus frames in memory.
+type StartCue struct {
+	fsRepo domain.FileRepository
+	once   sync.Once
+	frames [][]byte
+	err    error
+}
+
+func NewStartCue(fsRepo domain.FileRepository) *StartCue {
+	return &StartCue{fsRepo: fsRepo}
+}
+
+// Available is true, as the cue is only set up in hosts with ffmpeg
+func (c *StartCue) Available() bool {
+	return true
+}
+
+func (c *StartCue) Frames() ([][]byte, error) {
+	c.once.Do(func() {
+		beep := ffmpeg.Input("sine=frequency=880:duration=0.25", ffmpeg.KwArgs{"f": "lavfi"}).Filter("volume", ffmpeg.Args{"0.3"})
+		c.frames, c.err = encodeOpusFrames(c.fsRepo, beep, cueFileName)
+	})
+	return c.frames, c.err
+}
@@ -0,0 +1,47 @@
+package ffmpeg
+
+import (
+	"fmt"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+// filter is an ffmpeg audio filter and its positional arguments
+type filter struct {
+	name string
+	args ffmpeg.Args
+}
+
+// effects is the registry of the filter chains rendering every voice effect, a new effect only needs its chain here
+var effects = map[domain.VoiceEffect][]filter{
+	domain.VoiceEffectChipmunk: pitchShift(1.5),
+	domain.VoiceEffectDeep:     pitchShift(0.75),
+	// every frequency bin keeps its magnitude with a fixed phase, the classic robotization
+	domain.VoiceEffectRobot: {{name: "afftfilt", args: ffmpeg.Args{"real=hypot(re,im)*sin(0)", "imag=hypot(re,im)*cos(0)", "win_size=512", "overlap=0.75"}}},
+	domain.VoiceEffectEcho:  {{name: "aecho", args: ffmpeg.Args{"0.8", "0.9", "250|500", "0.4|0.2"}}},
+	domain.VoiceEffectFast:  {{name: "atempo", args: ffmpeg.Args{"1.5"}}},
+	// a lower pitch through a telephone band, which also drops most of the timbre that tells voices apart
+	domain.VoiceEffectDisguise: append(pitchShift(0.8),
+		filter{name: "highpass", args: ffmpeg.Args{"f=300"}},
+		filter{name: "lowpass", args: ffmpeg.Args{"f=3000"}},
+	),
+}
+
+// pitchShift plays the audio at a different rate and stretches it back to its length, so only the pitch changes
+func pitchShift(factor float64) []filter {
+	return []filter{
+		{name: "aresample", args: ffmpeg.Args{fmt.Sprint(opusSampleRate)}},
+		{name: "asetrate", args: ffmpeg.Args{fmt.Sprintf("%.0f", opusSampleRate*factor)}},
+		{name: "aresample", args: ffmpeg.Args{fmt.Sprint(opusSampleRate)}},
+		{name: "atempo", args: ffmpeg.Args{fmt.Sprintf("%.6f", 1/factor)}},
+	}
+}
+
+// applyEffect appends the filter chain of the effect to the stream
+func applyEffect(stream *ffmpeg.Stream, effect domain.VoiceEffect) *ffmpeg.Stream {
+	for _, f := range effects[effect] {
+		stream = stream.Filter(f.name, f.args)
+	}
+	return stream
+}
@@ -0,0 +1,195 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+// discord sends stereo opus, always at 48khz
+const (
+	opusSampleRate = 48000
+	opusChannels   = 2
+)
+
+type codec struct {
+	name   string
+	muxer  string
+	extras ffmpeg.KwArgs
+	// passthrough copies a single ogg/opus input without transcoding it
+	passthrough bool
+}
+
+// codecs is the registry of how every supported format is written by ffmpeg
+var codecs = map[domain.AudioFormat]codec{
+	// a page per 20ms packet, as the recordings of the bot, so the in process pipeline can read what ffmpeg writes
+	domain.AudioFormatOpus: {name: "libopus", muxer: "ogg", extras: ffmpeg.KwArgs{"page_duration": 20000}, passthrough: true},
+	domain.AudioFormatMP3:  {name: "libmp3lame", muxer: "mp3"},
+	domain.AudioFormatM4A:  {name: "aac", muxer: "ipod", extras: ffmpeg.KwArgs{"movflags": "+faststart"}},
+	domain.AudioFormatWAV:  {name: "pcm_s16le", muxer: "wav"},
+}
+
+// Available tells if the ffmpeg binary can be found in the PATH
+func Available() bool {
+	_, err := exec.LookPath("ffmpeg")
+	return err == nil
+}
+
+type Encoder struct{}
+
+func NewEncoder() *Encoder {
+	return &Encoder{}
+}
+
+func (e *Encoder) Supports(format domain.AudioFormat) bool {
+	_, ok := codecs[format]
+	return ok
+}
+
+func (e *Encoder) SupportsEffect(effect domain.VoiceEffect) bool {
+	_, ok := effects[effect]
+	return ok || effect == domain.VoiceEffectNone
+}
+
+func (e *Encoder) Encode(inputs []string, output string, options domain.EncodeOptions) error {
+	c, ok := codecs[options.Format]
+	if !ok {
+		return fmt.Errorf("unsupported audio format %s", options.Format)
+	}
+	if !e.SupportsEffect(options.Effect) {
+		return fmt.Errorf("unsupported voice effect %s", options.Effect)
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("nothing to encode to %s", output)
+	}
+	if c.passthrough && options.Effect == domain.VoiceEffectNone && len(inputs) == 1 && inputs[0] == output {
+		return nil
+	}
+
+	// ffmpeg cannot read and write the same file, so write next to it and swap them after
+	target := output
+	for _, input := range inputs {
+		if input == output {
+			target = output + ".encoding"
+		}
+	}
+
+	var stream *ffmpeg.Stream
+	if len(inputs) == 1 {
+		stream = ffmpeg.Input(inputs[0]).Audio()
+	} else {
+		streams := make([]*ffmpeg.Stream, len(inputs))
+		for i, input := range inputs {
+			streams[i] = ffmpeg.Input(input).Audio()
+		}
+		stream = ffmpeg.Concat(streams, ffmpeg.KwArgs{"v": 0, "a": 1})
+	}
+	stream = applyEffect(stream, options.Effect)
+	args := outputArgs(c, options, len(inputs) == 1)
+
+	if err := stream.Output(target, args).OverWriteOutput().Run(); err != nil {
+		return fmt.Errorf("failed to encode %s, %w", options.Format, err)
+	}
+	if target != output {
+		if err := os.Rename(target, output); err != nil {
+			return fmt.Errorf("err replacing encoded file, %w", err)
+		}
+	}
+	return nil
+}
+
+// outputArgs returns the ffmpeg arguments to write the format, single inputs without effects are copied when the codec allows it
+func outputArgs(c codec, options domain.EncodeOptions, single bool) ffmpeg.KwArgs {
+	args := ffmpeg.KwArgs{"f": c.muxer}
+	for k, v := range c.extras {
+		args[k] = v
+	}
+	if c.passthrough && single && options.Effect == domain.VoiceEffectNone {
+		args["acodec"] = "copy"
+		return args
+	}
+	args["acodec"] = c.name
+	if !options.Format.Lossless() && options.Bitrate > 0 {
+		args["b:a"] = fmt.Sprintf("%dk", options.Bitrate)
+	}
+	return args
+}
+
+// Stream pipes the packets as an ogg stream to an ffmpeg process that encodes them while the recording goes on
+func (e *Encoder) Stream(output string, options domain.EncodeOptions) (domain.AudioStream, error) {
+	c, ok := codecs[options.Format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported audio format %s", options.Format)
+	}
+	if !e.SupportsEffect(options.Effect) {
+		return nil, fmt.Errorf("unsupported voice effect %s", options.Effect)
+	}
+	reader, writer := io.Pipe()
+	s := &pipeStream{pipe: writer, done: make(chan error, 1)}
+	go func() {
+		err := applyEffect(ffmpeg.Input("pipe:", ffmpeg.KwArgs{"f": "ogg"}).Audio(), options.Effect).
+			Output(output, outputArgs(c, options, true)).
+			OverWriteOutput().WithInput(reader).Run()
+		// if ffmpeg stops early the next write fails instead of blocking forever
+		if err != nil {
+			reader.CloseWithError(fmt.Errorf("ffmpeg stopped, %w", err))
+		} else {
+			reader.CloseWithError(errors.New("ffmpeg stopped"))
+		}
+		s.done <- err
+	}()
+	// the writer sends the ogg headers right away, so ffmpeg must be reading already
+	ogg, err := oggwriter.NewWith(writer, opusSampleRate, opusChannels)
+	if err != nil {
+		if err := writer.Close(); err != nil {
+			log.Println("err closing ffmpeg input", err)
+		}
+		<-s.done
+		return nil, fmt.Errorf("err starting ffmpeg stream, %w", err)
+	}
+	s.ogg = ogg
+	return s, nil
+}
+
+type pipeStream struct {
+	pipe *io.PipeWriter
+	ogg  *oggwriter.OggWriter
+	done chan error
+}
+
+func (s *pipeStream) Write(packet *discord.Packet) error {
+	err := s.ogg.WriteRTP(&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    0x78,
+			SequenceNumber: packet.Sequence,
+			Timestamp:      packet.Timestamp,
+			SSRC:           packet.SSRC,
+		},
+		Payload: packet.Opus,
+	})
+	if err != nil {
+		return fmt.Errorf("err piping packet to ffmpeg, %w", err)
+	}
+	return nil
+}
+
+// Close ends the input of ffmpeg and waits for it to finish the file
+func (s *pipeStream) Close() error {
+	if err := s.pipe.Close(); err != nil {
+		return fmt.Errorf("err closing ffmpeg input, %w", err)
+	}
+	if err := <-s.done; err != nil {
+		return fmt.Errorf("failed to encode stream, %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/pion/webrtc/v3/pkg/media/oggreader"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+type OpusTranscoder struct {
+	fsRepo domain.FileRepository
+}
+
+func NewOpusTranscoder(fsRepo domain.FileRepository) *OpusTranscoder {
+	return &OpusTranscoder{fsRepo: fsRepo}
+}
+
+func (t *OpusTranscoder) ToOpusFrames(fileName string) ([][]byte, error) {
+	return encodeOpusFrames(t.fsRepo, ffmpeg.Input(t.fsRepo.GetFullPath(fileName)).Audio(), fmt.Sprintf("%s-frames.ogg", fileName))
+}
+
+// encodeOpusFrames encodes the input as stereo 48khz opus and reads back its frames
+func encodeOpusFrames(fsRepo domain.FileRepository, input *ffmpeg.Stream, oggFileName string) ([][]byte, error) {
+	path := fsRepo.GetFullPath(oggFileName)
+	defer fsRepo.DeleteAll(oggFileName)
+	// one opus packet per ogg page, so every page payload is a frame ready to be sent
+	if err := input.
+		Output(path, ffmpeg.KwArgs{"ac": 2, "ar": 48000, "acodec": "libopus", "b:a": "64k", "frame_duration": 20, "page_duration": 20000}).
+		OverWriteOutput().Run(); err != nil {
+		return nil, fmt.Errorf("failed to encode opus, %w", err)
+	}
+	f, err := fsRepo.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("err opening opus file, %w", err)
+	}
+	defer func(f *os.File) {
+		if err := f.Close(); err != nil {
+			log.Println("err closing opus file", err)
+		}
+	}(f)
+	return readOpusFrames(f)
+}
+
+func readOpusFrames(r io.Reader) ([][]byte, error) {
+	reader, _, err := oggreader.NewWith(r)
+	if err != nil {
+		return nil, fmt.Errorf("err reading ogg header, %w", err)
+	}
+	var frames [][]byte
+	for {
+		payload, _, err := reader.ParseNextPage()
+		if errors.Is(err, io.EOF) {
+			return frames, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("err parsing ogg page, %w", err)
+		}
+		if bytes.HasPrefix(payload, []byte("OpusTags")) {
+			continue
+		}
+		frames = append(frames, payload)
+	}
+}
@@ -0,0 +1,61 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+// Prober asks ffprobe, which ships along ffmpeg and reads every format
+type Prober struct {
+	available bool
+}
+
+func NewProber() *Prober {
+	_, err := exec.LookPath("ffprobe")
+	return &Prober{available: err == nil}
+}
+
+func (p *Prober) Supports(format domain.AudioFormat) bool {
+	return p.available
+}
+
+// probeOutput holds the fields of the json ffprobe writes that are used, numbers other than channels come as strings
+type probeOutput struct {
+	Streams []struct {
+		CodecType  string `json:"codec_type"`
+		SampleRate string `json:"sample_rate"`
+		Channels   int    `json:"channels"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+		Size     string `json:"size"`
+	} `json:"format"`
+}
+
+func (p *Prober) Probe(path string, format domain.AudioFormat) (domain.MediaProperties, error) {
+	out, err := ffmpeg.Probe(path)
+	if err != nil {
+		return domain.MediaProperties{}, fmt.Errorf("failed to probe %s, %w", format, err)
+	}
+	var probe probeOutput
+	if err := json.Unmarshal([]byte(out), &probe); err != nil {
+		return domain.MediaProperties{}, fmt.Errorf("err reading ffprobe output, %w", err)
+	}
+	for _, stream := range probe.Streams {
+		if stream.CodecType != "audio" {
+			continue
+		}
+		// values ffprobe does not know are left as 0
+		duration, _ := strconv.ParseFloat(probe.Format.Duration, 64)
+		size, _ := strconv.ParseInt(probe.Format.Size, 10, 64)
+		sampleRate, _ := strconv.Atoi(stream.SampleRate)
+		return domain.NewMediaProperties(duration, sampleRate, stream.Channels, size), nil
+	}
+	return domain.MediaProperties{}, errors.New("no audio stream found")
+}
@@ -0,0 +1,81 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+// taggedFormat is how ffmpeg writes the tags of a format
+type taggedFormat struct {
+	muxer string
+	// cover tells if the muxer can embed cover art as an attached picture
+	cover  bool
+	extras ffmpeg.KwArgs
+}
+
+// taggedFormats is the registry of the formats ffmpeg tags, wav only keeps the text tags in its INFO chunk
+var taggedFormats = map[domain.AudioFormat]taggedFormat{
+	// id3v2.3 is the version most players read
+	domain.AudioFormatMP3: {muxer: "mp3", cover: true, extras: ffmpeg.KwArgs{"id3v2_version": "3"}},
+	domain.AudioFormatM4A: {muxer: "ipod", cover: true, extras: ffmpeg.KwArgs{"movflags": "+faststart"}},
+	domain.AudioFormatWAV: {muxer: "wav"},
+}
+
+// Tagger copies the audio to a new file with the tags, without transcoding it
+type Tagger struct{}
+
+func NewTagger() *Tagger {
+	return &Tagger{}
+}
+
+func (t *Tagger) Supports(format domain.AudioFormat) bool {
+	_, ok := taggedFormats[format]
+	return ok
+}
+
+func (t *Tagger) Tag(path string, format domain.AudioFormat, metadata domain.AudioMetadata) error {
+	f, ok := taggedFormats[format]
+	if !ok {
+		return fmt.Errorf("unsupported audio format %s", format)
+	}
+	args := ffmpeg.KwArgs{
+		"f":            f.muxer,
+		"c":            "copy",
+		"map_metadata": "-1",
+	}
+	for k, v := range f.extras {
+		args[k] = v
+	}
+	var tags []string
+	for _, tag := range [][2]string{{"artist", metadata.Artist}, {"album", metadata.Album}, {"title", metadata.Title}} {
+		if tag[1] != "" {
+			tags = append(tags, fmt.Sprintf("%s=%s", tag[0], tag[1]))
+		}
+	}
+	if !metadata.Date.IsZero() {
+		tags = append(tags, fmt.Sprintf("date=%s", metadata.Date.Format("2006-01-02")))
+	}
+	if len(tags) > 0 {
+		args["metadata"] = tags
+	}
+
+	streams := []*ffmpeg.Stream{ffmpeg.Input(path).Audio()}
+	if f.cover && metadata.CoverPath != "" {
+		streams = append(streams, ffmpeg.Input(metadata.CoverPath).Video())
+		args["disposition:v"] = "attached_pic"
+		args["metadata:s:v"] = []string{"title=Album cover", "comment=Cover (front)"}
+	}
+
+	target := path + ".tagging"
+	if err := ffmpeg.Output(streams, target, args).OverWriteOutput().Run(); err != nil {
+		_ = os.Remove(target)
+		return fmt.Errorf("failed to tag %s, %w", format, err)
+	}
+	if err := os.Rename(target, path); err != nil {
+		return fmt.Errorf("err replacing tagged file, %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+// layout of the audiogram: the avatar on top, the name under it and the waveform at the bottom
+const (
+	videoWidth       = 1280
+	videoHeight      = 720
+	videoFrameRate   = 25
+	videoBackground  = 0x23272A
+	avatarSize       = 256
+	avatarTop        = 96
+	titleFontSize    = 56
+	titleTop         = 384
+	waveformHeight   = 200
+	waveformTop      = 480
+	defaultWaveColor = 0xFFFFFF
+)
+
+// drawtextEscaper escapes the text as an option value, ffmpeg-go escapes it again for the filter graph
+var drawtextEscaper = strings.NewReplacer(`\`, `\\`, `:`, `\:`, `'`, `\'`)
+
+type VideoRenderer struct{}
+
+func NewVideoRenderer() *VideoRenderer {
+	return &VideoRenderer{}
+}
+
+func (r *VideoRenderer) Available() bool {
+	return Available()
+}
+
+// RenderAudiogram draws every frame over a plain background, the video ends with the audio
+func (r *VideoRenderer) RenderAudiogram(audiogram domain.Audiogram, output string) error {
+	audio := ffmpeg.Input(audiogram.AudioPath).Audio()
+	video := ffmpeg.Input(fmt.Sprintf("color=c=0x%06X:s=%dx%d:r=%d", videoBackground, videoWidth, videoHeight, videoFrameRate), ffmpeg.KwArgs{"f": "lavfi"})
+	if audiogram.AvatarPath != "" {
+		avatar := ffmpeg.Input(audiogram.AvatarPath, ffmpeg.KwArgs{"loop": "1", "framerate": fmt.Sprint(videoFrameRate)}).
+			Filter("scale", ffmpeg.Args{fmt.Sprintf("%d:%d", avatarSize, avatarSize)})
+		video = video.Overlay(avatar, "", ffmpeg.KwArgs{"x": "(W-w)/2", "y": avatarTop})
+	}
+	if audiogram.Title != "" {
+		video = video.Filter("drawtext", nil, ffmpeg.KwArgs{
+			"text": drawtextEscaper.Replace(audiogram.Title),
+			// names are shown as they are, without expanding the sequences drawtext understands
+			"expansion": "none",
+			"fontcolor": "white",
+			"fontsize":  titleFontSize,
+			"x":         "(w-text_w)/2",
+			"y":         titleTop,
+		})
+	}
+	color := audiogram.Color
+	if color == 0 {
+		color = defaultWaveColor
+	}
+	waves := audio.Filter("showwaves", nil, ffmpeg.KwArgs{
+		"s":      fmt.Sprintf("%dx%d", videoWidth, waveformHeight),
+		"mode":   "cline",
+		"colors": fmt.Sprintf("0x%06X", color),
+		"rate":   videoFrameRate,
+	})
+	// the background and the avatar never end, the waveform ends the video when the audio does
+	video = video.Overlay(waves, "endall", ffmpeg.KwArgs{"x": 0, "y": waveformTop, "shortest": 1})
+
+	args := ffmpeg.KwArgs{
+		"f":        "mp4",
+		"c:v":      "libx264",
+		"pix_fmt":  "yuv420p",
+		"b:v":      fmt.Sprintf("%dk", audiogram.VideoBitrate),
+		"maxrate":  fmt.Sprintf("%dk", audiogram.VideoBitrate),
+		"bufsize":  fmt.Sprintf("%dk", 2*audiogram.VideoBitrate),
+		"c:a":      "aac",
+		"b:a":      fmt.Sprintf("%dk", audiogram.AudioBitrate),
+		"shortest": "",
+		"movflags": "+faststart",
+	}
+	err := ffmpeg.Output([]*ffmpeg.Stream{video, audio}, output, args).OverWriteOutput().Run()
+	if err != nil {
+		_ = os.Remove(output)
+		return fmt.Errorf("failed to render audiogram, %w", err)
+	}
+	return nil
+}
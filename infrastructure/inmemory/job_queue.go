@@ -0,0 +1,238 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/kit/job"
+)
+
+// jobStatsInterval is how often the metrics of the queue are logged, as long as there were jobs meanwhile
+const jobStatsInterval = time.Minute
+
+var errJobQueueClosed = errors.New("job queue is closed")
+
+type queuedJob struct {
+	job.Job
+	queuedAt time.Time
+}
+
+// guildJobs are the jobs of a guild waiting in a priority
+type guildJobs struct {
+	guildID string
+	jobs    []queuedJob
+}
+
+// guildTurns queues the jobs of a priority so that guilds take turns, a guild goes back to the end after each job
+type guildTurns struct {
+	turns   []*guildJobs
+	byGuild map[string]*guildJobs
+	depth   int
+}
+
+func (t *guildTurns) push(j queuedJob) {
+	guild, ok := t.byGuild[j.GuildID]
+	if !ok {
+		guild = &guildJobs{guildID: j.GuildID}
+		t.byGuild[j.GuildID] = guild
+		t.turns = append(t.turns, guild)
+	}
+	guild.jobs = append(guild.jobs, j)
+	t.depth++
+}
+
+func (t *guildTurns) pop() queuedJob {
+	guild := t.turns[0]
+	j := guild.jobs[0]
+	guild.jobs = guild.jobs[1:]
+	t.turns = t.turns[1:]
+	if len(guild.jobs) > 0 {
+		t.turns = append(t.turns, guild)
+	} else {
+		delete(t.byGuild, guild.guildID)
+	}
+	t.depth--
+	return j
+}
+
+// JobQueue is an in-memory implementation of the job.Queue, run by a fixed pool of workers.
+type JobQueue struct {
+	mu     sync.Mutex
+	ready  *sync.Cond
+	queues map[job.Priority]*guildTurns
+	stats  map[job.Priority]job.Stats
+	// slots bound the queued jobs, submitters wait for one while the queue is full
+	slots   chan struct{}
+	closing chan struct{}
+	closed  bool
+	// active tells if jobs were submitted since the metrics were last logged
+	active  bool
+	workers sync.WaitGroup
+}
+
+// NewJobQueue starts the workers of a queue holding up to size jobs waiting for them.
+func NewJobQueue(workers int, size int) *JobQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	if size < 1 {
+		size = 1
+	}
+	q := &JobQueue{
+		queues:  make(map[job.Priority]*guildTurns),
+		stats:   make(map[job.Priority]job.Stats),
+		slots:   make(chan struct{}, size),
+		closing: make(chan struct{}),
+	}
+	q.ready = sync.NewCond(&q.mu)
+	for _, priority := range job.Priorities() {
+		q.queues[priority] = &guildTurns{byGuild: make(map[string]*guildJobs)}
+	}
+	q.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	go q.logStats()
+	return q
+}
+
+// Submit implements the job.Queue interface.
+func (q *JobQueue) Submit(ctx context.Context, j job.Job) error {
+	turns, ok := q.queues[j.Priority]
+	if !ok {
+		return fmt.Errorf("unknown job priority %d", j.Priority)
+	}
+	select {
+	case q.slots <- struct{}{}:
+	case <-q.closing:
+		return errJobQueueClosed
+	case <-ctx.Done():
+		return fmt.Errorf("err waiting for room in the job queue, %w", ctx.Err())
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		<-q.slots
+		return errJobQueueClosed
+	}
+	turns.push(queuedJob{Job: j, queuedAt: time.Now()})
+	q.active = true
+	q.ready.Signal()
+	return nil
+}
+
+// Stats implements the job.Queue interface.
+func (q *JobQueue) Stats() map[job.Priority]job.Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats := make(map[job.Priority]job.Stats, len(q.queues))
+	for priority, turns := range q.queues {
+		s := q.stats[priority]
+		s.Depth = turns.depth
+		stats[priority] = s
+	}
+	return stats
+}
+
+// Close stops taking jobs and waits for the workers to run the ones already queued.
+func (q *JobQueue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.closing)
+	q.ready.Broadcast()
+	q.mu.Unlock()
+	q.workers.Wait()
+	return nil
+}
+
+func (q *JobQueue) work() {
+	defer q.workers.Done()
+	for {
+		j, ok := q.next()
+		if !ok {
+			return
+		}
+		q.run(j)
+	}
+}
+
+// next waits for a job, taking it from the highest priority with jobs, false once the queue is closed and empty
+func (q *JobQueue) next() (job.Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		for _, priority := range job.Priorities() {
+			turns := q.queues[priority]
+			if turns.depth == 0 {
+				continue
+			}
+			j := turns.pop()
+			<-q.slots
+			wait := time.Since(j.queuedAt)
+			s := q.stats[priority]
+			s.Started++
+			s.TotalWait += wait
+			if wait > s.MaxWait {
+				s.MaxWait = wait
+			}
+			q.stats[priority] = s
+			return j.Job, true
+		}
+		if q.closed {
+			return job.Job{}, false
+		}
+		q.ready.Wait()
+	}
+}
+
+func (q *JobQueue) run(j job.Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("job %s of guild %s panicked: %v\n", j.Priority, j.GuildID, r)
+		}
+		q.mu.Lock()
+		s := q.stats[j.Priority]
+		s.Completed++
+		q.stats[j.Priority] = s
+		q.mu.Unlock()
+	}()
+	j.Run()
+}
+
+func (q *JobQueue) logStats() {
+	ticker := time.NewTicker(jobStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.closing:
+			return
+		case <-ticker.C:
+			q.mu.Lock()
+			active := q.active
+			q.active = false
+			q.mu.Unlock()
+			if active {
+				log.Println("jobs:", formatJobStats(q.Stats()))
+			}
+		}
+	}
+}
+
+func formatJobStats(stats map[job.Priority]job.Stats) string {
+	var parts []string
+	for _, priority := range job.Priorities() {
+		s := stats[priority]
+		parts = append(parts, fmt.Sprintf("%s depth=%d done=%d avg wait=%s max wait=%s",
+			priority, s.Depth, s.Completed, s.AverageWait().Round(time.Millisecond), s.MaxWait.Round(time.Millisecond)))
+	}
+	return strings.Join(parts, "; ")
+}
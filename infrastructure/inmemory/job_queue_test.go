@@ -0,0 +1,205 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/kit/job"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitTimeout bounds how long the tests wait for the workers, so a broken queue fails instead of hanging
+const waitTimeout = 5 * time.Second
+
+// blockWorker keeps the only worker of the queue busy until the returned function is called, so the jobs
+// submitted meanwhile are all queued before any of them runs
+func blockWorker(t *testing.T, q *JobQueue) func() {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	err := q.Submit(context.Background(), job.Job{GuildID: "blocker", Priority: job.PriorityUpload, Run: func() {
+		close(started)
+		<-release
+	}})
+	assert.NoError(t, err)
+	select {
+	case <-started:
+	case <-time.After(waitTimeout):
+		t.Fatal("worker never started the blocking job")
+	}
+	return func() { close(release) }
+}
+
+// runOrder records the names of the jobs in the order they run
+type runOrder struct {
+	mu    sync.Mutex
+	names []string
+	wg    sync.WaitGroup
+}
+
+func (o *runOrder) job(name string, guildID string, priority job.Priority) job.Job {
+	o.wg.Add(1)
+	return job.Job{GuildID: guildID, Priority: priority, Run: func() {
+		defer o.wg.Done()
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.names = append(o.names, name)
+	}}
+}
+
+func (o *runOrder) wait(t *testing.T) []string {
+	done := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(waitTimeout):
+		t.Fatal("jobs never ran")
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.names
+}
+
+func TestJobQueue_order(t *testing.T) {
+	type submitted struct {
+		name     string
+		guildID  string
+		priority job.Priority
+	}
+	tests := []struct {
+		name          string
+		jobs          []submitted
+		expectedOrder []string
+	}{
+		{
+			name: "higher priorities run first",
+			jobs: []submitted{
+				{name: "embed", guildID: "guild", priority: job.PriorityEmbed},
+				{name: "encode", guildID: "guild", priority: job.PriorityEncode},
+				{name: "upload", guildID: "guild", priority: job.PriorityUpload},
+			},
+			expectedOrder: []string{"upload", "encode", "embed"},
+		},
+		{
+			name: "jobs of the same guild and priority run in the order they were submitted",
+			jobs: []submitted{
+				{name: "first", guildID: "guild", priority: job.PriorityEncode},
+				{name: "second", guildID: "guild", priority: job.PriorityEncode},
+				{name: "third", guildID: "guild", priority: job.PriorityEncode},
+			},
+			expectedOrder: []string{"first", "second", "third"},
+		},
+		{
+			name: "guilds take turns within a priority",
+			jobs: []submitted{
+				{name: "a1", guildID: "a", priority: job.PriorityEncode},
+				{name: "a2", guildID: "a", priority: job.PriorityEncode},
+				{name: "a3", guildID: "a", priority: job.PriorityEncode},
+				{name: "b1", guildID: "b", priority: job.PriorityEncode},
+				{name: "c1", guildID: "c", priority: job.PriorityEncode},
+				{name: "b2", guildID: "b", priority: job.PriorityEncode},
+			},
+			expectedOrder: []string{"a1", "b1", "c1", "a2", "b2", "a3"},
+		},
+		{
+			name: "a busy guild does not delay the higher priorities of another one",
+			jobs: []submitted{
+				{name: "a1", guildID: "a", priority: job.PriorityEncode},
+				{name: "a2", guildID: "a", priority: job.PriorityEncode},
+				{name: "b1", guildID: "b", priority: job.PriorityUpload},
+				{name: "b2", guildID: "b", priority: job.PriorityEmbed},
+			},
+			expectedOrder: []string{"b1", "a1", "a2", "b2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewJobQueue(1, len(tt.jobs))
+			defer q.Close()
+			release := blockWorker(t, q)
+			order := &runOrder{}
+			for _, j := range tt.jobs {
+				assert.NoError(t, q.Submit(context.Background(), order.job(j.name, j.guildID, j.priority)))
+			}
+			release()
+
+			assert.Equal(t, tt.expectedOrder, order.wait(t))
+		})
+	}
+}
+
+func TestJobQueue_Submit(t *testing.T) {
+	tests := []struct {
+		name     string
+		priority job.Priority
+		// freeRoomAfter releases the worker, which takes the queued job and frees its room, 0 keeps it busy
+		freeRoomAfter time.Duration
+		expectedError string
+	}{
+		{name: "when queue is full, wait for room until the context is done", priority: job.PriorityEncode, expectedError: "err waiting for room in the job queue, context deadline exceeded"},
+		{name: "when queue is full, take the job once there is room", priority: job.PriorityEncode, freeRoomAfter: 50 * time.Millisecond},
+		{name: "when priority is unknown, return error", priority: job.Priority(42), expectedError: "unknown job priority 42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewJobQueue(1, 1)
+			defer q.Close()
+			release := blockWorker(t, q)
+			if tt.freeRoomAfter > 0 {
+				time.AfterFunc(tt.freeRoomAfter, release)
+			} else {
+				defer release()
+			}
+			assert.NoError(t, q.Submit(context.Background(), job.Job{GuildID: "guild", Priority: job.PriorityEncode, Run: func() {}}))
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			err := q.Submit(ctx, job.Job{GuildID: "guild", Priority: tt.priority, Run: func() {}})
+
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestJobQueue_Close(t *testing.T) {
+	q := NewJobQueue(1, 10)
+	release := blockWorker(t, q)
+	order := &runOrder{}
+	assert.NoError(t, q.Submit(context.Background(), order.job("first", "guild", job.PriorityEncode)))
+	assert.NoError(t, q.Submit(context.Background(), order.job("second", "guild", job.PriorityEmbed)))
+
+	closed := make(chan struct{})
+	go func() {
+		assert.NoError(t, q.Close())
+		close(closed)
+	}()
+	select {
+	case <-q.closing:
+	case <-time.After(waitTimeout):
+		t.Fatal("queue never started closing")
+	}
+
+	assert.ErrorIs(t, q.Submit(context.Background(), job.Job{GuildID: "guild", Priority: job.PriorityUpload, Run: func() {}}), errJobQueueClosed)
+	select {
+	case <-closed:
+		t.Fatal("close returned before the queued jobs ran")
+	default:
+	}
+
+	release()
+	select {
+	case <-closed:
+	case <-time.After(waitTimeout):
+		t.Fatal("close never returned")
+	}
+	assert.Equal(t, []string{"first", "second"}, order.wait(t))
+	assert.NoError(t, q.Close(), "closing twice does nothing")
+}
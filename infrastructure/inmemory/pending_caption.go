@@ -0,0 +1,44 @@
+package inmemory
+
+import (
+	"sync"
+	"time"
+)
+
+// pendingCaptionTTL forgets captions that were never used, so an old one does not title an unrelated recording
+const pendingCaptionTTL = time.Hour
+
+type pendingCaption struct {
+	caption     string
+	requestedAt time.Time
+}
+
+type PendingCaptionRepository struct {
+	mu      sync.Mutex
+	pending map[string]pendingCaption
+}
+
+func NewPendingCaptionRepository() *PendingCaptionRepository {
+	return &PendingCaptionRepository{pending: map[string]pendingCaption{}}
+}
+
+func (repo *PendingCaptionRepository) SetPending(guildID string, userID string, caption string) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.pending[guildID+"/"+userID] = pendingCaption{caption: caption, requestedAt: time.Now()}
+}
+
+func (repo *PendingCaptionRepository) PopPending(guildID string, userID string) (string, bool) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	key := guildID + "/" + userID
+	pending, ok := repo.pending[key]
+	if !ok {
+		return "", false
+	}
+	delete(repo.pending, key)
+	if time.Since(pending.requestedAt) > pendingCaptionTTL {
+		return "", false
+	}
+	return pending.caption, true
+}
@@ -0,0 +1,44 @@
+package inmemory
+
+import (
+	"sync"
+	"time"
+)
+
+// pendingIntroTTL matches how long discord lets us edit the interaction response
+const pendingIntroTTL = 15 * time.Minute
+
+type pendingIntro struct {
+	interactionToken string
+	requestedAt      time.Time
+}
+
+type PendingIntroRepository struct {
+	mu      sync.Mutex
+	pending map[string]pendingIntro
+}
+
+func NewPendingIntroRepository() *PendingIntroRepository {
+	return &PendingIntroRepository{pending: map[string]pendingIntro{}}
+}
+
+func (repo *PendingIntroRepository) SetPending(guildID string, userID string, interactionToken string) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.pending[guildID+"/"+userID] = pendingIntro{interactionToken: interactionToken, requestedAt: time.Now()}
+}
+
+func (repo *PendingIntroRepository) PopPending(guildID string, userID string) (string, bool) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	key := guildID + "/" + userID
+	pending, ok := repo.pending[key]
+	if !ok {
+		return "", false
+	}
+	delete(repo.pending, key)
+	if time.Since(pending.requestedAt) > pendingIntroTTL {
+		return "", false
+	}
+	return pending.interactionToken, true
+}
@@ -1,11 +1,16 @@
 package inmemory
 
+import "sync"
+
 type LockUser struct {
 	id   string
 	done chan bool
 }
 
+// Repository keeps the user being recorded in each guild. The recording of a guild and the voice state updates of its
+// members lock and release it from different goroutines
 type Repository struct {
+	mu          sync.Mutex
 	lockedUsers map[string]*LockUser
 }
 
@@ -13,35 +18,44 @@ func NewLockedUserRepository() *Repository {
 	return &Repository{lockedUsers: map[string]*LockUser{}}
 }
 
+// newDone holds a signal, so finishing a recording never waits for it to be read
+func newDone() chan bool {
+	return make(chan bool, 1)
+}
+
 func (repo *Repository) SetLock(guildID string, userID string) {
-	previousLock := repo.lockedUsers[guildID]
-	if previousLock == nil {
-		previousLock = &LockUser{
-			id:   "",
-			done: make(chan bool),
-		}
-	}
-	done := previousLock.done
-	if done == nil {
-		done = make(chan bool)
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.setLock(guildID, userID)
+}
+
+func (repo *Repository) setLock(guildID string, userID string) {
+	done := newDone()
+	if previousLock := repo.lockedUsers[guildID]; previousLock != nil && previousLock.done != nil {
+		done = previousLock.done
 	}
 	repo.lockedUsers[guildID] = &LockUser{
 		id:   userID,
 		done: done,
 	}
 }
+
 func (repo *Repository) GetCurrentLock(guildID string) (string, chan bool) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
 	lockUser, ok := repo.lockedUsers[guildID]
 	if !ok {
-		repo.SetLock(guildID, "")
+		repo.setLock(guildID, "")
 		lockUser = repo.lockedUsers[guildID]
-
-		return lockUser.id, lockUser.done
 	}
 
 	return lockUser.id, lockUser.done
 }
 
+// ReleaseUserLock gives the guild a new done channel, so a signal left for a recording that already finished does not
+// reach the next one
 func (repo *Repository) ReleaseUserLock(guildID string) {
-	repo.lockedUsers[guildID].id = ""
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.lockedUsers[guildID] = &LockUser{id: "", done: newDone()}
 }
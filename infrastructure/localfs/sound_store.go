@@ -0,0 +1,57 @@
+package localfs
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// SoundStore keeps the soundboard files in an archive directory that lives apart from the temporary files
+type SoundStore struct {
+	archivePath string
+}
+
+func NewSoundStore(archivePath string) *SoundStore {
+	if err := os.MkdirAll(archivePath, 0750); err != nil {
+		log.Fatalln("could not create archive dir for sounds", err)
+	}
+	return &SoundStore{archivePath: archivePath}
+}
+
+func (store *SoundStore) Put(key string, r io.Reader) error {
+	f, err := os.Create(store.path(key))
+	if err != nil {
+		return fmt.Errorf("err creating archived sound, %w", err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		_ = os.Remove(store.path(key))
+		return fmt.Errorf("err writing archived sound, %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("err closing archived sound, %w", err)
+	}
+	return nil
+}
+
+func (store *SoundStore) Get(key string) (io.ReadCloser, error) {
+	f, err := os.Open(store.path(key))
+	if err != nil {
+		return nil, fmt.Errorf("err opening archived sound, %w", err)
+	}
+	return f, nil
+}
+
+func (store *SoundStore) Delete(key string) error {
+	if err := os.Remove(store.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("err deleting archived sound, %w", err)
+	}
+	return nil
+}
+
+// path keeps keys inside the archive dir
+func (store *SoundStore) path(key string) string {
+	return filepath.Join(store.archivePath, filepath.Base(filepath.Clean("/"+key)))
+}
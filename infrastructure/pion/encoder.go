@@ -0,0 +1,328 @@
+package pion
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/pion/opus"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media/oggreader"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+)
+
+const (
+	// discord always sends stereo opus, and opus always decodes at 48khz
+	pcmChannels = 2
+	// maxPacketSamples is the longest opus packet, 120ms, per channel
+	maxPacketSamples = opusGranuleRate * 120 / 1000
+	wavHeaderSize    = 44
+)
+
+// Encoder writes the recorded ogg/opus files in process, without external programs.
+// Opus is remuxed as it is and wav is decoded with a pure go opus decoder, any other format needs another encoder.
+type Encoder struct {
+}
+
+func NewEncoder() *Encoder {
+	return &Encoder{}
+}
+
+func (e *Encoder) Supports(format domain.AudioFormat) bool {
+	return format == domain.AudioFormatOpus || format == domain.AudioFormatWAV
+}
+
+// SupportsEffect is only true for no effect, rendering them needs another encoder
+func (e *Encoder) SupportsEffect(effect domain.VoiceEffect) bool {
+	return effect == domain.VoiceEffectNone
+}
+
+func (e *Encoder) Encode(inputs []string, output string, options domain.EncodeOptions) error {
+	if !e.Supports(options.Format) {
+		return fmt.Errorf("unsupported audio format %s", options.Format)
+	}
+	if !e.SupportsEffect(options.Effect) {
+		return fmt.Errorf("unsupported voice effect %s", options.Effect)
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("nothing to encode to %s", output)
+	}
+	if options.Format == domain.AudioFormatOpus && len(inputs) == 1 && inputs[0] == output {
+		return nil
+	}
+
+	// the output may be one of the inputs, so write next to it and swap them after
+	target := output + ".encoding"
+	var err error
+	if options.Format == domain.AudioFormatOpus {
+		err = remuxOpus(inputs, target)
+	} else {
+		err = writeWav(inputs, target)
+	}
+	if err != nil {
+		if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Println("err removing failed encoding", err)
+		}
+		return fmt.Errorf("failed to encode %s, %w", options.Format, err)
+	}
+	if err := os.Rename(target, output); err != nil {
+		return fmt.Errorf("err replacing encoded file, %w", err)
+	}
+	return nil
+}
+
+func (e *Encoder) Stream(output string, options domain.EncodeOptions) (domain.AudioStream, error) {
+	if !e.SupportsEffect(options.Effect) {
+		return nil, fmt.Errorf("unsupported voice effect %s", options.Effect)
+	}
+	switch options.Format {
+	case domain.AudioFormatOpus:
+		writer, err := oggwriter.New(output, opusGranuleRate, pcmChannels)
+		if err != nil {
+			return nil, fmt.Errorf("err creating ogg writer, %w", err)
+		}
+		return &oggStream{writer: writer}, nil
+	case domain.AudioFormatWAV:
+		// discord packets come straight from the encoder of the client, there is no header telling what to skip
+		w, err := newWavWriter(output)
+		if err != nil {
+			return nil, err
+		}
+		return &wavStream{writer: w}, nil
+	default:
+		return nil, fmt.Errorf("unsupported audio format %s", options.Format)
+	}
+}
+
+type oggStream struct {
+	writer *oggwriter.OggWriter
+}
+
+func (s *oggStream) Write(packet *discord.Packet) error {
+	if err := s.writer.WriteRTP(createPionRTPPacket(packet)); err != nil {
+		return fmt.Errorf("err writing rtp packet, %w", err)
+	}
+	return nil
+}
+
+func (s *oggStream) Close() error {
+	return s.writer.Close()
+}
+
+type wavStream struct {
+	writer *wavWriter
+}
+
+func (s *wavStream) Write(packet *discord.Packet) error {
+	return s.writer.writePacket(packet.Opus)
+}
+
+func (s *wavStream) Close() error {
+	return s.writer.Close()
+}
+
+// remuxOpus copies the packets of every input one after the other into a single ogg stream
+func remuxOpus(inputs []string, output string) (err error) {
+	writer, err := oggwriter.New(output, opusGranuleRate, pcmChannels)
+	if err != nil {
+		return fmt.Errorf("err creating ogg writer, %w", err)
+	}
+	defer func() {
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	// the writer only counts the time between packets, so every input starts a frame after the last packet of the one before,
+	// and the first timestamp is not 1, which the writer takes as no packet written yet
+	next := uint32(frameSamples)
+	var sequence uint16
+	for _, input := range inputs {
+		started := false
+		var offset, last uint32
+		err := readOpusPackets(input, func(_ *oggreader.OggHeader, payload []byte, granule uint64) error {
+			if !started {
+				started = true
+				offset = next - uint32(granule)
+			}
+			last = offset + uint32(granule)
+			sequence++
+			return writer.WriteRTP(&rtp.Packet{
+				Header:  rtp.Header{Version: 2, PayloadType: 0x78, SequenceNumber: sequence, Timestamp: last},
+				Payload: payload,
+			})
+		})
+		if err != nil {
+			return err
+		}
+		if started {
+			next = last + frameSamples
+		}
+	}
+	return nil
+}
+
+// writeWav decodes every input to 16 bit stereo pcm and writes them in a single wav file
+func writeWav(inputs []string, output string) (err error) {
+	w, err := newWavWriter(output)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	for _, input := range inputs {
+		started := false
+		err := readOpusPackets(input, func(header *oggreader.OggHeader, payload []byte, _ uint64) error {
+			if !started {
+				started = true
+				if err := w.startStream(int(header.PreSkip), outputGain(header)); err != nil {
+					return err
+				}
+			}
+			return w.writePacket(payload)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wavWriter decodes opus packets to 16 bit stereo pcm as they come, the sizes in the header are written on Close
+type wavWriter struct {
+	f        *os.File
+	buffered *bufio.Writer
+	decoder  opus.Decoder
+	pcm      []int16
+	dataSize uint32
+	skip     int
+	// gain multiplies the decoded samples, 1 keeps them as they are
+	gain float64
+}
+
+func newWavWriter(output string) (*wavWriter, error) {
+	f, err := os.Create(output)
+	if err != nil {
+		return nil, fmt.Errorf("err creating wav file, %w", err)
+	}
+	if _, err := f.Write(make([]byte, wavHeaderSize)); err != nil {
+		if err := f.Close(); err != nil {
+			log.Println("err closing wav file", err)
+		}
+		return nil, fmt.Errorf("err writing wav header, %w", err)
+	}
+	w := &wavWriter{f: f, buffered: bufio.NewWriter(f), pcm: make([]int16, maxPacketSamples*pcmChannels)}
+	return w, w.startStream(0, 1)
+}
+
+// startStream resets the decoder for a new opus stream, whose first preSkip samples are the encoder warming up
+func (w *wavWriter) startStream(preSkip int, gain float64) error {
+	decoder, err := opus.NewDecoderWithOutput(opusGranuleRate, pcmChannels)
+	if err != nil {
+		return fmt.Errorf("err creating opus decoder, %w", err)
+	}
+	w.decoder = decoder
+	w.skip = preSkip * pcmChannels
+	w.gain = gain
+	return nil
+}
+
+// outputGain returns the factor of the gain the header asks to apply when decoding
+func outputGain(header *oggreader.OggHeader) float64 {
+	return math.Pow(10, float64(int16(header.OutputGain))/256/20)
+}
+
+func (w *wavWriter) writePacket(payload []byte) error {
+	samples, err := w.decoder.DecodeToInt16(payload, w.pcm)
+	if err != nil {
+		return fmt.Errorf("err decoding opus packet, %w", err)
+	}
+	decoded := w.pcm[:samples*pcmChannels]
+	n := min(w.skip, len(decoded))
+	decoded, w.skip = decoded[n:], w.skip-n
+	if w.gain != 1 {
+		for i, sample := range decoded {
+			decoded[i] = int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(float64(sample)*w.gain))))
+		}
+	}
+	if err := binary.Write(w.buffered, binary.LittleEndian, decoded); err != nil {
+		return fmt.Errorf("err writing pcm, %w", err)
+	}
+	w.dataSize += uint32(len(decoded) * 2)
+	return nil
+}
+
+func (w *wavWriter) Close() error {
+	err := w.buffered.Flush()
+	if err == nil {
+		_, err = w.f.WriteAt(wavHeader(w.dataSize), 0)
+	}
+	if closeErr := w.f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("err finishing wav file, %w", err)
+	}
+	return nil
+}
+
+func wavHeader(dataSize uint32) []byte {
+	const bitsPerSample = 16
+	blockAlign := pcmChannels * bitsPerSample / 8
+	header := make([]byte, 0, wavHeaderSize)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, 36+dataSize)
+	header = append(header, "WAVEfmt "...)
+	header = binary.LittleEndian.AppendUint32(header, 16)
+	header = binary.LittleEndian.AppendUint16(header, 1) // pcm
+	header = binary.LittleEndian.AppendUint16(header, pcmChannels)
+	header = binary.LittleEndian.AppendUint32(header, opusGranuleRate)
+	header = binary.LittleEndian.AppendUint32(header, uint32(opusGranuleRate*blockAlign))
+	header = binary.LittleEndian.AppendUint16(header, uint16(blockAlign))
+	header = binary.LittleEndian.AppendUint16(header, bitsPerSample)
+	header = append(header, "data"...)
+	return binary.LittleEndian.AppendUint32(header, dataSize)
+}
+
+// readOpusPackets calls fn with every audio packet of the ogg/opus file and the granule position of its page
+func readOpusPackets(path string, fn func(header *oggreader.OggHeader, payload []byte, granule uint64) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("err opening ogg file, %w", err)
+	}
+	defer func(f *os.File) {
+		if err := f.Close(); err != nil {
+			log.Println("err closing ogg file", err)
+		}
+	}(f)
+
+	reader, header, err := oggreader.NewWith(f)
+	if err != nil {
+		return fmt.Errorf("err reading ogg header, %w", err)
+	}
+	for {
+		payload, pageHeader, err := reader.ParseNextPage()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("err parsing ogg page, %w", err)
+		}
+		if bytes.HasPrefix(payload, []byte("OpusTags")) {
+			continue
+		}
+		if err := fn(header, payload, pageHeader.GranulePosition); err != nil {
+			return err
+		}
+	}
+}
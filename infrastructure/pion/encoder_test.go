@@ -0,0 +1,204 @@
+package pion
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// stepsOf returns the distance between consecutive granule positions
+func stepsOf(granules []uint64) []uint64 {
+	steps := make([]uint64, 0, len(granules))
+	for i := 1; i < len(granules); i++ {
+		steps = append(steps, granules[i]-granules[i-1])
+	}
+	return steps
+}
+
+func Test_remuxOpus(t *testing.T) {
+	first := noiseFrames(1, 10)
+	second := noiseFrames(2, 5)
+	// the second input has a second without packets in the middle, as discord sends none while nobody speaks
+	gapped := append(timed(5000, second[:2]), timed(5000+2*frameSamples+opusGranuleRate, second[2:])...)
+	inputs := []string{writeTestOgg(t, "first.ogg", timed(1000, first)), writeTestOgg(t, "second.ogg", gapped)}
+	output := filepath.Join(t.TempDir(), "joined.ogg")
+
+	if !assert.NoError(t, remuxOpus(inputs, output)) {
+		return
+	}
+
+	header, payloads, granules := readTestOgg(t, output)
+	assert.Equal(t, uint8(pcmChannels), header.Channels)
+	assert.Equal(t, uint16(oggPreSkip), header.PreSkip)
+	assert.Equal(t, append(append([][]byte{}, first...), second...), payloads, "every packet is copied as it is, in order")
+	assert.Equal(t, uint64(1), granules[0], "the granule of the first packet is the one of any recording")
+	expectedSteps := append(repeatSteps(frameSamples, 11), frameSamples+opusGranuleRate)
+	expectedSteps = append(expectedSteps, repeatSteps(frameSamples, 2)...)
+	assert.Equal(t, expectedSteps, stepsOf(granules), "inputs follow each other a frame apart and keep their gaps")
+}
+
+func repeatSteps(step uint64, count int) []uint64 {
+	steps := make([]uint64, count)
+	for i := range steps {
+		steps[i] = step
+	}
+	return steps
+}
+
+// readTestWav returns the header and the 16 bit samples of the wav file
+func readTestWav(t *testing.T, path string) ([]byte, []int16) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < wavHeaderSize {
+		t.Fatalf("wav file has %d bytes, less than a header", len(data))
+	}
+	samples := make([]int16, (len(data)-wavHeaderSize)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(data[wavHeaderSize+i*2:]))
+	}
+	return data[:wavHeaderSize], samples
+}
+
+func rms(samples []int16) float64 {
+	var sum float64
+	for _, sample := range samples {
+		sum += float64(sample) * float64(sample)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+func Test_writeWav(t *testing.T) {
+	frames := noiseFrames(1, 10)
+	first := writeTestOgg(t, "first.ogg", timed(1000, frames))
+	second := writeTestOgg(t, "second.ogg", timed(1000, frames))
+	output := filepath.Join(t.TempDir(), "joined.wav")
+
+	if !assert.NoError(t, writeWav([]string{first, second}, output)) {
+		return
+	}
+
+	header, samples := readTestWav(t, output)
+	// the pre-skip of every input is dropped, as each of them starts a new opus stream
+	expectedSamples := 2 * (10*frameSamples - oggPreSkip) * pcmChannels
+	assert.Len(t, samples, expectedSamples)
+	dataSize := uint32(expectedSamples * 2)
+	assert.Equal(t, "RIFF", string(header[0:4]))
+	assert.Equal(t, 36+dataSize, binary.LittleEndian.Uint32(header[4:]))
+	assert.Equal(t, "WAVEfmt ", string(header[8:16]))
+	assert.Equal(t, uint32(16), binary.LittleEndian.Uint32(header[16:]))
+	assert.Equal(t, uint16(1), binary.LittleEndian.Uint16(header[20:]), "pcm")
+	assert.Equal(t, uint16(pcmChannels), binary.LittleEndian.Uint16(header[22:]))
+	assert.Equal(t, uint32(opusGranuleRate), binary.LittleEndian.Uint32(header[24:]))
+	assert.Equal(t, uint32(opusGranuleRate*pcmChannels*2), binary.LittleEndian.Uint32(header[28:]), "byte rate")
+	assert.Equal(t, uint16(pcmChannels*2), binary.LittleEndian.Uint16(header[32:]), "block align")
+	assert.Equal(t, uint16(16), binary.LittleEndian.Uint16(header[34:]), "bits per sample")
+	assert.Equal(t, "data", string(header[36:40]))
+	assert.Equal(t, dataSize, binary.LittleEndian.Uint32(header[40:]))
+	assert.Equal(t, samples[:len(samples)/2], samples[len(samples)/2:], "both inputs are decoded the same")
+	assert.NotZero(t, rms(samples))
+}
+
+func Test_writeWav_outputGain(t *testing.T) {
+	frames := noiseFrames(1, 10)
+	plain := writeTestOgg(t, "plain.ogg", timed(1000, frames))
+	quieter := writeTestOgg(t, "quieter.ogg", timed(1000, frames))
+	if err := setOutputGain(quieter, -6); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	if !assert.NoError(t, writeWav([]string{plain}, filepath.Join(dir, "plain.wav"))) {
+		return
+	}
+	if !assert.NoError(t, writeWav([]string{quieter}, filepath.Join(dir, "quieter.wav"))) {
+		return
+	}
+
+	_, plainSamples := readTestWav(t, filepath.Join(dir, "plain.wav"))
+	_, quieterSamples := readTestWav(t, filepath.Join(dir, "quieter.wav"))
+	assert.Len(t, quieterSamples, len(plainSamples))
+	assert.InDelta(t, math.Pow(10, -6.0/20), rms(quieterSamples)/rms(plainSamples), 0.01, "the gain of the header is applied, lowering it so nothing clips")
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputs        int
+		sameOutput    bool
+		options       domain.EncodeOptions
+		expectedError bool
+		assertOutput  func(t *testing.T, output string)
+	}{
+		{
+			name:    "when format is opus, join the inputs in the output",
+			inputs:  2,
+			options: domain.EncodeOptions{Format: domain.AudioFormatOpus},
+			assertOutput: func(t *testing.T, output string) {
+				_, payloads, _ := readTestOgg(t, output)
+				assert.Len(t, payloads, 10)
+			},
+		},
+		{
+			name:       "when the only opus input is the output, leave it as it is",
+			inputs:     1,
+			sameOutput: true,
+			options:    domain.EncodeOptions{Format: domain.AudioFormatOpus},
+			assertOutput: func(t *testing.T, output string) {
+				_, payloads, _ := readTestOgg(t, output)
+				assert.Len(t, payloads, 5)
+			},
+		},
+		{
+			name:       "when output is one of the inputs, replace it once encoded",
+			inputs:     2,
+			sameOutput: true,
+			options:    domain.EncodeOptions{Format: domain.AudioFormatOpus},
+			assertOutput: func(t *testing.T, output string) {
+				_, payloads, _ := readTestOgg(t, output)
+				assert.Len(t, payloads, 10)
+				_, err := os.Stat(output + ".encoding")
+				assert.ErrorIs(t, err, os.ErrNotExist)
+			},
+		},
+		{
+			name:    "when format is wav, decode the inputs in the output",
+			inputs:  1,
+			options: domain.EncodeOptions{Format: domain.AudioFormatWAV},
+			assertOutput: func(t *testing.T, output string) {
+				_, samples := readTestWav(t, output)
+				assert.Len(t, samples, (5*frameSamples-oggPreSkip)*pcmChannels)
+			},
+		},
+		{name: "when format is not supported, return error", inputs: 1, options: domain.EncodeOptions{Format: domain.AudioFormatMP3}, expectedError: true},
+		{name: "when there is an effect, return error", inputs: 1, options: domain.EncodeOptions{Format: domain.AudioFormatOpus, Effect: domain.VoiceEffectChipmunk}, expectedError: true},
+		{name: "when there are no inputs, return error", options: domain.EncodeOptions{Format: domain.AudioFormatOpus}, expectedError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			inputs := make([]string, tt.inputs)
+			for i := range inputs {
+				inputs[i] = writeTestOgg(t, "input.ogg", timed(1000, noiseFrames(int64(i), 5)))
+			}
+			output := filepath.Join(dir, "output")
+			if tt.sameOutput {
+				output = inputs[0]
+			}
+
+			err := NewEncoder().Encode(inputs, output, tt.options)
+
+			assert.Equal(t, tt.expectedError, err != nil)
+			if tt.assertOutput != nil {
+				tt.assertOutput(t, output)
+			}
+		})
+	}
+}
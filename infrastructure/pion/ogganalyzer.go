@@ -0,0 +1,105 @@
+package pion
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/pion/webrtc/v3/pkg/media/oggreader"
+)
+
+// opus granule positions always count 48khz samples
+const opusGranuleRate = 48000
+
+type Analyzer struct {
+}
+
+func NewAnalyzer() *Analyzer {
+	return &Analyzer{}
+}
+
+// Analyze reads the duration from the last granule position and approximates the waveform with the packet sizes,
+// as opus spends more bytes on louder audio and almost none on silence
+func (a *Analyzer) Analyze(path string, waveformSize int) (float64, []byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, nil, fmt.Errorf("err opening ogg file, %w", err)
+	}
+	defer func(f *os.File) {
+		if err := f.Close(); err != nil {
+			log.Println("err closing ogg file", err)
+		}
+	}(f)
+
+	reader, header, err := oggreader.NewWith(f)
+	if err != nil {
+		return 0, nil, fmt.Errorf("err reading ogg header, %w", err)
+	}
+	var sizes []int
+	var granule uint64
+	for {
+		payload, pageHeader, err := reader.ParseNextPage()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("err parsing ogg page, %w", err)
+		}
+		if bytes.HasPrefix(payload, []byte("OpusTags")) {
+			continue
+		}
+		sizes = append(sizes, len(payload))
+		granule = pageHeader.GranulePosition
+	}
+
+	return granuleSeconds(granule, header.PreSkip), waveform(sizes, waveformSize), nil
+}
+
+// granuleSeconds is the duration of the audio up to the granule position, the first preSkip samples are not played
+func granuleSeconds(granule uint64, preSkip uint16) float64 {
+	samples := int64(granule) - int64(preSkip)
+	if samples < 0 {
+		samples = 0
+	}
+	return float64(samples) / opusGranuleRate
+}
+
+// waveform averages the sizes into at most size buckets and scales them from 0 to 255
+func waveform(sizes []int, size int) []byte {
+	if len(sizes) == 0 || size <= 0 {
+		return []byte{}
+	}
+	if len(sizes) < size {
+		size = len(sizes)
+	}
+	buckets := make([]float64, size)
+	for i := range buckets {
+		from := i * len(sizes) / size
+		to := (i + 1) * len(sizes) / size
+		total := 0
+		for _, s := range sizes[from:to] {
+			total += s
+		}
+		buckets[i] = float64(total) / float64(to-from)
+	}
+	lowest, highest := buckets[0], buckets[0]
+	for _, b := range buckets {
+		if b < lowest {
+			lowest = b
+		}
+		if b > highest {
+			highest = b
+		}
+	}
+	amplitudes := make([]byte, size)
+	if highest == lowest {
+		return amplitudes
+	}
+	for i, b := range buckets {
+		amplitudes[i] = byte((b - lowest) / (highest - lowest) * 255)
+	}
+	return amplitudes
+}
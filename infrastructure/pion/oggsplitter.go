@@ -0,0 +1,61 @@
+package pion
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/pion/webrtc/v3/pkg/media/oggreader"
+)
+
+type Splitter struct {
+}
+
+func NewSplitter() *Splitter {
+	return &Splitter{}
+}
+
+// Split deals the opus packets out in consecutive runs of the same length, every packet of a recording lasts the
+// same 20ms so they also last the same. The output gain of the recording is kept in every part.
+func (s *Splitter) Split(path string, outputs []string) error {
+	if len(outputs) == 0 {
+		return errors.New("no outputs to split the recording in")
+	}
+	var packets []packetStats
+	var outputGain uint16
+	err := readOpusPackets(path, func(header *oggreader.OggHeader, payload []byte, granule uint64) error {
+		outputGain = header.OutputGain
+		packets = append(packets, packetStats{payload: payload, granule: granule})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(packets) < len(outputs) {
+		return fmt.Errorf("recording of %d packets is too short to split in %d parts", len(packets), len(outputs))
+	}
+	// the output gain is stored as a Q7.8 number, https://www.rfc-editor.org/rfc/rfc7845#section-5.1
+	gainDB := float64(int16(outputGain)) / 256
+	for i, output := range outputs {
+		from := i * len(packets) / len(outputs)
+		to := (i + 1) * len(packets) / len(outputs)
+		err := writePackets(output, packets[from:to])
+		if err == nil && gainDB != 0 {
+			err = setOutputGain(output, gainDB)
+		}
+		if err != nil {
+			removeParts(outputs[:i+1])
+			return fmt.Errorf("err writing part %d of the recording, %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func removeParts(parts []string) {
+	for _, part := range parts {
+		if err := os.Remove(part); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Println("err removing recording part", err)
+		}
+	}
+}
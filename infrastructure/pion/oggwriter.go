@@ -10,28 +10,58 @@ import (
 	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
 )
 
+// oggPreSkip is the pre-skip the pion ogg writer declares in the opus header
+const oggPreSkip = 3840
+
 type Writer struct {
 }
 
+// recording follows the granule position the ogg writer gives to the pages, which advances with the rtp timestamps
+type recording struct {
+	*oggwriter.OggWriter
+	granule       uint64
+	lastTimestamp uint32
+	started       bool
+}
+
 func (w *Writer) NewWriter(path string) (io.Closer, error) {
 	writer, err := oggwriter.New(path, 48000, 2)
 	if err != nil {
 		return nil, fmt.Errorf("error getting new ogg writer: %w", err)
 	}
-	return writer, nil
+	return &recording{OggWriter: writer}, nil
 }
 
 func (w *Writer) WriteVoice(writer io.Closer, packet *discord.Packet) error {
-	pionOggWriter, ok := writer.(*oggwriter.OggWriter)
+	r, ok := writer.(*recording)
 	if !ok {
-		log.Fatal("Could not cast to pion oggwriter")
+		return fmt.Errorf("unexpected ogg writer %T", writer)
 	}
-	if err := pionOggWriter.WriteRTP(createPionRTPPacket(packet)); err != nil {
+	if err := r.WriteRTP(createPionRTPPacket(packet)); err != nil {
 		return fmt.Errorf("err writing rtp packet, %w", err)
 	}
+	// empty packets are not written
+	if len(packet.Opus) == 0 {
+		return nil
+	}
+	if r.started {
+		r.granule += uint64(packet.Timestamp - r.lastTimestamp)
+	}
+	r.started = true
+	r.lastTimestamp = packet.Timestamp
 	return nil
 }
 
+func (w *Writer) Duration(writer io.Closer) float64 {
+	r, ok := writer.(*recording)
+	if !ok {
+		log.Printf("unexpected ogg writer %T, its duration is unknown\n", writer)
+		return 0
+	}
+	// the writer starts the granule position at 1
+	return granuleSeconds(r.granule+1, oggPreSkip)
+}
+
 func createPionRTPPacket(p *discord.Packet) *rtp.Packet {
 	return &rtp.Packet{
 		Header: rtp.Header{
@@ -0,0 +1,111 @@
+package pion
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/pion/webrtc/v3/pkg/media/oggreader"
+)
+
+// Prober reads the properties of opus and wav files from their headers, without decoding their audio
+type Prober struct {
+}
+
+func NewProber() *Prober {
+	return &Prober{}
+}
+
+func (p *Prober) Supports(format domain.AudioFormat) bool {
+	return format == domain.AudioFormatOpus || format == domain.AudioFormatWAV
+}
+
+func (p *Prober) Probe(path string, format domain.AudioFormat) (domain.MediaProperties, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return domain.MediaProperties{}, fmt.Errorf("err opening audio file, %w", err)
+	}
+	defer func(f *os.File) {
+		if err := f.Close(); err != nil {
+			log.Println("err closing audio file", err)
+		}
+	}(f)
+	info, err := f.Stat()
+	if err != nil {
+		return domain.MediaProperties{}, fmt.Errorf("err reading audio file, %w", err)
+	}
+	switch format {
+	case domain.AudioFormatOpus:
+		return probeOpus(f, info.Size())
+	case domain.AudioFormatWAV:
+		return probeWAV(f, info.Size())
+	default:
+		return domain.MediaProperties{}, fmt.Errorf("unsupported audio format %s", format)
+	}
+}
+
+// probeOpus reads the duration from the last granule position, as the Analyzer does
+func probeOpus(f io.Reader, size int64) (domain.MediaProperties, error) {
+	reader, header, err := oggreader.NewWith(f)
+	if err != nil {
+		return domain.MediaProperties{}, fmt.Errorf("err reading ogg header, %w", err)
+	}
+	var granule uint64
+	for {
+		_, pageHeader, err := reader.ParseNextPage()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return domain.MediaProperties{}, fmt.Errorf("err parsing ogg page, %w", err)
+		}
+		granule = pageHeader.GranulePosition
+	}
+	return domain.NewMediaProperties(granuleSeconds(granule, header.PreSkip), opusGranuleRate, int(header.Channels), size), nil
+}
+
+// probeWAV reads the fmt chunk and the size of the data chunk
+func probeWAV(f io.Reader, size int64) (domain.MediaProperties, error) {
+	reader := bufio.NewReader(f)
+	riff := make([]byte, 12)
+	if _, err := io.ReadFull(reader, riff); err != nil {
+		return domain.MediaProperties{}, fmt.Errorf("err reading wav header, %w", err)
+	}
+	if string(riff[:4]) != "RIFF" || string(riff[8:]) != "WAVE" {
+		return domain.MediaProperties{}, errors.New("missing wav signature")
+	}
+	var channels, sampleRate, byteRate uint32
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return domain.MediaProperties{}, fmt.Errorf("err reading wav chunk, %w", err)
+		}
+		chunkSize := binary.LittleEndian.Uint32(chunk[4:])
+		switch string(chunk[:4]) {
+		case "fmt ":
+			fmtChunk := make([]byte, chunkSize)
+			if _, err := io.ReadFull(reader, fmtChunk); err != nil || chunkSize < 16 {
+				return domain.MediaProperties{}, fmt.Errorf("err reading wav format, %w", err)
+			}
+			channels = uint32(binary.LittleEndian.Uint16(fmtChunk[2:]))
+			sampleRate = binary.LittleEndian.Uint32(fmtChunk[4:])
+			byteRate = binary.LittleEndian.Uint32(fmtChunk[8:])
+		case "data":
+			if byteRate == 0 {
+				return domain.MediaProperties{}, errors.New("wav data before its format")
+			}
+			duration := float64(chunkSize) / float64(byteRate)
+			return domain.NewMediaProperties(duration, int(sampleRate), int(channels), size), nil
+		default:
+			// chunks are padded to an even size
+			if _, err := reader.Discard(int(chunkSize + chunkSize%2)); err != nil {
+				return domain.MediaProperties{}, fmt.Errorf("err skipping wav chunk, %w", err)
+			}
+		}
+	}
+}
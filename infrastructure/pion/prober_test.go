@@ -0,0 +1,149 @@
+package pion
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestFile writes the data in a file named name, returning its path
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testWav lays out a wav file of a second of 8khz 16 bit mono audio, with a chunk of an odd size before the data
+// and the chunks in order unless dataFirst
+func testWav(dataFirst bool) []byte {
+	format := binary.LittleEndian.AppendUint16(nil, 1)
+	format = binary.LittleEndian.AppendUint16(format, 1)
+	format = binary.LittleEndian.AppendUint32(format, 8000)
+	format = binary.LittleEndian.AppendUint32(format, 16000)
+	format = binary.LittleEndian.AppendUint16(format, 2)
+	format = binary.LittleEndian.AppendUint16(format, 16)
+	chunk := func(id string, body []byte) []byte {
+		c := binary.LittleEndian.AppendUint32([]byte(id), uint32(len(body)))
+		c = append(c, body...)
+		if len(body)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	chunks := [][]byte{chunk("fmt ", format), chunk("LIST", []byte("INFO!")), chunk("data", make([]byte, 16000))}
+	if dataFirst {
+		chunks[0], chunks[2] = chunks[2], chunks[0]
+	}
+	var body []byte
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	wav := binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(4+len(body)))
+	wav = append(wav, "WAVE"...)
+	return append(wav, body...)
+}
+
+func TestProber_Probe(t *testing.T) {
+	tests := []struct {
+		name               string
+		file               func(t *testing.T) string
+		format             domain.AudioFormat
+		expectedDuration   float64
+		expectedSampleRate int
+		expectedChannels   int
+		expectedError      bool
+	}{
+		{
+			name: "when file is a recording, read the duration from its last granule position",
+			file: func(t *testing.T) string {
+				return writeTestOgg(t, "recording.ogg", timed(1000, noiseFrames(1, 100)))
+			},
+			format: domain.AudioFormatOpus,
+			// the pion writer gives the last page the granule position where its packet starts
+			expectedDuration:   float64(1+99*frameSamples-oggPreSkip) / opusGranuleRate,
+			expectedSampleRate: opusGranuleRate,
+			expectedChannels:   pcmChannels,
+		},
+		{
+			name: "when file is a wav of the encoder, read its properties",
+			file: func(t *testing.T) string {
+				output := filepath.Join(t.TempDir(), "recording.wav")
+				if err := writeWav([]string{writeTestOgg(t, "recording.ogg", timed(1000, noiseFrames(1, 10)))}, output); err != nil {
+					t.Fatal(err)
+				}
+				return output
+			},
+			format:             domain.AudioFormatWAV,
+			expectedDuration:   float64(10*frameSamples-oggPreSkip) / opusGranuleRate,
+			expectedSampleRate: opusGranuleRate,
+			expectedChannels:   pcmChannels,
+		},
+		{
+			name:               "when wav has other chunks before the data, skip them",
+			file:               func(t *testing.T) string { return writeTestFile(t, "sound.wav", testWav(false)) },
+			format:             domain.AudioFormatWAV,
+			expectedDuration:   1,
+			expectedSampleRate: 8000,
+			expectedChannels:   1,
+		},
+		{
+			name:          "when wav has the data before its format, return error",
+			file:          func(t *testing.T) string { return writeTestFile(t, "sound.wav", testWav(true)) },
+			format:        domain.AudioFormatWAV,
+			expectedError: true,
+		},
+		{
+			name:          "when wav ends before its data, return error",
+			file:          func(t *testing.T) string { return writeTestFile(t, "sound.wav", testWav(false)[:44]) },
+			format:        domain.AudioFormatWAV,
+			expectedError: true,
+		},
+		{
+			name:          "when file is not a wav, return error",
+			file:          func(t *testing.T) string { return writeTestFile(t, "sound.wav", []byte("RIFF....AVI LIST")) },
+			format:        domain.AudioFormatWAV,
+			expectedError: true,
+		},
+		{
+			name:          "when file is not ogg, return error",
+			file:          func(t *testing.T) string { return writeTestFile(t, "sound.ogg", testWav(false)) },
+			format:        domain.AudioFormatOpus,
+			expectedError: true,
+		},
+		{
+			name: "when format is not supported, return error",
+			file: func(t *testing.T) string {
+				return writeTestOgg(t, "recording.ogg", timed(1000, noiseFrames(1, 10)))
+			},
+			format:        domain.AudioFormatMP3,
+			expectedError: true,
+		},
+		{
+			name:          "when file does not exist, return error",
+			file:          func(t *testing.T) string { return filepath.Join(t.TempDir(), "missing.ogg") },
+			format:        domain.AudioFormatOpus,
+			expectedError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.file(t)
+
+			properties, err := NewProber().Probe(path, tt.format)
+
+			assert.Equal(t, tt.expectedError, err != nil, "err %v", err)
+			if tt.expectedError {
+				return
+			}
+			info, _ := os.Stat(path)
+			assert.Equal(t, domain.NewMediaProperties(tt.expectedDuration, tt.expectedSampleRate, tt.expectedChannels, info.Size()), properties)
+		})
+	}
+}
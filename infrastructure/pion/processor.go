@@ -0,0 +1,349 @@
+package pion
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"os"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/pion/opus"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media/oggreader"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+)
+
+const (
+	// targetLoudness in LUFS, the usual level of streaming platforms, louder than broadcast as voice chats are listened on headphones
+	targetLoudness = -16.0
+	maxGainDB      = 20.0
+	// peakCeiling in dBFS, the gain never pushes the loudest sample over it
+	peakCeiling = -1.0
+	// trimMarginSecs of audio kept around the first and last sound, so words are not cut
+	trimMarginSecs = 0.1
+
+	loudnessBlockSecs    = 0.4
+	loudnessStepSecs     = 0.1
+	absoluteGateLoudness = -70.0
+	relativeGateLU       = -10.0
+)
+
+// packetStats are the measures of a decoded packet needed to process the recording, so no pcm is kept in memory
+type packetStats struct {
+	payload []byte
+	granule uint64
+	samples int
+	// weighted is the sum of the squared K-weighted samples of every channel
+	weighted float64
+	rmsDB    float64
+	peak     float64
+}
+
+// Processor normalizes and trims recordings without re-encoding them: silence is trimmed dropping whole packets, and
+// the gain is written in the output gain field of the Opus header, that players apply when decoding, as RFC 7845 advises.
+type Processor struct {
+}
+
+func NewProcessor() *Processor {
+	return &Processor{}
+}
+
+func (p *Processor) Process(path string, options domain.ProcessOptions) (domain.ProcessReport, error) {
+	var report domain.ProcessReport
+	if !options.Enabled() {
+		return report, nil
+	}
+	packets, err := measurePackets(path)
+	if err != nil {
+		return report, err
+	}
+	if len(packets) == 0 {
+		return report, nil
+	}
+
+	first, last := 0, len(packets)-1
+	if options.TrimSilence {
+		first, last = soundRange(packets, float64(options.SilenceThreshold))
+		report.TrimmedSecs = trimmedSecs(packets, first, last)
+	}
+	kept := packets[first : last+1]
+	if options.Normalize {
+		if loudness, ok := integratedLoudness(kept); ok {
+			report.Normalized = true
+			report.GainDB = normalizationGain(loudness, kept)
+		}
+	}
+	if report.TrimmedSecs == 0 && !report.Normalized {
+		return report, nil
+	}
+	if err := rewrite(path, kept, report.GainDB); err != nil {
+		return domain.ProcessReport{}, err
+	}
+	return report, nil
+}
+
+func measurePackets(path string) ([]packetStats, error) {
+	decoder, err := opus.NewDecoderWithOutput(opusGranuleRate, pcmChannels)
+	if err != nil {
+		return nil, fmt.Errorf("err creating opus decoder, %w", err)
+	}
+	filter := newKWeighting()
+	pcm := make([]int16, maxPacketSamples*pcmChannels)
+	var packets []packetStats
+	err = readOpusPackets(path, func(_ *oggreader.OggHeader, payload []byte, granule uint64) error {
+		samples, err := decoder.DecodeToInt16(payload, pcm)
+		if err != nil {
+			return fmt.Errorf("err decoding opus packet, %w", err)
+		}
+		stats := packetStats{payload: payload, granule: granule, samples: samples}
+		var squares float64
+		for i, sample := range pcm[:samples*pcmChannels] {
+			value := float64(sample) / math.MaxInt16
+			squares += value * value
+			stats.peak = math.Max(stats.peak, math.Abs(value))
+			weighted := filter.apply(i%pcmChannels, value)
+			stats.weighted += weighted * weighted
+		}
+		stats.rmsDB = math.Inf(-1)
+		if samples > 0 && squares > 0 {
+			stats.rmsDB = 10 * math.Log10(squares/float64(samples*pcmChannels))
+		}
+		packets = append(packets, stats)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return packets, nil
+}
+
+// soundRange returns the first and last packets over the threshold with some margin, everything when all is silence
+func soundRange(packets []packetStats, threshold float64) (int, int) {
+	first, last := -1, -1
+	for i, packet := range packets {
+		if packet.rmsDB > threshold {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return 0, len(packets) - 1
+	}
+	margin := int(math.Ceil(trimMarginSecs * opusGranuleRate / float64(frameSamples)))
+	return max(first-margin, 0), min(last+margin, len(packets)-1)
+}
+
+// trimmedSecs is the time of the recording before the first and after the last kept packets. It is measured with the
+// granule positions, as discord sends no packets while nobody speaks and those gaps are part of the recording too
+func trimmedSecs(packets []packetStats, first int, last int) float64 {
+	end := func(packet packetStats) uint64 {
+		return packet.granule + uint64(packet.samples)
+	}
+	leading := packets[first].granule - packets[0].granule
+	trailing := end(packets[len(packets)-1]) - end(packets[last])
+	return float64(leading+trailing) / opusGranuleRate
+}
+
+// integratedLoudness measures the gated loudness of ITU-R BS.1770, the one EBU R128 uses, false if everything is under the gate
+func integratedLoudness(packets []packetStats) (float64, bool) {
+	var blocks []float64
+	for start := 0; start < len(packets); {
+		var weighted float64
+		var samples, end int
+		for end = start; end < len(packets) && float64(samples) < loudnessBlockSecs*opusGranuleRate; end++ {
+			weighted += packets[end].weighted
+			samples += packets[end].samples
+		}
+		if float64(samples) < loudnessBlockSecs*opusGranuleRate {
+			break
+		}
+		blocks = append(blocks, weighted/float64(samples))
+		// blocks overlap, the next one starts a step later
+		step := 0
+		for stepSamples := 0; start+step < end && float64(stepSamples) < loudnessStepSecs*opusGranuleRate; step++ {
+			stepSamples += packets[start+step].samples
+		}
+		start += max(step, 1)
+	}
+
+	gated := gateBlocks(blocks, absoluteGateLoudness)
+	if len(gated) == 0 {
+		return 0, false
+	}
+	gated = gateBlocks(gated, loudness(mean(gated))+relativeGateLU)
+	if len(gated) == 0 {
+		return 0, false
+	}
+	return loudness(mean(gated)), true
+}
+
+func gateBlocks(blocks []float64, gate float64) []float64 {
+	var gated []float64
+	for _, block := range blocks {
+		if loudness(block) > gate {
+			gated = append(gated, block)
+		}
+	}
+	return gated
+}
+
+func loudness(meanSquare float64) float64 {
+	return -0.691 + 10*math.Log10(meanSquare)
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// normalizationGain returns the gain in dB that brings the loudness to the target without clipping
+func normalizationGain(loudness float64, packets []packetStats) float64 {
+	gain := math.Max(-maxGainDB, math.Min(maxGainDB, targetLoudness-loudness))
+	var peak float64
+	for _, packet := range packets {
+		peak = math.Max(peak, packet.peak)
+	}
+	if peak > 0 {
+		gain = math.Min(gain, peakCeiling-20*math.Log10(peak))
+	}
+	// the header stores the gain in 1/256 dB steps
+	return math.Round(gain*256) / 256
+}
+
+// rewrite replaces the recording with the kept packets and the gain in its header
+func rewrite(path string, packets []packetStats, gainDB float64) error {
+	target := path + ".processing"
+	err := writePackets(target, packets)
+	if err == nil {
+		err = setOutputGain(target, gainDB)
+	}
+	if err != nil {
+		if err := os.Remove(target); err != nil {
+			log.Println("err removing failed processing", err)
+		}
+		return err
+	}
+	if err := os.Rename(target, path); err != nil {
+		return fmt.Errorf("err replacing processed recording, %w", err)
+	}
+	return nil
+}
+
+func writePackets(path string, packets []packetStats) (err error) {
+	writer, err := oggwriter.New(path, opusGranuleRate, pcmChannels)
+	if err != nil {
+		return fmt.Errorf("err creating ogg writer, %w", err)
+	}
+	defer func() {
+		if closeErr := writer.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("err closing processed recording, %w", closeErr)
+		}
+	}()
+	for i, packet := range packets {
+		// the writer takes a timestamp of 1, the granule position of the first packet, as no packet written yet,
+		// so the timestamps are moved a frame away from it
+		err := writer.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: 0x78, SequenceNumber: uint16(i), Timestamp: uint32(packet.granule) + frameSamples},
+			Payload: packet.payload,
+		})
+		if err != nil {
+			return fmt.Errorf("err writing processed packet, %w", err)
+		}
+	}
+	return nil
+}
+
+// setOutputGain writes the gain in the Opus header of the first page of the file and updates the page checksum,
+// https://www.rfc-editor.org/rfc/rfc7845#section-5.1
+func setOutputGain(path string, gainDB float64) error {
+	const (
+		pageHeaderSize   = 28 // the opus header page has a single segment
+		headerPageSize   = pageHeaderSize + 19
+		checksumOffset   = 22
+		outputGainOffset = pageHeaderSize + 16
+	)
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("err opening processed recording, %w", err)
+	}
+	defer func(f *os.File) {
+		if err := f.Close(); err != nil {
+			log.Println("err closing processed recording", err)
+		}
+	}(f)
+	page := make([]byte, headerPageSize)
+	if _, err := f.ReadAt(page, 0); err != nil {
+		return fmt.Errorf("err reading opus header, %w", err)
+	}
+	if string(page[:4]) != "OggS" || string(page[pageHeaderSize:pageHeaderSize+8]) != "OpusHead" {
+		return fmt.Errorf("unexpected opus header page in %s", path)
+	}
+	binary.LittleEndian.PutUint16(page[outputGainOffset:], uint16(int16(math.Round(gainDB*256))))
+	binary.LittleEndian.PutUint32(page[checksumOffset:], 0)
+	binary.LittleEndian.PutUint32(page[checksumOffset:], oggChecksum(page))
+	if _, err := f.WriteAt(page, 0); err != nil {
+		return fmt.Errorf("err writing opus header, %w", err)
+	}
+	return nil
+}
+
+var oggChecksumTable = func() [256]uint32 {
+	var table [256]uint32
+	const poly = 0x04c11db7
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = (r << 1) ^ poly
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+func oggChecksum(page []byte) uint32 {
+	var crc uint32
+	for _, b := range page {
+		crc = (crc << 8) ^ oggChecksumTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// kWeighting is the filter of ITU-R BS.1770 at 48khz, a high shelf that models the head followed by a high pass
+type kWeighting struct {
+	stages [2][pcmChannels]biquad
+}
+
+func newKWeighting() *kWeighting {
+	shelf := biquad{b0: 1.53512485958697, b1: -2.69169618940638, b2: 1.19839281085285, a1: -1.69065929318241, a2: 0.73248077421585}
+	highPass := biquad{b0: 1, b1: -2, b2: 1, a1: -1.99004745483398, a2: 0.99007225036621}
+	return &kWeighting{stages: [2][pcmChannels]biquad{{shelf, shelf}, {highPass, highPass}}}
+}
+
+func (k *kWeighting) apply(channel int, sample float64) float64 {
+	for i := range k.stages {
+		sample = k.stages[i][channel].apply(sample)
+	}
+	return sample
+}
+
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) apply(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
@@ -0,0 +1,385 @@
+package pion
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media/oggreader"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+	"github.com/stretchr/testify/assert"
+)
+
+// silenceFrame is the 20ms celt frame of silence discord sends
+var silenceFrame = []byte{0xF8, 0xFF, 0xFE}
+
+// testPacket is an opus packet of a fixture recording, sent at its rtp timestamp
+type testPacket struct {
+	payload   []byte
+	timestamp uint32
+}
+
+// noiseFrames are 20ms stereo celt frames of random data, which decode to noise around -30 dBFS
+func noiseFrames(seed int64, count int) [][]byte {
+	r := rand.New(rand.NewSource(seed))
+	frames := make([][]byte, count)
+	for i := range frames {
+		frames[i] = make([]byte, 80)
+		r.Read(frames[i])
+		frames[i][0] = 0xFC
+	}
+	return frames
+}
+
+func repeatFrame(frame []byte, count int) [][]byte {
+	frames := make([][]byte, count)
+	for i := range frames {
+		frames[i] = frame
+	}
+	return frames
+}
+
+// timed gives the frames the timestamps of consecutive 20ms packets from start
+func timed(start uint32, frames [][]byte) []testPacket {
+	packets := make([]testPacket, len(frames))
+	for i, frame := range frames {
+		packets[i] = testPacket{payload: frame, timestamp: start + uint32(i*frameSamples)}
+	}
+	return packets
+}
+
+// writeTestOgg writes the packets as the recordings of the bot are written, returning the path of the file
+func writeTestOgg(t *testing.T, name string, packets []testPacket) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	writer, err := oggwriter.New(path, opusGranuleRate, pcmChannels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, packet := range packets {
+		err := writer.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: 0x78, SequenceNumber: uint16(i), Timestamp: packet.timestamp},
+			Payload: packet.payload,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readTestOgg returns the header and the packets of the file, failing when a page checksum does not match
+func readTestOgg(t *testing.T, path string) (*oggreader.OggHeader, [][]byte, []uint64) {
+	t.Helper()
+	var header *oggreader.OggHeader
+	var payloads [][]byte
+	var granules []uint64
+	err := readOpusPackets(path, func(h *oggreader.OggHeader, payload []byte, granule uint64) error {
+		header = h
+		payloads = append(payloads, payload)
+		granules = append(granules, granule)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header, payloads, granules
+}
+
+// sine feeds a second of a sine of the frequency to a new K-weighting filter, returning its gain in dB once settled
+func kWeightingGain(frequency float64) float64 {
+	filter := newKWeighting()
+	var in, out float64
+	for i := 0; i < opusGranuleRate; i++ {
+		x := math.Sin(2 * math.Pi * frequency * float64(i) / opusGranuleRate)
+		y := filter.apply(0, x)
+		if i >= opusGranuleRate/2 {
+			in += x * x
+			out += y * y
+		}
+	}
+	return 10 * math.Log10(out/in)
+}
+
+func Test_kWeighting(t *testing.T) {
+	tests := []struct {
+		name         string
+		frequency    float64
+		expectedGain float64
+	}{
+		{name: "when frequency is very low, the high pass cuts it", frequency: 20, expectedGain: -13.3},
+		{name: "when frequency is low, the high pass lowers it a bit", frequency: 100, expectedGain: -1.1},
+		{name: "when frequency is 1khz, it is almost untouched, which the -0.691 of the loudness makes up for", frequency: 1000, expectedGain: 0.7},
+		{name: "when frequency is high, the shelf raises it", frequency: 10000, expectedGain: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expectedGain, kWeightingGain(tt.frequency), 0.1)
+		})
+	}
+}
+
+func Test_kWeighting_channels(t *testing.T) {
+	filter := newKWeighting()
+	filter.apply(0, 1)
+	assert.Equal(t, newKWeighting().apply(1, 0.5), filter.apply(1, 0.5), "every channel keeps its own state")
+}
+
+// steady returns packets of 20ms whose K-weighted audio has the loudness in LUFS
+func steady(count int, lufs float64) []packetStats {
+	// the weighted sum adds both channels, as BS.1770 does
+	meanSquare := math.Pow(10, (lufs+0.691)/10)
+	packets := make([]packetStats, count)
+	for i := range packets {
+		packets[i] = packetStats{samples: frameSamples, weighted: meanSquare * frameSamples}
+	}
+	return packets
+}
+
+func Test_integratedLoudness(t *testing.T) {
+	tests := []struct {
+		name             string
+		packets          []packetStats
+		expectedLoudness float64
+		expectedOk       bool
+	}{
+		{name: "when audio is steady, it is its loudness", packets: steady(100, -23), expectedLoudness: -23, expectedOk: true},
+		{name: "when audio is shorter than a block, it cannot be measured", packets: steady(19, -23)},
+		{name: "when audio is as long as a block, it is measured", packets: steady(20, -23), expectedLoudness: -23, expectedOk: true},
+		{name: "when everything is silence, it cannot be measured", packets: make([]packetStats, 100)},
+		{name: "when everything is under the absolute gate, it cannot be measured", packets: steady(100, -75)},
+		// the blocks overlapping the loud parts are over the gates, so the result is a bit under -20
+		{
+			name:             "when there are pauses under the absolute gate, they are left out",
+			packets:          append(append(steady(100, -20), steady(200, -80)...), steady(100, -20)...),
+			expectedLoudness: -20.3,
+			expectedOk:       true,
+		},
+		{
+			name:             "when there are parts 10 LU quieter than the rest, the relative gate leaves them out",
+			packets:          append(steady(100, -20), steady(100, -40)...),
+			expectedLoudness: -20.3,
+			expectedOk:       true,
+		},
+		{
+			name:             "when there are parts a bit quieter than the rest, they are measured",
+			packets:          append(steady(100, -20), steady(100, -23)...),
+			expectedLoudness: -21.2,
+			expectedOk:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loudness, ok := integratedLoudness(tt.packets)
+			assert.Equal(t, tt.expectedOk, ok)
+			assert.InDelta(t, tt.expectedLoudness, loudness, 0.1)
+		})
+	}
+}
+
+func Test_normalizationGain(t *testing.T) {
+	tests := []struct {
+		name         string
+		loudness     float64
+		peak         float64
+		expectedGain float64
+	}{
+		{name: "when audio is quiet, raise it to the target", loudness: -30, peak: 0.01, expectedGain: 14},
+		{name: "when audio is loud, lower it to the target", loudness: -6, peak: 0.9, expectedGain: -10},
+		{name: "when audio is too quiet, raise it as much as the limit", loudness: -50, peak: 0.001, expectedGain: maxGainDB},
+		{name: "when audio is too loud, lower it as much as the limit", loudness: 10, peak: 1, expectedGain: -maxGainDB},
+		{name: "when raising it would clip the peak, stop under the ceiling", loudness: -30, peak: 0.5, expectedGain: math.Round((peakCeiling+6.0206)*256) / 256},
+		{name: "when audio is silence, only the limit applies", loudness: -30, expectedGain: 14},
+		{name: "when gain is not a step of the header, round it", loudness: -16.001, peak: 0.01, expectedGain: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gain := normalizationGain(tt.loudness, []packetStats{{peak: tt.peak / 2}, {peak: tt.peak}})
+			assert.InDelta(t, tt.expectedGain, gain, 0.001)
+			assert.Equal(t, gain, math.Round(gain*256)/256, "the gain is a multiple of 1/256 dB")
+		})
+	}
+}
+
+// levels returns packets 20ms apart with the rms levels, from the granule position start
+func levels(start uint64, rmsDB ...float64) []packetStats {
+	packets := make([]packetStats, len(rmsDB))
+	for i, level := range rmsDB {
+		packets[i] = packetStats{granule: start + uint64(i*frameSamples), samples: frameSamples, rmsDB: level}
+	}
+	return packets
+}
+
+func Test_soundRange(t *testing.T) {
+	quiet, loud := -60.0, -20.0
+	silence := func(count int) []float64 {
+		rms := make([]float64, count)
+		for i := range rms {
+			rms[i] = quiet
+		}
+		return rms
+	}
+	tests := []struct {
+		name          string
+		rmsDB         []float64
+		expectedFirst int
+		expectedLast  int
+	}{
+		{name: "when there is sound all along, keep everything", rmsDB: []float64{loud, loud, loud}, expectedFirst: 0, expectedLast: 2},
+		{name: "when everything is silence, keep everything", rmsDB: silence(20), expectedFirst: 0, expectedLast: 19},
+		{
+			name:          "when there is silence around the sound, trim it keeping a margin of 100ms",
+			rmsDB:         append(append(silence(10), loud, loud), silence(10)...),
+			expectedFirst: 5,
+			expectedLast:  16,
+		},
+		{
+			name:          "when silence is shorter than the margin, keep it",
+			rmsDB:         append(append(silence(2), loud), silence(3)...),
+			expectedFirst: 0,
+			expectedLast:  5,
+		},
+		{
+			name:          "when there is silence between sounds, keep it",
+			rmsDB:         append(append(append(silence(6), loud), silence(20)...), loud),
+			expectedFirst: 1,
+			expectedLast:  27,
+		},
+		{name: "when level is the threshold, it is silence", rmsDB: []float64{quiet, -40, quiet}, expectedFirst: 0, expectedLast: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last := soundRange(levels(0, tt.rmsDB...), -40)
+			assert.Equal(t, tt.expectedFirst, first)
+			assert.Equal(t, tt.expectedLast, last)
+		})
+	}
+}
+
+func Test_trimmedSecs(t *testing.T) {
+	// nobody spoke for a second between the two first packets, discord sent nothing meanwhile
+	gap := append(levels(1, -60), levels(1+frameSamples+opusGranuleRate, -60, -20, -20, -60, -60)...)
+	tests := []struct {
+		name         string
+		packets      []packetStats
+		first        int
+		last         int
+		expectedSecs float64
+	}{
+		{name: "when everything is kept, nothing is trimmed", packets: levels(1, -20, -20, -20), first: 0, last: 2},
+		{name: "when packets are trimmed, count their time", packets: levels(1, -60, -60, -20, -60), first: 2, last: 2, expectedSecs: 0.06},
+		{name: "when there is a gap before the first packet kept, count it", packets: gap, first: 2, last: 3, expectedSecs: 1.08},
+		{name: "when the gap is kept, do not count it", packets: gap, first: 0, last: 3, expectedSecs: 0.04},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expectedSecs, trimmedSecs(tt.packets, tt.first, tt.last), 1e-9)
+		})
+	}
+}
+
+func Test_setOutputGain(t *testing.T) {
+	tests := []struct {
+		name         string
+		gainDB       float64
+		expectedGain int16
+	}{
+		{name: "when gain is positive, write it in 1/256 dB", gainDB: 12.25, expectedGain: 3136},
+		{name: "when gain is negative, write it in two's complement", gainDB: -3.5, expectedGain: -896},
+		{name: "when gain is 0, write no gain", gainDB: 0, expectedGain: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestOgg(t, "recording.ogg", timed(1000, repeatFrame(silenceFrame, 5)))
+
+			assert.NoError(t, setOutputGain(path, tt.gainDB))
+
+			// the reader checks the checksum of every page
+			header, payloads, _ := readTestOgg(t, path)
+			assert.Equal(t, tt.expectedGain, int16(header.OutputGain))
+			assert.Equal(t, uint16(oggPreSkip), header.PreSkip, "the rest of the header is kept")
+			assert.Len(t, payloads, 5)
+		})
+	}
+}
+
+func Test_setOutputGain_notOpus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.ogg")
+	if err := os.WriteFile(path, make([]byte, 100), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Error(t, setOutputGain(path, 3))
+}
+
+func TestProcessor_Process(t *testing.T) {
+	// a second without packets follows the first ones, as discord sends none while nobody speaks
+	leading := timed(1000, repeatFrame(silenceFrame, 5))
+	packets := append(leading, timed(1000+5*frameSamples+opusGranuleRate, repeatFrame(silenceFrame, 15))...)
+	packets = append(packets, timed(packets[len(packets)-1].timestamp+frameSamples, noiseFrames(1, 30))...)
+	packets = append(packets, timed(packets[len(packets)-1].timestamp+frameSamples, repeatFrame(silenceFrame, 20))...)
+	original := (float64(packets[len(packets)-1].timestamp-packets[0].timestamp) + frameSamples) / opusGranuleRate
+	span := func(granules []uint64) float64 {
+		return float64(granules[len(granules)-1]-granules[0]+frameSamples) / opusGranuleRate
+	}
+	tests := []struct {
+		name      string
+		options   domain.ProcessOptions
+		assertRun func(t *testing.T, report domain.ProcessReport, path string)
+	}{
+		{
+			name:    "when nothing is enabled, leave the recording as it is",
+			options: domain.ProcessOptions{SilenceThreshold: -50},
+			assertRun: func(t *testing.T, report domain.ProcessReport, path string) {
+				assert.Equal(t, domain.ProcessReport{}, report)
+				_, payloads, _ := readTestOgg(t, path)
+				assert.Len(t, payloads, len(packets))
+			},
+		},
+		{
+			name:    "when trimming, drop the silence around the sound and count the time without packets too",
+			options: domain.ProcessOptions{TrimSilence: true, SilenceThreshold: -50},
+			assertRun: func(t *testing.T, report domain.ProcessReport, path string) {
+				assert.False(t, report.Normalized)
+				assert.Greater(t, report.TrimmedSecs, 1.0, "the second without packets was trimmed")
+				header, payloads, granules := readTestOgg(t, path)
+				assert.Zero(t, header.OutputGain)
+				assert.Less(t, len(payloads), len(packets))
+				assert.Greater(t, len(payloads), 30)
+				assert.InDelta(t, original-report.TrimmedSecs, span(granules), 1e-9, "the trimmed time is the time the recording lost")
+			},
+		},
+		{
+			name:    "when normalizing, write the gain in the header and keep every packet",
+			options: domain.ProcessOptions{Normalize: true, SilenceThreshold: -50},
+			assertRun: func(t *testing.T, report domain.ProcessReport, path string) {
+				assert.True(t, report.Normalized)
+				assert.NotZero(t, report.GainDB)
+				assert.Zero(t, report.TrimmedSecs)
+				header, payloads, granules := readTestOgg(t, path)
+				assert.Equal(t, int16(math.Round(report.GainDB*256)), int16(header.OutputGain))
+				assert.Len(t, payloads, len(packets))
+				assert.InDelta(t, original, span(granules), 1e-9, "the recording lasts as long as before")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestOgg(t, "recording.ogg", packets)
+
+			report, err := NewProcessor().Process(path, tt.options)
+
+			if !assert.NoError(t, err) {
+				return
+			}
+			tt.assertRun(t, report, path)
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package pion
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	// avatars are png, the decoder is needed to read their size
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+)
+
+const (
+	oggPageHeaderSize = 27
+	maxPageSegments   = 255
+	// tagsVendor is written as the vendor of the comment header, as encoders do
+	tagsVendor = "taterubot"
+	// frontCoverPicture is the picture type of front covers in flac picture blocks
+	frontCoverPicture = 3
+	// maxTagsSize fits the comment header in a single page, the ogg readers of the bot skip it as a single page
+	maxTagsSize = maxPageSegments*maxPageSegments - 1
+)
+
+// Tagger writes the metadata of ogg/opus files as vorbis comments in their comment header,
+// https://www.rfc-editor.org/rfc/rfc7845#section-5.2
+type Tagger struct {
+}
+
+func NewTagger() *Tagger {
+	return &Tagger{}
+}
+
+func (t *Tagger) Supports(format domain.AudioFormat) bool {
+	return format == domain.AudioFormatOpus
+}
+
+// Tag replaces the comment header of the file, the audio pages are copied as they are and only renumbered
+func (t *Tagger) Tag(path string, format domain.AudioFormat, metadata domain.AudioMetadata) error {
+	if !t.Supports(format) {
+		return fmt.Errorf("unsupported audio format %s", format)
+	}
+	tags, err := opusTags(metadata)
+	if err != nil {
+		return err
+	}
+	if len(tags) > maxTagsSize && metadata.CoverPath != "" {
+		log.Println("cover is too large for the comment header, tagging without it")
+		metadata.CoverPath = ""
+		if tags, err = opusTags(metadata); err != nil {
+			return err
+		}
+	}
+	if len(tags) > maxTagsSize {
+		return errors.New("tags are too large for the comment header")
+	}
+	target := path + ".tagging"
+	if err := rewriteTags(path, target, tags); err != nil {
+		if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Println("err removing failed tagging", err)
+		}
+		return err
+	}
+	if err := os.Rename(target, path); err != nil {
+		return fmt.Errorf("err replacing tagged file, %w", err)
+	}
+	return nil
+}
+
+func rewriteTags(path string, target string, tags []byte) (err error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("err opening file to tag, %w", err)
+	}
+	defer func(in *os.File) {
+		if err := in.Close(); err != nil {
+			log.Println("err closing tagged file", err)
+		}
+	}(in)
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("err creating tagged file, %w", err)
+	}
+	defer func(out *os.File) {
+		if closeErr := out.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("err closing tagged file, %w", closeErr)
+		}
+	}(out)
+	reader := bufio.NewReader(in)
+	writer := bufio.NewWriter(out)
+
+	head, err := readPage(reader)
+	if err != nil {
+		return fmt.Errorf("err reading opus header, %w", err)
+	}
+	if !bytes.HasPrefix(head.body, []byte("OpusHead")) {
+		return fmt.Errorf("unexpected opus header page in %s", path)
+	}
+	if _, err := writer.Write(head.bytes()); err != nil {
+		return fmt.Errorf("err writing opus header, %w", err)
+	}
+
+	// the comment header starts in its own page and the audio starts in a new page after it
+	oldPages := 0
+	for {
+		page, err := readPage(reader)
+		if err != nil {
+			return fmt.Errorf("err reading comment header, %w", err)
+		}
+		if oldPages == 0 && !bytes.HasPrefix(page.body, []byte("OpusTags")) {
+			return fmt.Errorf("unexpected comment header page in %s", path)
+		}
+		oldPages++
+		if page.completesPacket() {
+			break
+		}
+	}
+	if _, err := writer.Write(tagsPage(tags, head.serial()).bytes()); err != nil {
+		return fmt.Errorf("err writing comment header, %w", err)
+	}
+
+	for {
+		page, err := readPage(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("err reading audio page, %w", err)
+		}
+		page.setSequence(page.sequence() - uint32(oldPages) + 1)
+		if _, err := writer.Write(page.bytes()); err != nil {
+			return fmt.Errorf("err writing audio page, %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("err writing tagged file, %w", err)
+	}
+	return nil
+}
+
+// opusTags builds the comment header with the metadata, fields without a value are left out
+func opusTags(metadata domain.AudioMetadata) ([]byte, error) {
+	comments := map[string]string{
+		"ARTIST": metadata.Artist,
+		"ALBUM":  metadata.Album,
+		"TITLE":  metadata.Title,
+	}
+	if !metadata.Date.IsZero() {
+		comments["DATE"] = metadata.Date.Format("2006-01-02")
+	}
+	if metadata.CoverPath != "" {
+		picture, err := pictureBlock(metadata.CoverPath)
+		if err != nil {
+			return nil, err
+		}
+		comments["METADATA_BLOCK_PICTURE"] = base64.StdEncoding.EncodeToString(picture)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("OpusTags")
+	writeLengthPrefixed(&buf, binary.LittleEndian, []byte(tagsVendor))
+	var fields []string
+	for _, key := range []string{"ARTIST", "ALBUM", "TITLE", "DATE", "METADATA_BLOCK_PICTURE"} {
+		if comments[key] != "" {
+			fields = append(fields, key+"="+comments[key])
+		}
+	}
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(fields)))
+	for _, field := range fields {
+		writeLengthPrefixed(&buf, binary.LittleEndian, []byte(field))
+	}
+	return buf.Bytes(), nil
+}
+
+// pictureBlock is the flac picture block of the image, the format vorbis comments embed cover art with,
+// https://xiph.org/flac/format.html#metadata_block_picture
+func pictureBlock(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("err reading cover, %w", err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("err decoding cover, %w", err)
+	}
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, uint32(frontCoverPicture))
+	writeLengthPrefixed(&buf, binary.BigEndian, []byte(http.DetectContentType(data)))
+	// no description
+	writeLengthPrefixed(&buf, binary.BigEndian, nil)
+	// width, height, color depth and colors of indexed images, 0 when unknown
+	for _, value := range []int{config.Width, config.Height, 0, 0} {
+		_ = binary.Write(&buf, binary.BigEndian, uint32(value))
+	}
+	writeLengthPrefixed(&buf, binary.BigEndian, data)
+	return buf.Bytes(), nil
+}
+
+func writeLengthPrefixed(buf *bytes.Buffer, order binary.ByteOrder, value []byte) {
+	_ = binary.Write(buf, order, uint32(len(value)))
+	buf.Write(value)
+}
+
+type oggPage struct {
+	// header holds the fixed header followed by the segment table
+	header []byte
+	body   []byte
+}
+
+func readPage(reader io.Reader) (oggPage, error) {
+	header := make([]byte, oggPageHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return oggPage{}, fmt.Errorf("truncated ogg page, %w", err)
+		}
+		return oggPage{}, err
+	}
+	if string(header[:4]) != "OggS" {
+		return oggPage{}, errors.New("missing ogg page signature")
+	}
+	segments := make([]byte, header[oggPageHeaderSize-1])
+	if _, err := io.ReadFull(reader, segments); err != nil {
+		return oggPage{}, fmt.Errorf("err reading ogg segment table, %w", err)
+	}
+	size := 0
+	for _, segment := range segments {
+		size += int(segment)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return oggPage{}, fmt.Errorf("err reading ogg page, %w", err)
+	}
+	return oggPage{header: append(header, segments...), body: body}, nil
+}
+
+// tagsPage lays the comment header out in the page after the opus header page
+func tagsPage(tags []byte, serial uint32) oggPage {
+	// a packet ends with a segment shorter than 255 bytes, an empty one when its length is a multiple of 255
+	segments := bytes.Repeat([]byte{maxPageSegments}, len(tags)/maxPageSegments)
+	segments = append(segments, byte(len(tags)%maxPageSegments))
+	header := make([]byte, oggPageHeaderSize)
+	copy(header, "OggS")
+	binary.LittleEndian.PutUint32(header[14:], serial)
+	header[oggPageHeaderSize-1] = byte(len(segments))
+	page := oggPage{header: append(header, segments...), body: tags}
+	page.setSequence(1)
+	return page
+}
+
+func (p oggPage) serial() uint32 {
+	return binary.LittleEndian.Uint32(p.header[14:])
+}
+
+func (p oggPage) sequence() uint32 {
+	return binary.LittleEndian.Uint32(p.header[18:])
+}
+
+func (p oggPage) setSequence(sequence uint32) {
+	binary.LittleEndian.PutUint32(p.header[18:], sequence)
+}
+
+// completesPacket tells if the last packet of the page ends in it
+func (p oggPage) completesPacket() bool {
+	segments := p.header[oggPageHeaderSize:]
+	return len(segments) > 0 && segments[len(segments)-1] < maxPageSegments
+}
+
+// bytes returns the page with its checksum updated
+func (p oggPage) bytes() []byte {
+	page := append(append([]byte{}, p.header...), p.body...)
+	binary.LittleEndian.PutUint32(page[22:], 0)
+	binary.LittleEndian.PutUint32(page[22:], oggChecksum(page))
+	return page
+}
@@ -0,0 +1,359 @@
+package pion
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/pion/webrtc/v3/pkg/media/oggreader"
+	"github.com/stretchr/testify/assert"
+)
+
+// readTestPages returns every page of the ogg file as it was read
+func readTestPages(t *testing.T, path string) []oggPage {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	var pages []oggPage
+	for {
+		page, err := readPage(reader)
+		if errors.Is(err, io.EOF) {
+			return pages
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page)
+	}
+}
+
+func writeTestPages(t *testing.T, path string, pages []oggPage) {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, page := range pages {
+		buf.Write(page.bytes())
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// withTagsPages replaces the comment header of the recording with one spanning several pages, as other encoders write them
+func withTagsPages(t *testing.T, path string, tags []byte) {
+	t.Helper()
+	pages := readTestPages(t, path)
+	var tagsPages []oggPage
+	for rest := tags; ; {
+		// a segment per page, the packet goes on in the next page while the segment is full
+		size := min(len(rest), maxPageSegments)
+		header := append([]byte{}, pages[0].header[:oggPageHeaderSize]...)
+		header[5] = 0
+		if len(tagsPages) > 0 {
+			// continued packet
+			header[5] = 1
+		}
+		header[oggPageHeaderSize-1] = 1
+		tagsPages = append(tagsPages, oggPage{header: append(header, byte(size)), body: rest[:size]})
+		rest = rest[size:]
+		if size < maxPageSegments {
+			break
+		}
+	}
+	pages = append(append([]oggPage{pages[0]}, tagsPages...), pages[2:]...)
+	for i, page := range pages {
+		page.setSequence(uint32(i))
+	}
+	writeTestPages(t, path, pages)
+}
+
+// readTestTags returns the vendor and the comments of the comment header of the file
+func readTestTags(t *testing.T, path string) (string, []string) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	reader, _, err := oggreader.NewWith(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, _, err := reader.ParseNextPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(payload, []byte("OpusTags")) {
+		t.Fatalf("page after the opus header is not the comment header")
+	}
+	r := bytes.NewReader(payload[len("OpusTags"):])
+	readString := func() string {
+		var length uint32
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			t.Fatal(err)
+		}
+		value := make([]byte, length)
+		if _, err := io.ReadFull(r, value); err != nil {
+			t.Fatal(err)
+		}
+		return string(value)
+	}
+	vendor := readString()
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		t.Fatal(err)
+	}
+	comments := make([]string, count)
+	for i := range comments {
+		comments[i] = readString()
+	}
+	assert.Zero(t, r.Len(), "nothing follows the comments")
+	return vendor, comments
+}
+
+// writeTestCover writes a png of the size, of random pixels so it barely compresses
+func writeTestCover(t *testing.T, width int, height int) string {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	rand.New(rand.NewSource(1)).Read(img.Pix)
+	path := filepath.Join(t.TempDir(), "cover.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func assertPages(t *testing.T, path string, serial uint32) {
+	t.Helper()
+	for i, page := range readTestPages(t, path) {
+		assert.Equal(t, uint32(i), page.sequence(), "pages are numbered one after the other")
+		assert.Equal(t, serial, page.serial(), "every page belongs to the same stream")
+		assert.Equal(t, binary.LittleEndian.Uint32(page.bytes()[22:]), binary.LittleEndian.Uint32(page.header[22:]), "page %d has a valid checksum", i)
+	}
+}
+
+func TestTagger_Tag(t *testing.T) {
+	frames := noiseFrames(1, 5)
+	date := time.Date(2024, 3, 9, 18, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		prepare       func(t *testing.T, path string)
+		format        domain.AudioFormat
+		metadata      func(t *testing.T) domain.AudioMetadata
+		expectedError bool
+		assertTags    func(t *testing.T, comments []string)
+	}{
+		{
+			name:   "when metadata is set, write it as comments",
+			format: domain.AudioFormatOpus,
+			metadata: func(t *testing.T) domain.AudioMetadata {
+				return domain.AudioMetadata{Artist: "tater", Album: "general", Title: "hello = world", Date: date}
+			},
+			assertTags: func(t *testing.T, comments []string) {
+				assert.Equal(t, []string{"ARTIST=tater", "ALBUM=general", "TITLE=hello = world", "DATE=2024-03-09"}, comments)
+			},
+		},
+		{
+			name:   "when fields are empty, leave them out",
+			format: domain.AudioFormatOpus,
+			metadata: func(t *testing.T) domain.AudioMetadata {
+				return domain.AudioMetadata{Artist: "tater"}
+			},
+			assertTags: func(t *testing.T, comments []string) {
+				assert.Equal(t, []string{"ARTIST=tater"}, comments)
+			},
+		},
+		{
+			name:   "when the old comment header spans several pages, replace all of them",
+			format: domain.AudioFormatOpus,
+			prepare: func(t *testing.T, path string) {
+				var old bytes.Buffer
+				old.WriteString("OpusTags")
+				writeLengthPrefixed(&old, binary.LittleEndian, []byte("other encoder"))
+				_ = binary.Write(&old, binary.LittleEndian, uint32(1))
+				writeLengthPrefixed(&old, binary.LittleEndian, []byte("COMMENT="+strings.Repeat("a", 3*maxPageSegments)))
+				withTagsPages(t, path, old.Bytes())
+				assert.Equal(t, 1+4+len(frames), len(readTestPages(t, path)), "the fixture has a comment header of four pages")
+			},
+			metadata: func(t *testing.T) domain.AudioMetadata {
+				return domain.AudioMetadata{Title: "title"}
+			},
+			assertTags: func(t *testing.T, comments []string) {
+				assert.Equal(t, []string{"TITLE=title"}, comments)
+			},
+		},
+		{
+			name:   "when there is a cover, embed it as a picture block",
+			format: domain.AudioFormatOpus,
+			metadata: func(t *testing.T) domain.AudioMetadata {
+				return domain.AudioMetadata{Artist: "tater", CoverPath: writeTestCover(t, 3, 2)}
+			},
+			assertTags: func(t *testing.T, comments []string) {
+				if !assert.Len(t, comments, 2) {
+					return
+				}
+				assert.Equal(t, "ARTIST=tater", comments[0])
+				block, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(comments[1], "METADATA_BLOCK_PICTURE="))
+				if !assert.NoError(t, err) {
+					return
+				}
+				r := bytes.NewReader(block)
+				var pictureType, mimeLength uint32
+				_ = binary.Read(r, binary.BigEndian, &pictureType)
+				_ = binary.Read(r, binary.BigEndian, &mimeLength)
+				mime := make([]byte, mimeLength)
+				_, _ = io.ReadFull(r, mime)
+				var descriptionLength, width, height uint32
+				_ = binary.Read(r, binary.BigEndian, &descriptionLength)
+				_ = binary.Read(r, binary.BigEndian, &width)
+				_ = binary.Read(r, binary.BigEndian, &height)
+				assert.Equal(t, uint32(frontCoverPicture), pictureType)
+				assert.Equal(t, "image/png", string(mime))
+				assert.Zero(t, descriptionLength)
+				assert.Equal(t, []uint32{3, 2}, []uint32{width, height})
+			},
+		},
+		{
+			name:   "when the cover is large, fill the comment header up to its single page",
+			format: domain.AudioFormatOpus,
+			metadata: func(t *testing.T) domain.AudioMetadata {
+				// 100x100 random pixels are around 40KB, over 200 segments once base64 encoded
+				return domain.AudioMetadata{Artist: "tater", CoverPath: writeTestCover(t, 100, 100)}
+			},
+			assertTags: func(t *testing.T, comments []string) {
+				if !assert.Len(t, comments, 2) {
+					return
+				}
+				assert.Greater(t, len(comments[1]), 200*maxPageSegments)
+			},
+		},
+		{
+			name:   "when the cover does not fit in the comment header, tag without it",
+			format: domain.AudioFormatOpus,
+			metadata: func(t *testing.T) domain.AudioMetadata {
+				return domain.AudioMetadata{Artist: "tater", CoverPath: writeTestCover(t, 200, 200)}
+			},
+			assertTags: func(t *testing.T, comments []string) {
+				assert.Equal(t, []string{"ARTIST=tater"}, comments)
+			},
+		},
+		{
+			name:   "when the cover cannot be read, return error",
+			format: domain.AudioFormatOpus,
+			metadata: func(t *testing.T) domain.AudioMetadata {
+				return domain.AudioMetadata{Artist: "tater", CoverPath: filepath.Join(t.TempDir(), "missing.png")}
+			},
+			expectedError: true,
+		},
+		{
+			name:   "when format is not opus, return error",
+			format: domain.AudioFormatMP3,
+			metadata: func(t *testing.T) domain.AudioMetadata {
+				return domain.AudioMetadata{Artist: "tater"}
+			},
+			expectedError: true,
+		},
+		{
+			name:   "when file is not ogg, return error and leave it as it is",
+			format: domain.AudioFormatOpus,
+			prepare: func(t *testing.T, path string) {
+				if err := os.WriteFile(path, []byte("not an ogg file at all, but long enough"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			metadata: func(t *testing.T) domain.AudioMetadata {
+				return domain.AudioMetadata{Artist: "tater"}
+			},
+			expectedError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestOgg(t, "recording.ogg", timed(1000, frames))
+			if tt.prepare != nil {
+				tt.prepare(t, path)
+			}
+			before, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = NewTagger().Tag(path, tt.format, tt.metadata(t))
+
+			_, statErr := os.Stat(path + ".tagging")
+			assert.ErrorIs(t, statErr, os.ErrNotExist, "nothing is left behind")
+			if tt.expectedError {
+				assert.Error(t, err)
+				after, _ := os.ReadFile(path)
+				assert.Equal(t, before, after)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			pages := readTestPages(t, path)
+			assert.Equal(t, 2+len(frames), len(pages), "the comment header takes a single page")
+			assertPages(t, path, pages[0].serial())
+			vendor, comments := readTestTags(t, path)
+			assert.Equal(t, tagsVendor, vendor)
+			tt.assertTags(t, comments)
+			// the reader checks the checksum of every page
+			header, payloads, granules := readTestOgg(t, path)
+			assert.Equal(t, uint8(pcmChannels), header.Channels)
+			assert.Equal(t, frames, payloads, "audio is copied as it is")
+			assert.Equal(t, []uint64{1, 1 + frameSamples, 1 + 2*frameSamples, 1 + 3*frameSamples, 1 + 4*frameSamples}, granules)
+		})
+	}
+}
+
+func Test_tagsPage(t *testing.T) {
+	tests := []struct {
+		name             string
+		size             int
+		expectedSegments int
+		expectedLast     byte
+	}{
+		{name: "when tags are shorter than a segment, take one segment", size: 100, expectedSegments: 1, expectedLast: 100},
+		{name: "when tags fill whole segments, end them with an empty one", size: 2 * maxPageSegments, expectedSegments: 3, expectedLast: 0},
+		{name: "when tags are as large as allowed, take every segment of the page", size: maxTagsSize, expectedSegments: maxPageSegments, expectedLast: maxPageSegments - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := tagsPage(make([]byte, tt.size), 42)
+
+			segments := page.header[oggPageHeaderSize:]
+			assert.Len(t, segments, tt.expectedSegments)
+			assert.Equal(t, byte(tt.expectedSegments), page.header[oggPageHeaderSize-1])
+			assert.Equal(t, tt.expectedLast, segments[len(segments)-1])
+			assert.True(t, page.completesPacket())
+			assert.Equal(t, uint32(1), page.sequence())
+			assert.Equal(t, uint32(42), page.serial())
+
+			read, err := readPage(bytes.NewReader(page.bytes()))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Len(t, read.body, tt.size)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package pion
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/pion/webrtc/v3/pkg/media/oggreader"
+)
+
+// frameSamples are the samples of a 20ms opus frame
+const frameSamples = opusGranuleRate / 50
+
+// errNotPlayable is returned for files whose packets cannot be sent to discord as they are
+var errNotPlayable = errors.New("not 20ms ogg/opus audio")
+
+// OpusTranscoder reads the frames of ogg/opus files that already have 20ms packets, as the recordings of the bot,
+// and hands any other file to the fallback transcoder, if there is one.
+type OpusTranscoder struct {
+	fsRepo   domain.FileRepository
+	fallback domain.OpusTranscoder
+}
+
+func NewOpusTranscoder(fsRepo domain.FileRepository, fallback domain.OpusTranscoder) *OpusTranscoder {
+	return &OpusTranscoder{fsRepo: fsRepo, fallback: fallback}
+}
+
+func (t *OpusTranscoder) ToOpusFrames(fileName string) ([][]byte, error) {
+	frames, err := readPlayableFrames(t.fsRepo.GetFullPath(fileName))
+	if err == nil {
+		return frames, nil
+	}
+	if t.fallback == nil {
+		return nil, fmt.Errorf("err reading opus frames and no transcoder available, %w", err)
+	}
+	return t.fallback.ToOpusFrames(fileName)
+}
+
+func readPlayableFrames(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("err opening audio file, %w", err)
+	}
+	defer func(f *os.File) {
+		if err := f.Close(); err != nil {
+			log.Println("err closing audio file", err)
+		}
+	}(f)
+
+	reader, header, err := oggreader.NewWith(f)
+	if err != nil {
+		return nil, errNotPlayable
+	}
+	if header.Channels != pcmChannels {
+		return nil, errNotPlayable
+	}
+	var frames [][]byte
+	var granule uint64
+	for {
+		payload, pageHeader, err := reader.ParseNextPage()
+		if errors.Is(err, io.EOF) {
+			return frames, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("err parsing ogg page, %w", err)
+		}
+		if bytes.HasPrefix(payload, []byte("OpusTags")) {
+			continue
+		}
+		// a page advancing more than 20ms holds more than one packet
+		if !isSingle20msFrame(payload) || pageHeader.GranulePosition-granule > frameSamples {
+			return nil, errNotPlayable
+		}
+		granule = pageHeader.GranulePosition
+		frames = append(frames, payload)
+	}
+}
+
+// isSingle20msFrame reads the table of contents byte of the packet, https://www.rfc-editor.org/rfc/rfc6716#section-3.1
+func isSingle20msFrame(packet []byte) bool {
+	if len(packet) == 0 {
+		return false
+	}
+	config := packet[0] >> 3
+	if packet[0]&0x03 != 0 {
+		// more than one frame in the packet
+		return false
+	}
+	switch {
+	case config < 12:
+		// silk frames last 10, 20, 40 or 60ms
+		return config%4 == 1
+	case config < 16:
+		// hybrid frames last 10 or 20ms
+		return config%2 == 1
+	default:
+		// celt frames last 2.5, 5, 10 or 20ms
+		return config%4 == 3
+	}
+}
@@ -0,0 +1,122 @@
+package pion
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_readPlayableFrames(t *testing.T) {
+	frames := noiseFrames(1, 5)
+	tests := []struct {
+		name           string
+		file           func(t *testing.T) string
+		expectedFrames [][]byte
+		expectedError  error
+	}{
+		{
+			name: "when every page holds a 20ms frame, return the frames in order",
+			file: func(t *testing.T) string {
+				return writeTestOgg(t, "recording.ogg", timed(1000, frames))
+			},
+			expectedFrames: frames,
+		},
+		{
+			name: "when it was remuxed from several recordings, return the frames of all of them",
+			file: func(t *testing.T) string {
+				output := filepath.Join(t.TempDir(), "joined.ogg")
+				inputs := []string{writeTestOgg(t, "first.ogg", timed(1000, frames[:2])), writeTestOgg(t, "second.ogg", timed(1000, frames[2:]))}
+				if err := remuxOpus(inputs, output); err != nil {
+					t.Fatal(err)
+				}
+				return output
+			},
+			expectedFrames: frames,
+		},
+		{
+			name: "when a packet holds a 10ms frame, it is not playable",
+			file: func(t *testing.T) string {
+				// celt 10ms stereo frame
+				return writeTestOgg(t, "recording.ogg", timed(1000, [][]byte{frames[0], {0xF4, 0xFF, 0xFE}}))
+			},
+			expectedError: errNotPlayable,
+		},
+		{
+			name: "when a packet holds several frames, it is not playable",
+			file: func(t *testing.T) string {
+				return writeTestOgg(t, "recording.ogg", timed(1000, [][]byte{frames[0], {0xFD, 0xFF, 0xFE}}))
+			},
+			expectedError: errNotPlayable,
+		},
+		{
+			name: "when a page advances more than 20ms, it is not playable",
+			file: func(t *testing.T) string {
+				return writeTestOgg(t, "recording.ogg", append(timed(1000, frames[:2]), timed(1000+3*frameSamples, frames[2:])...))
+			},
+			expectedError: errNotPlayable,
+		},
+		{
+			name: "when audio is mono, it is not playable",
+			file: func(t *testing.T) string {
+				path := filepath.Join(t.TempDir(), "mono.ogg")
+				writer, err := oggwriter.New(path, opusGranuleRate, 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := writer.Close(); err != nil {
+					t.Fatal(err)
+				}
+				return path
+			},
+			expectedError: errNotPlayable,
+		},
+		{
+			name: "when file is not ogg, it is not playable",
+			file: func(t *testing.T) string {
+				path := filepath.Join(t.TempDir(), "sound.mp3")
+				if err := os.WriteFile(path, []byte("ID3 not an ogg file"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				return path
+			},
+			expectedError: errNotPlayable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			played, err := readPlayableFrames(tt.file(t))
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedFrames, played)
+		})
+	}
+}
+
+func Test_isSingle20msFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		packet   []byte
+		expected bool
+	}{
+		{name: "when packet is a 20ms silk frame, it is", packet: []byte{1 << 3}, expected: true},
+		{name: "when packet is a 60ms silk frame, it is not", packet: []byte{3 << 3}},
+		{name: "when packet is a 20ms hybrid frame, it is", packet: []byte{13 << 3}, expected: true},
+		{name: "when packet is a 10ms hybrid frame, it is not", packet: []byte{12 << 3}},
+		{name: "when packet is a 20ms celt frame, it is", packet: []byte{31 << 3}, expected: true},
+		{name: "when packet is a 2.5ms celt frame, it is not", packet: []byte{28 << 3}},
+		{name: "when packet has two frames, it is not", packet: []byte{31<<3 | 1}},
+		{name: "when packet is empty, it is not", packet: []byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isSingle20msFrame(tt.packet))
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/hectorgabucio/taterubot-dc/kit/command"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+)
+
+const (
+	// defaultRouteTimeout is as long as the token of an interaction lasts, its answer cannot be edited after it
+	defaultRouteTimeout = 15 * time.Minute
+	// autocompleteTimeout is how long discord waits for the choices of an autocomplete
+	autocompleteTimeout = 3 * time.Second
+	// placeholder is the answer shown until the command edits it
+	placeholder = "..."
+)
+
+// Response is how an interaction is answered before its command is dispatched, discord drops the interactions
+// that are not answered within 3 seconds
+type Response int
+
+const (
+	// ResponseEphemeral answers with a placeholder only the member sees
+	ResponseEphemeral Response = iota
+	// ResponsePublic answers with a placeholder everyone in the channel sees
+	ResponsePublic
+	// ResponseDeferred shows everyone the bot is thinking until the command edits the answer
+	ResponseDeferred
+	// ResponseDeferredEphemeral shows only the member the bot is thinking until the command edits the answer
+	ResponseDeferredEphemeral
+	// ResponseReplace replaces the ephemeral message holding the component with a placeholder, so it is not used twice
+	ResponseReplace
+	// ResponseNone leaves the answer to the command, like autocompletes do
+	ResponseNone
+)
+
+// Route tells how the interactions of a command, component, modal or autocomplete are handled
+type Route struct {
+	// Command builds the command dispatched for the interaction, nil to ignore it
+	Command func(in discord.Interaction) command.Command
+	// Modal is shown as the answer when set, instead of dispatching a command. Nil to ignore the interaction
+	Modal    func(in discord.Interaction) *discord.Modal
+	Response Response
+	// Timeout is how long the command may take, defaultRouteTimeout when 0. It is the deadline of the context the
+	// command is dispatched with, once over the answer tells the member it timed out. The handlers and discord calls do
+	// not stop on it, what they do afterwards still happens
+	Timeout time.Duration
+}
+
+type routeKey struct {
+	kind discord.InteractionKind
+	name string
+}
+
+// Router answers the interactions and dispatches their commands through the command bus, by their kind and the name
+// of their command or custom id
+type Router struct {
+	commandBus command.Bus
+	// discord edits the answers of the commands that time out
+	discord      discord.Client
+	localization *localizations.Localizer
+	routes       map[routeKey]Route
+}
+
+func NewRouter(commandBus command.Bus, discordClient discord.Client, localization *localizations.Localizer) *Router {
+	return &Router{commandBus: commandBus, discord: discordClient, localization: localization, routes: make(map[routeKey]Route)}
+}
+
+// Command routes the slash command with the name
+func (r *Router) Command(name string, route Route) {
+	r.routes[routeKey{discord.InteractionKindCommand, name}] = route
+}
+
+// Component routes the components whose custom id has the name
+func (r *Router) Component(name string, route Route) {
+	r.routes[routeKey{discord.InteractionKindComponent, name}] = route
+}
+
+// Modal routes the submissions of the modals whose custom id has the name
+func (r *Router) Modal(name string, route Route) {
+	r.routes[routeKey{discord.InteractionKindModal, name}] = route
+}
+
+// Autocomplete routes the autocompletes of the slash command with the name, answered by the command it dispatches.
+// Their choices are expected within the seconds discord waits for them
+func (r *Router) Autocomplete(name string, route Route) {
+	route.Response = ResponseNone
+	if route.Timeout == 0 {
+		route.Timeout = autocompleteTimeout
+	}
+	r.routes[routeKey{discord.InteractionKindAutocomplete, name}] = route
+}
+
+// Handle is the handler of the interactions of the session
+func (r *Router) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	in, ok := toInteraction(i)
+	if !ok {
+		return
+	}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("panic handling %s %s: %v\n%s", in.Kind, in.Name, recovered, debug.Stack())
+		}
+	}()
+	route, ok := r.routes[routeKey{in.Kind, in.Name}]
+	if !ok {
+		log.Printf("no route for %s %s, ignoring it\n", in.Kind, in.Name)
+		return
+	}
+	if route.Modal != nil {
+		if modal := route.Modal(in); modal != nil {
+			if err := showModal(s, i.Interaction, *modal); err != nil {
+				log.Printf("err showing modal of %s %s: %v\n", in.Kind, in.Name, err)
+			}
+		}
+		return
+	}
+	if route.Command == nil {
+		return
+	}
+	cmd := route.Command(in)
+	if cmd == nil {
+		return
+	}
+	if err := respond(s, i.Interaction, route.Response); err != nil {
+		log.Printf("err answering %s %s: %v\n", in.Kind, in.Name, err)
+		return
+	}
+	go r.dispatch(in, cmd, route)
+}
+
+// dispatch runs the command until it finishes or its timeout is over, when the answer is edited to tell the member.
+// The command keeps running in the background meanwhile, and may still edit the answer when it finishes
+func (r *Router) dispatch(in discord.Interaction, cmd command.Command, route Route) {
+	timeout := route.Timeout
+	if timeout == 0 {
+		timeout = defaultRouteTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				log.Printf("panic in %s of %s %s: %v\n%s", cmd.Type(), in.Kind, in.Name, recovered, debug.Stack())
+			}
+		}()
+		if err := r.commandBus.Dispatch(ctx, cmd); err != nil {
+			log.Printf("err %s of %s %s: %v\n", cmd.Type(), in.Kind, in.Name, err)
+		}
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		log.Printf("%s of %s %s took longer than %s\n", cmd.Type(), in.Kind, in.Name, timeout)
+		// autocompletes have no answer to edit, discord already stopped waiting for their choices
+		if route.Response == ResponseNone {
+			return
+		}
+		if err := r.discord.EditInteraction(in.Token, r.localization.Get("texts.command_timed_out")); err != nil {
+			log.Printf("err answering %s %s timed out: %v\n", in.Kind, in.Name, err)
+		}
+	}
+}
+
+// respond answers the interaction as the response tells
+func respond(s *discordgo.Session, i *discordgo.Interaction, response Response) error {
+	var answer *discordgo.InteractionResponse
+	switch response {
+	case ResponseNone:
+		return nil
+	case ResponseEphemeral:
+		answer = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: placeholder, Flags: discordgo.MessageFlagsEphemeral},
+		}
+	case ResponsePublic:
+		answer = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: placeholder},
+		}
+	case ResponseDeferred:
+		answer = &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredChannelMessageWithSource}
+	case ResponseDeferredEphemeral:
+		answer = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+		}
+	case ResponseReplace:
+		answer = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{Content: placeholder, Flags: discordgo.MessageFlagsEphemeral, Components: []discordgo.MessageComponent{}},
+		}
+	default:
+		return fmt.Errorf("unknown response %d", response)
+	}
+	return s.InteractionRespond(i, answer)
+}
+
+func showModal(s *discordgo.Session, i *discordgo.Interaction, modal discord.Modal) error {
+	rows := make([]discordgo.MessageComponent, len(modal.Fields))
+	for j, field := range modal.Fields {
+		style := discordgo.TextInputShort
+		if field.Paragraph {
+			style = discordgo.TextInputParagraph
+		}
+		rows[j] = discordgo.ActionsRow{Components: []discordgo.MessageComponent{discordgo.TextInput{
+			CustomID:    field.CustomID,
+			Label:       field.Label,
+			Style:       style,
+			Placeholder: field.Placeholder,
+			Value:       field.Value,
+			Required:    field.Required,
+			MinLength:   field.MinLength,
+			MaxLength:   field.MaxLength,
+		}}}
+	}
+	return s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{CustomID: modal.CustomID, Title: modal.Title, Components: rows},
+	})
+}
+
+// toInteraction reads the interaction, false for the kinds that are not routed
+func toInteraction(i *discordgo.InteractionCreate) (discord.Interaction, bool) {
+	in := discord.Interaction{ID: i.ID, Token: i.Token, GuildID: i.GuildID, ChannelID: i.ChannelID}
+	switch {
+	case i.Member != nil && i.Member.User != nil:
+		in.User = toUser(i.Member.User)
+	case i.User != nil:
+		in.User = toUser(i.User)
+	}
+	if i.Message != nil {
+		in.MessageID = i.Message.ID
+	}
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete:
+		in.Kind = discord.InteractionKindCommand
+		if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+			in.Kind = discord.InteractionKindAutocomplete
+		}
+		data := i.ApplicationCommandData()
+		in.Name = data.Name
+		options := data.Options
+		if len(options) == 1 && options[0].Type == discordgo.ApplicationCommandOptionSubCommand {
+			in.Subcommand = options[0].Name
+			options = options[0].Options
+		}
+		for _, option := range options {
+			in.Options = append(in.Options, discord.InteractionOption{Name: option.Name, Value: fmt.Sprintf("%v", option.Value)})
+			if option.Focused {
+				in.Focused = option.Name
+			}
+		}
+	case discordgo.InteractionMessageComponent:
+		in.Kind = discord.InteractionKindComponent
+		data := i.MessageComponentData()
+		in.Name, in.Args = discord.ParseCustomID(data.CustomID)
+		in.Values = data.Values
+	case discordgo.InteractionModalSubmit:
+		in.Kind = discord.InteractionKindModal
+		data := i.ModalSubmitData()
+		in.Name, in.Args = discord.ParseCustomID(data.CustomID)
+		for _, component := range data.Components {
+			row, ok := component.(*discordgo.ActionsRow)
+			if !ok {
+				continue
+			}
+			for _, field := range row.Components {
+				if input, ok := field.(*discordgo.TextInput); ok {
+					in.Options = append(in.Options, discord.InteractionOption{Name: input.CustomID, Value: input.Value})
+				}
+			}
+		}
+	default:
+		return discord.Interaction{}, false
+	}
+	return in, true
+}
+
+func toUser(user *discordgo.User) discord.User {
+	return discord.User{
+		ID:          user.ID,
+		Username:    user.Username,
+		AvatarURL:   user.AvatarURL(""),
+		AccentColor: user.AccentColor,
+		Bot:         user.Bot,
+	}
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	discordmocks "github.com/hectorgabucio/taterubot-dc/domain/discord/mocks"
+	"github.com/hectorgabucio/taterubot-dc/kit/command"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// routedCommand tells which route built it and from which interaction
+type routedCommand struct {
+	route string
+	in    discord.Interaction
+}
+
+func (c routedCommand) Type() command.Type {
+	return "routed"
+}
+
+// recordingBus hands the dispatched commands over through a channel, as the router dispatches them in the background
+type recordingBus struct {
+	dispatched chan routedCommand
+}
+
+func (b *recordingBus) Dispatch(_ context.Context, cmd command.Command) error {
+	b.dispatched <- cmd.(routedCommand)
+	return nil
+}
+
+func (b *recordingBus) Register(command.Type, command.Handler) {}
+
+func (b *recordingBus) Close() error {
+	return nil
+}
+
+func routeTo(name string) Route {
+	return Route{Response: ResponseNone, Command: func(in discord.Interaction) command.Command {
+		return routedCommand{route: name, in: in}
+	}}
+}
+
+func TestRouter_Handle(t *testing.T) {
+	tests := []struct {
+		name          string
+		interaction   *discordgo.Interaction
+		expectedRoute string
+		assertIn      func(t *testing.T, in discord.Interaction)
+	}{
+		{
+			name: "when slash command is used, route it by its name",
+			interaction: &discordgo.Interaction{Type: discordgo.InteractionApplicationCommand, GuildID: "guild", Member: &discordgo.Member{User: &discordgo.User{ID: "user"}}, Data: discordgo.ApplicationCommandInteractionData{
+				Name:    "sound",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{{Name: "name", Type: discordgo.ApplicationCommandOptionString, Value: "airhorn"}},
+			}},
+			expectedRoute: "command sound",
+			assertIn: func(t *testing.T, in discord.Interaction) {
+				assert.Equal(t, "airhorn", in.Option("name"))
+				assert.Equal(t, "user", in.User.ID)
+				assert.Equal(t, "guild", in.GuildID)
+			},
+		},
+		{
+			name: "when slash command has a subcommand, read the options of the subcommand",
+			interaction: &discordgo.Interaction{Type: discordgo.InteractionApplicationCommand, User: &discordgo.User{ID: "user"}, Data: discordgo.ApplicationCommandInteractionData{
+				Name: "settings",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{{Name: "bitrate", Type: discordgo.ApplicationCommandOptionSubCommand, Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: "kbps", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(96)},
+				}}},
+			}},
+			expectedRoute: "command settings",
+			assertIn: func(t *testing.T, in discord.Interaction) {
+				assert.Equal(t, "bitrate", in.Subcommand)
+				assert.Equal(t, "96", in.Option("kbps"))
+				assert.Equal(t, "user", in.User.ID, "direct messages have the user instead of the member")
+			},
+		},
+		{
+			name: "when autocomplete is sent, route it apart from the command",
+			interaction: &discordgo.Interaction{Type: discordgo.InteractionApplicationCommandAutocomplete, Data: discordgo.ApplicationCommandInteractionData{
+				Name:    "sound",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{{Name: "name", Type: discordgo.ApplicationCommandOptionString, Value: "air", Focused: true}},
+			}},
+			expectedRoute: "autocomplete sound",
+			assertIn: func(t *testing.T, in discord.Interaction) {
+				assert.Equal(t, "name", in.Focused)
+			},
+		},
+		{
+			name:          "when component is clicked, route it by the name of its custom id",
+			interaction:   &discordgo.Interaction{Type: discordgo.InteractionMessageComponent, Message: &discordgo.Message{ID: "message"}, Data: discordgo.MessageComponentInteractionData{CustomID: "sound:a%3Ab:c"}},
+			expectedRoute: "component sound",
+			assertIn: func(t *testing.T, in discord.Interaction) {
+				assert.Equal(t, []string{"a:b", "c"}, in.Args)
+				assert.Equal(t, "message", in.MessageID)
+			},
+		},
+		{
+			name: "when modal is submitted, route it by the name of its custom id with its fields as options",
+			interaction: &discordgo.Interaction{Type: discordgo.InteractionModalSubmit, Data: discordgo.ModalSubmitInteractionData{CustomID: "sound:id", Components: []discordgo.MessageComponent{
+				&discordgo.ActionsRow{Components: []discordgo.MessageComponent{&discordgo.TextInput{CustomID: "title", Value: "new title"}}},
+			}}},
+			expectedRoute: "modal sound",
+			assertIn: func(t *testing.T, in discord.Interaction) {
+				assert.Equal(t, []string{"id"}, in.Args)
+				assert.Equal(t, "new title", in.Option("title"))
+			},
+		},
+		{
+			name:        "when there is no route, ignore it",
+			interaction: &discordgo.Interaction{Type: discordgo.InteractionApplicationCommand, Data: discordgo.ApplicationCommandInteractionData{Name: "unknown"}},
+		},
+		{
+			name:        "when interaction is a ping, ignore it",
+			interaction: &discordgo.Interaction{Type: discordgo.InteractionPing},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &recordingBus{dispatched: make(chan routedCommand, 1)}
+			router := NewRouter(bus, nil, nil)
+			router.Command("sound", routeTo("command sound"))
+			router.Command("settings", routeTo("command settings"))
+			router.Autocomplete("sound", routeTo("autocomplete sound"))
+			router.Component("sound", routeTo("component sound"))
+			router.Modal("sound", routeTo("modal sound"))
+
+			router.Handle(nil, &discordgo.InteractionCreate{Interaction: tt.interaction})
+
+			select {
+			case cmd := <-bus.dispatched:
+				assert.Equal(t, tt.expectedRoute, cmd.route)
+				if tt.assertIn != nil {
+					tt.assertIn(t, cmd.in)
+				}
+			case <-time.After(100 * time.Millisecond):
+				assert.Empty(t, tt.expectedRoute, "no command was dispatched")
+			}
+		})
+	}
+}
+
+func TestRouter_Autocomplete(t *testing.T) {
+	router := NewRouter(&recordingBus{}, nil, nil)
+	router.Autocomplete("sound", Route{Response: ResponsePublic, Command: routeTo("").Command})
+	router.Autocomplete("play", Route{Timeout: time.Second, Command: routeTo("").Command})
+
+	sound := router.routes[routeKey{discord.InteractionKindAutocomplete, "sound"}]
+	assert.Equal(t, ResponseNone, sound.Response, "autocompletes answer with their choices")
+	assert.Equal(t, autocompleteTimeout, sound.Timeout)
+	assert.Equal(t, time.Second, router.routes[routeKey{discord.InteractionKindAutocomplete, "play"}].Timeout)
+	_, ok := router.routes[routeKey{discord.InteractionKindCommand, "sound"}]
+	assert.False(t, ok, "autocompletes do not route the command")
+}
+
+// slowBus runs the commands as the handler does, reporting when the context of the command is over
+type slowBus struct {
+	handle      func(ctx context.Context) error
+	ctxFinished chan error
+}
+
+func (b *slowBus) Dispatch(ctx context.Context, _ command.Command) error {
+	err := b.handle(ctx)
+	b.ctxFinished <- ctx.Err()
+	return err
+}
+
+func (b *slowBus) Register(command.Type, command.Handler) {}
+
+func (b *slowBus) Close() error {
+	return nil
+}
+
+func TestRouter_dispatch(t *testing.T) {
+	timeout := 20 * time.Millisecond
+	waitContext := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	tests := []struct {
+		name       string
+		handle     func(ctx context.Context) error
+		response   Response
+		expectEdit bool
+		// expectDeadline waits for the command to see its context is over
+		expectDeadline bool
+	}{
+		{name: "when command finishes in time, leave its answer", handle: func(context.Context) error { return nil }, response: ResponseDeferred},
+		{name: "when command fails in time, leave its answer", handle: func(context.Context) error { return errors.New("err handler") }, response: ResponseDeferred},
+		{name: "when command takes longer than its timeout, answer it timed out", handle: waitContext, response: ResponseDeferred, expectEdit: true, expectDeadline: true},
+		{
+			name: "when command ignores the context, answer it timed out anyway",
+			handle: func(context.Context) error {
+				time.Sleep(5 * timeout)
+				return nil
+			},
+			response:   ResponseEphemeral,
+			expectEdit: true,
+		},
+		{name: "when autocomplete takes longer than its timeout, there is no answer to edit", handle: waitContext, response: ResponseNone, expectDeadline: true},
+		{name: "when command panics, recover from it", handle: func(context.Context) error { panic("handler") }, response: ResponseDeferred},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &slowBus{handle: tt.handle, ctxFinished: make(chan error, 1)}
+			client := &discordmocks.Client{}
+			if tt.expectEdit {
+				client.On("EditInteraction", "token", "This is taking longer than expected, try again in a while").Return(nil).Once()
+			}
+			router := NewRouter(bus, client, localizations.New("en", "en"))
+
+			router.dispatch(discord.Interaction{Kind: discord.InteractionKindCommand, Name: "sound", Token: "token"}, routedCommand{}, Route{Response: tt.response, Timeout: timeout})
+
+			client.AssertExpectations(t)
+			if !tt.expectEdit {
+				client.AssertNotCalled(t, "EditInteraction", mock.Anything, mock.Anything)
+			}
+			if tt.expectDeadline {
+				assert.ErrorIs(t, <-bus.ctxFinished, context.DeadlineExceeded, "the command is dispatched with the deadline")
+			}
+		})
+	}
+}
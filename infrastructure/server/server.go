@@ -9,18 +9,33 @@ import (
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/hectorgabucio/taterubot-dc/application"
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
 	"github.com/hectorgabucio/taterubot-dc/kit/command"
+	"github.com/hectorgabucio/taterubot-dc/localizations"
 )
 
+var manageServerPermission int64 = discordgo.PermissionManageServer
+
+var moderateMembersPermission int64 = discordgo.PermissionModerateMembers
+
+var minAudioBitrate float64 = domain.MinAudioBitrate
+
+var minSilenceThreshold float64 = domain.MinSilenceThreshold
+
 type Server struct {
-	session    *discordgo.Session
-	commandBus command.Bus
+	session *discordgo.Session
+	// discordClient looks up the users the gateway events do not carry
+	discordClient discord.Client
+	commandBus    command.Bus
+	router        *Router
 }
 
-func NewServer(ctx context.Context, session *discordgo.Session, commandBus command.Bus) (context.Context, *Server) {
+func NewServer(ctx context.Context, session *discordgo.Session, discordClient discord.Client, commandBus command.Bus, localization *localizations.Localizer) (context.Context, *Server) {
 	log.Println("Bot server running")
 
-	srv := Server{session, commandBus}
+	srv := Server{session, discordClient, commandBus, NewRouter(commandBus, discordClient, localization)}
+	srv.installRoutes()
 	srv.registerHandlers()
 
 	return serverContext(ctx), &srv
@@ -49,42 +64,369 @@ func (server *Server) installInteractions() {
 				discordgo.SpanishES: "Vamos a ver algunas estadísticas chulas de este servidor",
 			},
 		},
-	}
-	commandHandlers := map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
-		"taterubot": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-			if err := s.InteractionRespond(&discordgo.Interaction{ID: i.ID, Token: i.Token}, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "...",
-				},
-			}); err != nil {
-				log.Println(err)
-				return
-			}
-			go func() {
-				err := server.commandBus.Dispatch(context.Background(), application.NewGreetingCommand(i.Token))
-				if err != nil {
-					log.Println("err greeting command", err)
-				}
-			}()
+		{
+			Name:        "play",
+			Description: "Play the last voice message in your voice channel",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.SpanishES: "Reproduce el último mensaje de voz en tu canal de voz",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "Play the last voice message of this user",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Reproduce el último mensaje de voz de este usuario",
+					},
+				},
+			},
 		},
-		"stats": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-			if err := s.InteractionRespond(&discordgo.Interaction{ID: i.ID, Token: i.Token}, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "...",
-				},
-			}); err != nil {
-				log.Println(err)
-				return
-			}
-			go func() {
-				err := server.commandBus.Dispatch(context.Background(), application.NewStatsCommand(i.Token, i.GuildID))
-				if err != nil {
-					log.Println("err stats command", err)
-				}
-			}()
-
+		{
+			Name:        "intro",
+			Description: "Choose the clip I play when you join a voice channel",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.SpanishES: "Elige el audio que reproduzco cuando entras en un canal de voz",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.IntroActionSet,
+					Description: "Use your next recording or an existing voice message as your intro",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Usa tu próxima grabación o un mensaje de voz existente como tu intro",
+					},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "message",
+							Description: "Link or ID of the voice message, leave it empty to record a new one",
+							DescriptionLocalizations: map[discordgo.Locale]string{
+								discordgo.SpanishES: "Enlace o ID del mensaje de voz, déjalo vacío para grabar uno nuevo",
+							},
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.IntroActionClear,
+					Description: "Stop playing your intro",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Deja de reproducir tu intro",
+					},
+				},
+			},
+		},
+		{
+			Name:        "sound",
+			Description: "Save voice messages as sounds and play them back",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.SpanishES: "Guarda mensajes de voz como sonidos y vuelve a reproducirlos",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.SoundActionSave,
+					Description: "Save a voice message as a sound of this server",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Guarda un mensaje de voz como sonido de este servidor",
+					},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "name",
+							Description: "Name of the sound",
+							DescriptionLocalizations: map[discordgo.Locale]string{
+								discordgo.SpanishES: "Nombre del sonido",
+							},
+							Required: true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "message",
+							Description: "Link or ID of the voice message, the last one is saved if empty",
+							DescriptionLocalizations: map[discordgo.Locale]string{
+								discordgo.SpanishES: "Enlace o ID del mensaje de voz, si está vacío se guarda el último",
+							},
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.SoundActionPlay,
+					Description: "Play a sound in your voice channel",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Reproduce un sonido en tu canal de voz",
+					},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "name",
+							Description: "Name of the sound",
+							DescriptionLocalizations: map[discordgo.Locale]string{
+								discordgo.SpanishES: "Nombre del sonido",
+							},
+							Required:     true,
+							Autocomplete: true,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:        "effect",
+			Description: "Choose the voice effect of your recordings",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.SpanishES: "Elige el efecto de voz de tus grabaciones",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.EffectActionSet,
+					Description: "Set the effect applied to your next recordings",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Elige el efecto que se aplica a tus próximas grabaciones",
+					},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "name",
+							Description: "Voice effect",
+							DescriptionLocalizations: map[discordgo.Locale]string{
+								discordgo.SpanishES: "Efecto de voz",
+							},
+							Required: true,
+							Choices:  voiceEffectChoices(),
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:                     "settings",
+			Description:              "Change how I behave in this server",
+			DefaultMemberPermissions: &manageServerPermission,
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.SpanishES: "Cambia cómo me comporto en este servidor",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.SettingStartCue,
+					Description: "Play a beep when I start recording",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Haz un pitido cuando empiece a grabar",
+					},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "enabled",
+							Description: "Whether the beep is played",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.SettingVoiceMessages,
+					Description: "Send recordings as voice messages instead of audio files",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Envía las grabaciones como mensajes de voz en vez de archivos de audio",
+					},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "enabled",
+							Description: "Whether voice messages are used",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.SettingFormat,
+					Description: "Audio format of the voice messages",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Formato de audio de los mensajes de voz",
+					},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "format",
+							Description: "Audio format",
+							Required:    true,
+							Choices:     audioFormatChoices(),
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.SettingBitrate,
+					Description: "Bitrate of the voice messages, lossless formats ignore it",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Bitrate de los mensajes de voz, los formatos sin pérdida lo ignoran",
+					},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "kbps",
+							Description: "Bitrate in kbps",
+							Required:    true,
+							MinValue:    &minAudioBitrate,
+							MaxValue:    domain.MaxAudioBitrate,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.SettingNormalize,
+					Description: "Bring every recording to the same loudness",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Iguala el volumen de todas las grabaciones",
+					},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "enabled",
+							Description: "Whether recordings are normalized",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.SettingTrimSilence,
+					Description: "Cut the silence at the start and end of the recordings",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Recorta el silencio al principio y al final de las grabaciones",
+					},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "enabled",
+							Description: "Whether silence is trimmed",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.SettingSilenceThreshold,
+					Description: "Level under which audio is considered silence when trimming",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Nivel por debajo del cual el audio se considera silencio al recortar",
+					},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "db",
+							Description: "Level in dBFS",
+							Required:    true,
+							MinValue:    &minSilenceThreshold,
+							MaxValue:    domain.MaxSilenceThreshold,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.SettingAnonymousChannel,
+					Description: "Voice channel whose recordings are sent anonymously, with a disguised voice",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Canal de voz cuyas grabaciones se envían anónimas, con la voz disfrazada",
+					},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							Name:         "channel",
+							Description:  "Anonymous voice channel, leave it empty to stop recording anonymously",
+							ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:                     "reveal",
+			Description:              "Reveal who sent an anonymous voice message, the reveal is recorded",
+			DefaultMemberPermissions: &moderateMembersPermission,
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.SpanishES: "Revela quién envió un mensaje de voz anónimo, la revelación queda registrada",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "message",
+					Description: "Link or ID of the anonymous voice message",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Enlace o ID del mensaje de voz anónimo",
+					},
+					Required: true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "reason",
+					Description: "Why the author needs to be revealed",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Por qué hay que revelar al autor",
+					},
+					Required: true,
+				},
+			},
+		},
+		{
+			Name:        "caption",
+			Description: "Give a title to your next recording, it is shown in its embed and tags",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.SpanishES: "Pon un título a tu próxima grabación, se muestra en su embed y sus etiquetas",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "text",
+					Description: "Title of the recording",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Título de la grabación",
+					},
+					Required:  true,
+					MaxLength: domain.MaxCaptionLength,
+				},
+			},
+		},
+		{
+			Name:                     "deliveries",
+			Description:              "See what could not be delivered to Discord and send it again",
+			DefaultMemberPermissions: &manageServerPermission,
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.SpanishES: "Mira lo que no se pudo entregar a Discord y vuelve a enviarlo",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.DeliveriesActionList,
+					Description: "List the failed deliveries of this server",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Lista las entregas fallidas de este servidor",
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        application.DeliveriesActionReplay,
+					Description: "Send a failed delivery again",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.SpanishES: "Vuelve a enviar una entrega fallida",
+					},
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "id",
+							Description: "ID of the failed delivery, as listed",
+							DescriptionLocalizations: map[discordgo.Locale]string{
+								discordgo.SpanishES: "ID de la entrega fallida, como aparece en la lista",
+							},
+							Required: true,
+						},
+					},
+				},
+			},
 		},
 	}
 	guilds, err := server.session.UserGuilds(100, "", "")
@@ -102,12 +444,114 @@ func (server *Server) installInteractions() {
 			registeredCommands[i] = cmd
 		}
 	}
+}
 
-	server.session.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		if h, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
-			h(s, i)
+func (server *Server) installRoutes() {
+	router := server.router
+	router.Command("taterubot", Route{Response: ResponsePublic, Command: func(in discord.Interaction) command.Command {
+		return application.NewGreetingCommand(in.Token)
+	}})
+	router.Command("stats", Route{Response: ResponsePublic, Command: func(in discord.Interaction) command.Command {
+		return application.NewStatsCommand(in.Token, in.GuildID)
+	}})
+	router.Command("play", Route{Command: func(in discord.Interaction) command.Command {
+		if in.GuildID == "" {
+			return nil
 		}
-	})
+		return application.NewPlayCommand(in.Token, in.GuildID, in.User.ID, "", "", in.Option("user"))
+	}})
+	router.Command("intro", Route{Command: func(in discord.Interaction) command.Command {
+		if in.GuildID == "" || in.Subcommand == "" {
+			return nil
+		}
+		return application.NewIntroCommand(in.Token, in.GuildID, in.User.ID, in.ChannelID, in.Subcommand, in.Option("message"))
+	}})
+	router.Command("sound", Route{Command: func(in discord.Interaction) command.Command {
+		if in.GuildID == "" || in.Subcommand == "" {
+			return nil
+		}
+		return application.NewSoundCommand(in.Token, in.GuildID, in.User.ID, in.ChannelID, in.Subcommand, in.Option("name"), in.Option("message"))
+	}})
+	router.Command("effect", Route{Command: func(in discord.Interaction) command.Command {
+		if in.GuildID == "" || in.Subcommand == "" || len(in.Options) == 0 {
+			return nil
+		}
+		return application.NewEffectCommand(in.Token, in.GuildID, in.User.ID, in.Subcommand, in.Options[0].Value)
+	}})
+	router.Command("settings", Route{Command: func(in discord.Interaction) command.Command {
+		if in.Subcommand == "" {
+			return nil
+		}
+		// only optional values can be left empty, which clears the setting
+		value := ""
+		if len(in.Options) > 0 {
+			value = in.Options[0].Value
+		}
+		return application.NewSettingsCommand(in.Token, in.GuildID, in.Subcommand, value)
+	}})
+	router.Command("reveal", Route{Command: func(in discord.Interaction) command.Command {
+		if in.GuildID == "" {
+			return nil
+		}
+		return application.NewRevealCommand(in.Token, in.GuildID, in.User.ID, in.ChannelID, in.Option("message"), in.Option("reason"))
+	}})
+	router.Command("deliveries", Route{Command: func(in discord.Interaction) command.Command {
+		if in.Subcommand == "" {
+			return nil
+		}
+		return application.NewDeliveriesCommand(in.Token, in.GuildID, in.Subcommand, in.Option("id"))
+	}})
+	router.Command("caption", Route{Command: func(in discord.Interaction) command.Command {
+		if in.GuildID == "" {
+			return nil
+		}
+		return application.NewCaptionCommand(in.Token, in.GuildID, in.User.ID, in.Option("text"))
+	}})
+
+	router.Component(application.PlayComponentID, Route{Command: func(in discord.Interaction) command.Command {
+		if in.GuildID == "" {
+			return nil
+		}
+		return application.NewPlayCommand(in.Token, in.GuildID, in.User.ID, in.ChannelID, in.MessageID, "")
+	}})
+	router.Component(application.RemixComponentID, Route{Command: func(in discord.Interaction) command.Command {
+		if in.GuildID == "" {
+			return nil
+		}
+		return application.NewRemixCommand(in.Token, in.GuildID, in.User.ID, in.User.Username, in.User.AvatarURL, in.ChannelID, in.MessageID, "")
+	}})
+	router.Component(application.ExportVideoComponentID, Route{Command: func(in discord.Interaction) command.Command {
+		return application.NewExportVideoCommand(in.Token, in.GuildID, in.ChannelID, in.MessageID)
+	}})
+	// the menu is replaced by the answer, so the effect cannot be chosen twice
+	router.Component(application.RemixEffectComponentID, Route{Response: ResponseReplace, Command: func(in discord.Interaction) command.Command {
+		if in.GuildID == "" || len(in.Args) != 2 || in.Args[0] == "" || in.Args[1] == "" || len(in.Values) == 0 {
+			return nil
+		}
+		return application.NewRemixCommand(in.Token, in.GuildID, in.User.ID, in.User.Username, in.User.AvatarURL, in.Args[0], in.Args[1], in.Values[0])
+	}})
+
+	router.Autocomplete("sound", Route{Command: func(in discord.Interaction) command.Command {
+		return application.NewSoundAutocompleteCommand(in.ID, in.Token, in.GuildID, in.Option(in.Focused))
+	}})
+}
+
+func voiceEffectChoices() []*discordgo.ApplicationCommandOptionChoice {
+	effects := append([]domain.VoiceEffect{domain.VoiceEffectNone}, domain.VoiceEffects()...)
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, len(effects))
+	for i, effect := range effects {
+		choices[i] = &discordgo.ApplicationCommandOptionChoice{Name: effect.Label(), Value: effect.Name()}
+	}
+	return choices
+}
+
+func audioFormatChoices() []*discordgo.ApplicationCommandOptionChoice {
+	formats := domain.AudioFormats()
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, len(formats))
+	for i, format := range formats {
+		choices[i] = &discordgo.ApplicationCommandOptionChoice{Name: format.Label(), Value: string(format)}
+	}
+	return choices
 }
 
 func (server *Server) registerHandlers() {
@@ -116,30 +560,64 @@ func (server *Server) registerHandlers() {
 		server.installInteractions()
 	})
 
+	server.session.AddHandler(server.router.Handle)
+
 	server.session.AddHandler(func(s *discordgo.Session, r *discordgo.VoiceStateUpdate) {
-		user, err := s.User(r.UserID)
+		user, err := server.voiceStateUser(r)
 		if err != nil {
+			log.Println("err getting user of voice state update", err)
 			return
 		}
 		if user.Bot {
 			return
 		}
 
+		var before *discord.VoiceState
 		if r.BeforeUpdate != nil {
-			if (r.SelfMute && !r.BeforeUpdate.SelfMute) || (!r.SelfMute && r.BeforeUpdate.SelfMute) {
-				return
-			}
+			state := voiceState(r.BeforeUpdate)
+			before = &state
 		}
-
-		go func() {
-			err := server.commandBus.Dispatch(context.Background(), application.NewRecordingCommand(r.UserID, r.ChannelID, r.GuildID, user.Username, user.AvatarURL("")))
-			if err != nil {
-				log.Println("err recording command", err)
+		for _, intent := range application.ClassifyVoiceStateUpdate(before, voiceState(r.VoiceState)) {
+			go func() {
+				err := server.commandBus.Dispatch(context.Background(), application.NewRecordingCommand(intent, r.UserID, r.ChannelID, r.GuildID, user.Username, user.AvatarURL))
+				if err != nil {
+					log.Println("err recording command", err)
+				}
+			}()
+			if intent != discord.VoiceIntentJoined && intent != discord.VoiceIntentMoved {
+				continue
 			}
-		}()
+			go func() {
+				err := server.commandBus.Dispatch(context.Background(), application.NewPlayIntroCommand(r.GuildID, r.UserID, r.ChannelID))
+				if err != nil {
+					log.Println("err play intro command", err)
+				}
+			}()
+		}
 	})
 }
 
+// voiceStateUser is the member sent with the update, only looked up when discord leaves it out
+func (server *Server) voiceStateUser(r *discordgo.VoiceStateUpdate) (discord.User, error) {
+	if r.Member == nil || r.Member.User == nil {
+		return server.discordClient.GetUser(r.UserID)
+	}
+	return toUser(r.Member.User), nil
+}
+
+func voiceState(state *discordgo.VoiceState) discord.VoiceState {
+	return discord.VoiceState{
+		ChannelID:  state.ChannelID,
+		SelfMute:   state.SelfMute,
+		SelfDeaf:   state.SelfDeaf,
+		Mute:       state.Mute,
+		Deaf:       state.Deaf,
+		SelfStream: state.SelfStream,
+		SelfVideo:  state.SelfVideo,
+		Suppress:   state.Suppress,
+	}
+}
+
 func (server *Server) Run(ctx context.Context) error {
 	if err := server.session.Open(); err != nil {
 		return fmt.Errorf("Cannot open the session: %w", err)
@@ -0,0 +1,169 @@
+package sqlrepo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/hectorgabucio/taterubot-dc/domain/discord"
+	"github.com/jmoiron/sqlx"
+)
+
+// DeadLetterRepository keeps the files of the failed deliveries in the database, the disk of the host may not outlive a restart
+type DeadLetterRepository struct {
+	db *sqlx.DB
+}
+
+type dbFailedDelivery struct {
+	ID          string    `db:"id"`
+	GuildID     string    `db:"guildid"`
+	ChannelID   string    `db:"channelid"`
+	MessageID   string    `db:"messageid"`
+	Kind        string    `db:"kind"`
+	Content     string    `db:"content"`
+	FileName    string    `db:"filename"`
+	ContentType string    `db:"contenttype"`
+	File        []byte    `db:"file"`
+	Payload     string    `db:"payload"`
+	Error       string    `db:"error"`
+	FailedAt    time.Time `db:"failedat"`
+}
+
+// deliveryPayload holds the parts of a delivery that only some kinds have
+type deliveryPayload struct {
+	Voice  discord.VoiceMessage   `json:"voice"`
+	Embeds []discord.MessageEmbed `json:"embeds"`
+	// EmbedImages are small, unlike the files of the other kinds
+	EmbedImages [][]byte         `json:"embed_images"`
+	Buttons     []discord.Button `json:"buttons"`
+	// AudioSent is only set once the delivery was kept, by SetAudioSent
+	AudioSent *domain.AudioSentEvent `json:"audio_sent,omitempty"`
+}
+
+func (r DeadLetterRepository) Save(delivery domain.FailedDelivery) error {
+	embeds := make([]discord.MessageEmbed, len(delivery.Embeds))
+	for i, embed := range delivery.Embeds {
+		// the data of the images is kept apart, the readers were already read
+		if embed.Image != nil {
+			embed.Image = &discord.File{Name: embed.Image.Name, ContentType: embed.Image.ContentType}
+		}
+		embeds[i] = embed
+	}
+	payload, err := json.Marshal(deliveryPayload{Voice: delivery.Voice, Embeds: embeds, EmbedImages: delivery.EmbedImages, Buttons: delivery.Buttons, AudioSent: delivery.AudioSent})
+	if err != nil {
+		return fmt.Errorf("err encoding failed delivery: %w", err)
+	}
+	row := dbFailedDelivery{
+		ID:          delivery.ID,
+		GuildID:     delivery.GuildID,
+		ChannelID:   delivery.ChannelID,
+		MessageID:   delivery.MessageID,
+		Kind:        string(delivery.Kind),
+		Content:     delivery.Content,
+		FileName:    delivery.FileName,
+		ContentType: delivery.ContentType,
+		File:        delivery.File,
+		Payload:     string(payload),
+		Error:       delivery.Error,
+		FailedAt:    delivery.FailedAt,
+	}
+	_, err = r.db.NamedExec("INSERT INTO failed_deliveries (id, guildid, channelid, messageid, kind, content, filename, contenttype, file, payload, error, failedat) "+
+		"VALUES (:id, :guildid, :channelid, :messageid, :kind, :content, :filename, :contenttype, :file, :payload, :error, :failedat)", row)
+	if err != nil {
+		return fmt.Errorf("err save failed delivery: %w", err)
+	}
+	return nil
+}
+
+func (r DeadLetterRepository) ListByGuild(guildID string) ([]domain.FailedDelivery, error) {
+	var rows []dbFailedDelivery
+	err := r.db.Select(&rows, "SELECT id, guildid, channelid, messageid, kind, content, filename, contenttype, NULL AS file, payload, error, failedat "+
+		"FROM failed_deliveries WHERE guildid = $1 ORDER BY failedat", guildID)
+	if err != nil {
+		return nil, fmt.Errorf("err list failed deliveries: %w", err)
+	}
+	deliveries := make([]domain.FailedDelivery, 0, len(rows))
+	for _, row := range rows {
+		delivery, err := row.toDomain()
+		if err != nil {
+			return nil, err
+		}
+		delivery.EmbedImages = nil
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (r DeadLetterRepository) Get(id string) (domain.FailedDelivery, bool, error) {
+	var row dbFailedDelivery
+	if err := r.db.Get(&row, "SELECT * FROM failed_deliveries WHERE id = $1", id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.FailedDelivery{}, false, nil
+		}
+		return domain.FailedDelivery{}, false, fmt.Errorf("err get failed delivery: %w", err)
+	}
+	delivery, err := row.toDomain()
+	if err != nil {
+		return domain.FailedDelivery{}, false, err
+	}
+	return delivery, true, nil
+}
+
+func (r DeadLetterRepository) SetAudioSent(id string, evt domain.AudioSentEvent) error {
+	var encoded string
+	if err := r.db.Get(&encoded, "SELECT payload FROM failed_deliveries WHERE id = $1", id); err != nil {
+		return fmt.Errorf("err get failed delivery payload: %w", err)
+	}
+	var payload deliveryPayload
+	if err := json.Unmarshal([]byte(encoded), &payload); err != nil {
+		return fmt.Errorf("err decoding failed delivery %s: %w", id, err)
+	}
+	payload.AudioSent = &evt
+	updated, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("err encoding failed delivery: %w", err)
+	}
+	if _, err := r.db.Exec("UPDATE failed_deliveries SET payload = $1 WHERE id = $2", string(updated), id); err != nil {
+		return fmt.Errorf("err update failed delivery: %w", err)
+	}
+	return nil
+}
+
+func (r DeadLetterRepository) Delete(id string) error {
+	if _, err := r.db.Exec("DELETE FROM failed_deliveries WHERE id = $1", id); err != nil {
+		return fmt.Errorf("err delete failed delivery: %w", err)
+	}
+	return nil
+}
+
+func (row dbFailedDelivery) toDomain() (domain.FailedDelivery, error) {
+	var payload deliveryPayload
+	if err := json.Unmarshal([]byte(row.Payload), &payload); err != nil {
+		return domain.FailedDelivery{}, fmt.Errorf("err decoding failed delivery %s: %w", row.ID, err)
+	}
+	return domain.FailedDelivery{
+		ID:          row.ID,
+		GuildID:     row.GuildID,
+		ChannelID:   row.ChannelID,
+		MessageID:   row.MessageID,
+		Kind:        domain.DeliveryKind(row.Kind),
+		Content:     row.Content,
+		FileName:    row.FileName,
+		ContentType: row.ContentType,
+		File:        row.File,
+		Voice:       payload.Voice,
+		Embeds:      payload.Embeds,
+		EmbedImages: payload.EmbedImages,
+		Buttons:     payload.Buttons,
+		AudioSent:   payload.AudioSent,
+		Error:       row.Error,
+		FailedAt:    row.FailedAt,
+	}, nil
+}
+
+func NewDeadLetterRepository(db *sqlx.DB) *DeadLetterRepository {
+	return &DeadLetterRepository{db: db}
+}
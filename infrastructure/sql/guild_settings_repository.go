@@ -0,0 +1,85 @@
+package sqlrepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type GuildSettingsRepository struct {
+	db *sqlx.DB
+}
+
+type dbGuildSettings struct {
+	GuildID       string `db:"guildid"`
+	StartCue      bool   `db:"startcue"`
+	AudioFormat   string `db:"audioformat"`
+	AudioBitrate  int    `db:"audiobitrate"`
+	VoiceMessages bool   `db:"voicemessages"`
+	Normalize     bool   `db:"normalize"`
+	TrimSilence   bool   `db:"trimsilence"`
+	SilenceDB     int    `db:"silencethreshold"`
+	AnonymousChID string `db:"anonymouschannelid"`
+}
+
+func convertSettingsToModel(settings domain.GuildSettings) dbGuildSettings {
+	return dbGuildSettings{
+		GuildID:       settings.GuildID,
+		StartCue:      settings.StartCueEnabled,
+		AudioFormat:   string(settings.AudioFormat),
+		AudioBitrate:  settings.AudioBitrate,
+		VoiceMessages: settings.VoiceMessagesEnabled,
+		Normalize:     settings.NormalizeEnabled,
+		TrimSilence:   settings.TrimSilenceEnabled,
+		SilenceDB:     settings.SilenceThreshold,
+		AnonymousChID: settings.AnonymousChannelID,
+	}
+}
+
+func convertSettingsToDomain(model dbGuildSettings) domain.GuildSettings {
+	format, ok := domain.ParseAudioFormat(model.AudioFormat)
+	if !ok {
+		format = domain.DefaultAudioFormat
+	}
+	return domain.GuildSettings{
+		GuildID:              model.GuildID,
+		StartCueEnabled:      model.StartCue,
+		VoiceMessagesEnabled: model.VoiceMessages,
+		AudioFormat:          format,
+		AudioBitrate:         model.AudioBitrate,
+		NormalizeEnabled:     model.Normalize,
+		TrimSilenceEnabled:   model.TrimSilence,
+		SilenceThreshold:     model.SilenceDB,
+		AnonymousChannelID:   model.AnonymousChID,
+	}
+}
+
+func (r GuildSettingsRepository) Get(guildID string) (domain.GuildSettings, error) {
+	var row dbGuildSettings
+	if err := r.db.Get(&row, "select * from guildsettings where guildid = $1", guildID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.DefaultGuildSettings(guildID), nil
+		}
+		return domain.GuildSettings{}, fmt.Errorf("err get guild settings:%w", err)
+	}
+	return convertSettingsToDomain(row), nil
+}
+
+func (r GuildSettingsRepository) Save(settings domain.GuildSettings) error {
+	_, err := r.db.NamedExec("INSERT INTO guildsettings (guildid, startcue, audioformat, audiobitrate, voicemessages, normalize, trimsilence, silencethreshold, anonymouschannelid) "+
+		"VALUES (:guildid, :startcue, :audioformat, :audiobitrate, :voicemessages, :normalize, :trimsilence, :silencethreshold, :anonymouschannelid) "+
+		"ON CONFLICT (guildid) DO UPDATE SET startcue = EXCLUDED.startcue, audioformat = EXCLUDED.audioformat, audiobitrate = EXCLUDED.audiobitrate, voicemessages = EXCLUDED.voicemessages, "+
+		"normalize = EXCLUDED.normalize, trimsilence = EXCLUDED.trimsilence, silencethreshold = EXCLUDED.silencethreshold, "+
+		"anonymouschannelid = EXCLUDED.anonymouschannelid", convertSettingsToModel(settings))
+	if err != nil {
+		return fmt.Errorf("err save guild settings: %w", err)
+	}
+	return nil
+}
+
+func NewGuildSettingsRepository(db *sqlx.DB) *GuildSettingsRepository {
+	return &GuildSettingsRepository{db: db}
+}
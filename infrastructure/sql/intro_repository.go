@@ -0,0 +1,86 @@
+package sqlrepo
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type IntroRepository struct {
+	db *sqlx.DB
+}
+
+type dbIntro struct {
+	GuildID string `db:"guildid"`
+	UserID  string `db:"userid"`
+	Frames  []byte `db:"frames"`
+}
+
+// encodeFrames stores every opus frame prefixed by its length, opus frames are never bigger than 1275 bytes
+func encodeFrames(frames [][]byte) []byte {
+	var encoded []byte
+	for _, frame := range frames {
+		size := make([]byte, 2)
+		binary.BigEndian.PutUint16(size, uint16(len(frame)))
+		encoded = append(encoded, size...)
+		encoded = append(encoded, frame...)
+	}
+	return encoded
+}
+
+func decodeFrames(encoded []byte) ([][]byte, error) {
+	var frames [][]byte
+	for len(encoded) > 0 {
+		if len(encoded) < 2 {
+			return nil, errors.New("truncated frame length")
+		}
+		size := int(binary.BigEndian.Uint16(encoded))
+		encoded = encoded[2:]
+		if len(encoded) < size {
+			return nil, errors.New("truncated frame")
+		}
+		frames = append(frames, encoded[:size])
+		encoded = encoded[size:]
+	}
+	return frames, nil
+}
+
+func (r IntroRepository) Get(guildID string, userID string) (domain.Intro, bool, error) {
+	var row dbIntro
+	if err := r.db.Get(&row, "select * from intros where guildid = $1 and userid = $2", guildID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Intro{}, false, nil
+		}
+		return domain.Intro{}, false, fmt.Errorf("err get intro:%w", err)
+	}
+	frames, err := decodeFrames(row.Frames)
+	if err != nil {
+		return domain.Intro{}, false, fmt.Errorf("err decoding intro frames:%w", err)
+	}
+	return domain.Intro{GuildID: row.GuildID, UserID: row.UserID, Frames: frames}, true, nil
+}
+
+func (r IntroRepository) Save(intro domain.Intro) error {
+	row := dbIntro{GuildID: intro.GuildID, UserID: intro.UserID, Frames: encodeFrames(intro.Frames)}
+	_, err := r.db.NamedExec("INSERT INTO intros (guildid, userid, frames) VALUES (:guildid, :userid, :frames) "+
+		"ON CONFLICT (guildid, userid) DO UPDATE SET frames = EXCLUDED.frames", row)
+	if err != nil {
+		return fmt.Errorf("err save intro: %w", err)
+	}
+	return nil
+}
+
+func (r IntroRepository) Delete(guildID string, userID string) error {
+	if _, err := r.db.Exec("DELETE FROM intros WHERE guildid = $1 AND userid = $2", guildID, userID); err != nil {
+		return fmt.Errorf("err delete intro: %w", err)
+	}
+	return nil
+}
+
+func NewIntroRepository(db *sqlx.DB) *IntroRepository {
+	return &IntroRepository{db: db}
+}
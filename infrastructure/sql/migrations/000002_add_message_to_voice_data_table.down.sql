@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS public.voicedata_userid_idx;
+ALTER TABLE public.voicedata DROP COLUMN IF EXISTS attachmenturl;
+ALTER TABLE public.voicedata DROP COLUMN IF EXISTS channelid;
+ALTER TABLE public.voicedata DROP COLUMN IF EXISTS messageid;
@@ -0,0 +1,4 @@
+ALTER TABLE public.voicedata ADD COLUMN IF NOT EXISTS messageid varchar NOT NULL DEFAULT '';
+ALTER TABLE public.voicedata ADD COLUMN IF NOT EXISTS channelid varchar NOT NULL DEFAULT '';
+ALTER TABLE public.voicedata ADD COLUMN IF NOT EXISTS attachmenturl varchar NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS voicedata_userid_idx ON public.voicedata USING btree (guildid, userid);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public.guildsettings;
\ No newline at end of file
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS public.guildsettings (
+                                  guildid varchar NOT NULL,
+                                  startcue boolean NOT NULL DEFAULT true,
+                                  CONSTRAINT guildsettings_pk PRIMARY KEY (guildid)
+);
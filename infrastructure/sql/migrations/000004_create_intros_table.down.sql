@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public.intros;
\ No newline at end of file
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS public.intros (
+                                  guildid varchar NOT NULL,
+                                  userid varchar NOT NULL,
+                                  frames bytea NOT NULL,
+                                  CONSTRAINT intros_pk PRIMARY KEY (guildid, userid)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public.sounds;
\ No newline at end of file
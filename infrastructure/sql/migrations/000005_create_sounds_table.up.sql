@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS public.sounds (
+                                  guildid varchar NOT NULL,
+                                  "name" varchar NOT NULL,
+                                  filekey varchar NOT NULL,
+                                  savedby varchar NOT NULL,
+                                  plays integer NOT NULL DEFAULT 0,
+                                  createdat timestamptz NOT NULL,
+                                  CONSTRAINT sounds_pk PRIMARY KEY (guildid, "name")
+);
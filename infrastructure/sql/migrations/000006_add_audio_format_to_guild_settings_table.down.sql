@@ -0,0 +1,2 @@
+ALTER TABLE public.guildsettings DROP COLUMN IF EXISTS audiobitrate;
+ALTER TABLE public.guildsettings DROP COLUMN IF EXISTS audioformat;
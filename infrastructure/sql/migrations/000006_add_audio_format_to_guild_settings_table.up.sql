@@ -0,0 +1,2 @@
+ALTER TABLE public.guildsettings ADD COLUMN IF NOT EXISTS audioformat varchar NOT NULL DEFAULT 'mp3';
+ALTER TABLE public.guildsettings ADD COLUMN IF NOT EXISTS audiobitrate integer NOT NULL DEFAULT 96;
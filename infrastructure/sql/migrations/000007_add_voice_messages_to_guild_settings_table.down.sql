@@ -0,0 +1 @@
+ALTER TABLE public.guildsettings DROP COLUMN IF EXISTS voicemessages;
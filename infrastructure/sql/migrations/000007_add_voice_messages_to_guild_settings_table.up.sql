@@ -0,0 +1 @@
+ALTER TABLE public.guildsettings ADD COLUMN IF NOT EXISTS voicemessages boolean NOT NULL DEFAULT true;
@@ -0,0 +1,3 @@
+ALTER TABLE public.guildsettings DROP COLUMN IF EXISTS normalize;
+ALTER TABLE public.guildsettings DROP COLUMN IF EXISTS trimsilence;
+ALTER TABLE public.guildsettings DROP COLUMN IF EXISTS silencethreshold;
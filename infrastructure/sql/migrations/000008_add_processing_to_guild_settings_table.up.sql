@@ -0,0 +1,3 @@
+ALTER TABLE public.guildsettings ADD COLUMN IF NOT EXISTS normalize boolean NOT NULL DEFAULT false;
+ALTER TABLE public.guildsettings ADD COLUMN IF NOT EXISTS trimsilence boolean NOT NULL DEFAULT false;
+ALTER TABLE public.guildsettings ADD COLUMN IF NOT EXISTS silencethreshold integer NOT NULL DEFAULT -50;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS public.voice_effects (
+                                  guildid varchar NOT NULL,
+                                  userid varchar NOT NULL,
+                                  effect varchar NOT NULL,
+                                  CONSTRAINT voice_effects_pk PRIMARY KEY (guildid, userid)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.reveal_audits;
+ALTER TABLE public.voicedata DROP COLUMN IF EXISTS anonymous;
+ALTER TABLE public.guildsettings DROP COLUMN IF EXISTS anonymouschannelid;
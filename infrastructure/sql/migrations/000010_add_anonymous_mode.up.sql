@@ -0,0 +1,9 @@
+ALTER TABLE public.guildsettings ADD COLUMN IF NOT EXISTS anonymouschannelid varchar NOT NULL DEFAULT '';
+ALTER TABLE public.voicedata ADD COLUMN IF NOT EXISTS anonymous boolean NOT NULL DEFAULT false;
+CREATE TABLE IF NOT EXISTS public.reveal_audits (
+                                  guildid varchar NOT NULL,
+                                  messageid varchar NOT NULL,
+                                  moderatorid varchar NOT NULL,
+                                  reason varchar NOT NULL,
+                                  createdat timestamptz NOT NULL
+);
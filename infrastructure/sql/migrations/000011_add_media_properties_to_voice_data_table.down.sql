@@ -0,0 +1,4 @@
+ALTER TABLE public.voicedata DROP COLUMN IF EXISTS size;
+ALTER TABLE public.voicedata DROP COLUMN IF EXISTS bitrate;
+ALTER TABLE public.voicedata DROP COLUMN IF EXISTS channels;
+ALTER TABLE public.voicedata DROP COLUMN IF EXISTS samplerate;
@@ -0,0 +1,4 @@
+ALTER TABLE public.voicedata ADD COLUMN IF NOT EXISTS samplerate integer NOT NULL DEFAULT 0;
+ALTER TABLE public.voicedata ADD COLUMN IF NOT EXISTS channels integer NOT NULL DEFAULT 0;
+ALTER TABLE public.voicedata ADD COLUMN IF NOT EXISTS bitrate integer NOT NULL DEFAULT 0;
+ALTER TABLE public.voicedata ADD COLUMN IF NOT EXISTS size bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE public.voicedata DROP COLUMN IF EXISTS latepackets;
+ALTER TABLE public.voicedata DROP COLUMN IF EXISTS droppedpackets;
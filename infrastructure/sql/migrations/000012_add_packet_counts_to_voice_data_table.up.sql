@@ -0,0 +1,2 @@
+ALTER TABLE public.voicedata ADD COLUMN IF NOT EXISTS droppedpackets bigint NOT NULL DEFAULT 0;
+ALTER TABLE public.voicedata ADD COLUMN IF NOT EXISTS latepackets bigint NOT NULL DEFAULT 0;
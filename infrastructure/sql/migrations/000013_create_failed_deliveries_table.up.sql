@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS public.failed_deliveries (
+                                  id varchar NOT NULL,
+                                  guildid varchar NOT NULL,
+                                  channelid varchar NOT NULL,
+                                  messageid varchar NOT NULL,
+                                  kind varchar NOT NULL,
+                                  content varchar NOT NULL,
+                                  filename varchar NOT NULL,
+                                  contenttype varchar NOT NULL,
+                                  file bytea,
+                                  payload varchar NOT NULL,
+                                  error varchar NOT NULL,
+                                  failedat timestamptz NOT NULL,
+                                  CONSTRAINT failed_deliveries_pk PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS failed_deliveries_guildid_idx ON public.failed_deliveries (guildid, failedat);
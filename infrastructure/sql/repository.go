@@ -1,6 +1,8 @@
 package sqlrepo
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/hectorgabucio/taterubot-dc/domain"
 	"github.com/jmoiron/sqlx"
@@ -12,46 +14,88 @@ type VoiceDataRepository struct {
 }
 
 type dbVoiceData struct {
-	ID        string    `db:"id"`
-	GuildD    string    `db:"guildid"`
-	Timestamp time.Time `db:"timestamp"`
-	Name      string    `db:"name"`
-	UserID    string    `db:"userid"`
-	Duration  int       `db:"duration"`
+	ID             string    `db:"id"`
+	GuildD         string    `db:"guildid"`
+	Timestamp      time.Time `db:"timestamp"`
+	Name           string    `db:"name"`
+	UserID         string    `db:"userid"`
+	Duration       int       `db:"duration"`
+	MessageID      string    `db:"messageid"`
+	ChannelID      string    `db:"channelid"`
+	AttachmentURL  string    `db:"attachmenturl"`
+	Anonymous      bool      `db:"anonymous"`
+	SampleRate     int       `db:"samplerate"`
+	Channels       int       `db:"channels"`
+	Bitrate        int       `db:"bitrate"`
+	Size           int64     `db:"size"`
+	DroppedPackets int64     `db:"droppedpackets"`
+	LatePackets    int64     `db:"latepackets"`
 }
 
 func convertToModel(domainVoice domain.VoiceData) dbVoiceData {
 	return dbVoiceData{
-		ID:        domainVoice.ID,
-		GuildD:    domainVoice.GuildID,
-		Timestamp: domainVoice.Timestamp,
-		Name:      domainVoice.Name,
-		UserID:    domainVoice.UserID,
-		Duration:  domainVoice.Duration,
+		ID:             domainVoice.ID,
+		GuildD:         domainVoice.GuildID,
+		Timestamp:      domainVoice.Timestamp,
+		Name:           domainVoice.Name,
+		UserID:         domainVoice.UserID,
+		Duration:       domainVoice.Duration,
+		MessageID:      domainVoice.MessageID,
+		ChannelID:      domainVoice.ChannelID,
+		AttachmentURL:  domainVoice.AttachmentURL,
+		Anonymous:      domainVoice.Anonymous,
+		SampleRate:     domainVoice.SampleRate,
+		Channels:       domainVoice.Channels,
+		Bitrate:        domainVoice.Bitrate,
+		Size:           domainVoice.Size,
+		DroppedPackets: domainVoice.DroppedPackets,
+		LatePackets:    domainVoice.LatePackets,
 	}
 }
 
 func convertToDomain(modelVoice dbVoiceData) domain.VoiceData {
 	return domain.VoiceData{
-		GuildID:   modelVoice.GuildD,
-		ID:        modelVoice.ID,
-		Timestamp: modelVoice.Timestamp,
-		Name:      modelVoice.Name,
-		UserID:    modelVoice.UserID,
-		Duration:  modelVoice.Duration,
+		GuildID:        modelVoice.GuildD,
+		ID:             modelVoice.ID,
+		Timestamp:      modelVoice.Timestamp,
+		Name:           modelVoice.Name,
+		UserID:         modelVoice.UserID,
+		Duration:       modelVoice.Duration,
+		MessageID:      modelVoice.MessageID,
+		ChannelID:      modelVoice.ChannelID,
+		AttachmentURL:  modelVoice.AttachmentURL,
+		Anonymous:      modelVoice.Anonymous,
+		SampleRate:     modelVoice.SampleRate,
+		Channels:       modelVoice.Channels,
+		Bitrate:        modelVoice.Bitrate,
+		Size:           modelVoice.Size,
+		DroppedPackets: modelVoice.DroppedPackets,
+		LatePackets:    modelVoice.LatePackets,
 	}
 }
 
 func (v VoiceDataRepository) Save(data domain.VoiceData) error {
 	model := convertToModel(data)
-	_, err := v.db.NamedExec("INSERT INTO voicedata (id, guildid, timestamp,name,userid,duration) "+
-		"VALUES (:id, :guildid, :timestamp, :name, :userid, :duration)", model)
+	_, err := v.db.NamedExec("INSERT INTO voicedata (id, guildid, timestamp,name,userid,duration,messageid,channelid,attachmenturl,anonymous,samplerate,channels,bitrate,size,droppedpackets,latepackets) "+
+		"VALUES (:id, :guildid, :timestamp, :name, :userid, :duration, :messageid, :channelid, :attachmenturl, :anonymous, :samplerate, :channels, :bitrate, :size, :droppedpackets, :latepackets)", model)
 	if err != nil {
 		return fmt.Errorf("err save voice data: %w", err)
 	}
 	return nil
 }
 
+func (v VoiceDataRepository) Update(data domain.VoiceData) error {
+	model := convertToModel(data)
+	_, err := v.db.NamedExec("UPDATE voicedata SET timestamp = :timestamp, name = :name, duration = :duration, "+
+		"messageid = :messageid, channelid = :channelid, attachmenturl = :attachmenturl, "+
+		"samplerate = :samplerate, channels = :channels, bitrate = :bitrate, size = :size, "+
+		"droppedpackets = :droppedpackets, latepackets = :latepackets WHERE id = :id", model)
+	if err != nil {
+		return fmt.Errorf("err update voice data: %w", err)
+	}
+	return nil
+}
+
 func (v VoiceDataRepository) GetOnRange(guildID string, from time.Time, to time.Time) ([]domain.VoiceData, error) {
 	var rows []dbVoiceData
 	query := "select * from voicedata v where guildid = $1 and timestamp >= $2 and timestamp <= $3"
@@ -65,6 +109,32 @@ func (v VoiceDataRepository) GetOnRange(guildID string, from time.Time, to time.
 	return models, nil
 }
 
+func (v VoiceDataRepository) GetLastByUser(guildID string, userID string) (domain.VoiceData, bool, error) {
+	query := "select * from voicedata v where guildid = $1 and userid = $2 order by timestamp desc limit 1"
+	return v.getOne(query, guildID, userID)
+}
+
+func (v VoiceDataRepository) GetLastByGuild(guildID string) (domain.VoiceData, bool, error) {
+	query := "select * from voicedata v where guildid = $1 order by timestamp desc limit 1"
+	return v.getOne(query, guildID)
+}
+
+func (v VoiceDataRepository) GetByMessage(guildID string, messageID string) (domain.VoiceData, bool, error) {
+	query := "select * from voicedata v where guildid = $1 and messageid = $2 limit 1"
+	return v.getOne(query, guildID, messageID)
+}
+
+func (v VoiceDataRepository) getOne(query string, args ...any) (domain.VoiceData, bool, error) {
+	var row dbVoiceData
+	if err := v.db.Get(&row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.VoiceData{}, false, nil
+		}
+		return domain.VoiceData{}, false, fmt.Errorf("err get voice data:%w", err)
+	}
+	return convertToDomain(row), true, nil
+}
+
 func NewVoiceDataRepository(db *sqlx.DB) *VoiceDataRepository {
 	return &VoiceDataRepository{db: db}
 }
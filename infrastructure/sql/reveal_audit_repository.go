@@ -0,0 +1,35 @@
+package sqlrepo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type RevealAuditRepository struct {
+	db *sqlx.DB
+}
+
+type dbRevealAudit struct {
+	GuildID     string    `db:"guildid"`
+	MessageID   string    `db:"messageid"`
+	ModeratorID string    `db:"moderatorid"`
+	Reason      string    `db:"reason"`
+	CreatedAt   time.Time `db:"createdat"`
+}
+
+func (r RevealAuditRepository) Save(audit domain.RevealAudit) error {
+	row := dbRevealAudit{GuildID: audit.GuildID, MessageID: audit.MessageID, ModeratorID: audit.ModeratorID, Reason: audit.Reason, CreatedAt: audit.CreatedAt}
+	_, err := r.db.NamedExec("INSERT INTO reveal_audits (guildid, messageid, moderatorid, reason, createdat) "+
+		"VALUES (:guildid, :messageid, :moderatorid, :reason, :createdat)", row)
+	if err != nil {
+		return fmt.Errorf("err save reveal audit: %w", err)
+	}
+	return nil
+}
+
+func NewRevealAuditRepository(db *sqlx.DB) *RevealAuditRepository {
+	return &RevealAuditRepository{db: db}
+}
@@ -0,0 +1,86 @@
+package sqlrepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type SoundRepository struct {
+	db *sqlx.DB
+}
+
+type dbSound struct {
+	GuildID   string    `db:"guildid"`
+	Name      string    `db:"name"`
+	FileKey   string    `db:"filekey"`
+	SavedBy   string    `db:"savedby"`
+	Plays     int       `db:"plays"`
+	CreatedAt time.Time `db:"createdat"`
+}
+
+func convertSoundToModel(sound domain.Sound) dbSound {
+	return dbSound{
+		GuildID:   sound.GuildID,
+		Name:      sound.Name,
+		FileKey:   sound.FileKey,
+		SavedBy:   sound.SavedBy,
+		Plays:     sound.Plays,
+		CreatedAt: sound.CreatedAt,
+	}
+}
+
+func convertSoundToDomain(model dbSound) domain.Sound {
+	return domain.Sound{
+		GuildID:   model.GuildID,
+		Name:      model.Name,
+		FileKey:   model.FileKey,
+		SavedBy:   model.SavedBy,
+		Plays:     model.Plays,
+		CreatedAt: model.CreatedAt,
+	}
+}
+
+func (r SoundRepository) Save(sound domain.Sound) error {
+	_, err := r.db.NamedExec("INSERT INTO sounds (guildid, name, filekey, savedby, plays, createdat) "+
+		"VALUES (:guildid, :name, :filekey, :savedby, :plays, :createdat)", convertSoundToModel(sound))
+	if err != nil {
+		return fmt.Errorf("err save sound: %w", err)
+	}
+	return nil
+}
+
+func (r SoundRepository) Get(guildID string, name string) (domain.Sound, bool, error) {
+	var row dbSound
+	if err := r.db.Get(&row, "select * from sounds where guildid = $1 and name = $2", guildID, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Sound{}, false, nil
+		}
+		return domain.Sound{}, false, fmt.Errorf("err get sound:%w", err)
+	}
+	return convertSoundToDomain(row), true, nil
+}
+
+func (r SoundRepository) SearchNames(guildID string, prefix string, limit int) ([]string, error) {
+	var names []string
+	err := r.db.Select(&names, "select name from sounds where guildid = $1 and name like $2 || '%' order by plays desc, name limit $3", guildID, prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("err search sound names:%w", err)
+	}
+	return names, nil
+}
+
+func (r SoundRepository) IncrementPlays(guildID string, name string) error {
+	if _, err := r.db.Exec("UPDATE sounds SET plays = plays + 1 WHERE guildid = $1 AND name = $2", guildID, name); err != nil {
+		return fmt.Errorf("err increment sound plays: %w", err)
+	}
+	return nil
+}
+
+func NewSoundRepository(db *sqlx.DB) *SoundRepository {
+	return &SoundRepository{db: db}
+}
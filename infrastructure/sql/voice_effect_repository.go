@@ -0,0 +1,55 @@
+package sqlrepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/hectorgabucio/taterubot-dc/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type VoiceEffectRepository struct {
+	db *sqlx.DB
+}
+
+type dbVoiceEffect struct {
+	GuildID string `db:"guildid"`
+	UserID  string `db:"userid"`
+	Effect  string `db:"effect"`
+}
+
+func (r VoiceEffectRepository) Get(guildID string, userID string) (domain.VoiceEffect, error) {
+	var row dbVoiceEffect
+	if err := r.db.Get(&row, "select * from voice_effects where guildid = $1 and userid = $2", guildID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.VoiceEffectNone, nil
+		}
+		return domain.VoiceEffectNone, fmt.Errorf("err get voice effect:%w", err)
+	}
+	effect, ok := domain.ParseVoiceEffect(row.Effect)
+	if !ok {
+		return domain.VoiceEffectNone, fmt.Errorf("unknown stored voice effect %s", row.Effect)
+	}
+	return effect, nil
+}
+
+func (r VoiceEffectRepository) Save(guildID string, userID string, effect domain.VoiceEffect) error {
+	if effect == domain.VoiceEffectNone {
+		if _, err := r.db.Exec("DELETE FROM voice_effects WHERE guildid = $1 AND userid = $2", guildID, userID); err != nil {
+			return fmt.Errorf("err delete voice effect: %w", err)
+		}
+		return nil
+	}
+	row := dbVoiceEffect{GuildID: guildID, UserID: userID, Effect: effect.Name()}
+	_, err := r.db.NamedExec("INSERT INTO voice_effects (guildid, userid, effect) VALUES (:guildid, :userid, :effect) "+
+		"ON CONFLICT (guildid, userid) DO UPDATE SET effect = EXCLUDED.effect", row)
+	if err != nil {
+		return fmt.Errorf("err save voice effect: %w", err)
+	}
+	return nil
+}
+
+func NewVoiceEffectRepository(db *sqlx.DB) *VoiceEffectRepository {
+	return &VoiceEffectRepository{db: db}
+}
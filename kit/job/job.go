@@ -0,0 +1,73 @@
+package job
+
+import (
+	"context"
+	"time"
+)
+
+// Priority orders the jobs waiting for a worker, higher priorities go first.
+type Priority int
+
+const (
+	// PriorityEmbed is for the embeds of messages already sent, they can wait the longest.
+	PriorityEmbed Priority = iota
+	// PriorityEncode is for encoding and rendering audio after a recording ends.
+	PriorityEncode
+	// PriorityUpload is for sending audio already encoded, finishing it frees its files the soonest.
+	PriorityUpload
+)
+
+// Priorities returns every priority, from the highest to the lowest.
+func Priorities() []Priority {
+	return []Priority{PriorityUpload, PriorityEncode, PriorityEmbed}
+}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityEmbed:
+		return "embed"
+	case PriorityEncode:
+		return "encode"
+	case PriorityUpload:
+		return "upload"
+	default:
+		return "unknown"
+	}
+}
+
+// Job is a piece of work run by a worker of the Queue.
+type Job struct {
+	// GuildID keeps a busy guild from taking every worker, guilds take turns within a priority.
+	GuildID  string
+	Priority Priority
+	Run      func()
+}
+
+// Queue defines the expected behaviour from a job queue.
+type Queue interface {
+	// Submit queues the job, blocking while the queue is full until ctx is done.
+	Submit(ctx context.Context, job Job) error
+	// Stats returns the metrics of every priority.
+	Stats() map[Priority]Stats
+	Close() error
+}
+
+// Stats are the metrics of the jobs of a priority since the queue started.
+type Stats struct {
+	// Depth is how many jobs are waiting for a worker.
+	Depth int
+	// Started jobs got a worker, Completed ones finished running.
+	Started   int
+	Completed int
+	// TotalWait and MaxWait are how long the started jobs waited for a worker.
+	TotalWait time.Duration
+	MaxWait   time.Duration
+}
+
+// AverageWait is how long the started jobs waited for a worker on average.
+func (s Stats) AverageWait() time.Duration {
+	if s.Started == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Started)
+}
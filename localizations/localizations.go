@@ -1,6 +1,6 @@
 // Code generated by go-localize; DO NOT EDIT.
 // This file was generated by robots at
-// 2022-05-27 20:09:47.3578026 +0200 CEST m=+0.006934201
+// 2026-10-19 12:23:57.9282520 +0200 CEST m=+0.006934201
 
 package localizations
 
@@ -23,11 +23,73 @@ var localizations = map[string]string{
 	"en.texts.achievement_random_description_4":         "Because you are the best of the best",
 	"en.texts.achievement_random_description_5":         "You won this achievement. Maybe next time other person wins it, ha ha ha",
 	"en.texts.achievement_random_title":                 ":flushed: Because you deserve it!",
+	"en.texts.anonymous":                                ":detective: Anonymous",
+	"en.texts.caption_empty":                            ":pencil: Write the caption of your next voice message",
+	"en.texts.caption_set":                              ":pencil: Your next voice message will be titled **{{.caption}}**",
+	"en.texts.caption_too_long":                         ":pencil: Captions can be up to {{.max}} characters long",
+	"en.texts.command_timed_out":                        "This is taking longer than expected, try again in a while",
+	"en.texts.deliveries_empty":                         ":white_check_mark: Everything was delivered, there is nothing to replay",
+	"en.texts.deliveries_list":                          ":outbox_tray: These could not be delivered to Discord, send them again with `/deliveries replay`:\n{{.deliveries}}",
+	"en.texts.deliveries_more":                          "...and {{.count}} more",
+	"en.texts.delivery_not_found":                       ":x: There is no failed delivery with that ID in this server",
+	"en.texts.delivery_replay_failed":                   ":x: It still could not be delivered: {{.error}}",
+	"en.texts.delivery_replayed":                        ":white_check_mark: Delivered",
 	"en.texts.download_link_title":                      "Download link",
 	"en.texts.duration":                                 "Duration",
+	"en.texts.effect":                                   "Effect",
+	"en.texts.effect_cleared":                           ":microphone2: Your next recordings will have no effect",
+	"en.texts.effect_set":                               ":sparkles: Your next recordings will have the **{{.effect}}** effect",
+	"en.texts.effect_unavailable":                       ":no_entry: Voice effects are not available in this server",
+	"en.texts.effect_unknown":                           ":shrug: There is no effect called **{{.effect}}**",
+	"en.texts.export_video":                             "Export video",
 	"en.texts.hello":                                    ">>> :wave: Hey! I am **{{.botName}}**. Im capable of recording everything you say and transform it into an audio message.\n:flag_gb: I am configured to talk to you in english.\n:microphone2: To start recording, enter the channel **{{.voiceChannel}}**, wait until discord shows :green_circle: voice connected and start talking to me.\n:sound: When you get out the channel, I will send the audio message.\n\nMade with :heart: by Héctor <https://github.com/hectorgabucio/taterubot-dc> and tested by Aranchi and Raulio.",
+	"en.texts.intro_cleared":                            ":wastebasket: Your intro was removed",
+	"en.texts.intro_empty":                              ":mute: That recording had no audio, try again with /intro set",
+	"en.texts.intro_not_found":                          ":shrug: I couldn't find a voice message with audio there",
+	"en.texts.intro_saved":                              ":tada: Intro saved! I will play these **{{.seconds}} seconds** every time you join a voice channel",
+	"en.texts.intro_waiting_recording":                  ":microphone2: Enter **{{.voiceChannel}}** and record your intro, I will keep your next recording instead of sending it",
+	"en.texts.play_in_voice":                            "Play in voice",
+	"en.texts.playback_busy":                            ":red_circle: I am recording right now, try again when I finish",
+	"en.texts.playback_not_found":                       ":shrug: I couldn't find any voice message to play",
+	"en.texts.playback_not_in_voice":                    ":mute: Join a voice channel first so I can play it there",
+	"en.texts.playback_queue_full":                      ":hourglass: There are too many voice messages waiting to be played, try again later",
+	"en.texts.playback_queued":                          ":loud_sound: Queued, it will be played in position **{{.position}}**",
+	"en.texts.processing":                               ":level_slider: Processing",
+	"en.texts.processing_normalized":                    "normalized {{.gain}} dB",
+	"en.texts.processing_trimmed":                       "trimmed {{.seconds}}s of silence",
+	"en.texts.recording_interrupted":                    ":warning: {{.user}}, the connection to the voice channel was lost and could not be recovered, so your recording was finished early and what was captured until then was sent. Join the channel again to keep recording",
+	"en.texts.recording_interrupted_anonymous":          ":warning: The connection to the voice channel was lost and could not be recovered, so the anonymous recording was finished early and what was captured until then was sent. Join the channel again to keep recording",
+	"en.texts.remix":                                    "Remix",
+	"en.texts.remix_choose":                             ":control_knobs: Choose the effect of the remix",
+	"en.texts.remix_failed":                             ":x: I couldn't render the remix, try again later",
+	"en.texts.remix_message_not_found":                  ":shrug: I couldn't find the audio of that voice message",
+	"en.texts.remix_placeholder":                        "Effect",
+	"en.texts.remix_sent":                               ":sparkles: Remix with the **{{.effect}}** effect sent",
+	"en.texts.reveal_author":                            ":detective: That anonymous voice message was sent by {{.user}}. This reveal was recorded",
+	"en.texts.reveal_author_left":                       ":detective: The author of that anonymous voice message is not a member of this server anymore. This reveal was recorded",
+	"en.texts.reveal_not_anonymous":                     ":shrug: That is not an anonymous voice message",
+	"en.texts.reveal_reason_required":                   ":no_entry: Tell why you need to reveal the author",
+	"en.texts.settings_anonymous_cleared":               ":detective: There is no anonymous channel anymore",
+	"en.texts.settings_anonymous_set":                   ":detective: Recordings in {{.channel}} are now anonymous",
+	"en.texts.settings_anonymous_unavailable":           ":no_entry: Anonymous recordings need voice effects, which are not available in this bot",
+	"en.texts.settings_format_unavailable":              ":warning: **{{.format}}** is not available in this bot, pick one of: {{.available}}",
+	"en.texts.settings_start_cue_unavailable":           ":no_entry: The start cue needs ffmpeg, which is not installed in this bot",
+	"en.texts.settings_updated":                         ":gear: Setting **{{.setting}}** is now **{{.value}}**",
+	"en.texts.sound_exists":                             ":no_entry: There is already a sound called **{{.name}}**",
+	"en.texts.sound_invalid_name":                       ":no_entry: Sound names need at least one letter or number",
+	"en.texts.sound_message_not_found":                  ":shrug: I couldn't find a voice message with audio to save",
+	"en.texts.sound_saved":                              ":floppy_disk: Sound **{{.name}}** saved, play it with /sound play",
+	"en.texts.sound_unknown":                            ":shrug: There is no sound called **{{.name}}**",
 	"en.texts.stats":                                    ">>> :chart_with_upwards_trend: **Monthly stats**: \n\n:earth_africa: Global stats:\n- {{.globalDuration}} seconds of audio sent\n- {{.globalAmount}} audio files recorded\n- Median duration of {{.globalMedianDuration}} seconds",
 	"en.texts.stats-empty":                              ">>> :tired_face: Start sending voice messages to have stats!",
+	"en.texts.video_anonymous":                          "Anonymous",
+	"en.texts.video_caption":                            ":film_frames: Video of {{.link}}",
+	"en.texts.video_failed":                             ":x: I couldn't render the video, try again later",
+	"en.texts.video_message_not_found":                  ":shrug: I couldn't find the audio of that voice message",
+	"en.texts.video_sent":                               ":film_frames: Video sent",
+	"en.texts.video_too_large":                          ":package: The video of that voice message would be too large to upload in this server",
+	"en.texts.video_too_long":                           ":hourglass: Only voice messages up to {{.minutes}} minutes can be exported as videos",
+	"en.texts.video_unavailable":                        ":no_entry: Videos cannot be rendered in this bot",
 	"es.texts.achievement":                              ":trophy: Logro :trophy: ",
 	"es.texts.achievement_longest_audio_description":    "Ha podido hablar durante **{{.seconds}} segundos ** de golpe. Respira un poco!",
 	"es.texts.achievement_longest_audio_title":          ":lungs: Experto en aguantar la respiración",
@@ -39,11 +101,73 @@ var localizations = map[string]string{
 	"es.texts.achievement_random_description_4":         "Porque eres el mejor de los mejores, y punto.",
 	"es.texts.achievement_random_description_5":         "Has ganado este premio, y ya. Puede que la próxima vez lo gane otra persona, ja ja ja :robot:",
 	"es.texts.achievement_random_title":                 ":flushed: Porque te lo mereces, y porque me da la gana!",
+	"es.texts.anonymous":                                ":detective: Anónimo",
+	"es.texts.caption_empty":                            ":pencil: Escribe el título de tu próximo mensaje de voz",
+	"es.texts.caption_set":                              ":pencil: Tu próximo mensaje de voz se titulará **{{.caption}}**",
+	"es.texts.caption_too_long":                         ":pencil: Los títulos pueden tener hasta {{.max}} caracteres",
+	"es.texts.command_timed_out":                        "Esto está tardando más de lo esperado, inténtalo de nuevo en un rato",
+	"es.texts.deliveries_empty":                         ":white_check_mark: Se entregó todo, no hay nada que reenviar",
+	"es.texts.deliveries_list":                          ":outbox_tray: Esto no se pudo entregar a Discord, vuelve a enviarlo con `/deliveries replay`:\n{{.deliveries}}",
+	"es.texts.deliveries_more":                          "...y {{.count}} más",
+	"es.texts.delivery_not_found":                       ":x: No hay ninguna entrega fallida con ese ID en este servidor",
+	"es.texts.delivery_replay_failed":                   ":x: Sigue sin poder entregarse: {{.error}}",
+	"es.texts.delivery_replayed":                        ":white_check_mark: Entregado",
 	"es.texts.download_link_title":                      "Enlace de descarga",
 	"es.texts.duration":                                 "Duración",
+	"es.texts.effect":                                   "Efecto",
+	"es.texts.effect_cleared":                           ":microphone2: Tus próximas grabaciones no tendrán efecto",
+	"es.texts.effect_set":                               ":sparkles: Tus próximas grabaciones tendrán el efecto **{{.effect}}**",
+	"es.texts.effect_unavailable":                       ":no_entry: Los efectos de voz no están disponibles en este servidor",
+	"es.texts.effect_unknown":                           ":shrug: No hay ningún efecto llamado **{{.effect}}**",
+	"es.texts.export_video":                             "Exportar vídeo",
 	"es.texts.hello":                                    ">>> :wave: Hola! Soy **{{.botName}}**. Soy un bot capaz de grabar lo que dices y transformarlo en mensajes de voz.\n:flag_es: Estoy configurado para responderte en castellano.\n:microphone2: Para empezar a grabar, entra en el canal **{{.voiceChannel}}**, espera a que Discord muestre :green_circle: *Voz conectada* y empieza a hablarme.\n:sound: Cuando salgas del mismo, mandaré el mensaje de voz.\n\nHecho con :heart: por Héctor <https://github.com/hectorgabucio/taterubot-dc> y probado por Aranchi y Raulio.",
+	"es.texts.intro_cleared":                            ":wastebasket: Tu intro se ha eliminado",
+	"es.texts.intro_empty":                              ":mute: Esa grabación no tenía audio, prueba otra vez con /intro set",
+	"es.texts.intro_not_found":                          ":shrug: No he encontrado ningún mensaje de voz con audio ahí",
+	"es.texts.intro_saved":                              ":tada: ¡Intro guardada! Reproduciré estos **{{.seconds}} segundos** cada vez que entres en un canal de voz",
+	"es.texts.intro_waiting_recording":                  ":microphone2: Entra en **{{.voiceChannel}}** y graba tu intro, me quedaré tu próxima grabación en vez de enviarla",
+	"es.texts.play_in_voice":                            "Reproducir en voz",
+	"es.texts.playback_busy":                            ":red_circle: Estoy grabando ahora mismo, vuelve a intentarlo cuando termine",
+	"es.texts.playback_not_found":                       ":shrug: No he encontrado ningún mensaje de voz que reproducir",
+	"es.texts.playback_not_in_voice":                    ":mute: Entra primero en un canal de voz para que pueda reproducirlo allí",
+	"es.texts.playback_queue_full":                      ":hourglass: Hay demasiados mensajes de voz esperando a ser reproducidos, inténtalo más tarde",
+	"es.texts.playback_queued":                          ":loud_sound: En cola, se reproducirá en la posición **{{.position}}**",
+	"es.texts.processing":                               ":level_slider: Procesado",
+	"es.texts.processing_normalized":                    "normalizado {{.gain}} dB",
+	"es.texts.processing_trimmed":                       "recortados {{.seconds}}s de silencio",
+	"es.texts.recording_interrupted":                    ":warning: {{.user}}, se perdió la conexión con el canal de voz y no se pudo recuperar, así que tu grabación terminó antes de tiempo y se envió lo capturado hasta entonces. Vuelve a entrar al canal para seguir grabando",
+	"es.texts.recording_interrupted_anonymous":          ":warning: Se perdió la conexión con el canal de voz y no se pudo recuperar, así que la grabación anónima terminó antes de tiempo y se envió lo capturado hasta entonces. Vuelve a entrar al canal para seguir grabando",
+	"es.texts.remix":                                    "Remezclar",
+	"es.texts.remix_choose":                             ":control_knobs: Elige el efecto de la remezcla",
+	"es.texts.remix_failed":                             ":x: No he podido generar la remezcla, inténtalo más tarde",
+	"es.texts.remix_message_not_found":                  ":shrug: No he encontrado el audio de ese mensaje de voz",
+	"es.texts.remix_placeholder":                        "Efecto",
+	"es.texts.remix_sent":                               ":sparkles: Remezcla con el efecto **{{.effect}}** enviada",
+	"es.texts.reveal_author":                            ":detective: Ese mensaje de voz anónimo lo envió {{.user}}. Esta revelación ha quedado registrada",
+	"es.texts.reveal_author_left":                       ":detective: El autor de ese mensaje de voz anónimo ya no es miembro de este servidor. Esta revelación ha quedado registrada",
+	"es.texts.reveal_not_anonymous":                     ":shrug: Ese no es un mensaje de voz anónimo",
+	"es.texts.reveal_reason_required":                   ":no_entry: Explica por qué necesitas revelar al autor",
+	"es.texts.settings_anonymous_cleared":               ":detective: Ya no hay canal anónimo",
+	"es.texts.settings_anonymous_set":                   ":detective: Las grabaciones en {{.channel}} ahora son anónimas",
+	"es.texts.settings_anonymous_unavailable":           ":no_entry: Las grabaciones anónimas necesitan efectos de voz, que no están disponibles en este bot",
+	"es.texts.settings_format_unavailable":              ":warning: **{{.format}}** no está disponible en este bot, elige uno de: {{.available}}",
+	"es.texts.settings_start_cue_unavailable":           ":no_entry: El aviso de inicio necesita ffmpeg, que no está instalado en este bot",
+	"es.texts.settings_updated":                         ":gear: El ajuste **{{.setting}}** ahora es **{{.value}}**",
+	"es.texts.sound_exists":                             ":no_entry: Ya hay un sonido llamado **{{.name}}**",
+	"es.texts.sound_invalid_name":                       ":no_entry: Los nombres de sonido necesitan al menos una letra o número",
+	"es.texts.sound_message_not_found":                  ":shrug: No he encontrado ningún mensaje de voz con audio para guardar",
+	"es.texts.sound_saved":                              ":floppy_disk: Sonido **{{.name}}** guardado, reprodúcelo con /sound play",
+	"es.texts.sound_unknown":                            ":shrug: No hay ningún sonido llamado **{{.name}}**",
 	"es.texts.stats":                                    ">>> :chart_with_upwards_trend: **Estadísticas del mes**: \n\n:earth_africa: Estadísticas generales:\n- Un total de {{.globalDuration}} segundos enviados como audio\n- {{.globalAmount}} archivos de audio grabados\n- Duración media de {{.globalMedianDuration}} segundos",
 	"es.texts.stats-empty":                              ">>> :tired_face: Empieza a mandar mensajes de voz para tener estadísticas!",
+	"es.texts.video_anonymous":                          "Anónimo",
+	"es.texts.video_caption":                            ":film_frames: Vídeo de {{.link}}",
+	"es.texts.video_failed":                             ":x: No he podido crear el vídeo, inténtalo más tarde",
+	"es.texts.video_message_not_found":                  ":shrug: No he encontrado el audio de ese mensaje de voz",
+	"es.texts.video_sent":                               ":film_frames: Vídeo enviado",
+	"es.texts.video_too_large":                          ":package: El vídeo de ese mensaje de voz sería demasiado grande para subirlo a este servidor",
+	"es.texts.video_too_long":                           ":hourglass: Solo se pueden exportar como vídeo los mensajes de voz de hasta {{.minutes}} minutos",
+	"es.texts.video_unavailable":                        ":no_entry: Este bot no puede crear vídeos",
 }
 
 type Replacements map[string]interface{}